@@ -0,0 +1,120 @@
+// Package cedartest provides fixtures and assertions for integration tests
+// that write logs through a real, local filesystem backed bucket logger.
+package cedartest
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+)
+
+// Prefix is the bucket prefix of loggers created by NewLocalLogger.
+const Prefix = "cedartest"
+
+// manifestLogger is implemented by the bucket logger.
+type manifestLogger interface {
+	Manifest(context.Context, string) ([]logger.ManifestEntry, error)
+}
+
+// NewLocalLogger returns a bucket logger backed by a new temporary directory,
+// along with a function that removes the directory. The test fails
+// immediately if the logger cannot be created.
+func NewLocalLogger(t testing.TB, loggerOpts ...logger.BucketLoggerOption) (logger.Logger, func()) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "cedartest")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %v", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	l, err := logger.NewBucketLogger(context.Background(), options.Bucket{
+		Type:   options.PailLocal,
+		Name:   dir,
+		Prefix: Prefix,
+	}, loggerOpts...)
+	if err != nil {
+		cleanup()
+		t.Fatalf("creating bucket logger: %v", err)
+	}
+
+	return l, cleanup
+}
+
+// AssertChunkCount asserts that exactly expected chunks have been written to
+// the key.
+func AssertChunkCount(t testing.TB, l logger.Logger, key string, expected int) bool {
+	t.Helper()
+
+	ml, ok := l.(manifestLogger)
+	if !ok {
+		t.Errorf("logger of type %T does not record chunk manifests", l)
+		return false
+	}
+
+	entries, err := ml.Manifest(context.Background(), key)
+	if err != nil {
+		t.Errorf("getting manifest for key '%s': %v", key, err)
+		return false
+	}
+	if len(entries) != expected {
+		t.Errorf("expected %d chunks for key '%s', got %d", expected, key, len(entries))
+		return false
+	}
+
+	return true
+}
+
+// AssertLinesContain asserts that, for each of the given substrings, at
+// least one line logged to the key contains it.
+func AssertLinesContain(t testing.TB, l logger.Logger, key string, substrings ...string) bool {
+	t.Helper()
+
+	lines, err := ReadLines(l, key)
+	if err != nil {
+		t.Errorf("reading lines for key '%s': %v", key, err)
+		return false
+	}
+
+	ok := true
+	for _, substring := range substrings {
+		found := false
+		for _, line := range lines {
+			if strings.Contains(line, substring) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no line logged to key '%s' contains '%s'", key, substring)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// ReadLines returns all lines logged to the key, in order.
+func ReadLines(l logger.Logger, key string) ([]string, error) {
+	r, err := l.NewReadCloser(context.Background(), options.Read{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
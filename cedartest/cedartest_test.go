@@ -0,0 +1,50 @@
+package cedartest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTB records failed assertions instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingTB) Helper() {}
+
+func (t *recordingTB) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestLocalLogger(t *testing.T) {
+	ctx := context.Background()
+	l, cleanup := NewLocalLogger(t)
+	defer cleanup()
+
+	for _, data := range []string{"first line\nsecond line\n", "third line\n"} {
+		_, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte(data)})
+		require.NoError(t, err)
+	}
+
+	lines, err := ReadLines(l, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first line", "second line", "third line"}, lines)
+
+	assert.True(t, AssertChunkCount(t, l, "key", 2))
+	assert.True(t, AssertLinesContain(t, l, "key", "first", "third"))
+
+	rec := &recordingTB{TB: t}
+	assert.False(t, AssertChunkCount(rec, l, "key", 1))
+	assert.False(t, AssertLinesContain(rec, l, "key", "missing"))
+	assert.Len(t, rec.errors, 2)
+
+	lines, err = ReadLines(l, "empty")
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+}
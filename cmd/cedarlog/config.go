@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/julianedwards/cedar/server"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+const defaultAddr = ":8080"
+
+// config is the JSON configuration file of the cedarlog commands, e.g.:
+//
+//	{
+//		"addr": ":8080",
+//		"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+//		"auth": {"api_keys": {"<key>": "ci"}},
+//		"policies": [{"principal": "ci", "prefixes": ["project"], "permissions": ["read"]}]
+//	}
+type config struct {
+	// Addr is the address the server of the serve command listens on.
+	// Defaults to ":8080".
+	Addr string `json:"addr"`
+	// Bucket is the bucket whose logs are served or reported on, read
+	// only.
+	Bucket options.Bucket `json:"bucket"`
+	// Auth configures how clients are authenticated. The serve command
+	// requires it, so that serving without authentication is an explicit
+	// choice, e.g. with {"none": true}.
+	Auth *authConfig `json:"auth"`
+	// Policies, if set, restrict the keys that each authenticated client
+	// may read. Requires Auth.
+	Policies []logger.PrefixPolicy `json:"policies"`
+	// Audit, if set, records every read, along with the authenticated
+	// client, if any, in the audit trail of this bucket, usually the same
+	// bucket as Bucket with credentials that may only write audit events;
+	// see logger.WithAuditBucket.
+	Audit *options.Bucket `json:"audit"`
+}
+
+// authConfig configures exactly one way of authenticating clients, or
+// that clients are not authenticated.
+type authConfig struct {
+	// None disables authentication, so that every client may read every
+	// log.
+	None bool `json:"none"`
+	// APIKeys maps API keys to the IDs of their principals.
+	APIKeys map[string]string `json:"api_keys"`
+	OIDC    *options.OIDC     `json:"oidc"`
+}
+
+func (c *authConfig) validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(c.None && (len(c.APIKeys) > 0 || c.OIDC != nil), "cannot both disable and configure authentication")
+	catcher.NewWhen(len(c.APIKeys) > 0 && c.OIDC != nil, "cannot specify both API keys and OIDC authentication")
+	catcher.NewWhen(!c.None && len(c.APIKeys) == 0 && c.OIDC == nil, "must specify API keys or OIDC authentication, or disable authentication")
+
+	return catcher.Resolve()
+}
+
+// authenticator returns the authenticator of the server, which is nil if
+// authentication is disabled.
+func (c *config) authenticator(ctx context.Context) (server.Authenticator, error) {
+	switch {
+	case c.Auth == nil:
+		return nil, errors.New(`must configure "auth" to serve, or set "auth": {"none": true} to serve without authentication`)
+	case c.Auth.None:
+		return nil, nil
+	case c.Auth.OIDC != nil:
+		return server.NewOIDCAuthenticator(ctx, *c.Auth.OIDC)
+	default:
+		return server.NewAPIKeyAuthenticator(c.Auth.APIKeys)
+	}
+}
+
+func (c *config) validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.Wrap(c.Bucket.Validate(), "invalid bucket options")
+	if c.Auth != nil {
+		catcher.Wrap(c.Auth.validate(), "invalid auth")
+	}
+	catcher.NewWhen(len(c.Policies) > 0 && (c.Auth == nil || c.Auth.None), "policies require authentication")
+	if c.Audit != nil {
+		catcher.Wrap(c.Audit.Validate(), "invalid audit bucket options")
+	}
+
+	if c.Addr == "" {
+		c.Addr = defaultAddr
+	}
+
+	return catcher.Resolve()
+}
+
+func readConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading config file")
+	}
+
+	conf := &config{}
+	if err = json.Unmarshal(data, conf); err != nil {
+		return nil, errors.Wrapf(err, "decoding config file '%s'", path)
+	}
+	if err = conf.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid config file '%s'", path)
+	}
+
+	return conf, nil
+}
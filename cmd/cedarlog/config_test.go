@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfig(t *testing.T) {
+	write := func(t *testing.T, data string) string {
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		return path
+	}
+
+	t.Run("Local", func(t *testing.T) {
+		conf, err := readConfig(write(t, `{
+			"bucket": {
+				"type": "local",
+				"name": "/var/lib/cedar",
+				"prefix": "logs",
+				"operation_timeout": 5000000000,
+				"local_sharding": "date"
+			}
+		}`))
+		require.NoError(t, err)
+		assert.Equal(t, defaultAddr, conf.Addr)
+		assert.Equal(t, options.Bucket{
+			Type:             options.PailLocal,
+			Name:             "/var/lib/cedar",
+			Prefix:           "logs",
+			OperationTimeout: 5 * time.Second,
+			LocalSharding:    options.LocalShardDate,
+		}, conf.Bucket)
+	})
+	t.Run("S3", func(t *testing.T) {
+		conf, err := readConfig(write(t, `{
+			"addr": ":9090",
+			"bucket": {
+				"type": "s3",
+				"name": "logs-bucket",
+				"prefix": "logs",
+				"s3": {"key": "key", "secret": "secret", "region": "us-west-2", "multipart_part_size": 8388608, "disable_compression": true, "storage_class": "STANDARD_IA"}
+			}
+		}`))
+		require.NoError(t, err)
+		assert.Equal(t, ":9090", conf.Addr)
+		require.NotNil(t, conf.Bucket.S3)
+		assert.Equal(t, "key", conf.Bucket.S3.Key)
+		assert.Equal(t, "us-west-2", conf.Bucket.S3.Region)
+		assert.EqualValues(t, 8388608, conf.Bucket.S3.MultipartPartSize)
+		assert.True(t, conf.Bucket.S3.DisableCompression)
+		assert.Equal(t, options.S3StorageClassStandardIA, conf.Bucket.S3.StorageClass)
+	})
+	t.Run("Policies", func(t *testing.T) {
+		conf, err := readConfig(write(t, `{
+			"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+			"auth": {"api_keys": {"key": "ci"}},
+			"policies": [{"principal": "ci", "prefixes": ["project"], "permissions": ["read"]}]
+		}`))
+		require.NoError(t, err)
+		assert.Equal(t, []logger.PrefixPolicy{{Principal: "ci", Prefixes: []string{"project"}, Permissions: []logger.Permission{logger.PermissionRead}}}, conf.Policies)
+	})
+	t.Run("Auth", func(t *testing.T) {
+		ctx := context.Background()
+		conf, err := readConfig(write(t, `{"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"}}`))
+		require.NoError(t, err, "commands other than serve do not require auth")
+		_, err = conf.authenticator(ctx)
+		assert.Error(t, err, "serving requires auth")
+
+		conf, err = readConfig(write(t, `{
+			"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+			"auth": {"none": true}
+		}`))
+		require.NoError(t, err)
+		a, err := conf.authenticator(ctx)
+		require.NoError(t, err)
+		assert.Nil(t, a, "authentication is disabled explicitly")
+
+		conf, err = readConfig(write(t, `{
+			"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+			"auth": {"api_keys": {"key": "ci"}}
+		}`))
+		require.NoError(t, err)
+		a, err = conf.authenticator(ctx)
+		require.NoError(t, err)
+		assert.NotNil(t, a)
+
+		for _, auth := range []string{
+			`{}`,
+			`{"none": true, "api_keys": {"key": "ci"}}`,
+			`{"api_keys": {"key": "ci"}, "oidc": {"issuer": "https://issuer.example.com", "audience": "cedar"}}`,
+		} {
+			_, err = readConfig(write(t, `{
+				"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+				"auth": `+auth+`
+			}`))
+			assert.Error(t, err, auth)
+		}
+		_, err = readConfig(write(t, `{
+			"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+			"auth": {"none": true},
+			"policies": [{"principal": "ci", "prefixes": ["project"], "permissions": ["read"]}]
+		}`))
+		assert.Error(t, err, "policies require authentication")
+	})
+	t.Run("Audit", func(t *testing.T) {
+		conf, err := readConfig(write(t, `{
+			"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+			"audit": {"type": "local", "name": "/var/lib/cedar-audit", "prefix": "logs"}
+		}`))
+		require.NoError(t, err)
+		assert.Equal(t, &options.Bucket{Type: options.PailLocal, Name: "/var/lib/cedar-audit", Prefix: "logs"}, conf.Audit)
+
+		_, err = readConfig(write(t, `{
+			"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+			"audit": {"type": "local", "prefix": "logs"}
+		}`))
+		assert.Error(t, err)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := readConfig(write(t, `{"bucket": {"type": "local", "name": "/var/lib/cedar"}}`))
+		assert.Error(t, err)
+		_, err = readConfig(write(t, `{
+			"bucket": {"type": "local", "name": "/var/lib/cedar", "prefix": "logs"},
+			"policies": [{"principal": "ci", "prefixes": ["project"], "permissions": ["read"]}]
+		}`))
+		assert.Error(t, err, "policies require authentication")
+		_, err = readConfig(write(t, `{"bucket": `))
+		assert.Error(t, err)
+		_, err = readConfig(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,37 @@
+// Command cedarlog serves and reports on the logs stored in a bucket.
+//
+// Usage:
+//
+//	cedarlog serve --config <path>
+//	cedarlog report --config <path> [--prefix <prefix>] [--depth <n>] [--format json|csv]
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `usage: cedarlog serve --config <path>
+       cedarlog report --config <path> [--prefix <prefix>] [--depth <n>] [--format json|csv]`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "serve":
+		err = serve(os.Args[2:])
+	case "report":
+		err = report(os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cedarlog: %v\n", err)
+		os.Exit(1)
+	}
+}
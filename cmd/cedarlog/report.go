@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// report writes the storage used by the configured bucket's logs, by prefix
+// and storage class, to stdout as JSON or CSV.
+func report(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path to the JSON config file")
+	prefix := flags.String("prefix", "", "only report on the keys under this prefix")
+	depth := flags.Int("depth", 1, "number of key elements to group by")
+	format := flags.String("format", "json", "output format, json or csv")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *configPath == "" {
+		return errors.New("must specify a config file with --config")
+	}
+	if *format != "json" && *format != "csv" {
+		return errors.Errorf("unrecognized format '%s'", *format)
+	}
+
+	conf, err := readConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	l, err := logger.NewBucketReader(ctx, conf.Bucket)
+	if err != nil {
+		return errors.Wrap(err, "creating bucket reader")
+	}
+	r, err := l.StorageReport(ctx, options.StorageReport{Prefix: *prefix, Depth: *depth})
+	if err != nil {
+		return errors.Wrap(err, "generating storage report")
+	}
+
+	if *format == "csv" {
+		return writeReportCSV(os.Stdout, r)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(r), "writing report")
+}
+
+// writeReportCSV writes one row per prefix and storage class.
+func writeReportCSV(w io.Writer, r *logger.StorageReport) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"prefix", "storage_class", "chunks", "bytes"}); err != nil {
+		return errors.Wrap(err, "writing report")
+	}
+	for _, p := range r.Prefixes {
+		classes := make([]string, 0, len(p.StorageClasses))
+		for class := range p.StorageClasses {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			usage := p.StorageClasses[class]
+			row := []string{p.Prefix, class, strconv.Itoa(usage.Chunks), strconv.FormatInt(usage.Bytes, 10)}
+			if err := out.Write(row); err != nil {
+				return errors.Wrap(err, "writing report")
+			}
+		}
+	}
+	out.Flush()
+	return errors.Wrap(out.Error(), "writing report")
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReportCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeReportCSV(&buf, &logger.StorageReport{
+		Prefixes: []logger.PrefixStorage{
+			{Prefix: "project", StorageClasses: map[string]logger.StorageClassUsage{
+				"STANDARD_IA": {Chunks: 1, Bytes: 10},
+				"STANDARD":    {Chunks: 2, Bytes: 20},
+			}},
+			{Prefix: "project2", StorageClasses: map[string]logger.StorageClassUsage{
+				"": {Chunks: 3, Bytes: 30},
+			}},
+		},
+	}))
+	assert.Equal(t, "prefix,storage_class,chunks,bytes\n"+
+		"project,STANDARD,2,20\n"+
+		"project,STANDARD_IA,1,10\n"+
+		"project2,,3,30\n", buf.String())
+}
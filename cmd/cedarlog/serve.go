@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/server"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+// serve runs the HTTP and gRPC server for the configured bucket until
+// interrupted.
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path to the JSON config file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *configPath == "" {
+		return errors.New("must specify a config file with --config")
+	}
+
+	conf, err := readConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var serverOpts []server.Option
+	a, err := conf.authenticator(ctx)
+	if err != nil {
+		return errors.Wrap(err, "creating authenticator")
+	}
+	if a != nil {
+		serverOpts = append(serverOpts, server.WithAuthenticator(a))
+	}
+
+	var loggerOpts []logger.BucketLoggerOption
+	if conf.Audit != nil {
+		auditBucket, err := logger.NewAuditBucket(ctx, *conf.Audit)
+		if err != nil {
+			return err
+		}
+		loggerOpts = append(loggerOpts, logger.WithAuditBucket(auditBucket))
+	}
+	l, err := logger.NewBucketReader(ctx, conf.Bucket, loggerOpts...)
+	if err != nil {
+		return errors.Wrap(err, "creating bucket reader")
+	}
+	if len(conf.Policies) > 0 {
+		a, err := logger.NewPrefixAuthorizer(conf.Policies...)
+		if err != nil {
+			return errors.Wrap(err, "creating authorizer")
+		}
+		serverOpts = append(serverOpts, server.WithAuthorizer(a))
+	}
+	handler, err := server.New(l, serverOpts...)
+	if err != nil {
+		return errors.Wrap(err, "creating server")
+	}
+
+	srv := &http.Server{
+		Addr: conf.Addr,
+		// h2c accepts cleartext HTTP/2, which gRPC clients require.
+		Handler:     h2c.NewHandler(handler, &http2.Server{}),
+		BaseContext: func(_ net.Listener) context.Context { return ctx },
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	select {
+	case err = <-errs:
+		return errors.Wrap(err, "serving")
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return errors.Wrap(srv.Shutdown(shutdownCtx), "shutting down server")
+}
@@ -0,0 +1,179 @@
+// Package avro provides an Avro encoding that writes each flush as an Avro
+// object container file, readable by the standard Avro toolchain. Schemas
+// are registered per key prefix, typically via the bucket logger's
+// RegisterSchema method, which also stores the schema in the metadata
+// bucket. The encoding is not registered by default; register it with:
+//
+//	encode.GetGlobalRegistry().AddNew(avro.NewEncoding())
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+)
+
+const AVRO = "avro"
+
+type avroEncoding struct {
+	mu     sync.RWMutex
+	codecs map[string]*goavro.Codec
+}
+
+// NewEncoding returns a new Avro encoding with no registered schemas.
+func NewEncoding() encode.SchemaEncoding {
+	return &avroEncoding{codecs: map[string]*goavro.Codec{}}
+}
+
+func (e *avroEncoding) String() string          { return AVRO }
+func (e *avroEncoding) Extension() string       { return AVRO }
+func (e *avroEncoding) SchemaExtension() string { return "avsc" }
+func (e *avroEncoding) ContentType() string     { return "application/avro" }
+
+// RegisterSchema parses the schema and uses it for all keys with the given
+// prefix, replacing any schema previously registered for the prefix.
+func (e *avroEncoding) RegisterSchema(prefix, schema string) error {
+	codec, err := goavro.NewCodecForStandardJSON(schema)
+	if err != nil {
+		return errors.Wrapf(err, "parsing avro schema for key prefix '%s'", prefix)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.codecs[prefix] = codec
+
+	return nil
+}
+
+// Marshal encodes the value using the schema registered for the empty
+// prefix, if any.
+func (e *avroEncoding) Marshal(v interface{}) ([]byte, error) {
+	return e.MarshalKey("", v)
+}
+
+// MarshalKey encodes the value as an object container file using the schema
+// registered for the longest prefix of the key. Slices are written as one
+// record per element. Values other than the native goavro types, e.g.
+// structs, are converted via their standard JSON representation.
+func (e *avroEncoding) MarshalKey(key string, v interface{}) ([]byte, error) {
+	codec, err := e.getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := toNative(codec, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buf,
+		Codec:           codec,
+		CompressionName: goavro.CompressionSnappyLabel,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating avro writer")
+	}
+	if err = w.Append(records); err != nil {
+		return nil, errors.Wrap(err, "writing avro records")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes all records of an object container file, using its
+// embedded schema, into v, which must be a *[]interface{} or a
+// *[]map[string]interface{}.
+func (e *avroEncoding) Unmarshal(data []byte, v interface{}) error {
+	r, err := goavro.NewOCFReader(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "creating avro reader")
+	}
+
+	var records []interface{}
+	for r.Scan() {
+		record, err := r.Read()
+		if err != nil {
+			return errors.Wrap(err, "reading avro record")
+		}
+		records = append(records, record)
+	}
+	if err = r.Err(); err != nil {
+		return errors.Wrap(err, "reading avro records")
+	}
+
+	switch t := v.(type) {
+	case *[]interface{}:
+		*t = records
+	case *[]map[string]interface{}:
+		maps := make([]map[string]interface{}, 0, len(records))
+		for _, record := range records {
+			m, ok := record.(map[string]interface{})
+			if !ok {
+				return errors.Errorf("avro record of type '%T' is not a map", record)
+			}
+			maps = append(maps, m)
+		}
+		*t = maps
+	default:
+		return errors.Errorf("cannot unmarshal avro to type '%T'", v)
+	}
+
+	return nil
+}
+
+func (e *avroEncoding) getCodec(key string) (*goavro.Codec, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var (
+		codec   *goavro.Codec
+		longest = -1
+	)
+	for prefix, c := range e.codecs {
+		if strings.HasPrefix(key, prefix) && len(prefix) > longest {
+			codec, longest = c, len(prefix)
+		}
+	}
+	if codec == nil {
+		return nil, errors.Errorf("no avro schema registered for key '%s'", key)
+	}
+
+	return codec, nil
+}
+
+func toNative(codec *goavro.Codec, v interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		rv = reflect.ValueOf([]interface{}{v})
+	}
+
+	records := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		elem := rv.Index(i).Interface()
+		if _, ok := elem.(map[string]interface{}); ok {
+			records = append(records, elem)
+			continue
+		}
+
+		textual, err := json.Marshal(elem)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding record %d as JSON", i)
+		}
+		record, _, err := codec.NativeFromTextual(textual)
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting record %d to avro", i)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
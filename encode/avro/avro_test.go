@@ -0,0 +1,73 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"type": "record",
+	"name": "line",
+	"fields": [
+		{"name": "msg", "type": "string"},
+		{"name": "count", "type": "long"}
+	]
+}`
+
+func TestAvroEncoding(t *testing.T) {
+	type line struct {
+		Msg   string `json:"msg"`
+		Count int64  `json:"count"`
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		e := NewEncoding()
+		require.NoError(t, e.RegisterSchema("logs", testSchema))
+
+		data, err := e.MarshalKey("logs/task", []line{{Msg: "a", Count: 1}, {Msg: "b", Count: 2}})
+		require.NoError(t, err)
+
+		var out []map[string]interface{}
+		require.NoError(t, e.Unmarshal(data, &out))
+		require.Len(t, out, 2)
+		assert.Equal(t, "a", out[0]["msg"])
+		assert.EqualValues(t, 2, out[1]["count"])
+	})
+	t.Run("SingleValue", func(t *testing.T) {
+		e := NewEncoding()
+		require.NoError(t, e.RegisterSchema("", testSchema))
+
+		data, err := e.Marshal(map[string]interface{}{"msg": "a", "count": int64(1)})
+		require.NoError(t, err)
+
+		var out []interface{}
+		require.NoError(t, e.Unmarshal(data, &out))
+		assert.Len(t, out, 1)
+	})
+	t.Run("LongestPrefix", func(t *testing.T) {
+		e := NewEncoding()
+		require.NoError(t, e.RegisterSchema("logs", testSchema))
+		require.NoError(t, e.RegisterSchema("logs/strings", `"string"`))
+
+		_, err := e.MarshalKey("logs/strings/task", []string{"a"})
+		assert.NoError(t, err)
+		_, err = e.MarshalKey("logs/strings/task", []line{{Msg: "a"}})
+		assert.Error(t, err)
+	})
+	t.Run("NoSchema", func(t *testing.T) {
+		e := NewEncoding()
+		_, err := e.MarshalKey("logs", []line{{Msg: "a"}})
+		assert.Error(t, err)
+	})
+	t.Run("InvalidSchema", func(t *testing.T) {
+		assert.Error(t, NewEncoding().RegisterSchema("logs", "not a schema"))
+	})
+	t.Run("InvalidRecord", func(t *testing.T) {
+		e := NewEncoding()
+		require.NoError(t, e.RegisterSchema("logs", testSchema))
+		_, err := e.MarshalKey("logs", []map[string]string{{"other": "a"}})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,250 @@
+package encode
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const CSV = "csv"
+
+// csvEncoding encodes tabular data, either a [][]string whose first row is
+// the header or a slice of structs. Struct columns are named by the `csv`
+// field tag, falling back to the field name; fields tagged "-" and
+// unexported fields are skipped. Integers are written as numbers, even if
+// their type has a String method, and maps, slices, and non-string
+// interface values as JSON.
+type csvEncoding struct{}
+
+func (e *csvEncoding) String() string      { return CSV }
+func (e *csvEncoding) Extension() string   { return CSV }
+func (e *csvEncoding) ContentType() string { return "text/csv; charset=utf-8" }
+func (e *csvEncoding) Marshal(v interface{}) ([]byte, error) {
+	var records [][]string
+	if t, ok := v.([][]string); ok {
+		records = t
+	} else {
+		var err error
+		if records, err = structsToRecords(v); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, errors.Wrap(err, "writing csv")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes into a *[][]string, including the header row, or a
+// pointer to a slice of structs, matching columns to fields by name.
+func (e *csvEncoding) Unmarshal(data []byte, v interface{}) error {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return errors.Wrap(err, "reading csv")
+	}
+
+	if t, ok := v.(*[][]string); ok {
+		*t = records
+		return nil
+	}
+
+	return recordsToStructs(records, v)
+}
+
+type csvField struct {
+	name  string
+	index int
+}
+
+func csvFields(t reflect.Type) []csvField {
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := f.Tag.Get("csv")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields = append(fields, csvField{name: name, index: i})
+	}
+
+	return fields
+}
+
+func structsToRecords(v interface{}) ([][]string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("cannot marshal type '%T' to csv", v)
+	}
+
+	fields := csvFields(rv.Type().Elem())
+	header := make([]string, 0, len(fields))
+	for _, f := range fields {
+		header = append(header, f.name)
+	}
+
+	records := [][]string{header}
+	for i := 0; i < rv.Len(); i++ {
+		record := make([]string, 0, len(fields))
+		for _, f := range fields {
+			value, err := formatCSVValue(rv.Index(i).Field(f.index))
+			if err != nil {
+				return nil, errors.Wrapf(err, "formatting column '%s' of row %d", f.name, i+1)
+			}
+			record = append(record, value)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func formatCSVValue(v reflect.Value) (string, error) {
+	switch t := v.Interface().(type) {
+	case time.Time:
+		return t.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return t.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return "", nil
+		}
+		if s, ok := v.Interface().(string); ok {
+			return s, nil
+		}
+		fallthrough
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return "", nil
+		}
+		data, err := json.Marshal(v.Interface())
+		return string(data), err
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+
+	return fmt.Sprint(v.Interface()), nil
+}
+
+func recordsToStructs(records [][]string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice || rv.Elem().Type().Elem().Kind() != reflect.Struct {
+		return errors.Errorf("cannot unmarshal csv to type '%T'", v)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	fieldsByName := map[string]int{}
+	for _, f := range csvFields(elemType) {
+		fieldsByName[f.name] = f.index
+	}
+
+	header := records[0]
+	for i, record := range records[1:] {
+		elem := reflect.New(elemType).Elem()
+		for j, value := range record {
+			if j >= len(header) {
+				break
+			}
+			idx, ok := fieldsByName[header[j]]
+			if !ok {
+				continue
+			}
+			if err := parseCSVValue(elem.Field(idx), value); err != nil {
+				return errors.Wrapf(err, "parsing column '%s' of row %d", header[j], i+1)
+			}
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	rv.Elem().Set(slice)
+
+	return nil
+}
+
+func parseCSVValue(v reflect.Value, value string) error {
+	switch v.Interface().(type) {
+	case time.Time:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if value != "" {
+			v.Set(reflect.ValueOf(value))
+		}
+	case reflect.Map, reflect.Slice:
+		if value != "" {
+			return json.Unmarshal([]byte(value), v.Addr().Interface())
+		}
+	default:
+		return errors.Errorf("unsupported field type '%s'", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package encode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// level is an integer type with a String method, which is encoded as a
+// number.
+type level int
+
+func (l level) String() string { return "level" }
+
+func TestCSVEncoding(t *testing.T) {
+	type row struct {
+		Time     time.Time     `csv:"time"`
+		Name     string        `csv:"name"`
+		Count    int           `csv:"count"`
+		Ratio    float64       `csv:"ratio"`
+		OK       bool          `csv:"ok"`
+		Duration time.Duration `csv:"duration"`
+		Skipped  string        `csv:"-"`
+		hidden   string
+	}
+	e := &csvEncoding{}
+
+	t.Run("Structs", func(t *testing.T) {
+		in := []row{
+			{Time: time.Unix(1, 0).UTC(), Name: "a, b", Count: 1, Ratio: 0.5, OK: true, Duration: time.Second, Skipped: "x", hidden: "y"},
+			{Time: time.Unix(2, 0).UTC(), Name: "c", Count: -2},
+		}
+		data, err := e.Marshal(in)
+		require.NoError(t, err)
+		assert.Equal(t, "time,name,count,ratio,ok,duration\n"+
+			"1970-01-01T00:00:01Z,\"a, b\",1,0.5,true,1s\n"+
+			"1970-01-01T00:00:02Z,c,-2,0,false,0s\n", string(data))
+
+		var out []row
+		require.NoError(t, e.Unmarshal(data, &out))
+		in[0].Skipped, in[0].hidden = "", ""
+		assert.Equal(t, in, out)
+	})
+	t.Run("EncodedFields", func(t *testing.T) {
+		type row struct {
+			Level level          `csv:"level"`
+			Attrs map[string]int `csv:"attrs"`
+			Data  interface{}    `csv:"data"`
+			Tags  []string       `csv:"tags"`
+			Empty map[string]int `csv:"empty"`
+		}
+		in := []row{{Level: 45, Attrs: map[string]int{"a": 1}, Data: "data", Tags: []string{"x"}}}
+		data, err := e.Marshal(in)
+		require.NoError(t, err)
+		assert.Equal(t, "level,attrs,data,tags,empty\n"+
+			"45,\"{\"\"a\"\":1}\",data,\"[\"\"x\"\"]\",\n", string(data))
+
+		var out []row
+		require.NoError(t, e.Unmarshal(data, &out))
+		assert.Equal(t, in, out)
+	})
+	t.Run("Records", func(t *testing.T) {
+		in := [][]string{{"a", "b"}, {"1", "2"}}
+		data, err := e.Marshal(in)
+		require.NoError(t, err)
+		assert.Equal(t, "a,b\n1,2\n", string(data))
+
+		var out [][]string
+		require.NoError(t, e.Unmarshal(data, &out))
+		assert.Equal(t, in, out)
+	})
+	t.Run("UnknownColumns", func(t *testing.T) {
+		var out []row
+		require.NoError(t, e.Unmarshal([]byte("name,extra\na,b\n"), &out))
+		assert.Equal(t, []row{{Name: "a"}}, out)
+	})
+	t.Run("InvalidInput", func(t *testing.T) {
+		_, err := e.Marshal([]int{1})
+		assert.Error(t, err)
+		var out []row
+		assert.Error(t, e.Unmarshal([]byte("count\nnot a number\n"), &out))
+		assert.Error(t, e.Unmarshal([]byte("name\na\n"), out))
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/gob"
 	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
 
 	"github.com/pkg/errors"
 )
@@ -12,26 +15,47 @@ const TEXT = "plain_text"
 
 type textEncoding struct{}
 
-func (e *textEncoding) String() string    { return TEXT }
-func (e *textEncoding) Extension() string { return "txt" }
+func (e *textEncoding) String() string      { return TEXT }
+func (e *textEncoding) Extension() string   { return "txt" }
+func (e *textEncoding) ContentType() string { return "text/plain; charset=utf-8" }
+
+// Marshal renders strings, byte slices, and fmt.Stringers as is. Slices of
+// fmt.Stringers, such as log lines, are rendered one element per line.
 func (e *textEncoding) Marshal(v interface{}) ([]byte, error) {
-	var out []byte
 	switch t := v.(type) {
 	case []byte:
-		out = t
+		return t, nil
 	case string:
-		out = []byte(t)
+		return []byte(t), nil
 	case *string:
-		out = []byte(*t)
-	default:
+		return []byte(*t), nil
+	case fmt.Stringer:
+		return []byte(t.String()), nil
+	case []string:
 		var buf bytes.Buffer
-		if err := gob.NewEncoder(&buf).Encode(v); err != nil {
-			return nil, errors.WithStack(err)
+		for _, line := range t {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
 		}
-		out = buf.Bytes()
+		return buf.Bytes(), nil
 	}
 
-	return out, nil
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, errors.Errorf("cannot marshal type '%T' to plain text", v)
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < rv.Len(); i++ {
+		s, ok := rv.Index(i).Interface().(fmt.Stringer)
+		if !ok {
+			return nil, errors.Errorf("cannot marshal slice element of type '%s' to plain text", rv.Type().Elem())
+		}
+		buf.WriteString(s.String())
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
 }
 
 // TODO: should this even be implemented for plain text?
@@ -51,12 +75,155 @@ const JSON = "json"
 
 type jsonEncoding struct{}
 
-func (e *jsonEncoding) String() string    { return JSON }
-func (e *jsonEncoding) Extension() string { return JSON }
+func (e *jsonEncoding) String() string      { return JSON }
+func (e *jsonEncoding) Extension() string   { return JSON }
+func (e *jsonEncoding) ContentType() string { return "application/json" }
 func (e *jsonEncoding) Marshal(v interface{}) ([]byte, error) {
 	return json.Marshal(v)
 }
 
+// Encode writes the same bytes as Marshal. Unlike Marshal, json.Encoder
+// terminates the value with a newline, which is trimmed.
+func (e *jsonEncoding) Encode(w io.Writer, v interface{}) error {
+	buf, ok := w.(*bytes.Buffer)
+	if !ok {
+		data, err := e.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+
+		return errors.WithStack(err)
+	}
+
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1)
+
+	return nil
+}
+
 func (e *jsonEncoding) Unmarshal(data []byte, v interface{}) error {
 	return json.Unmarshal(data, v)
 }
+
+const BLOB = "blob"
+
+// blobEncoding stores opaque payloads, such as artifacts written with
+// content-type sniffing, as is. Blobs are not log lines and are skipped by
+// line-oriented readers.
+type blobEncoding struct{}
+
+func (e *blobEncoding) String() string    { return BLOB }
+func (e *blobEncoding) Extension() string { return BLOB }
+func (e *blobEncoding) Marshal(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case []byte:
+		return t, nil
+	case string:
+		return []byte(t), nil
+	default:
+		return nil, errors.Errorf("cannot marshal type '%T' to blob", v)
+	}
+}
+
+func (e *blobEncoding) Unmarshal(data []byte, v interface{}) error {
+	switch t := v.(type) {
+	case *[]byte:
+		*t = append((*t)[:0], data...)
+	case *string:
+		*t = string(data)
+	default:
+		return errors.Errorf("cannot unmarshal blob to type '%T'", v)
+	}
+
+	return nil
+}
+
+const GOB = "gob"
+
+// gobEncoding encodes values with encoding/gob. Concrete types stored in
+// interface fields, e.g. LogLine.Data, must be registered with gob.Register.
+type gobEncoding struct{}
+
+func (e *gobEncoding) String() string      { return GOB }
+func (e *gobEncoding) Extension() string   { return GOB }
+func (e *gobEncoding) ContentType() string { return "application/octet-stream" }
+func (e *gobEncoding) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (e *gobEncoding) Encode(w io.Writer, v interface{}) error {
+	return errors.WithStack(gob.NewEncoder(w).Encode(v))
+}
+
+func (e *gobEncoding) Unmarshal(data []byte, v interface{}) error {
+	return errors.WithStack(gob.NewDecoder(bytes.NewReader(data)).Decode(v))
+}
+
+const NDJSON = "ndjson"
+
+// ndjsonEncoding encodes each element of a slice, e.g. a flush of log lines,
+// as a JSON object on its own line. Values that are not slices are encoded
+// as a single line.
+type ndjsonEncoding struct{}
+
+func (e *ndjsonEncoding) String() string      { return NDJSON }
+func (e *ndjsonEncoding) Extension() string   { return NDJSON }
+func (e *ndjsonEncoding) ContentType() string { return "application/x-ndjson" }
+func (e *ndjsonEncoding) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := e.Encode(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (e *ndjsonEncoding) Encode(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return errors.WithStack(enc.Encode(v))
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return errors.Wrapf(err, "encoding element %d", i)
+		}
+	}
+
+	return nil
+}
+
+// Unmarshal decodes each line into a new element appended to the slice
+// pointed to by v. If v does not point to a slice, the data must contain a
+// single line.
+func (e *ndjsonEncoding) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("cannot unmarshal ndjson to non-pointer type '%T'", v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	slice := rv.Elem()
+	if slice.Kind() != reflect.Slice {
+		return errors.WithStack(dec.Decode(v))
+	}
+	for dec.More() {
+		elem := reflect.New(slice.Type().Elem())
+		if err := dec.Decode(elem.Interface()); err != nil {
+			return errors.Wrapf(err, "decoding line %d", slice.Len()+1)
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	rv.Elem().Set(slice)
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package encode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+func TestTextEncoding(t *testing.T) {
+	e := &textEncoding{}
+
+	for _, test := range []struct {
+		name     string
+		data     interface{}
+		expected string
+	}{
+		{name: "Bytes", data: []byte("bytes"), expected: "bytes"},
+		{name: "String", data: "string", expected: "string"},
+		{name: "Stringer", data: stringer("stringer"), expected: "stringer"},
+		{name: "Strings", data: []string{"a", "b"}, expected: "a\nb\n"},
+		{name: "Stringers", data: []stringer{"a", "b"}, expected: "a\nb\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := e.Marshal(test.data)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(data))
+		})
+	}
+	t.Run("InvalidType", func(t *testing.T) {
+		_, err := e.Marshal(1)
+		assert.Error(t, err)
+		_, err = e.Marshal([]int{1})
+		assert.Error(t, err)
+	})
+}
+
+func TestGobEncoding(t *testing.T) {
+	type value struct {
+		A string
+		B int
+	}
+	e := &gobEncoding{}
+	in := value{A: "a", B: 1}
+
+	data, err := e.Marshal(in)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, e.Encode(&buf, in))
+	assert.Equal(t, data, buf.Bytes())
+
+	var out value
+	require.NoError(t, e.Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+	assert.Error(t, e.Unmarshal([]byte("invalid"), &out))
+}
+
+func TestBlobEncoding(t *testing.T) {
+	e := &blobEncoding{}
+
+	data, err := e.Marshal([]byte{0, 1, 2})
+	require.NoError(t, err)
+	var out []byte
+	require.NoError(t, e.Unmarshal(data, &out))
+	assert.Equal(t, []byte{0, 1, 2}, out)
+
+	_, err = e.Marshal(1)
+	assert.Error(t, err)
+	assert.Error(t, e.Unmarshal(data, new(int)))
+}
+
+func TestNDJSONEncoding(t *testing.T) {
+	type line struct {
+		Data string `json:"data"`
+	}
+	e := &ndjsonEncoding{}
+
+	t.Run("Slice", func(t *testing.T) {
+		data, err := e.Marshal([]line{{Data: "a"}, {Data: "b"}})
+		require.NoError(t, err)
+		assert.Equal(t, "{\"data\":\"a\"}\n{\"data\":\"b\"}\n", string(data))
+
+		var out []line
+		require.NoError(t, e.Unmarshal(data, &out))
+		assert.Equal(t, []line{{Data: "a"}, {Data: "b"}}, out)
+	})
+	t.Run("SingleValue", func(t *testing.T) {
+		data, err := e.Marshal(line{Data: "a"})
+		require.NoError(t, err)
+		assert.Equal(t, "{\"data\":\"a\"}\n", string(data))
+
+		var out line
+		require.NoError(t, e.Unmarshal(data, &out))
+		assert.Equal(t, line{Data: "a"}, out)
+	})
+	t.Run("Bytes", func(t *testing.T) {
+		data, err := e.Marshal([]byte("a"))
+		require.NoError(t, err)
+		assert.Equal(t, "\"YQ==\"\n", string(data))
+	})
+	t.Run("InvalidLine", func(t *testing.T) {
+		var out []line
+		assert.Error(t, e.Unmarshal([]byte("{\"data\":\"a\"}\nnot json\n"), &out))
+		assert.Error(t, e.Unmarshal([]byte("{}"), out))
+	})
+}
@@ -1,5 +1,7 @@
 package encode
 
+import "io"
+
 type Encoding interface {
 	String() string
 	Extension() string
@@ -8,6 +10,47 @@ type Encoding interface {
 }
 
 type EncodingRegistry interface {
-	AddNew(Encoding)
+	// AddNew registers a new encoding, returning an error if an encoding
+	// with the same name is already registered.
+	AddNew(Encoding) error
+	// Replace registers the encoding, overwriting any existing encoding
+	// with the same name.
+	Replace(Encoding)
+	// Remove unregisters the encoding with the given name, if any.
+	Remove(string)
 	Get(string) (Encoding, bool)
+	// GetByExtension returns the encoding with the given extension. If
+	// several encodings share the extension, the one named after it is
+	// preferred, followed by the first by name.
+	GetByExtension(string) (Encoding, bool)
+	// Names returns the names of all registered encodings, sorted.
+	Names() []string
+}
+
+// StreamEncoding is implemented by encodings that can write the encoded form
+// of a value directly to an io.Writer, avoiding the intermediate allocation
+// of Marshal. Encode must write exactly the bytes Marshal returns.
+type StreamEncoding interface {
+	Encoding
+	Encode(io.Writer, interface{}) error
+}
+
+// SchemaEncoding is implemented by encodings that encode data according to a
+// schema registered per key prefix, e.g. Avro.
+type SchemaEncoding interface {
+	Encoding
+	// SchemaExtension returns the extension of stored schema files.
+	SchemaExtension() string
+	// RegisterSchema sets the schema used for keys with the given prefix.
+	RegisterSchema(prefix, schema string) error
+	// MarshalKey marshals data written to the given key using the schema
+	// registered for the longest matching prefix.
+	MarshalKey(key string, v interface{}) ([]byte, error)
+}
+
+// ContentTypeEncoding is implemented by encodings that know the MIME type of
+// their encoded data, which is stored with uploaded objects.
+type ContentTypeEncoding interface {
+	Encoding
+	ContentType() string
 }
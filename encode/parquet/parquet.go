@@ -0,0 +1,330 @@
+// Package parquet provides a parquet encoding for flushes of log lines, so
+// that stored logs can be queried directly by tools such as Athena or Spark.
+// The encoding is not registered by default; register it with:
+//
+//	encode.GetGlobalRegistry().AddNew(parquet.NewEncoding())
+package parquet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/logger"
+	"github.com/mongodb/grip/level"
+	"github.com/pkg/errors"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const PARQUET = "parquet"
+
+// Column names.
+const (
+	ColumnTimestamp = "ts"
+	ColumnSequence  = "seq"
+	ColumnPriority  = "priority"
+	ColumnMessage   = "msg"
+	ColumnTraceID   = "trace_id"
+	ColumnSpanID    = "span_id"
+	ColumnAttrs     = "attrs"
+	ColumnData      = "data"
+)
+
+// Row is a single log line as stored in parquet. Timestamps are stored as
+// microseconds since the Unix epoch and data is rendered as text, with
+// values that are neither strings nor fmt.Stringers encoded as JSON. Lines
+// without data, e.g. with only a message, have empty data. Attributes are
+// encoded as a JSON object, or empty if the line has none.
+type Row struct {
+	Timestamp int64  `parquet:"name=ts, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	Sequence  int64  `parquet:"name=seq, type=INT64"`
+	Priority  int32  `parquet:"name=priority, type=INT32"`
+	Message   string `parquet:"name=msg, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TraceID   string `parquet:"name=trace_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SpanID    string `parquet:"name=span_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Attrs     string `parquet:"name=attrs, type=BYTE_ARRAY, convertedtype=JSON"`
+	Data      string `parquet:"name=data, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// Time returns the timestamp of the row.
+func (r Row) Time() time.Time { return time.Unix(0, r.Timestamp*int64(time.Microsecond)) }
+
+type parquetEncoding struct{}
+
+// NewEncoding returns a new parquet encoding. Only slices of log lines or
+// rows can be marshaled.
+func NewEncoding() encode.Encoding { return &parquetEncoding{} }
+
+func (e *parquetEncoding) String() string      { return PARQUET }
+func (e *parquetEncoding) Extension() string   { return PARQUET }
+func (e *parquetEncoding) ContentType() string { return "application/vnd.apache.parquet" }
+func (e *parquetEncoding) Marshal(v interface{}) ([]byte, error) {
+	var rows []Row
+	switch t := v.(type) {
+	case []Row:
+		rows = t
+	case []logger.LogLine:
+		rows = make([]Row, 0, len(t))
+		for _, line := range t {
+			row, err := newRow(line)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		}
+	default:
+		return nil, errors.Errorf("cannot marshal type '%T' to parquet", v)
+	}
+
+	var buf bytes.Buffer
+	pw, err := writer.NewParquetWriterFromWriter(&buf, new(Row), 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating parquet writer")
+	}
+	for _, row := range rows {
+		if err = pw.Write(row); err != nil {
+			return nil, errors.Wrap(err, "writing parquet row")
+		}
+	}
+	if err = pw.WriteStop(); err != nil {
+		return nil, errors.Wrap(err, "finishing parquet file")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal reads all rows into v, which must be a *[]Row or a
+// *[]logger.LogLine. Log line data is returned as a string, or nil if the
+// row has a message and no data.
+func (e *parquetEncoding) Unmarshal(data []byte, v interface{}) error {
+	rows, err := Read(data, Projection{})
+	if err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case *[]Row:
+		*t = rows
+	case *[]logger.LogLine:
+		lines := make([]logger.LogLine, 0, len(rows))
+		for _, row := range rows {
+			line, err := newLogLine(row)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+		*t = lines
+	default:
+		return errors.Errorf("cannot unmarshal parquet to type '%T'", v)
+	}
+
+	return nil
+}
+
+func newRow(line logger.LogLine) (Row, error) {
+	row := Row{
+		Timestamp: line.Timestamp.UnixNano() / int64(time.Microsecond),
+		Sequence:  int64(line.Sequence),
+		Priority:  int32(line.Priority),
+		Message:   line.Message,
+		TraceID:   line.TraceID,
+		SpanID:    line.SpanID,
+	}
+	if len(line.Attrs) > 0 {
+		attrs, err := json.Marshal(line.Attrs)
+		if err != nil {
+			return Row{}, errors.Wrap(err, "encoding log line attributes")
+		}
+		row.Attrs = string(attrs)
+	}
+
+	switch d := line.Data.(type) {
+	case nil:
+	case string:
+		row.Data = d
+	case fmt.Stringer:
+		row.Data = d.String()
+	default:
+		data, err := json.Marshal(d)
+		if err != nil {
+			return Row{}, errors.Wrap(err, "encoding log line data")
+		}
+		row.Data = string(data)
+	}
+
+	return row, nil
+}
+
+func newLogLine(row Row) (logger.LogLine, error) {
+	line := logger.LogLine{
+		Timestamp: row.Time(),
+		Sequence:  uint64(row.Sequence),
+		Priority:  level.Priority(row.Priority),
+		Message:   row.Message,
+		TraceID:   row.TraceID,
+		SpanID:    row.SpanID,
+	}
+	if line.Priority != 0 {
+		line.PriorityString = line.Priority.String()
+	}
+	if row.Attrs != "" {
+		if err := json.Unmarshal([]byte(row.Attrs), &line.Attrs); err != nil {
+			return logger.LogLine{}, errors.Wrap(err, "decoding log line attributes")
+		}
+	}
+	if row.Data != "" || row.Message == "" {
+		line.Data = row.Data
+	}
+
+	return line, nil
+}
+
+// Projection selects the columns and rows returned by Read. Only the
+// columns needed to return the selected columns and apply the filters are
+// decoded.
+type Projection struct {
+	// Columns are the columns to return. Columns that are neither
+	// selected nor needed by a filter are left as zero values. Defaults to
+	// all columns.
+	Columns []string
+	// Start and End, if set, only return rows with timestamps in the
+	// range [Start, End).
+	Start time.Time
+	End   time.Time
+	// MinPriority, if set, only returns rows at or above the priority.
+	MinPriority level.Priority
+}
+
+func (p Projection) needs(column string) bool {
+	switch {
+	case column == ColumnTimestamp && (!p.Start.IsZero() || !p.End.IsZero()):
+		return true
+	case column == ColumnPriority && p.MinPriority > 0:
+		return true
+	case len(p.Columns) == 0:
+		return true
+	}
+
+	for _, c := range p.Columns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p Projection) matches(row Row) bool {
+	if !p.Start.IsZero() && row.Time().Before(p.Start) {
+		return false
+	}
+	if !p.End.IsZero() && !row.Time().Before(p.End) {
+		return false
+	}
+
+	return row.Priority >= int32(p.MinPriority)
+}
+
+// Read decodes the rows of a parquet chunk, reading only the columns required
+// by the projection.
+func Read(data []byte, p Projection) ([]Row, error) {
+	for _, column := range p.Columns {
+		switch column {
+		case ColumnTimestamp, ColumnSequence, ColumnPriority, ColumnMessage, ColumnTraceID, ColumnSpanID, ColumnAttrs, ColumnData:
+		default:
+			return nil, errors.Errorf("unrecognized column '%s'", column)
+		}
+	}
+
+	file, err := buffer.NewBufferFile(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating parquet buffer")
+	}
+	pr, err := reader.NewParquetColumnReader(file, 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating parquet reader")
+	}
+	defer pr.ReadStop()
+
+	numRows := pr.GetNumRows()
+	rows := make([]Row, numRows)
+	readColumn := func(column string, set func(*Row, interface{}) bool) error {
+		if !p.needs(column) || numRows == 0 {
+			return nil
+		}
+
+		path := pr.SchemaHandler.GetRootExName() + common.PAR_GO_PATH_DELIMITER + column
+		if _, ok := pr.SchemaHandler.ExPathToInPath[path]; !ok {
+			// Chunks written before the column was added leave it
+			// zero.
+			return nil
+		}
+		values, _, _, err := pr.ReadColumnByPath(path, numRows)
+		if err != nil {
+			return errors.Wrapf(err, "reading column '%s'", column)
+		}
+		if int64(len(values)) != numRows {
+			return errors.Errorf("column '%s' has %d values, expected %d", column, len(values), numRows)
+		}
+		for i, value := range values {
+			if !set(&rows[i], value) {
+				return errors.Errorf("column '%s' has unexpected value type '%T'", column, value)
+			}
+		}
+
+		return nil
+	}
+
+	err = readColumn(ColumnTimestamp, func(r *Row, v interface{}) (ok bool) {
+		r.Timestamp, ok = v.(int64)
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = readColumn(ColumnPriority, func(r *Row, v interface{}) (ok bool) {
+		r.Priority, ok = v.(int32)
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = readColumn(ColumnSequence, func(r *Row, v interface{}) (ok bool) {
+		r.Sequence, ok = v.(int64)
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	for column, field := range map[string]func(*Row) *string{
+		ColumnMessage: func(r *Row) *string { return &r.Message },
+		ColumnTraceID: func(r *Row) *string { return &r.TraceID },
+		ColumnSpanID:  func(r *Row) *string { return &r.SpanID },
+		ColumnAttrs:   func(r *Row) *string { return &r.Attrs },
+		ColumnData:    func(r *Row) *string { return &r.Data },
+	} {
+		field := field
+		err = readColumn(column, func(r *Row, v interface{}) (ok bool) {
+			*field(r), ok = v.(string)
+			return ok
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filtered := rows[:0]
+	for _, row := range rows {
+		if p.matches(row) {
+			filtered = append(filtered, row)
+		}
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,110 @@
+package parquet
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+func TestParquetEncoding(t *testing.T) {
+	e := NewEncoding()
+	start := time.Unix(1000, 0).UTC()
+	lines := []logger.LogLine{
+		{Timestamp: start, Priority: level.Debug, Data: "debug"},
+		{Timestamp: start.Add(time.Second), Priority: level.Info, Data: "info"},
+		{Timestamp: start.Add(2 * time.Second), Priority: level.Error, Data: map[string]int{"a": 1}},
+	}
+	data, err := e.Marshal(lines)
+	require.NoError(t, err)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var out []logger.LogLine
+		require.NoError(t, e.Unmarshal(data, &out))
+		require.Len(t, out, len(lines))
+		for i, line := range out {
+			assert.True(t, lines[i].Timestamp.Equal(line.Timestamp))
+			assert.Equal(t, lines[i].Priority, line.Priority)
+		}
+		assert.Equal(t, "debug", out[0].Data)
+		assert.Equal(t, `{"a":1}`, out[2].Data)
+	})
+	t.Run("Version2Fields", func(t *testing.T) {
+		lines := []logger.LogLine{
+			{
+				Timestamp: start,
+				Sequence:  7,
+				Priority:  45,
+				Message:   "message",
+				TraceID:   "trace",
+				SpanID:    "span",
+				Attrs:     map[string]interface{}{"task": "compile"},
+			},
+			{Timestamp: start, Data: ""},
+		}
+		data, err := e.Marshal(lines)
+		require.NoError(t, err)
+
+		var out []logger.LogLine
+		require.NoError(t, e.Unmarshal(data, &out))
+		require.Len(t, out, 2)
+		assert.EqualValues(t, 7, out[0].Sequence)
+		assert.EqualValues(t, 45, out[0].Priority)
+		assert.Equal(t, "message", out[0].Message)
+		assert.Equal(t, "trace", out[0].TraceID)
+		assert.Equal(t, "span", out[0].SpanID)
+		assert.Equal(t, map[string]interface{}{"task": "compile"}, out[0].Attrs)
+		assert.Nil(t, out[0].Data, "message only lines are not written with null data")
+		assert.Equal(t, "", out[1].Data)
+	})
+	t.Run("Version1Chunks", func(t *testing.T) {
+		type v1Row struct {
+			Timestamp int64  `parquet:"name=ts, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+			Priority  int32  `parquet:"name=priority, type=INT32"`
+			Data      string `parquet:"name=data, type=BYTE_ARRAY, convertedtype=UTF8"`
+		}
+		var buf bytes.Buffer
+		pw, err := writer.NewParquetWriterFromWriter(&buf, new(v1Row), 1)
+		require.NoError(t, err)
+		require.NoError(t, pw.Write(v1Row{Timestamp: 1, Priority: int32(level.Info), Data: "data"}))
+		require.NoError(t, pw.WriteStop())
+
+		var out []logger.LogLine
+		require.NoError(t, e.Unmarshal(buf.Bytes(), &out))
+		require.Len(t, out, 1)
+		assert.Equal(t, level.Info, out[0].Priority)
+		assert.Equal(t, "data", out[0].Data)
+		assert.Empty(t, out[0].Message)
+	})
+	t.Run("ColumnProjection", func(t *testing.T) {
+		rows, err := Read(data, Projection{Columns: []string{ColumnData}})
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, "info", rows[1].Data)
+		assert.Zero(t, rows[1].Timestamp)
+		assert.Zero(t, rows[1].Priority)
+	})
+	t.Run("Filters", func(t *testing.T) {
+		rows, err := Read(data, Projection{Columns: []string{ColumnData}, Start: start.Add(time.Second), MinPriority: level.Info})
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, "info", rows[0].Data)
+
+		rows, err = Read(data, Projection{End: start.Add(time.Second)})
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "debug", rows[0].Data)
+	})
+	t.Run("InvalidInput", func(t *testing.T) {
+		_, err := Read(data, Projection{Columns: []string{"unknown"}})
+		assert.Error(t, err)
+		_, err = e.Marshal("string")
+		assert.Error(t, err)
+		assert.Error(t, e.Unmarshal(data, new(string)))
+	})
+}
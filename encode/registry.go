@@ -1,11 +1,20 @@
 package encode
 
-import "sync"
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
 
 var globalRegistry = &encodingRegistry{
 	registry: map[string]Encoding{
-		TEXT: &textEncoding{},
-		JSON: &jsonEncoding{},
+		TEXT:   &textEncoding{},
+		JSON:   &jsonEncoding{},
+		BLOB:   &blobEncoding{},
+		GOB:    &gobEncoding{},
+		NDJSON: &ndjsonEncoding{},
+		CSV:    &csvEncoding{},
 	},
 }
 
@@ -22,15 +31,31 @@ func NewEncodingRegistry() *encodingRegistry {
 	}
 }
 
-func (r *encodingRegistry) AddNew(encoding Encoding) {
+func (r *encodingRegistry) AddNew(encoding Encoding) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, ok := r.registry[encoding.String()]; ok {
-		return
+		return errors.Errorf("encoding '%s' is already registered", encoding)
 	}
 
 	r.registry[encoding.String()] = encoding
+
+	return nil
+}
+
+func (r *encodingRegistry) Replace(encoding Encoding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.registry[encoding.String()] = encoding
+}
+
+func (r *encodingRegistry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.registry, name)
 }
 
 func (r *encodingRegistry) Get(name string) (Encoding, bool) {
@@ -40,3 +65,37 @@ func (r *encodingRegistry) Get(name string) (Encoding, bool) {
 	encoding, ok := r.registry[name]
 	return encoding, ok
 }
+
+func (r *encodingRegistry) GetByExtension(ext string) (Encoding, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if encoding, ok := r.registry[ext]; ok && encoding.Extension() == ext {
+		return encoding, true
+	}
+
+	var match Encoding
+	for name, encoding := range r.registry {
+		if encoding.Extension() != ext {
+			continue
+		}
+		if match == nil || name < match.String() {
+			match = encoding
+		}
+	}
+
+	return match, match != nil
+}
+
+func (r *encodingRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.registry))
+	for name := range r.registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,65 @@
+package encode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodingRegistry(t *testing.T) {
+	assert.Implements(t, (*EncodingRegistry)(nil), NewEncodingRegistry())
+
+	r := NewEncodingRegistry()
+	assert.Empty(t, r.Names())
+
+	require.NoError(t, r.AddNew(&textEncoding{}))
+	require.NoError(t, r.AddNew(&jsonEncoding{}))
+	assert.Error(t, r.AddNew(&jsonEncoding{}))
+	assert.Equal(t, []string{JSON, TEXT}, r.Names())
+
+	replacement := &textEncoding{}
+	r.Replace(replacement)
+	encoding, ok := r.Get(TEXT)
+	require.True(t, ok)
+	assert.True(t, encoding == replacement)
+
+	r.Remove(TEXT)
+	_, ok = r.Get(TEXT)
+	assert.False(t, ok)
+	assert.Equal(t, []string{JSON}, r.Names())
+	r.Remove("unknown")
+
+	assert.Contains(t, GetGlobalRegistry().Names(), TEXT)
+}
+
+// renamedEncoding registers an encoding under a different name.
+type renamedEncoding struct {
+	Encoding
+	name string
+}
+
+func (e renamedEncoding) String() string { return e.name }
+
+func TestEncodingRegistryGetByExtension(t *testing.T) {
+	r := NewEncodingRegistry()
+	require.NoError(t, r.AddNew(&jsonEncoding{}))
+	require.NoError(t, r.AddNew(&textEncoding{}))
+
+	encoding, ok := r.GetByExtension("txt")
+	require.True(t, ok)
+	assert.Equal(t, TEXT, encoding.String())
+	_, ok = r.GetByExtension("unknown")
+	assert.False(t, ok)
+
+	require.NoError(t, r.AddNew(renamedEncoding{Encoding: &jsonEncoding{}, name: "a_json"}))
+	encoding, ok = r.GetByExtension(JSON)
+	require.True(t, ok)
+	assert.Equal(t, JSON, encoding.String())
+
+	r.Remove(JSON)
+	require.NoError(t, r.AddNew(renamedEncoding{Encoding: &jsonEncoding{}, name: "b_json"}))
+	encoding, ok = r.GetByExtension(JSON)
+	require.True(t, ok)
+	assert.Equal(t, "a_json", encoding.String())
+}
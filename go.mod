@@ -3,9 +3,20 @@ module github.com/julianedwards/cedar
 go 1.16
 
 require (
+	github.com/aws/aws-sdk-go v1.41.11
 	github.com/evergreen-ci/pail v0.0.0-20211119154247-0c51f12ed31b
+	github.com/klauspost/compress v1.13.6
+	github.com/linkedin/goavro/v2 v2.10.1
 	github.com/mongodb/grip v0.0.0-20211119154157-aca5d459de3f
 	github.com/papertrail/go-tail v0.0.0-20180509224916-973c153b0431
 	github.com/pkg/errors v0.9.1
-	github.com/stretchr/testify v1.7.0 // indirect
+	github.com/pkg/sftp v1.13.4
+	github.com/stretchr/testify v1.7.0
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
+	go.mongodb.org/mongo-driver v1.7.3
+	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b
+	golang.org/x/net v0.0.0-20211020060615-d418f374d309
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.27.1
 )
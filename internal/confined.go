@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/pkg/errors"
+)
+
+// confinedBucket rejects keys and prefixes that could address objects
+// outside of the wrapped bucket's prefix on backends that resolve keys as
+// file paths, e.g. "../other/logs/chunk".
+type confinedBucket struct {
+	pail.Bucket
+}
+
+// NewConfinedBucket returns a bucket that refuses every operation on a key
+// that is absolute or contains "." or ".." elements.
+func NewConfinedBucket(bucket pail.Bucket) pail.Bucket {
+	return &confinedBucket{Bucket: bucket}
+}
+
+func checkConfinedKey(key string) error {
+	if strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return errors.Errorf("key '%s' is not confined to the bucket", key)
+	}
+	for _, elem := range strings.Split(key, "/") {
+		if elem == "." || elem == ".." {
+			return errors.Errorf("key '%s' is not confined to the bucket", key)
+		}
+	}
+
+	return nil
+}
+
+func (b *confinedBucket) Exists(ctx context.Context, key string) (bool, error) {
+	if err := checkConfinedKey(key); err != nil {
+		return false, err
+	}
+
+	return b.Bucket.Exists(ctx, key)
+}
+
+func (b *confinedBucket) Writer(ctx context.Context, key string) (io.WriteCloser, error) {
+	if err := checkConfinedKey(key); err != nil {
+		return nil, err
+	}
+
+	return b.Bucket.Writer(ctx, key)
+}
+
+func (b *confinedBucket) Reader(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := checkConfinedKey(key); err != nil {
+		return nil, err
+	}
+
+	return b.Bucket.Reader(ctx, key)
+}
+
+func (b *confinedBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := checkConfinedKey(key); err != nil {
+		return err
+	}
+
+	return b.Bucket.Put(ctx, key, r)
+}
+
+func (b *confinedBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := checkConfinedKey(key); err != nil {
+		return nil, err
+	}
+
+	return b.Bucket.Get(ctx, key)
+}
+
+func (b *confinedBucket) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	if err := checkConfinedKey(key); err != nil {
+		return nil, err
+	}
+
+	return GetRange(ctx, b.Bucket, key, offset)
+}
+
+func (b *confinedBucket) Upload(ctx context.Context, key, path string) error {
+	if err := checkConfinedKey(key); err != nil {
+		return err
+	}
+
+	return b.Bucket.Upload(ctx, key, path)
+}
+
+func (b *confinedBucket) Download(ctx context.Context, key, path string) error {
+	if err := checkConfinedKey(key); err != nil {
+		return err
+	}
+
+	return b.Bucket.Download(ctx, key, path)
+}
+
+func (b *confinedBucket) Push(ctx context.Context, opts pail.SyncOptions) error {
+	if err := checkConfinedKey(opts.Remote); err != nil {
+		return err
+	}
+
+	return b.Bucket.Push(ctx, opts)
+}
+
+func (b *confinedBucket) Pull(ctx context.Context, opts pail.SyncOptions) error {
+	if err := checkConfinedKey(opts.Remote); err != nil {
+		return err
+	}
+
+	return b.Bucket.Pull(ctx, opts)
+}
+
+func (b *confinedBucket) Copy(ctx context.Context, opts pail.CopyOptions) error {
+	if err := checkConfinedKey(opts.SourceKey); err != nil {
+		return err
+	}
+	if err := checkConfinedKey(opts.DestinationKey); err != nil {
+		return err
+	}
+
+	return b.Bucket.Copy(ctx, opts)
+}
+
+func (b *confinedBucket) Remove(ctx context.Context, key string) error {
+	if err := checkConfinedKey(key); err != nil {
+		return err
+	}
+
+	return b.Bucket.Remove(ctx, key)
+}
+
+func (b *confinedBucket) RemoveMany(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := checkConfinedKey(key); err != nil {
+			return err
+		}
+	}
+
+	return b.Bucket.RemoveMany(ctx, keys...)
+}
+
+func (b *confinedBucket) RemovePrefix(ctx context.Context, prefix string) error {
+	if err := checkConfinedKey(prefix); err != nil {
+		return err
+	}
+
+	return b.Bucket.RemovePrefix(ctx, prefix)
+}
+
+func (b *confinedBucket) List(ctx context.Context, prefix string) (pail.BucketIterator, error) {
+	if err := checkConfinedKey(prefix); err != nil {
+		return nil, err
+	}
+
+	return b.Bucket.List(ctx, prefix)
+}
+
+func (b *confinedBucket) ListRange(ctx context.Context, prefix, startAfter, end string) (pail.BucketIterator, error) {
+	if err := checkConfinedKey(prefix); err != nil {
+		return nil, err
+	}
+
+	return ListRange(ctx, b.Bucket, prefix, startAfter, end)
+}
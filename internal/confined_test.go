@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfinedBucket(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	local, err := pail.NewLocalBucket(pail.LocalOptions{Path: dir, Prefix: "tenants/a"})
+	require.NoError(t, err)
+	b := NewConfinedBucket(local)
+
+	for _, key := range []string{"key/0", "key/.hidden", "key..0"} {
+		t.Run(key, func(t *testing.T) {
+			require.NoError(t, b.Put(ctx, key, strings.NewReader("data")))
+			exists, err := b.Exists(ctx, key)
+			require.NoError(t, err)
+			assert.True(t, exists)
+		})
+	}
+	for _, key := range []string{"../b/key/0", "key/../../b/0", "/etc/passwd", "key/./0", `..\b\0`} {
+		t.Run(key, func(t *testing.T) {
+			assert.Error(t, b.Put(ctx, key, strings.NewReader("data")))
+			_, err := b.Get(ctx, key)
+			assert.Error(t, err)
+			_, err = b.Exists(ctx, key)
+			assert.Error(t, err)
+			assert.Error(t, b.Remove(ctx, key))
+			assert.Error(t, b.RemoveMany(ctx, "key/0", key))
+			_, err = b.List(ctx, key)
+			assert.Error(t, err)
+			assert.Error(t, b.Pull(ctx, pail.SyncOptions{Local: t.TempDir(), Remote: key}))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// CompressionExtensions are the key extensions of chunks stored compressed,
+// which readers decompress regardless of their extension.
+var CompressionExtensions = []string{"gz", "zst"}
+
+// TrimCompressionExtension returns the key without its compression
+// extension, if any, e.g. "chunk.json" for "chunk.json.gz".
+func TrimCompressionExtension(key string) string {
+	for _, ext := range CompressionExtensions {
+		if trimmed := strings.TrimSuffix(key, "."+ext); trimmed != key {
+			return trimmed
+		}
+	}
+
+	return key
+}
+
+// Decompress returns a reader of the decompressed contents of the reader if
+// the object's key has a compression extension or the object was stored
+// with a gzip or zstd content encoding, and otherwise of the contents
+// unchanged, so that compressed data written as is, e.g. an uploaded
+// archive, reads back as written. Closing the returned reader closes the
+// given one.
+func Decompress(r io.ReadCloser, key, contentEncoding string) (io.ReadCloser, error) {
+	codec := contentEncoding
+	switch {
+	case strings.HasSuffix(key, ".gz"):
+		codec = "gzip"
+	case strings.HasSuffix(key, ".zst"):
+		codec = "zstd"
+	}
+
+	switch codec {
+	case "gzip":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			_ = r.Close()
+			return nil, errors.Wrap(err, "creating gzip reader")
+		}
+		return &decompressingReadCloser{Reader: gr, closers: []io.Closer{gr, r}}, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			_ = r.Close()
+			return nil, errors.Wrap(err, "creating zstd reader")
+		}
+		return &decompressingReadCloser{Reader: zr, closers: []io.Closer{zr.IOReadCloser(), r}}, nil
+	default:
+		return r, nil
+	}
+}
+
+type decompressingReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *decompressingReadCloser) Close() error {
+	var err error
+	for _, closer := range r.closers {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
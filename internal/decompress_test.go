@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompress(t *testing.T) {
+	data := []byte("line0\nline1\n")
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err := gw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	zw, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	zst := zw.EncodeAll(data, nil)
+	require.NoError(t, zw.Close())
+
+	for _, test := range []struct {
+		name            string
+		key             string
+		contentEncoding string
+		stored          []byte
+		expected        []byte
+	}{
+		{name: "Plain", key: "key", stored: data, expected: data},
+		{name: "Gzip", key: "key.gz", stored: gz.Bytes(), expected: data},
+		{name: "Zstd", key: "key.zst", stored: zst, expected: data},
+		{name: "GzipContentEncoding", key: "key", contentEncoding: "gzip", stored: gz.Bytes(), expected: data},
+		{name: "ZstdContentEncoding", key: "key", contentEncoding: "zstd", stored: zst, expected: data},
+		{name: "GzipWithoutExtension", key: "key.gz.blob", stored: gz.Bytes(), expected: gz.Bytes()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := Decompress(io.NopCloser(bytes.NewReader(test.stored)), test.key, test.contentEncoding)
+			require.NoError(t, err)
+			decompressed, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, test.expected, decompressed)
+		})
+		if test.contentEncoding != "" {
+			continue
+		}
+		t.Run(test.name+"Range", func(t *testing.T) {
+			ctx := context.Background()
+			session, err := NewBucketSession(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+			require.NoError(t, err)
+			bucket, err := session.Create(ctx, "prefix")
+			require.NoError(t, err)
+			require.NoError(t, bucket.Put(ctx, test.key, bytes.NewReader(test.stored)))
+
+			r, err := GetRange(ctx, bucket, test.key, 6)
+			require.NoError(t, err)
+			rest, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, test.expected[6:], rest, "offsets are of the decompressed contents")
+		})
+	}
+	t.Run("Empty", func(t *testing.T) {
+		r, err := Decompress(io.NopCloser(bytes.NewReader(nil)), "key", "")
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Empty(t, decompressed)
+	})
+	t.Run("Corrupt", func(t *testing.T) {
+		_, err := Decompress(io.NopCloser(bytes.NewReader(gz.Bytes()[:4])), "key.gz", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestTrimCompressionExtension(t *testing.T) {
+	assert.Equal(t, "chunk.json", TrimCompressionExtension("chunk.json.gz"))
+	assert.Equal(t, "chunk.json", TrimCompressionExtension("chunk.json.zst"))
+	assert.Equal(t, "chunk.json", TrimCompressionExtension("chunk.json"))
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const gridfsConnectTimeout = 10 * time.Second
+
+// gridfsBucketSession creates GridFS backed buckets that share a single
+// MongoDB client.
+type gridfsBucketSession struct {
+	client *mongo.Client
+	opts   options.Bucket
+}
+
+var (
+	gridfsSessionsMu sync.Mutex
+	gridfsSessions   = map[string]*gridfsBucketSession{}
+)
+
+// getGridFSBucketSession returns the session for the bucket options' URI,
+// database, and GridFS bucket name, connecting a new client the first time
+// the session is requested.
+func getGridFSBucketSession(ctx context.Context, opts options.Bucket) (*gridfsBucketSession, error) {
+	gridfsSessionsMu.Lock()
+	defer gridfsSessionsMu.Unlock()
+
+	id := opts.GridFS.URI + "/" + opts.GridFS.Database + "/" + opts.Name
+	if session, ok := gridfsSessions[id]; ok {
+		return session, nil
+	}
+
+	client, err := mongo.NewClient(mongooptions.Client().ApplyURI(opts.GridFS.URI))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating MongoDB client")
+	}
+	connCtx, cancel := context.WithTimeout(ctx, gridfsConnectTimeout)
+	defer cancel()
+	if err = client.Connect(connCtx); err != nil {
+		return nil, errors.Wrap(err, "connecting to MongoDB")
+	}
+
+	session := &gridfsBucketSession{client: client, opts: opts}
+	gridfsSessions[id] = session
+
+	return session, nil
+}
+
+func (s *gridfsBucketSession) Create(ctx context.Context, prefix string) (pail.Bucket, error) {
+	bucket, err := pail.NewGridFSBucketWithClient(ctx, s.client, pail.GridFSOptions{
+		Name:     s.opts.Name,
+		Prefix:   prefix,
+		Database: s.opts.GridFS.Database,
+	})
+
+	return bucket, errors.Wrap(err, "creating GridFS backed bucket")
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGridFSBucketSession(t *testing.T) {
+	ctx := context.Background()
+	newOpts := func(uri, name string) options.Bucket {
+		return options.Bucket{
+			Type:   options.PailGridFS,
+			Name:   name,
+			Prefix: "prefix",
+			GridFS: &options.GridFSBucket{URI: uri, Database: "cedar"},
+		}
+	}
+
+	t.Run("Shared", func(t *testing.T) {
+		// Connecting does not wait for the server, so no server is
+		// needed.
+		a, err := getGridFSBucketSession(ctx, newOpts("mongodb://localhost:27017", "logs"))
+		require.NoError(t, err)
+		b, err := getGridFSBucketSession(ctx, newOpts("mongodb://localhost:27017", "logs"))
+		require.NoError(t, err)
+		assert.Same(t, a, b)
+
+		c, err := getGridFSBucketSession(ctx, newOpts("mongodb://localhost:27017", "other"))
+		require.NoError(t, err)
+		assert.NotSame(t, a, c)
+	})
+	t.Run("InvalidURI", func(t *testing.T) {
+		opts := newOpts("localhost:27017", "logs")
+		_, err := getGridFSBucketSession(ctx, opts)
+		assert.Error(t, err)
+
+		id := opts.GridFS.URI + "/" + opts.GridFS.Database + "/" + opts.Name
+		assert.NotContains(t, gridfsSessions, id)
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		opts := newOpts("", "logs")
+		assert.Error(t, opts.Validate())
+		opts.GridFS = nil
+		assert.Error(t, opts.Validate())
+	})
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// MaxLifecycleRuleIDLength is the maximum length of the ID of an S3
+// lifecycle rule.
+const MaxLifecycleRuleIDLength = 255
+
+// LifecycleRule is an S3 lifecycle rule for the objects under a prefix.
+type LifecycleRule struct {
+	ID              string
+	Prefix          string
+	Tags            map[string]string
+	ExpireAfterDays int
+	Transitions     []options.RetentionTransition
+}
+
+// ReplaceLifecycleRules replaces the lifecycle rules of the S3 bucket whose
+// IDs start with idPrefix with the given rules, leaving any other rules of
+// the bucket untouched.
+func ReplaceLifecycleRules(ctx context.Context, opts options.Bucket, idPrefix string, rules []LifecycleRule) error {
+	if err := opts.Validate(); err != nil {
+		return errors.Wrap(err, "invalid bucket options")
+	}
+	if opts.Type != options.PailS3 {
+		return errors.New("lifecycle rules are only supported by S3 buckets")
+	}
+
+	sess, err := newAWSSession(opts)
+	if err != nil {
+		return err
+	}
+	svc := s3.New(sess)
+
+	var existing []*s3.LifecycleRule
+	out, err := svc.GetBucketLifecycleConfigurationWithContext(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(opts.Name)})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchLifecycleConfiguration" {
+		err = nil
+	} else if err == nil {
+		existing = out.Rules
+	}
+	if err != nil {
+		return errors.Wrap(err, "getting bucket lifecycle rules")
+	}
+
+	merged := mergeLifecycleRules(existing, idPrefix, rules)
+	if len(merged) == 0 {
+		_, err = svc.DeleteBucketLifecycleWithContext(ctx, &s3.DeleteBucketLifecycleInput{Bucket: aws.String(opts.Name)})
+		return errors.Wrap(err, "deleting bucket lifecycle rules")
+	}
+	_, err = svc.PutBucketLifecycleConfigurationWithContext(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(opts.Name),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{Rules: merged},
+	})
+
+	return errors.Wrap(err, "putting bucket lifecycle rules")
+}
+
+// mergeLifecycleRules returns the existing rules whose IDs do not start with
+// idPrefix followed by the given rules.
+func mergeLifecycleRules(existing []*s3.LifecycleRule, idPrefix string, rules []LifecycleRule) []*s3.LifecycleRule {
+	var merged []*s3.LifecycleRule
+	for _, rule := range existing {
+		if !strings.HasPrefix(aws.StringValue(rule.ID), idPrefix) {
+			merged = append(merged, rule)
+		}
+	}
+	for _, rule := range rules {
+		merged = append(merged, rule.export())
+	}
+
+	return merged
+}
+
+func (r LifecycleRule) export() *s3.LifecycleRule {
+	rule := &s3.LifecycleRule{
+		ID:     aws.String(r.ID),
+		Status: aws.String(s3.ExpirationStatusEnabled),
+		Filter: &s3.LifecycleRuleFilter{Prefix: aws.String(r.Prefix)},
+	}
+	if len(r.Tags) > 0 {
+		keys := make([]string, 0, len(r.Tags))
+		for k := range r.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		and := &s3.LifecycleRuleAndOperator{Prefix: aws.String(r.Prefix)}
+		for _, k := range keys {
+			and.Tags = append(and.Tags, &s3.Tag{Key: aws.String(k), Value: aws.String(r.Tags[k])})
+		}
+		rule.Filter = &s3.LifecycleRuleFilter{And: and}
+	}
+	if r.ExpireAfterDays > 0 {
+		rule.Expiration = &s3.LifecycleExpiration{Days: aws.Int64(int64(r.ExpireAfterDays))}
+	}
+	for _, t := range r.Transitions {
+		rule.Transitions = append(rule.Transitions, &s3.Transition{
+			Days:         aws.Int64(int64(t.AfterDays)),
+			StorageClass: aws.String(string(t.StorageClass)),
+		})
+	}
+
+	return rule
+}
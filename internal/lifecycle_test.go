@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeLifecycleRules(t *testing.T) {
+	existing := []*s3.LifecycleRule{
+		{ID: aws.String("cedar:logs:old:logs")},
+		{ID: aws.String("cedar:logs2:rule:logs")},
+		{ID: aws.String("manual")},
+	}
+	merged := mergeLifecycleRules(existing, "cedar:logs:", []LifecycleRule{
+		{ID: "cedar:logs:new:logs", Prefix: "logs/logs/project/", ExpireAfterDays: 30},
+	})
+	var ids []string
+	for _, rule := range merged {
+		ids = append(ids, aws.StringValue(rule.ID))
+	}
+	assert.Equal(t, []string{"cedar:logs2:rule:logs", "manual", "cedar:logs:new:logs"}, ids, "only rules with the ID prefix are replaced")
+
+	assert.Empty(t, mergeLifecycleRules(existing[:1], "cedar:logs:", nil))
+}
+
+func TestLifecycleRuleExport(t *testing.T) {
+	t.Run("Prefix", func(t *testing.T) {
+		rule := LifecycleRule{
+			ID:              "id",
+			Prefix:          "logs/logs/project/",
+			ExpireAfterDays: 90,
+			Transitions:     []options.RetentionTransition{{AfterDays: 30, StorageClass: options.S3StorageClassStandardIA}},
+		}.export()
+		assert.Equal(t, "id", aws.StringValue(rule.ID))
+		assert.Equal(t, s3.ExpirationStatusEnabled, aws.StringValue(rule.Status))
+		require.NotNil(t, rule.Filter)
+		assert.Equal(t, "logs/logs/project/", aws.StringValue(rule.Filter.Prefix))
+		assert.Nil(t, rule.Filter.And)
+		require.NotNil(t, rule.Expiration)
+		assert.EqualValues(t, 90, aws.Int64Value(rule.Expiration.Days))
+		require.Len(t, rule.Transitions, 1)
+		assert.EqualValues(t, 30, aws.Int64Value(rule.Transitions[0].Days))
+		assert.Equal(t, "STANDARD_IA", aws.StringValue(rule.Transitions[0].StorageClass))
+	})
+	t.Run("Tags", func(t *testing.T) {
+		rule := LifecycleRule{
+			ID:          "id",
+			Prefix:      "logs/logs/",
+			Tags:        map[string]string{"ttl": "short", "project": "cedar"},
+			Transitions: []options.RetentionTransition{{AfterDays: 30, StorageClass: options.S3StorageClassGlacierIR}},
+		}.export()
+		require.NotNil(t, rule.Filter.And)
+		assert.Nil(t, rule.Filter.Prefix)
+		assert.Equal(t, "logs/logs/", aws.StringValue(rule.Filter.And.Prefix))
+		require.Len(t, rule.Filter.And.Tags, 2)
+		assert.Equal(t, "project", aws.StringValue(rule.Filter.And.Tags[0].Key), "tags are sorted")
+		assert.Equal(t, "ttl", aws.StringValue(rule.Filter.And.Tags[1].Key))
+		assert.Nil(t, rule.Expiration)
+	})
+}
+
+func TestReplaceLifecycleRulesUnsupported(t *testing.T) {
+	err := ReplaceLifecycleRules(context.Background(), options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "logs"}, "cedar:logs:", nil)
+	assert.Error(t, err)
+}
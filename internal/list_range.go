@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/evergreen-ci/pail"
+	"github.com/pkg/errors"
+)
+
+// ObjectInfoItem is implemented by the items of buckets whose listings
+// include the size and storage class of each object.
+type ObjectInfoItem interface {
+	// Size is the stored size of the object in bytes, e.g. after
+	// compression.
+	Size() int64
+	// StorageClass is the storage class of the object, e.g. "STANDARD".
+	StorageClass() string
+}
+
+// ListRangeBucket is implemented by buckets that can list only the keys
+// within a range without listing the keys before it.
+type ListRangeBucket interface {
+	ListRange(ctx context.Context, prefix, startAfter, end string) (pail.BucketIterator, error)
+}
+
+// ListRange lists the keys with the given prefix that are after startAfter
+// and at or before end, either of which may be empty to leave the range
+// unbounded on that side. Buckets that do not support ranged listings list
+// the whole prefix and skip the keys outside the range.
+func ListRange(ctx context.Context, bucket pail.Bucket, prefix, startAfter, end string) (pail.BucketIterator, error) {
+	if rb, ok := bucket.(ListRangeBucket); ok {
+		return rb.ListRange(ctx, prefix, startAfter, end)
+	}
+
+	it, err := bucket.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if startAfter == "" && end == "" {
+		return it, nil
+	}
+
+	return &rangeIterator{BucketIterator: it, startAfter: startAfter, end: end}, nil
+}
+
+// rangeIterator skips the keys of the wrapped iterator outside a range.
+type rangeIterator struct {
+	pail.BucketIterator
+	startAfter string
+	end        string
+}
+
+func (it *rangeIterator) Next(ctx context.Context) bool {
+	for it.BucketIterator.Next(ctx) {
+		name := it.Item().Name()
+		if (it.startAfter == "" || name > it.startAfter) && (it.end == "" || name <= it.end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *s3RangeBucket) ListRange(ctx context.Context, prefix, startAfter, end string) (pail.BucketIterator, error) {
+	return &s3RangeIterator{b: b, prefix: prefix, startAfter: startAfter, end: end}, nil
+}
+
+// s3RangeIterator lists a range of keys of an S3 bucket, starting the
+// listing after the start of the range and stopping it at the end, since S3
+// lists keys in order.
+type s3RangeIterator struct {
+	b          *s3RangeBucket
+	prefix     string
+	startAfter string
+	end        string
+	token      *string
+	started    bool
+	page       []*s3.Object
+	idx        int
+	item       pail.BucketItem
+	done       bool
+	err        error
+}
+
+func (b *s3RangeBucket) normalizeKey(key string) string {
+	if b.prefix == "" {
+		return key
+	}
+
+	return b.prefix + "/" + key
+}
+
+func (b *s3RangeBucket) denormalizeKey(key string) string {
+	if b.prefix != "" && len(key) > len(b.prefix)+1 {
+		return key[len(b.prefix)+1:]
+	}
+
+	return key
+}
+
+func (it *s3RangeIterator) Next(ctx context.Context) bool {
+	for it.idx >= len(it.page) {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		input := &s3.ListObjectsV2Input{
+			Bucket:            aws.String(it.b.name),
+			Prefix:            aws.String(it.b.normalizeKey(it.prefix)),
+			ContinuationToken: it.token,
+		}
+		if !it.started && it.startAfter != "" {
+			input.StartAfter = aws.String(it.b.normalizeKey(it.startAfter))
+		}
+		out, err := it.b.svc.ListObjectsV2WithContext(ctx, input)
+		if err != nil {
+			it.err = errors.Wrap(err, "listing objects")
+			return false
+		}
+		it.started = true
+		it.page = out.Contents
+		it.idx = 0
+		it.token = out.NextContinuationToken
+		it.done = !aws.BoolValue(out.IsTruncated)
+	}
+
+	obj := it.page[it.idx]
+	it.idx++
+	name := it.b.denormalizeKey(aws.StringValue(obj.Key))
+	if it.end != "" && name > it.end {
+		it.done = true
+		it.page = nil
+		return false
+	}
+	it.item = &s3RangeItem{
+		b:            it.b,
+		name:         name,
+		hash:         aws.StringValue(obj.ETag),
+		size:         aws.Int64Value(obj.Size),
+		storageClass: aws.StringValue(obj.StorageClass),
+	}
+
+	return true
+}
+
+func (it *s3RangeIterator) Err() error { return it.err }
+
+func (it *s3RangeIterator) Item() pail.BucketItem { return it.item }
+
+type s3RangeItem struct {
+	b            *s3RangeBucket
+	name         string
+	hash         string
+	size         int64
+	storageClass string
+}
+
+func (i *s3RangeItem) Bucket() string       { return i.b.name }
+func (i *s3RangeItem) Name() string         { return i.name }
+func (i *s3RangeItem) Hash() string         { return i.hash }
+func (i *s3RangeItem) Size() int64          { return i.size }
+func (i *s3RangeItem) StorageClass() string { return i.storageClass }
+
+func (i *s3RangeItem) Get(ctx context.Context) (io.ReadCloser, error) {
+	return i.b.Get(ctx, i.name)
+}
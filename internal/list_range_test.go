@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListRange(t *testing.T) {
+	ctx := context.Background()
+	local, err := pail.NewLocalBucket(pail.LocalOptions{Path: t.TempDir()})
+	require.NoError(t, err)
+	for _, key := range []string{"a/1", "a/2", "a/3", "a/4", "b/1"} {
+		require.NoError(t, local.Put(ctx, key, strings.NewReader(key)))
+	}
+	list := func(t *testing.T, bucket pail.Bucket, prefix, startAfter, end string) []string {
+		it, err := ListRange(ctx, bucket, prefix, startAfter, end)
+		require.NoError(t, err)
+		var keys []string
+		for it.Next(ctx) {
+			keys = append(keys, it.Item().Name())
+		}
+		require.NoError(t, it.Err())
+		return keys
+	}
+
+	for name, bucket := range map[string]pail.Bucket{
+		"Local":    local,
+		"Retry":    NewRetryBucket(local, options.Retry{Attempts: 1}),
+		"Confined": NewConfinedBucket(local),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ElementsMatch(t, []string{"a/1", "a/2", "a/3", "a/4"}, list(t, bucket, "a", "", ""))
+			assert.ElementsMatch(t, []string{"a/2", "a/3", "a/4"}, list(t, bucket, "a", "a/1", ""))
+			assert.ElementsMatch(t, []string{"a/1", "a/2", "a/3"}, list(t, bucket, "a", "", "a/3"))
+			assert.ElementsMatch(t, []string{"a/2", "a/3"}, list(t, bucket, "a", "a/1", "a/3"))
+			assert.Empty(t, list(t, bucket, "a", "a/4", ""))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// ObjectAttributes are attributes of uploaded objects that are only stored
+// by backends supporting object metadata, e.g. S3.
+type ObjectAttributes struct {
+	ContentType string
+	Tags        map[string]string
+	// StorageClass, if set, overrides the uploader's storage class.
+	StorageClass options.S3StorageClass
+}
+
+// MultipartUploader uploads large objects to S3 using concurrent multipart
+// uploads, as well as objects with attributes that S3 buckets cannot set,
+// e.g. tags or a storage class. Objects are gzipped unless compression is
+// disabled, matching the S3 buckets returned by CreateBucket.
+type MultipartUploader struct {
+	name         string
+	prefix       string
+	threshold    int64
+	compress     bool
+	storageClass options.S3StorageClass
+	uploader     *s3manager.Uploader
+}
+
+// NewMultipartUploader returns a multipart uploader for objects under the
+// given prefix. A nil uploader is returned if the bucket type does not
+// support multipart uploads.
+func NewMultipartUploader(prefix string, opts options.Bucket) (*MultipartUploader, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid bucket options")
+	}
+	if opts.Type != options.PailS3 {
+		return nil, nil
+	}
+
+	sess, err := newAWSSession(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MultipartUploader{
+		name:         opts.Name,
+		prefix:       prefix,
+		threshold:    opts.S3.MultipartThreshold,
+		compress:     !opts.S3.DisableCompression,
+		storageClass: opts.S3.StorageClass,
+		uploader: s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+			u.PartSize = opts.S3.MultipartPartSize
+			u.Concurrency = opts.S3.MultipartConcurrency
+		}),
+	}, nil
+}
+
+// ShouldUpload returns whether an object of the given size and attributes
+// should be uploaded with the uploader, i.e. whether it is at or above the
+// multipart threshold, unless multipart uploads are disabled, or has tags
+// or a storage class. It is safe to call on a nil uploader.
+func (u *MultipartUploader) ShouldUpload(size int, attrs ObjectAttributes) bool {
+	if u == nil {
+		return false
+	}
+
+	return len(attrs.Tags) > 0 || u.storageClass != "" || attrs.StorageClass != "" || (u.threshold >= 0 && int64(size) >= u.threshold)
+}
+
+// Upload uploads the data to the given key, using multipart upload if it is
+// larger than a single part.
+func (u *MultipartUploader) Upload(ctx context.Context, key string, attrs ObjectAttributes, r io.Reader) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(u.name),
+		Key:    aws.String(u.prefix + "/" + key),
+		Body:   r,
+	}
+	if attrs.ContentType != "" {
+		input.ContentType = aws.String(attrs.ContentType)
+	}
+	if len(attrs.Tags) > 0 {
+		input.Tagging = aws.String(encodeTags(attrs.Tags))
+	}
+	storageClass := u.storageClass
+	if attrs.StorageClass != "" {
+		storageClass = attrs.StorageClass
+	}
+	if storageClass != "" {
+		input.StorageClass = aws.String(string(storageClass))
+	}
+	if u.compress {
+		pr, pw := io.Pipe()
+		go func() {
+			gz := gzip.NewWriter(pw)
+			if _, err := io.Copy(gz, r); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			_ = pw.CloseWithError(gz.Close())
+		}()
+		defer pr.Close()
+
+		input.Body = pr
+		input.ContentEncoding = aws.String("gzip")
+	}
+
+	_, err := u.uploader.UploadWithContext(ctx, input)
+
+	return errors.Wrapf(err, "uploading '%s' with multipart upload", key)
+}
+
+// encodeTags encodes object tags as the URL query parameters expected by
+// S3.
+func encodeTags(tags map[string]string) string {
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+
+	return values.Encode()
+}
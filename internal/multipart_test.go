@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMultipartUploader(t *testing.T) {
+	newS3Opts := func(threshold int64) options.Bucket {
+		return options.Bucket{
+			Type:   options.PailS3,
+			Name:   "bucket",
+			Prefix: "prefix",
+			S3: &options.S3Bucket{
+				Key:                "key",
+				Secret:             "secret",
+				MultipartThreshold: threshold,
+			},
+		}
+	}
+
+	t.Run("Local", func(t *testing.T) {
+		u, err := NewMultipartUploader("logs", options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "prefix"})
+		require.NoError(t, err)
+		assert.Nil(t, u)
+		assert.False(t, u.ShouldUpload(1<<30, ObjectAttributes{Tags: map[string]string{"key": "value"}}))
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		u, err := NewMultipartUploader("logs", newS3Opts(-1))
+		require.NoError(t, err)
+		require.NotNil(t, u, "tagged objects are still uploaded with the uploader")
+		assert.False(t, u.ShouldUpload(1<<30, ObjectAttributes{}))
+		assert.True(t, u.ShouldUpload(1, ObjectAttributes{Tags: map[string]string{"key": "value"}}))
+	})
+	t.Run("Threshold", func(t *testing.T) {
+		u, err := NewMultipartUploader("logs", newS3Opts(1024))
+		require.NoError(t, err)
+		require.NotNil(t, u)
+		assert.False(t, u.ShouldUpload(1023, ObjectAttributes{}))
+		assert.True(t, u.ShouldUpload(1024, ObjectAttributes{}))
+		assert.Equal(t, int64(16*1024*1024), u.uploader.PartSize)
+		assert.Equal(t, 5, u.uploader.Concurrency)
+	})
+	t.Run("Compression", func(t *testing.T) {
+		u, err := NewMultipartUploader("logs", newS3Opts(1024))
+		require.NoError(t, err)
+		require.NotNil(t, u)
+		assert.True(t, u.compress)
+
+		opts := newS3Opts(1024)
+		opts.S3.DisableCompression = true
+		u, err = NewMultipartUploader("logs", opts)
+		require.NoError(t, err)
+		require.NotNil(t, u)
+		assert.False(t, u.compress)
+	})
+	t.Run("StorageClass", func(t *testing.T) {
+		u, err := NewMultipartUploader("logs", newS3Opts(1024))
+		require.NoError(t, err)
+		assert.False(t, u.ShouldUpload(1, ObjectAttributes{}))
+		assert.True(t, u.ShouldUpload(1, ObjectAttributes{StorageClass: options.S3StorageClassStandardIA}), "storage classes are only set by the uploader")
+
+		opts := newS3Opts(1024)
+		opts.S3.StorageClass = options.S3StorageClassOneZoneIA
+		u, err = NewMultipartUploader("logs", opts)
+		require.NoError(t, err)
+		assert.Equal(t, options.S3StorageClassOneZoneIA, u.storageClass)
+		assert.True(t, u.ShouldUpload(1, ObjectAttributes{}))
+
+		opts.S3.StorageClass = "GLACIER"
+		_, err = NewMultipartUploader("logs", opts)
+		assert.Error(t, err, "classes requiring restores are not supported")
+	})
+	t.Run("InvalidPartSize", func(t *testing.T) {
+		opts := newS3Opts(0)
+		opts.S3.MultipartPartSize = 1024
+		_, err := NewMultipartUploader("logs", opts)
+		assert.Error(t, err)
+	})
+}
@@ -2,10 +2,163 @@ package internal
 
 import (
 	"context"
+	"path/filepath"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
 )
 
-type BucketSession interface {
-	Create(context.Context, string) (pail.Bucket, error)
+// BucketSession creates the buckets of a backend at any key prefix.
+type BucketSession = options.BucketSession
+
+// NewBucketSession returns a session creating buckets, at any prefix, of
+// the backend described by the bucket options. It is the single place
+// where backend defaults, e.g. S3 compression and retries, are defined.
+func NewBucketSession(ctx context.Context, opts options.Bucket) (BucketSession, error) {
+	return NewBucketSessionWithContentType(ctx, "", opts)
+}
+
+// NewBucketSessionWithContentType is the same as NewBucketSession but sets
+// the given MIME type on every object uploaded through the session's
+// buckets. The content type is only honored by backends that support
+// object metadata (e.g. S3).
+func NewBucketSessionWithContentType(ctx context.Context, contentType string, opts options.Bucket) (BucketSession, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid bucket options")
+	}
+
+	var session BucketSession
+	switch opts.Type {
+	case options.PailS3:
+		session = &s3BucketSession{opts: opts, contentType: contentType}
+	case options.PailGridFS:
+		gridfsSession, err := getGridFSBucketSession(ctx, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating GridFS bucket session")
+		}
+		session = gridfsSession
+	case options.PailSFTP:
+		if _, err := getSFTPClient(opts); err != nil {
+			return nil, errors.Wrap(err, "creating SFTP bucket session")
+		}
+		session = &sftpBucketSession{opts: opts}
+	case options.PailLocal:
+		session = &localBucketSession{opts: opts}
+	default:
+		factory, ok := options.GetGlobalBucketSessionRegistry().Get(opts.Type)
+		if !ok {
+			return nil, errors.Errorf("unrecognized Pail type '%s'", opts.Type)
+		}
+		customSession, err := factory(ctx, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating '%s' bucket session", opts.Type)
+		}
+		session = customSession
+	}
+
+	if opts.OperationTimeout > 0 {
+		session = &timeoutBucketSession{BucketSession: session, opts: opts}
+	}
+	if opts.Tenant != "" {
+		session = &confinedBucketSession{BucketSession: session}
+	}
+
+	return session, nil
+}
+
+type s3BucketSession struct {
+	opts        options.Bucket
+	contentType string
+}
+
+func (s *s3BucketSession) Create(_ context.Context, prefix string) (pail.Bucket, error) {
+	bucket, err := pail.NewS3Bucket(pail.S3Options{
+		Name:   s.opts.Name,
+		Prefix: prefix,
+		Region: s.opts.S3.Region,
+		//Permissions: pail.S3Permissions(permissions),
+		Credentials: pail.CreateAWSCredentials(s.opts.S3.Key, s.opts.S3.Secret, ""),
+		MaxRetries:  s3MaxRetries,
+		Compress:    !s.opts.S3.DisableCompression,
+		ContentType: s.contentType,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating AWS S3 backed bucket")
+	}
+	bucket, err = newS3RangeBucket(bucket, s.opts, prefix)
+
+	return bucket, errors.Wrap(err, "creating AWS S3 ranged reader")
+}
+
+// s3MaxRetries is the number of times S3 requests are retried.
+const s3MaxRetries = 10
+
+// newAWSSession returns an AWS session for the S3 bucket options with the
+// same region, credentials, and retries as the session's buckets. The given
+// configs are merged on top, e.g. to set a custom HTTP client. It is the
+// single place where AWS sessions are created; every direct use of the S3
+// API, e.g. multipart uploads or lifecycle rules, must go through it.
+func newAWSSession(opts options.Bucket, configs ...*aws.Config) (*session.Session, error) {
+	config := &aws.Config{
+		Region:      aws.String(opts.S3.Region),
+		Credentials: pail.CreateAWSCredentials(opts.S3.Key, opts.S3.Secret, ""),
+		MaxRetries:  aws.Int(s3MaxRetries),
+	}
+	sess, err := session.NewSession(append([]*aws.Config{config}, configs...)...)
+
+	return sess, errors.Wrap(err, "creating AWS session")
+}
+
+type localBucketSession struct {
+	opts options.Bucket
+}
+
+func (s *localBucketSession) Create(_ context.Context, prefix string) (pail.Bucket, error) {
+	bucket, err := pail.NewLocalBucket(pail.LocalOptions{
+		Path:   s.opts.Name,
+		Prefix: prefix,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating local filesystem backed bucket")
+	}
+	bucket = &localRangeBucket{Bucket: bucket, root: filepath.Join(s.opts.Name, prefix)}
+	if s.opts.LocalSharding != options.LocalShardNone {
+		bucket = NewShardedBucket(bucket, s.opts.LocalSharding)
+	}
+
+	return bucket, nil
+}
+
+// timeoutBucketSession bounds the operations of each bucket created by the
+// wrapped session by the options' operation timeout.
+type timeoutBucketSession struct {
+	BucketSession
+	opts options.Bucket
+}
+
+func (s *timeoutBucketSession) Create(ctx context.Context, prefix string) (pail.Bucket, error) {
+	bucket, err := s.BucketSession.Create(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTimeoutBucket(bucket, s.opts.OperationTimeout), nil
+}
+
+// confinedBucketSession confines each bucket created by the wrapped session
+// to its prefix.
+type confinedBucketSession struct {
+	BucketSession
+}
+
+func (s *confinedBucketSession) Create(ctx context.Context, prefix string) (pail.Bucket, error) {
+	bucket, err := s.BucketSession.Create(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConfinedBucket(bucket), nil
 }
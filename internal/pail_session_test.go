@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBucketSession(t *testing.T) {
+	ctx := context.Background()
+	newLocalOpts := func(t *testing.T) options.Bucket {
+		return options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "prefix"}
+	}
+
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := NewBucketSession(ctx, options.Bucket{Type: options.PailLocal})
+		assert.Error(t, err)
+	})
+	t.Run("Local", func(t *testing.T) {
+		session, err := NewBucketSession(ctx, newLocalOpts(t))
+		require.NoError(t, err)
+		assert.IsType(t, &localBucketSession{}, session)
+
+		b, err := session.Create(ctx, "prefix/logs")
+		require.NoError(t, err)
+		assert.NoError(t, b.Check(ctx))
+	})
+	t.Run("LocalSharding", func(t *testing.T) {
+		opts := newLocalOpts(t)
+		opts.LocalSharding = options.LocalShardHash
+		session, err := NewBucketSession(ctx, opts)
+		require.NoError(t, err)
+
+		b, err := session.Create(ctx, "prefix/logs")
+		require.NoError(t, err)
+		assert.IsType(t, &shardedBucket{}, b)
+	})
+	t.Run("OperationTimeout", func(t *testing.T) {
+		opts := newLocalOpts(t)
+		opts.OperationTimeout = time.Minute
+		session, err := NewBucketSession(ctx, opts)
+		require.NoError(t, err)
+
+		b, err := session.Create(ctx, "prefix/logs")
+		require.NoError(t, err)
+		assert.IsType(t, &timeoutBucket{}, b)
+	})
+	t.Run("S3", func(t *testing.T) {
+		opts := options.Bucket{
+			Type:   options.PailS3,
+			Name:   "bucket",
+			Prefix: "prefix",
+			S3:     &options.S3Bucket{Key: "key", Secret: "secret", Region: "us-east-1"},
+		}
+		session, err := NewBucketSession(ctx, opts)
+		require.NoError(t, err)
+		assert.IsType(t, &s3BucketSession{}, session)
+
+		sess, err := newAWSSession(opts)
+		require.NoError(t, err)
+		assert.NotNil(t, sess)
+	})
+	t.Run("Custom", func(t *testing.T) {
+		local := newLocalOpts(t)
+		var custom options.Bucket
+		require.NoError(t, options.GetGlobalBucketSessionRegistry().AddNew("custom", func(_ context.Context, opts options.Bucket) (BucketSession, error) {
+			custom = opts
+			return &localBucketSession{opts: local}, nil
+		}))
+		defer options.GetGlobalBucketSessionRegistry().Remove("custom")
+
+		opts := options.Bucket{Type: "custom", Name: "bucket", Prefix: "prefix", Custom: "settings"}
+		session, err := NewBucketSession(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, opts, custom)
+		assert.IsType(t, &localBucketSession{}, session)
+	})
+}
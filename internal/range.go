@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// RangeBucket is implemented by buckets that can fetch an object starting at
+// a byte offset without downloading the bytes before it. Offsets are of the
+// decompressed contents of the object, which GetRange returns.
+type RangeBucket interface {
+	GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error)
+}
+
+// GetRange returns the decompressed contents of the object, see Decompress,
+// starting at the byte offset, using a ranged fetch if the bucket supports
+// it and otherwise discarding the bytes before the offset. Offsets past the
+// end of the object return an empty reader. Objects are decompressed by
+// their key's extension, since the bucket does not report their content
+// encoding.
+func GetRange(ctx context.Context, bucket pail.Bucket, key string, offset int64) (io.ReadCloser, error) {
+	if rb, ok := bucket.(RangeBucket); ok {
+		return rb.GetRange(ctx, key, offset)
+	}
+
+	return discardRange(ctx, bucket, key, offset)
+}
+
+func discardRange(ctx context.Context, bucket pail.Bucket, key string, offset int64) (io.ReadCloser, error) {
+	r, err := bucket.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return skipDecompressed(r, key, "", offset)
+}
+
+// skipDecompressed decompresses the object and discards the bytes before
+// the offset.
+func skipDecompressed(r io.ReadCloser, key, contentEncoding string, offset int64) (io.ReadCloser, error) {
+	r, err := Decompress(r, key, contentEncoding)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decompressing '%s'", key)
+	}
+	if offset > 0 {
+		if _, err = io.CopyN(io.Discard, r, offset); err != nil && err != io.EOF {
+			_ = r.Close()
+			return nil, errors.Wrapf(err, "skipping to offset %d of '%s'", offset, key)
+		}
+	}
+
+	return r, nil
+}
+
+// s3RangeBucket fetches byte ranges of objects of an S3 bucket.
+type s3RangeBucket struct {
+	pail.Bucket
+	svc    *s3.S3
+	name   string
+	prefix string
+}
+
+// newS3RangeBucket returns the S3 bucket with support for ranged fetches.
+// Responses are not transparently decompressed, so that objects uploaded
+// compressed can be detected by their content encoding, decompressed, and
+// fetched in full instead.
+func newS3RangeBucket(bucket pail.Bucket, opts options.Bucket, prefix string) (pail.Bucket, error) {
+	sess, err := newAWSSession(opts, aws.NewConfig().WithHTTPClient(&http.Client{Transport: &http.Transport{
+		Proxy:              http.ProxyFromEnvironment,
+		DisableCompression: true,
+	}}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3RangeBucket{Bucket: bucket, svc: s3.New(sess), name: opts.Name, prefix: prefix}, nil
+}
+
+func (b *s3RangeBucket) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	if offset <= 0 || TrimCompressionExtension(key) != key {
+		return b.getDecompressed(ctx, key, offset)
+	}
+	out, err := b.getObject(ctx, key, aws.String(fmt.Sprintf("bytes=%d-", offset)))
+	if err != nil {
+		if awsErr, ok := errors.Cause(err).(awserr.Error); ok && awsErr.Code() == "InvalidRange" {
+			// The offset may be past the end of the stored bytes of an
+			// object uploaded compressed but not of its contents.
+			return b.getDecompressed(ctx, key, offset)
+		}
+		return nil, err
+	}
+	// Byte ranges of objects uploaded compressed, e.g. by pail, do not
+	// correspond to their contents.
+	if aws.StringValue(out.ContentEncoding) != "" {
+		_ = out.Body.Close()
+		return b.getDecompressed(ctx, key, offset)
+	}
+
+	return out.Body, nil
+}
+
+// getDecompressed fetches the whole object, decompressing it by its key's
+// extension or content encoding, and discards the bytes before the offset.
+func (b *s3RangeBucket) getDecompressed(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	out, err := b.getObject(ctx, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return skipDecompressed(out.Body, key, aws.StringValue(out.ContentEncoding), offset)
+}
+
+func (b *s3RangeBucket) getObject(ctx context.Context, key string, byteRange *string) (*s3.GetObjectOutput, error) {
+	out, err := b.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(b.normalizeKey(key)),
+		Range:  byteRange,
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, pail.MakeKeyNotFoundError(err)
+		}
+		return nil, errors.Wrapf(err, "getting '%s'", key)
+	}
+
+	return out, nil
+}
+
+// localRangeBucket fetches byte ranges of the files of a local bucket.
+type localRangeBucket struct {
+	pail.Bucket
+	root string
+}
+
+func (b *localRangeBucket) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(b.root, filepath.FromSlash(key)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, pail.MakeKeyNotFoundError(err)
+		}
+		return nil, errors.Wrapf(err, "opening '%s'", key)
+	}
+
+	if offset <= 0 || TrimCompressionExtension(key) != key {
+		_ = f.Close()
+		return discardRange(ctx, b.Bucket, key, offset)
+	}
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "seeking to offset %d of '%s'", offset, key)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRange(t *testing.T) {
+	ctx := context.Background()
+	read := func(t *testing.T, bucket pail.Bucket, offset int64) string {
+		r, err := GetRange(ctx, bucket, "key", offset)
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	for _, test := range []struct {
+		name     string
+		sharding options.LocalShardScheme
+		wrap     func(pail.Bucket) pail.Bucket
+	}{
+		{name: "Local"},
+		{name: "Sharded", sharding: options.LocalShardHash},
+		{name: "Timeout", wrap: func(b pail.Bucket) pail.Bucket { return NewTimeoutBucket(b, time.Minute) }},
+		{name: "Unsupported", wrap: func(b pail.Bucket) pail.Bucket { return struct{ pail.Bucket }{b} }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			session, err := NewBucketSession(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test", LocalSharding: test.sharding})
+			require.NoError(t, err)
+			bucket, err := session.Create(ctx, "prefix")
+			require.NoError(t, err)
+			if test.wrap != nil {
+				bucket = test.wrap(bucket)
+			}
+			require.NoError(t, bucket.Put(ctx, "key", strings.NewReader("0123456789")))
+
+			assert.Equal(t, "0123456789", read(t, bucket, 0))
+			assert.Equal(t, "56789", read(t, bucket, 5))
+			assert.Empty(t, read(t, bucket, 20), "offsets past the end are empty")
+
+			_, err = GetRange(ctx, bucket, "missing", 5)
+			require.Error(t, err)
+			assert.True(t, pail.IsKeyNotFoundError(err))
+		})
+	}
+}
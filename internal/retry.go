@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// Retry runs the operation until it succeeds, fails with an error that is
+// not retryable, or has been attempted the maximum number of times,
+// waiting with exponential backoff between attempts. The options must have
+// been validated.
+func Retry(ctx context.Context, opts options.Retry, op func() error) error {
+	var err error
+	backoff := opts.Backoff
+	for i := 0; i < opts.Attempts; i++ {
+		if i > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Wrap(ctx.Err(), "waiting to retry")
+			case <-timer.C:
+			}
+			backoff *= 2
+			if opts.MaxBackoff > 0 && backoff > opts.MaxBackoff {
+				backoff = opts.MaxBackoff
+			}
+		}
+
+		if err = op(); err == nil || !opts.Retryable(err) {
+			return err
+		}
+	}
+
+	return errors.Wrapf(err, "giving up after %d attempts", opts.Attempts)
+}
+
+// retryBucket retries the failed read, list, copy, and removal operations
+// of the wrapped bucket. Writes are not retried since the data streamed to
+// them cannot be replayed; callers retry uploads themselves.
+type retryBucket struct {
+	pail.Bucket
+	opts options.Retry
+}
+
+// NewRetryBucket returns a bucket that retries failed operations, other
+// than writes, according to the validated retry options.
+func NewRetryBucket(bucket pail.Bucket, opts options.Retry) pail.Bucket {
+	return &retryBucket{Bucket: bucket, opts: opts}
+}
+
+func (b *retryBucket) Reader(ctx context.Context, key string) (io.ReadCloser, error) {
+	var r io.ReadCloser
+	err := Retry(ctx, b.opts, func() (err error) {
+		r, err = b.Bucket.Reader(ctx, key)
+		return err
+	})
+
+	return r, err
+}
+
+func (b *retryBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	var r io.ReadCloser
+	err := Retry(ctx, b.opts, func() (err error) {
+		r, err = b.Bucket.Get(ctx, key)
+		return err
+	})
+
+	return r, err
+}
+
+func (b *retryBucket) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	var r io.ReadCloser
+	err := Retry(ctx, b.opts, func() (err error) {
+		r, err = GetRange(ctx, b.Bucket, key, offset)
+		return err
+	})
+
+	return r, err
+}
+
+func (b *retryBucket) Download(ctx context.Context, key, path string) error {
+	return Retry(ctx, b.opts, func() error { return b.Bucket.Download(ctx, key, path) })
+}
+
+func (b *retryBucket) Copy(ctx context.Context, opts pail.CopyOptions) error {
+	return Retry(ctx, b.opts, func() error { return b.Bucket.Copy(ctx, opts) })
+}
+
+func (b *retryBucket) Remove(ctx context.Context, key string) error {
+	return Retry(ctx, b.opts, func() error { return b.Bucket.Remove(ctx, key) })
+}
+
+func (b *retryBucket) RemoveMany(ctx context.Context, keys ...string) error {
+	return Retry(ctx, b.opts, func() error { return b.Bucket.RemoveMany(ctx, keys...) })
+}
+
+func (b *retryBucket) List(ctx context.Context, prefix string) (pail.BucketIterator, error) {
+	var it pail.BucketIterator
+	err := Retry(ctx, b.opts, func() (err error) {
+		it, err = b.Bucket.List(ctx, prefix)
+		return err
+	})
+
+	return it, err
+}
+
+func (b *retryBucket) ListRange(ctx context.Context, prefix, startAfter, end string) (pail.BucketIterator, error) {
+	var it pail.BucketIterator
+	err := Retry(ctx, b.opts, func() (err error) {
+		it, err = ListRange(ctx, b.Bucket, prefix, startAfter, end)
+		return err
+	})
+
+	return it, err
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyBucket fails the given number of gets and listings before passing
+// them on to the wrapped bucket.
+type flakyBucket struct {
+	pail.Bucket
+	failures int
+	calls    int
+}
+
+func (b *flakyBucket) fail() error {
+	b.calls++
+	if b.calls <= b.failures {
+		return errors.New("503 service unavailable")
+	}
+	return nil
+}
+
+func (b *flakyBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := b.fail(); err != nil {
+		return nil, err
+	}
+	return b.Bucket.Get(ctx, key)
+}
+
+func (b *flakyBucket) List(ctx context.Context, prefix string) (pail.BucketIterator, error) {
+	if err := b.fail(); err != nil {
+		return nil, err
+	}
+	return b.Bucket.List(ctx, prefix)
+}
+
+func TestRetryBucket(t *testing.T) {
+	ctx := context.Background()
+	local, err := pail.NewLocalBucket(pail.LocalOptions{Path: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, local.Put(ctx, "key", strings.NewReader("data")))
+	newOpts := func(t *testing.T) options.Retry {
+		opts := options.Retry{Attempts: 3, Backoff: time.Millisecond}
+		require.NoError(t, opts.Validate())
+		return opts
+	}
+
+	t.Run("Transient", func(t *testing.T) {
+		flaky := &flakyBucket{Bucket: local, failures: 2}
+		bucket := NewRetryBucket(flaky, newOpts(t))
+
+		r, err := bucket.Get(ctx, "key")
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, "data", string(data))
+		assert.Equal(t, 3, flaky.calls)
+	})
+	t.Run("GivesUp", func(t *testing.T) {
+		flaky := &flakyBucket{Bucket: local, failures: 3}
+		bucket := NewRetryBucket(flaky, newOpts(t))
+
+		_, err := bucket.List(ctx, "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "giving up after 3 attempts")
+		assert.Equal(t, 3, flaky.calls)
+	})
+	t.Run("NotRetryable", func(t *testing.T) {
+		flaky := &flakyBucket{Bucket: local, failures: 3}
+		opts := newOpts(t)
+		opts.Retryable = func(error) bool { return false }
+		bucket := NewRetryBucket(flaky, opts)
+
+		_, err := bucket.Get(ctx, "key")
+		require.Error(t, err)
+		assert.Equal(t, 1, flaky.calls)
+	})
+	t.Run("MaxBackoff", func(t *testing.T) {
+		opts := options.Retry{Attempts: 4, Backoff: 15 * time.Millisecond, MaxBackoff: 15 * time.Millisecond}
+		require.NoError(t, opts.Validate())
+
+		start := time.Now()
+		calls := 0
+		err := Retry(ctx, opts, func() error {
+			calls++
+			return errors.New("failed")
+		})
+		require.Error(t, err)
+		assert.Equal(t, 4, calls)
+		assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond), "backoff is capped")
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		opts := newOpts(t)
+		opts.Backoff = time.Minute
+		cctx, cancel := context.WithCancel(ctx)
+		calls := 0
+		err := Retry(cctx, opts, func() error {
+			calls++
+			cancel()
+			return errors.New("failed")
+		})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,450 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sftpDialTimeout = 10 * time.Second
+	// sftpTempExtension is the extension of the temporary files objects
+	// are written to, chosen so as not to collide with callers' keys.
+	sftpTempExtension = ".sftp-tmp"
+)
+
+var (
+	sftpClientsMu sync.Mutex
+	sftpClients   = map[string]*sftp.Client{}
+)
+
+// getSFTPClient returns the SFTP client for the bucket options' address,
+// credentials, and host key, connecting a new client the first time the
+// client is requested and whenever the previous client's connection
+// dropped.
+func getSFTPClient(opts options.Bucket) (*sftp.Client, error) {
+	sftpClientsMu.Lock()
+	defer sftpClientsMu.Unlock()
+
+	id := sftpClientID(opts.SFTP)
+	if client, ok := sftpClients[id]; ok {
+		return client, nil
+	}
+
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(opts.SFTP.HostKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing host key")
+	}
+	var auth []ssh.AuthMethod
+	if opts.SFTP.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(opts.SFTP.PrivateKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing private key")
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if opts.SFTP.Password != "" {
+		auth = append(auth, ssh.Password(opts.SFTP.Password))
+	}
+
+	conn, err := ssh.Dial("tcp", opts.SFTP.Address, &ssh.ClientConfig{
+		User:            opts.SFTP.User,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         sftpDialTimeout,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "connecting to '%s'", opts.SFTP.Address)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrap(err, "starting SFTP session")
+	}
+	cacheSFTPClient(id, client)
+
+	return client, nil
+}
+
+// cacheSFTPClient caches the client until its connection drops. The caller
+// must hold sftpClientsMu.
+func cacheSFTPClient(id string, client *sftp.Client) {
+	sftpClients[id] = client
+	go func() {
+		_ = client.Wait()
+		evictSFTPClient(id, client)
+	}()
+}
+
+// sftpClientID identifies the clients that may be shared, i.e. those of the
+// same server, credentials, and host key, without retaining the
+// credentials.
+func sftpClientID(opts *options.SFTPBucket) string {
+	h := sha256.New()
+	for _, field := range []string{opts.Address, opts.User, opts.Password, opts.PrivateKey, opts.HostKey} {
+		_, _ = h.Write([]byte(field))
+		_, _ = h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// evictSFTPClient removes the client, if it is still cached, so that the
+// next request reconnects.
+func evictSFTPClient(id string, client *sftp.Client) {
+	sftpClientsMu.Lock()
+	defer sftpClientsMu.Unlock()
+
+	if sftpClients[id] == client {
+		delete(sftpClients, id)
+	}
+}
+
+// sftpBucket is a pail.Bucket storing each object as a file under a root
+// directory of an SFTP server. Objects are written to a temporary file and
+// renamed into place, so readers never observe partially written objects.
+type sftpBucket struct {
+	// client returns the SFTP client, which is replaced once its
+	// connection drops.
+	client func() (*sftp.Client, error)
+	root   string
+}
+
+// sftpBucketSession creates SFTP backed buckets that share a single client.
+type sftpBucketSession struct {
+	opts options.Bucket
+}
+
+// Create returns a bucket storing objects under the bucket options' name,
+// which is a directory on the server, joined with the given prefix.
+func (s *sftpBucketSession) Create(_ context.Context, prefix string) (pail.Bucket, error) {
+	opts := s.opts
+	return &sftpBucket{
+		client: func() (*sftp.Client, error) { return getSFTPClient(opts) },
+		root:   path.Join(s.opts.Name, prefix),
+	}, nil
+}
+
+func (b *sftpBucket) path(key string) string { return path.Join(b.root, key) }
+
+func (b *sftpBucket) Check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Stat(b.root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "checking root directory '%s'", b.root)
+}
+
+func (b *sftpBucket) Exists(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	client, err := b.client()
+	if err != nil {
+		return false, err
+	}
+	_, err = client.Stat(b.path(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "checking '%s'", key)
+	}
+
+	return true, nil
+}
+
+func (b *sftpBucket) Join(elems ...string) string { return path.Join(elems...) }
+
+func (b *sftpBucket) Writer(ctx context.Context, key string) (io.WriteCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := b.client()
+	if err != nil {
+		return nil, err
+	}
+	fn := b.path(key)
+	if err = client.MkdirAll(path.Dir(fn)); err != nil {
+		return nil, errors.Wrapf(err, "creating directory for '%s'", key)
+	}
+	f, err := client.Create(fn + sftpTempExtension)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating temporary file for '%s'", key)
+	}
+
+	return &sftpWriter{File: f, client: client, fn: fn}, nil
+}
+
+// sftpWriter renames the temporary file into place once closed.
+type sftpWriter struct {
+	*sftp.File
+	client *sftp.Client
+	fn     string
+}
+
+func (w *sftpWriter) Close() error {
+	tmp := w.File.Name()
+	if err := w.File.Close(); err != nil {
+		_ = w.client.Remove(tmp)
+		return errors.Wrapf(err, "closing '%s'", tmp)
+	}
+	if err := w.client.PosixRename(tmp, w.fn); err == nil {
+		return nil
+	}
+
+	// Servers without the posix-rename extension refuse to rename over
+	// an existing file.
+	if err := w.client.Remove(w.fn); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "replacing '%s'", w.fn)
+	}
+
+	return errors.Wrapf(w.client.Rename(tmp, w.fn), "renaming '%s' into place", tmp)
+}
+
+func (b *sftpBucket) Reader(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := b.client()
+	if err != nil {
+		return nil, err
+	}
+	f, err := client.Open(b.path(key))
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening '%s'", key)
+	}
+
+	return f, nil
+}
+
+func (b *sftpBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	w, err := b.Writer(ctx, key)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, r); err != nil {
+		_ = w.Close()
+		return errors.Wrapf(err, "writing '%s'", key)
+	}
+
+	return w.Close()
+}
+
+func (b *sftpBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.Reader(ctx, key)
+}
+
+func (b *sftpBucket) Upload(ctx context.Context, key, fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return errors.Wrapf(err, "opening local file '%s'", fn)
+	}
+	defer f.Close()
+
+	return b.Put(ctx, key, f)
+}
+
+func (b *sftpBucket) Download(ctx context.Context, key, fn string) error {
+	r, err := b.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err = os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return errors.Wrapf(err, "creating directory for local file '%s'", fn)
+	}
+	f, err := os.Create(fn)
+	if err != nil {
+		return errors.Wrapf(err, "creating local file '%s'", fn)
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "downloading '%s'", key)
+	}
+
+	return errors.Wrapf(f.Close(), "closing local file '%s'", fn)
+}
+
+func (b *sftpBucket) Push(ctx context.Context, opts pail.SyncOptions) error {
+	return push(ctx, b, opts)
+}
+
+func (b *sftpBucket) Pull(ctx context.Context, opts pail.SyncOptions) error {
+	return pull(ctx, b, opts)
+}
+
+func (b *sftpBucket) Copy(ctx context.Context, opts pail.CopyOptions) error {
+	r, err := b.Get(ctx, opts.SourceKey)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return opts.DestinationBucket.Put(ctx, opts.DestinationKey, r)
+}
+
+func (b *sftpBucket) Remove(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+	err = client.Remove(b.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "removing '%s'", key)
+}
+
+func (b *sftpBucket) RemoveMany(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := b.Remove(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *sftpBucket) RemovePrefix(ctx context.Context, prefix string) error {
+	keys, err := b.keys(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	return b.RemoveMany(ctx, keys...)
+}
+
+func (b *sftpBucket) RemoveMatching(ctx context.Context, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return errors.Wrap(err, "compiling regular expression")
+	}
+	keys, err := b.keys(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	var matching []string
+	for _, key := range keys {
+		if re.MatchString(key) {
+			matching = append(matching, key)
+		}
+	}
+
+	return b.RemoveMany(ctx, matching...)
+}
+
+func (b *sftpBucket) List(ctx context.Context, prefix string) (pail.BucketIterator, error) {
+	keys, err := b.keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sftpIterator{bucket: b, keys: keys, idx: -1}, nil
+}
+
+// keys returns the sorted keys of the objects with the given prefix.
+// Temporary files of in-progress writes are skipped. Only the directory
+// containing the prefix is walked.
+func (b *sftpBucket) keys(ctx context.Context, prefix string) ([]string, error) {
+	client, err := b.client()
+	if err != nil {
+		return nil, err
+	}
+	dir := prefix
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+
+	var keys []string
+	walker := client.Walk(path.Join(b.root, dir))
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := walker.Err(); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "listing '%s'", walker.Path())
+		}
+		if walker.Stat().IsDir() || strings.HasSuffix(walker.Path(), sftpTempExtension) {
+			continue
+		}
+
+		key := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), b.root), "/")
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+type sftpIterator struct {
+	bucket *sftpBucket
+	keys   []string
+	idx    int
+}
+
+func (it *sftpIterator) Next(ctx context.Context) bool {
+	if ctx.Err() != nil || it.idx+1 >= len(it.keys) {
+		return false
+	}
+	it.idx++
+
+	return true
+}
+
+func (it *sftpIterator) Err() error { return nil }
+
+func (it *sftpIterator) Item() pail.BucketItem {
+	return &sftpItem{bucket: it.bucket, key: it.keys[it.idx]}
+}
+
+type sftpItem struct {
+	bucket *sftpBucket
+	key    string
+}
+
+func (i *sftpItem) Bucket() string { return i.bucket.root }
+func (i *sftpItem) Name() string   { return i.key }
+func (i *sftpItem) Hash() string   { return "" }
+func (i *sftpItem) Get(ctx context.Context) (io.ReadCloser, error) {
+	return i.bucket.Get(ctx, i.key)
+}
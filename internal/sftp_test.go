@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newInMemorySFTPBucket returns an SFTP bucket backed by an in-memory SFTP
+// server.
+func newInMemorySFTPBucket(t *testing.T) *sftpBucket {
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, sftp.InMemHandler())
+	go func() { _ = server.Serve() }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	return &sftpBucket{client: func() (*sftp.Client, error) { return client, nil }, root: "/logs/prefix"}
+}
+
+func TestSFTPBucket(t *testing.T) {
+	ctx := context.Background()
+	get := func(t *testing.T, b pail.Bucket, key string) string {
+		r, err := b.Get(ctx, key)
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+	list := func(t *testing.T, b pail.Bucket, prefix string) []string {
+		it, err := b.List(ctx, prefix)
+		require.NoError(t, err)
+		var keys []string
+		for it.Next(ctx) {
+			keys = append(keys, it.Item().Name())
+		}
+		require.NoError(t, it.Err())
+		return keys
+	}
+
+	t.Run("PutGet", func(t *testing.T) {
+		b := newInMemorySFTPBucket(t)
+		require.NoError(t, b.Check(ctx))
+		require.NoError(t, b.Put(ctx, "key/0", strings.NewReader("first")))
+		assert.Equal(t, "first", get(t, b, "key/0"))
+
+		require.NoError(t, b.Put(ctx, "key/0", strings.NewReader("second")))
+		assert.Equal(t, "second", get(t, b, "key/0"), "puts replace existing objects")
+
+		exists, err := b.Exists(ctx, "key/0")
+		require.NoError(t, err)
+		assert.True(t, exists)
+		exists, err = b.Exists(ctx, "key/1")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+	t.Run("ListAndRemove", func(t *testing.T) {
+		b := newInMemorySFTPBucket(t)
+		for _, key := range []string{"a/1", "a/0", "b/0"} {
+			require.NoError(t, b.Put(ctx, key, strings.NewReader(key)))
+		}
+		w, err := b.Writer(ctx, "a/2")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"a/0", "a/1", "b/0"}, list(t, b, ""), "in-progress writes are not listed")
+		assert.Equal(t, []string{"a/0", "a/1"}, list(t, b, "a/"))
+		assert.Equal(t, []string{"a/1"}, list(t, b, "a/1"))
+		assert.Empty(t, list(t, b, "c/"))
+		require.NoError(t, w.Close())
+
+		require.NoError(t, b.RemovePrefix(ctx, "a/"))
+		assert.Equal(t, []string{"b/0"}, list(t, b, ""))
+		require.NoError(t, b.Remove(ctx, "missing"))
+	})
+	t.Run("Sync", func(t *testing.T) {
+		b := newInMemorySFTPBucket(t)
+		local := t.TempDir()
+		require.NoError(t, b.Put(ctx, "key/0", strings.NewReader("0")))
+		require.NoError(t, b.Put(ctx, "key/1", strings.NewReader("1")))
+
+		require.NoError(t, b.Pull(ctx, pail.SyncOptions{Local: local, Remote: "key", Exclude: "^1$"}))
+		other := newInMemorySFTPBucket(t)
+		require.NoError(t, other.Push(ctx, pail.SyncOptions{Local: local, Remote: "copy"}))
+		assert.Equal(t, []string{"copy/0"}, list(t, other, ""))
+	})
+	t.Run("ClientID", func(t *testing.T) {
+		opts := options.SFTPBucket{Address: "localhost:22", User: "user", Password: "password", HostKey: "key"}
+		other := opts
+		other.HostKey = "other"
+		assert.NotEqual(t, sftpClientID(&opts), sftpClientID(&other), "clients verifying different host keys are not shared")
+		other = opts
+		other.Password = "other"
+		assert.NotEqual(t, sftpClientID(&opts), sftpClientID(&other), "clients with different credentials are not shared")
+		assert.NotContains(t, sftpClientID(&opts), "password")
+	})
+	t.Run("EvictDroppedClient", func(t *testing.T) {
+		serverConn, clientConn := net.Pipe()
+		server := sftp.NewRequestServer(serverConn, sftp.InMemHandler())
+		go func() { _ = server.Serve() }()
+		client, err := sftp.NewClientPipe(clientConn, clientConn)
+		require.NoError(t, err)
+
+		sftpClientsMu.Lock()
+		cacheSFTPClient(t.Name(), client)
+		sftpClientsMu.Unlock()
+		require.NoError(t, server.Close())
+		assert.Eventually(t, func() bool {
+			sftpClientsMu.Lock()
+			defer sftpClientsMu.Unlock()
+			_, ok := sftpClients[t.Name()]
+			return !ok
+		}, 5*time.Second, 10*time.Millisecond, "clients are evicted once their connection drops")
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := getSFTPClient(options.Bucket{
+			Type: options.PailSFTP,
+			Name: "logs",
+			SFTP: &options.SFTPBucket{Address: "localhost:22", User: "user", Password: "password", HostKey: "invalid"},
+		})
+		assert.Error(t, err)
+	})
+}
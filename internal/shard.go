@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// shardedBucket stores each object of the wrapped bucket under shard
+// subdirectories inserted between the directory and the name of its key.
+// The shard path is a pure function of the key, so objects are found
+// without listing, and listings map the stored paths back to keys, so
+// callers only ever see unsharded keys.
+type shardedBucket struct {
+	pail.Bucket
+	scheme options.LocalShardScheme
+}
+
+// NewShardedBucket returns a bucket that fans the objects of the given
+// bucket out into subdirectories according to the shard scheme. Objects
+// stored without sharding, e.g. before sharding was enabled, are still
+// listed under their own keys.
+func NewShardedBucket(bucket pail.Bucket, scheme options.LocalShardScheme) pail.Bucket {
+	return &shardedBucket{Bucket: bucket, scheme: scheme}
+}
+
+// shard returns the stored path of the key.
+func (b *shardedBucket) shard(key string) string {
+	dir, name := path.Split(key)
+
+	var shard string
+	switch b.scheme {
+	case options.LocalShardHash:
+		// Hash the name without its extensions so that temporary
+		// objects, e.g. "<name>.json.tmp", share their target's shard.
+		stem := name
+		if idx := strings.Index(stem, "."); idx >= 0 {
+			stem = stem[:idx]
+		}
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(stem))
+		sum := h.Sum32()
+		shard = fmt.Sprintf("%02x/%02x", sum>>24, (sum>>16)&0xff)
+	case options.LocalShardDate:
+		idx := strings.Index(name, "_")
+		if idx <= 0 {
+			return key
+		}
+		ts, err := strconv.ParseInt(name[:idx], 10, 64)
+		if err != nil {
+			return key
+		}
+		shard = time.Unix(0, ts).UTC().Format("2006/01/02")
+	default:
+		return key
+	}
+
+	return dir + shard + "/" + name
+}
+
+// unshard returns the key of the stored path. Paths that are not the shard
+// path of any key are returned as is.
+func (b *shardedBucket) unshard(stored string) string {
+	depth := 2
+	if b.scheme == options.LocalShardDate {
+		depth = 3
+	}
+
+	parts := strings.Split(stored, "/")
+	if len(parts) <= depth {
+		return stored
+	}
+	key := path.Join(path.Join(parts[:len(parts)-1-depth]...), parts[len(parts)-1])
+	if b.shard(key) != stored {
+		return stored
+	}
+
+	return key
+}
+
+func (b *shardedBucket) Exists(ctx context.Context, key string) (bool, error) {
+	return b.Bucket.Exists(ctx, b.shard(key))
+}
+
+func (b *shardedBucket) Writer(ctx context.Context, key string) (io.WriteCloser, error) {
+	return b.Bucket.Writer(ctx, b.shard(key))
+}
+
+func (b *shardedBucket) Reader(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.Bucket.Reader(ctx, b.shard(key))
+}
+
+func (b *shardedBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	return b.Bucket.Put(ctx, b.shard(key), r)
+}
+
+func (b *shardedBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.Bucket.Get(ctx, b.shard(key))
+}
+
+func (b *shardedBucket) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	return GetRange(ctx, b.Bucket, b.shard(key), offset)
+}
+
+func (b *shardedBucket) Upload(ctx context.Context, key, path string) error {
+	return b.Bucket.Upload(ctx, b.shard(key), path)
+}
+
+func (b *shardedBucket) Download(ctx context.Context, key, path string) error {
+	return b.Bucket.Download(ctx, b.shard(key), path)
+}
+
+func (b *shardedBucket) Push(ctx context.Context, opts pail.SyncOptions) error {
+	return push(ctx, b, opts)
+}
+
+func (b *shardedBucket) Pull(ctx context.Context, opts pail.SyncOptions) error {
+	return pull(ctx, b, opts)
+}
+
+// Copy copies from the source key's shard path. The destination bucket is
+// responsible for its own sharding.
+func (b *shardedBucket) Copy(ctx context.Context, opts pail.CopyOptions) error {
+	opts.SourceKey = b.shard(opts.SourceKey)
+	return b.Bucket.Copy(ctx, opts)
+}
+
+func (b *shardedBucket) Remove(ctx context.Context, key string) error {
+	return b.Bucket.Remove(ctx, b.shard(key))
+}
+
+func (b *shardedBucket) RemoveMany(ctx context.Context, keys ...string) error {
+	stored := make([]string, len(keys))
+	for i, key := range keys {
+		stored[i] = b.shard(key)
+	}
+
+	return b.Bucket.RemoveMany(ctx, stored...)
+}
+
+func (b *shardedBucket) RemovePrefix(ctx context.Context, prefix string) error {
+	return b.removeWhere(ctx, prefix, func(string) bool { return true })
+}
+
+func (b *shardedBucket) RemoveMatching(ctx context.Context, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return errors.Wrap(err, "compiling regular expression")
+	}
+
+	return b.removeWhere(ctx, "", re.MatchString)
+}
+
+// removeWhere removes the objects with the given key prefix whose keys
+// satisfy the predicate.
+func (b *shardedBucket) removeWhere(ctx context.Context, prefix string, match func(string) bool) error {
+	it, err := b.Bucket.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	var stored []string
+	for it.Next(ctx) {
+		name := it.Item().Name()
+		if key := b.unshard(name); strings.HasPrefix(key, prefix) && match(key) {
+			stored = append(stored, name)
+		}
+	}
+	if err = it.Err(); err != nil {
+		return err
+	}
+
+	return b.Bucket.RemoveMany(ctx, stored...)
+}
+
+func (b *shardedBucket) List(ctx context.Context, prefix string) (pail.BucketIterator, error) {
+	it, err := b.Bucket.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shardedIterator{BucketIterator: it, bucket: b, prefix: prefix}, nil
+}
+
+// shardedIterator returns the items of the wrapped iterator under their
+// unsharded keys, skipping any whose key does not have the listed prefix.
+type shardedIterator struct {
+	pail.BucketIterator
+	bucket *shardedBucket
+	prefix string
+	item   pail.BucketItem
+}
+
+func (it *shardedIterator) Next(ctx context.Context) bool {
+	for it.BucketIterator.Next(ctx) {
+		item := it.BucketIterator.Item()
+		key := it.bucket.unshard(item.Name())
+		if strings.HasPrefix(key, it.prefix) {
+			it.item = &shardedItem{BucketItem: item, key: key}
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *shardedIterator) Item() pail.BucketItem { return it.item }
+
+type shardedItem struct {
+	pail.BucketItem
+	key string
+}
+
+func (i *shardedItem) Name() string { return i.key }
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedBucket(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2021, 11, 19, 12, 0, 0, 0, time.UTC).UnixNano()
+	chunk := fmt.Sprintf("key/%d_instance_0", ts)
+	keys := []string{chunk, "key/metadata.json", "other/name"}
+
+	newBucket := func(t *testing.T, scheme options.LocalShardScheme) (pail.Bucket, string) {
+		dir := t.TempDir()
+		local, err := pail.NewLocalBucket(pail.LocalOptions{Path: dir})
+		require.NoError(t, err)
+		b := NewShardedBucket(local, scheme)
+		for _, key := range keys {
+			require.NoError(t, b.Put(ctx, key, strings.NewReader(key)))
+		}
+		return b, dir
+	}
+	list := func(t *testing.T, b pail.Bucket, prefix string) []string {
+		it, err := b.List(ctx, prefix)
+		require.NoError(t, err)
+		var listed []string
+		for it.Next(ctx) {
+			listed = append(listed, it.Item().Name())
+		}
+		require.NoError(t, it.Err())
+		sort.Strings(listed)
+		return listed
+	}
+
+	for _, test := range []struct {
+		scheme options.LocalShardScheme
+		stored string
+	}{
+		{scheme: options.LocalShardDate, stored: fmt.Sprintf("key/2021/11/19/%d_instance_0", ts)},
+		{scheme: options.LocalShardHash},
+	} {
+		t.Run(string(test.scheme), func(t *testing.T) {
+			b, dir := newBucket(t, test.scheme)
+			assert.Equal(t, keys, list(t, b, ""))
+			assert.Equal(t, keys[:2], list(t, b, "key/"))
+
+			stored := b.(*shardedBucket).shard(chunk)
+			assert.NotEqual(t, chunk, stored)
+			if test.stored != "" {
+				assert.Equal(t, test.stored, stored)
+			}
+			_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(stored)))
+			assert.NoError(t, err, "the chunk is stored at its shard path")
+			assert.Equal(t, chunk, b.(*shardedBucket).unshard(stored))
+
+			exists, err := b.Exists(ctx, chunk)
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			require.NoError(t, b.RemovePrefix(ctx, "key/"))
+			assert.Equal(t, keys[2:], list(t, b, ""))
+		})
+	}
+	t.Run("Unsharded", func(t *testing.T) {
+		dir := t.TempDir()
+		local, err := pail.NewLocalBucket(pail.LocalOptions{Path: dir})
+		require.NoError(t, err)
+		require.NoError(t, local.Put(ctx, "key/old", strings.NewReader("old")))
+
+		b := NewShardedBucket(local, options.LocalShardHash)
+		assert.Equal(t, []string{"key/old"}, list(t, b, ""), "objects stored before sharding are still listed")
+	})
+	t.Run("Pull", func(t *testing.T) {
+		b, _ := newBucket(t, options.LocalShardHash)
+		require.NoError(t, b.Put(ctx, "key2/name", strings.NewReader("sibling")))
+
+		local := t.TempDir()
+		require.NoError(t, b.Pull(ctx, pail.SyncOptions{Local: local, Remote: "key"}))
+		var pulled []string
+		require.NoError(t, filepath.Walk(local, func(fn string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				rel, _ := filepath.Rel(local, fn)
+				pulled = append(pulled, filepath.ToSlash(rel))
+			}
+			return err
+		}))
+		assert.Equal(t, []string{chunk[len("key/"):], "metadata.json"}, pulled, "sibling keys are not pulled")
+	})
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/pkg/errors"
+)
+
+// push uploads each file under the local directory of the sync options to
+// the bucket, keyed by its path relative to the directory joined to the
+// remote prefix, for buckets without a native sync.
+func push(ctx context.Context, bucket pail.Bucket, opts pail.SyncOptions) error {
+	exclude, err := compileExclude(opts.Exclude)
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(opts.Local, func(fn string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(opts.Local, fn)
+		if err != nil {
+			return err
+		}
+		if exclude != nil && exclude.MatchString(rel) {
+			return nil
+		}
+
+		return bucket.Upload(ctx, path.Join(opts.Remote, filepath.ToSlash(rel)), fn)
+	})
+}
+
+// pull downloads each object under the remote prefix of the sync options to
+// the local directory, for buckets without a native sync.
+func pull(ctx context.Context, bucket pail.Bucket, opts pail.SyncOptions) error {
+	exclude, err := compileExclude(opts.Exclude)
+	if err != nil {
+		return err
+	}
+	remote := syncListPrefix(opts.Remote)
+	it, err := bucket.List(ctx, remote)
+	if err != nil {
+		return err
+	}
+
+	for it.Next(ctx) {
+		key := it.Item().Name()
+		rel := strings.TrimPrefix(key, remote)
+		if exclude != nil && exclude.MatchString(rel) {
+			continue
+		}
+		if err = bucket.Download(ctx, key, filepath.Join(opts.Local, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+// syncListPrefix returns the prefix that lists the objects under the remote
+// directory, i.e. "<remote>/", so that pulling "logs" does not also pull
+// "logs2".
+func syncListPrefix(remote string) string {
+	if remote == "" || strings.HasSuffix(remote, "/") {
+		return remote
+	}
+
+	return remote + "/"
+}
+
+func compileExclude(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(expr)
+	return re, errors.Wrap(err, "compiling exclude regular expression")
+}
@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+)
+
+// timeoutBucket applies a deadline to each single object operation of the
+// wrapped bucket. Bulk operations, e.g. syncs and prefix removals, are not
+// bounded.
+type timeoutBucket struct {
+	pail.Bucket
+	timeout time.Duration
+}
+
+// NewTimeoutBucket returns a bucket that bounds each single object
+// operation, and each page of a listing, by the given timeout. Readers and
+// writers are bounded from when they are opened until they are closed.
+func NewTimeoutBucket(bucket pail.Bucket, timeout time.Duration) pail.Bucket {
+	return &timeoutBucket{Bucket: bucket, timeout: timeout}
+}
+
+func (b *timeoutBucket) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	return b.Bucket.Check(ctx)
+}
+
+func (b *timeoutBucket) Writer(ctx context.Context, key string) (io.WriteCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	w, err := b.Bucket.Writer(ctx, key)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelWriteCloser{WriteCloser: w, cancel: cancel}, nil
+}
+
+func (b *timeoutBucket) Reader(ctx context.Context, key string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	r, err := b.Bucket.Reader(ctx, key)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelReadCloser{ReadCloser: r, cancel: cancel}, nil
+}
+
+func (b *timeoutBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	return b.Bucket.Put(ctx, key, r)
+}
+
+func (b *timeoutBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	r, err := b.Bucket.Get(ctx, key)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelReadCloser{ReadCloser: r, cancel: cancel}, nil
+}
+
+func (b *timeoutBucket) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	r, err := GetRange(ctx, b.Bucket, key, offset)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelReadCloser{ReadCloser: r, cancel: cancel}, nil
+}
+
+func (b *timeoutBucket) Upload(ctx context.Context, key, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	return b.Bucket.Upload(ctx, key, path)
+}
+
+func (b *timeoutBucket) Download(ctx context.Context, key, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	return b.Bucket.Download(ctx, key, path)
+}
+
+func (b *timeoutBucket) Copy(ctx context.Context, opts pail.CopyOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	return b.Bucket.Copy(ctx, opts)
+}
+
+func (b *timeoutBucket) Remove(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	return b.Bucket.Remove(ctx, key)
+}
+
+func (b *timeoutBucket) RemoveMany(ctx context.Context, keys ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	return b.Bucket.RemoveMany(ctx, keys...)
+}
+
+func (b *timeoutBucket) List(ctx context.Context, prefix string) (pail.BucketIterator, error) {
+	listCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	it, err := b.Bucket.List(listCtx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &timeoutIterator{BucketIterator: it, timeout: b.timeout}, nil
+}
+
+func (b *timeoutBucket) ListRange(ctx context.Context, prefix, startAfter, end string) (pail.BucketIterator, error) {
+	listCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	it, err := ListRange(listCtx, b.Bucket, prefix, startAfter, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return &timeoutIterator{BucketIterator: it, timeout: b.timeout}, nil
+}
+
+// timeoutIterator bounds each call to Next, which may fetch a page of
+// results, by the timeout.
+type timeoutIterator struct {
+	pail.BucketIterator
+	timeout time.Duration
+}
+
+func (it *timeoutIterator) Next(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, it.timeout)
+	defer cancel()
+
+	return it.BucketIterator.Next(ctx)
+}
+
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReadCloser) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+type cancelWriteCloser struct {
+	io.WriteCloser
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriteCloser) Close() error {
+	defer w.cancel()
+	return w.WriteCloser.Close()
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hungBucket blocks each put until its context is done, as if the
+// connection had hung.
+type hungBucket struct {
+	pail.Bucket
+}
+
+func (b *hungBucket) Put(ctx context.Context, _ string, _ io.Reader) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTimeoutBucket(t *testing.T) {
+	ctx := context.Background()
+	local, err := pail.NewLocalBucket(pail.LocalOptions{Path: t.TempDir()})
+	require.NoError(t, err)
+
+	t.Run("Hung", func(t *testing.T) {
+		bucket := NewTimeoutBucket(&hungBucket{Bucket: local}, 10*time.Millisecond)
+		start := time.Now()
+		assert.Error(t, bucket.Put(ctx, "key", strings.NewReader("data")))
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+	t.Run("ReadersOutliveOpen", func(t *testing.T) {
+		bucket := NewTimeoutBucket(local, time.Minute)
+		require.NoError(t, bucket.Put(ctx, "key", strings.NewReader("data")))
+
+		r, err := bucket.Get(ctx, "key")
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, "data", string(data))
+
+		it, err := bucket.List(ctx, "")
+		require.NoError(t, err)
+		var keys []string
+		for it.Next(ctx) {
+			keys = append(keys, it.Item().Name())
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, []string{"key"}, keys)
+	})
+}
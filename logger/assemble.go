@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// assemblyMarkerName is the name of the metadata object, under the merged
+// log's key, recording a completed assembly, and assemblyProgressName that
+// of the object recording an assembly that was started but may not have
+// completed.
+const (
+	assemblyMarkerName   = "_assembled.json"
+	assemblyProgressName = "_assembling.json"
+)
+
+// assemblyMarker records the keys a log was assembled from.
+type assemblyMarker struct {
+	Keys  []string `json:"keys"`
+	Lines int      `json:"lines"`
+}
+
+// Assemble merges the lines of several keys, e.g. the logs of every host of
+// a distributed test, into a single timestamp-ordered log under a new key,
+// so that the merge happens once rather than at every read. Lines with
+// equal timestamps are ordered by the position of their key in the options.
+// Completed assemblies are recorded in the new key's metadata, and
+// assembling into a key that was already assembled is a no-op. Assembling
+// again after a failure resumes after the lines already written.
+func (l *bucketLogger) Assemble(ctx context.Context, opts options.Assemble) error {
+	if err := l.checkWritable("Assemble"); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	markerKey := JoinKey(opts.Key, assemblyMarkerName, "")
+	r, err := l.metaBucket.Get(ctx, markerKey)
+	switch {
+	case err == nil:
+		return r.Close()
+	case !pail.IsKeyNotFoundError(err):
+		return errors.Wrapf(err, "checking for previous assembly of '%s'", opts.Key)
+	}
+	written, err := l.assemblyProgress(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	merged := &lineHeap{}
+	for i, key := range opts.Keys {
+		r, err := l.newReadCloser(ctx, options.Read{Key: key}, false)
+		if err != nil {
+			return errors.Wrapf(err, "reading key '%s'", key)
+		}
+		defer r.Close()
+
+		it := &lineIterator{r: r, registry: l.encodingRegistry, source: i}
+		ok, err := it.next()
+		if err != nil {
+			return errors.Wrapf(err, "reading key '%s'", key)
+		}
+		if ok {
+			heap.Push(merged, it)
+		}
+	}
+
+	encoding := opts.Encoding
+	if encoding == "" {
+		encoding = encode.JSON
+	}
+	var (
+		buffer = make([]LogLine, 0, opts.ChunkLines)
+		total  int
+	)
+	flush := func() error {
+		if len(buffer) == 0 {
+			return nil
+		}
+		if err := l.Write(ctx, options.Write{Key: opts.Key, Data: buffer, Encoding: encoding}); err != nil {
+			return errors.Wrapf(err, "writing assembled log '%s'", opts.Key)
+		}
+		total += len(buffer)
+		buffer = buffer[:0]
+		return nil
+	}
+	for merged.Len() > 0 {
+		it := (*merged)[0]
+		if written > 0 {
+			written--
+			total++
+		} else {
+			buffer = append(buffer, it.line)
+		}
+		if len(buffer) >= opts.ChunkLines {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+
+		ok, err := it.next()
+		if err != nil {
+			return errors.Wrapf(err, "reading key '%s'", opts.Keys[it.source])
+		}
+		if ok {
+			heap.Fix(merged, 0)
+		} else {
+			heap.Pop(merged)
+		}
+	}
+	if err = flush(); err != nil {
+		return err
+	}
+
+	marker, err := json.Marshal(assemblyMarker{Keys: opts.Keys, Lines: total})
+	if err != nil {
+		return errors.Wrap(err, "encoding assembly marker")
+	}
+
+	if err = l.put(ctx, l.metaBucket, markerKey, marker); err != nil {
+		return errors.Wrapf(err, "recording assembly of '%s'", opts.Key)
+	}
+	if l.dryRun != nil {
+		return nil
+	}
+
+	return errors.Wrapf(l.metaBucket.Remove(ctx, JoinKey(opts.Key, assemblyProgressName, "")), "removing assembly progress of '%s'", opts.Key)
+}
+
+// assemblyProgress records the start of the assembly, returning the number
+// of lines already written to the key by a previous, failed, assembly of
+// the same keys. It returns an error if the key has chunks that were not
+// written by an assembly of the same keys.
+func (l *bucketLogger) assemblyProgress(ctx context.Context, opts options.Assemble) (int, error) {
+	progressKey := JoinKey(opts.Key, assemblyProgressName, "")
+	r, err := l.metaBucket.Get(ctx, progressKey)
+	if pail.IsKeyNotFoundError(err) {
+		exists, err := l.Exists(ctx, opts.Key)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, errors.Errorf("key '%s' already has chunks that were not assembled", opts.Key)
+		}
+
+		progress, err := json.Marshal(assemblyMarker{Keys: opts.Keys})
+		if err != nil {
+			return 0, errors.Wrap(err, "encoding assembly progress")
+		}
+
+		return 0, errors.Wrapf(l.put(ctx, l.metaBucket, progressKey, progress), "recording assembly progress of '%s'", opts.Key)
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "checking for previous assembly of '%s'", opts.Key)
+	}
+	defer r.Close()
+
+	var progress assemblyMarker
+	if err = json.NewDecoder(r).Decode(&progress); err != nil {
+		return 0, errors.Wrapf(err, "decoding assembly progress of '%s'", opts.Key)
+	}
+	if len(progress.Keys) != len(opts.Keys) {
+		return 0, errors.Errorf("key '%s' is partially assembled from different keys", opts.Key)
+	}
+	for i := range opts.Keys {
+		if progress.Keys[i] != opts.Keys[i] {
+			return 0, errors.Errorf("key '%s' is partially assembled from different keys", opts.Key)
+		}
+	}
+
+	// The chunks written are counted rather than recorded with the
+	// progress, so that a chunk written just before a failure is not
+	// written again.
+	rc, err := l.newReadCloser(ctx, options.Read{Key: opts.Key}, false)
+	if err != nil {
+		return 0, errors.Wrapf(err, "reading partially assembled key '%s'", opts.Key)
+	}
+	defer rc.Close()
+
+	var written int
+	it := &lineIterator{r: rc, registry: l.encodingRegistry}
+	for {
+		ok, err := it.next()
+		if err != nil {
+			return 0, errors.Wrapf(err, "reading partially assembled key '%s'", opts.Key)
+		}
+		if !ok {
+			return written, nil
+		}
+		written++
+	}
+}
+
+// lineIterator iterates over the decoded lines of a key, one chunk at a
+// time.
+type lineIterator struct {
+	r        ReadCloser
+	registry encode.EncodingRegistry
+	source   int
+	lines    []LogLine
+	idx      int
+	line     LogLine
+}
+
+// next advances to the next line, returning false once all lines have been
+// read.
+func (it *lineIterator) next() (bool, error) {
+	for it.idx >= len(it.lines) {
+		key := it.r.PageToken()
+		if key == "" {
+			return false, nil
+		}
+		data, err := it.r.ReadPage()
+		if err != nil {
+			return false, err
+		}
+		if it.lines, err = decodeLines(it.registry, key, data); err != nil {
+			return false, err
+		}
+		it.idx = 0
+	}
+
+	it.line = it.lines[it.idx]
+	it.idx++
+
+	return true, nil
+}
+
+// lineHeap orders line iterators by the timestamp of their current line,
+// breaking ties by source.
+type lineHeap []*lineIterator
+
+func (h lineHeap) Len() int { return len(h) }
+func (h lineHeap) Less(i, j int) bool {
+	if h[i].line.Timestamp.Equal(h[j].line.Timestamp) {
+		return h[i].source < h[j].source
+	}
+	return h[i].line.Timestamp.Before(h[j].line.Timestamp)
+}
+func (h lineHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *lineHeap) Push(x interface{}) { *h = append(*h, x.(*lineIterator)) }
+func (h *lineHeap) Pop() interface{} {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
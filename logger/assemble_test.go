@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerAssemble(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	line := func(offset int, data string) LogLine {
+		return LogLine{Priority: level.Info, Timestamp: start.Add(time.Duration(offset) * time.Second), Data: data}
+	}
+	newLogger := func(t *testing.T) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "host0", Data: []LogLine{line(0, "host0-a"), line(2, "host0-b")}, Encoding: encode.JSON}))
+		require.NoError(t, l.Write(ctx, options.Write{Key: "host0", Data: []LogLine{line(4, "host0-c")}, Encoding: encode.JSON}))
+		require.NoError(t, l.Write(ctx, options.Write{Key: "host1", Data: []LogLine{line(1, "host1-a"), line(2, "host1-b")}, Encoding: encode.JSON}))
+		return l
+	}
+	read := func(t *testing.T, l Logger, key string) []string {
+		var data []string
+		var token string
+		for {
+			lines, next, err := l.ReadLines(ctx, options.Read{Key: key, PageToken: token})
+			require.NoError(t, err)
+			for _, line := range lines {
+				data = append(data, line.Data.(string))
+			}
+			if next == "" {
+				return data
+			}
+			token = next
+		}
+	}
+	expected := []string{"host0-a", "host1-a", "host0-b", "host1-b", "host0-c"}
+
+	t.Run("Merges", func(t *testing.T) {
+		l := newLogger(t)
+		require.NoError(t, l.Assemble(ctx, options.Assemble{Keys: []string{"host0", "host1"}, Key: "merged", ChunkLines: 3}))
+		assert.Equal(t, expected, read(t, l, "merged"))
+		entries, err := l.Manifest(ctx, "merged")
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+	t.Run("Once", func(t *testing.T) {
+		l := newLogger(t)
+		opts := options.Assemble{Keys: []string{"host0", "host1"}, Key: "merged"}
+		require.NoError(t, l.Assemble(ctx, opts))
+		require.NoError(t, l.Write(ctx, options.Write{Key: "host1", Data: []LogLine{line(5, "host1-c")}, Encoding: encode.JSON}))
+		require.NoError(t, l.Assemble(ctx, opts))
+		assert.Equal(t, expected, read(t, l, "merged"), "assembled keys are not reassembled")
+	})
+	t.Run("ResumesAfterFailure", func(t *testing.T) {
+		l := newLogger(t)
+		opts := options.Assemble{Keys: []string{"host0", "host1"}, Key: "merged", ChunkLines: 2}
+		local := l.logsBucket
+		l.logsBucket = &failingChunkBucket{Bucket: local, seqs: map[uint64]bool{1: true}}
+		require.Error(t, l.Assemble(ctx, opts))
+		entries, err := l.Manifest(ctx, "merged")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		assert.Error(t, l.Assemble(ctx, options.Assemble{Keys: []string{"host1", "host0"}, Key: "merged"}), "a partial assembly cannot be resumed from different keys")
+
+		l.logsBucket = local
+		require.NoError(t, l.Assemble(ctx, opts))
+		assert.Equal(t, expected, read(t, l, "merged"))
+		require.NoError(t, l.Assemble(ctx, opts))
+		assert.Equal(t, expected, read(t, l, "merged"))
+	})
+	t.Run("ExistingKey", func(t *testing.T) {
+		l := newLogger(t)
+		assert.Error(t, l.Assemble(ctx, options.Assemble{Keys: []string{"host0"}, Key: "host1"}))
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		l := newLogger(t)
+		assert.Error(t, l.Assemble(ctx, options.Assemble{Key: "merged"}))
+		assert.Error(t, l.Assemble(ctx, options.Assemble{Keys: []string{"host0"}}))
+		assert.Error(t, l.Assemble(ctx, options.Assemble{Keys: []string{"merged"}, Key: "merged"}))
+	})
+}
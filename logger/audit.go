@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/internal"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// AuditAction is a kind of audited operation.
+type AuditAction string
+
+const (
+	// AuditRead is recorded for reads of a key's lines, e.g. by
+	// NewReadCloser, ReadLines, Search, or Download.
+	AuditRead AuditAction = "read"
+	// AuditExport is recorded when a key's chunks are copied out of the
+	// logger, e.g. by Export, SyncLocal, or Copy.
+	AuditExport AuditAction = "export"
+	// AuditDelete is recorded when a key's chunks are deleted, e.g. by a
+	// Copy that deletes its source.
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditEvent records an audited operation on a key.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Principal is the principal of the operation's context, see
+	// ContextWithPrincipal, if any.
+	Principal string      `json:"principal,omitempty"`
+	Action    AuditAction `json:"action"`
+	Key       string      `json:"key"`
+	// Instance is the ID of the logger instance that performed the
+	// operation.
+	Instance string `json:"instance"`
+}
+
+// audit records the operation on the key, if the logger audits operations,
+// before it is performed, so that an operation that cannot be recorded is
+// refused.
+func (l *bucketLogger) audit(ctx context.Context, action AuditAction, key string) error {
+	if !l.auditing {
+		return nil
+	}
+
+	event := AuditEvent{
+		Time:      l.now().UTC(),
+		Principal: PrincipalFromContext(ctx),
+		Action:    action,
+		Key:       key,
+		Instance:  l.instanceID,
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "encoding audit event")
+	}
+	bucket, err := l.getAuditBucket(ctx)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	name := chunkKey{ts: event.Time.UnixNano(), instance: l.instanceID, seq: l.auditSequence}.String()
+	l.auditSequence++
+	l.mu.Unlock()
+
+	return errors.Wrapf(l.put(ctx, bucket, JoinKey(auditDay(event.Time), name, "json"), data), "recording %s of '%s'", action, key)
+}
+
+// auditDay returns the prefix of the audit events recorded on the UTC day
+// of the time, e.g. "2021/11/19".
+func auditDay(t time.Time) string {
+	return t.UTC().Format("2006/01/02")
+}
+
+// NewAuditBucket returns the bucket in which loggers created from the
+// bucket options record audit events, i.e. the "audit" bucket alongside
+// the logs bucket, e.g. to pass to WithAuditBucket of a reader with
+// separate, write-only, credentials for it.
+func NewAuditBucket(ctx context.Context, opts options.Bucket) (pail.Bucket, error) {
+	session, err := internal.NewBucketSession(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := session.Create(ctx, bucketPrefix(opts, "audit"))
+	return bucket, errors.Wrap(err, "creating audit bucket")
+}
+
+// getAuditBucket returns the bucket storing audit events, creating it on
+// first use.
+func (l *bucketLogger) getAuditBucket(ctx context.Context) (pail.Bucket, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.auditBucket != nil {
+		return l.auditBucket, nil
+	}
+	if l.session == nil {
+		return nil, errors.New("auditing requires an audit bucket")
+	}
+
+	bucket, err := l.session.Create(ctx, bucketPrefix(l.opts, "audit"))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating audit bucket")
+	}
+	if l.retry != nil {
+		bucket = internal.NewRetryBucket(bucket, *l.retry)
+	}
+	l.auditBucket = bucket
+
+	return bucket, nil
+}
+
+// AuditEvents returns the audit events recorded between start and end, in
+// time order.
+func (l *bucketLogger) AuditEvents(ctx context.Context, start, end time.Time) ([]AuditEvent, error) {
+	if end.Before(start) {
+		return nil, errors.New("end cannot be before start")
+	}
+
+	bucket, err := l.getAuditBucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []AuditEvent
+	lastDay := auditDay(end)
+	for day := start.UTC().Truncate(24 * time.Hour); auditDay(day) <= lastDay; day = day.Add(24 * time.Hour) {
+		it, err := bucket.List(ctx, auditDay(day))
+		if err != nil {
+			return nil, errors.Wrap(err, "listing audit events")
+		}
+		for it.Next(ctx) {
+			event, err := getAuditEvent(ctx, bucket, it.Item().Name())
+			if err != nil {
+				return nil, err
+			}
+			if !event.Time.Before(start) && !event.Time.After(end) {
+				events = append(events, event)
+			}
+		}
+		if err = it.Err(); err != nil {
+			return nil, errors.Wrap(err, "iterating audit events")
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	return events, nil
+}
+
+func getAuditEvent(ctx context.Context, bucket pail.Bucket, key string) (AuditEvent, error) {
+	var event AuditEvent
+	r, err := bucket.Get(ctx, key)
+	if err != nil {
+		return event, errors.Wrapf(err, "getting audit event '%s'", key)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return event, errors.Wrapf(err, "reading audit event '%s'", key)
+	}
+
+	return event, errors.Wrapf(json.Unmarshal(data, &event), "decoding audit event '%s'", key)
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingPutBucket is a bucket whose puts all fail.
+type failingPutBucket struct {
+	pail.Bucket
+}
+
+func (failingPutBucket) Put(context.Context, string, io.Reader) error {
+	return errors.New("put failed")
+}
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Minute)
+	newOpts := func(t *testing.T) options.Bucket {
+		return options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+	}
+
+	t.Run("RecordsReads", func(t *testing.T) {
+		opts := newOpts(t)
+		l, err := NewBucketLogger(ctx, opts, WithAudit())
+		require.NoError(t, err)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line\n"}))
+
+		_, _, err = l.ReadLines(ContextWithPrincipal(ctx, "ci"), options.Read{Key: "key"})
+		require.NoError(t, err)
+
+		events, err := l.AuditEvents(ctx, start, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, AuditRead, events[0].Action)
+		assert.Equal(t, "key", events[0].Key)
+		assert.Equal(t, "ci", events[0].Principal)
+	})
+	t.Run("UserKeysCannotForgeEvents", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, newOpts(t), WithAudit())
+		require.NoError(t, err)
+
+		day := auditDay(time.Now())
+		forged := []byte(`{"time":"` + time.Now().UTC().Format(time.RFC3339) + `","action":"read","key":"forged"}`)
+		require.NoError(t, l.AddMetadata(ctx, options.AddMetadata{Key: "_audit/" + day, Data: forged}))
+		_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "_audit/" + day, Data: forged})
+		require.NoError(t, err)
+
+		events, err := l.AuditEvents(ctx, start, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+	t.Run("RefusesUnrecordedOperations", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, newOpts(t))
+		require.NoError(t, err)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line\n"}))
+		auditBucket, err := pail.NewLocalBucket(pail.LocalOptions{Path: t.TempDir()})
+		require.NoError(t, err)
+		require.NoError(t, WithAuditBucket(failingPutBucket{Bucket: auditBucket})(l))
+
+		_, _, err = l.ReadLines(ctx, options.Read{Key: "key"})
+		assert.Error(t, err)
+	})
+	t.Run("ReaderRequiresAuditBucket", func(t *testing.T) {
+		_, err := NewBucketReader(ctx, newOpts(t), WithAudit())
+		assert.Error(t, err)
+	})
+	t.Run("ReaderRecordsInAuditBucket", func(t *testing.T) {
+		opts := newOpts(t)
+		w, err := NewBucketLogger(ctx, opts)
+		require.NoError(t, err)
+		require.NoError(t, w.Write(ctx, options.Write{Key: "key", Data: "line\n"}))
+
+		auditBucket, err := NewAuditBucket(ctx, opts)
+		require.NoError(t, err)
+		r, err := NewBucketReader(ctx, opts, WithAuditBucket(auditBucket))
+		require.NoError(t, err)
+		_, _, err = r.ReadLines(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+
+		audited, err := NewBucketLogger(ctx, opts, WithAudit())
+		require.NoError(t, err)
+		events, err := audited.AuditEvents(ctx, start, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, r.instanceID, events[0].Instance)
+	})
+}
@@ -0,0 +1,416 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// Permission is a kind of operation on the keys of a logger.
+type Permission string
+
+const (
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+	// PermissionDelete is required to remove chunks, metadata, or
+	// manifest entries, i.e. to copy with DeleteSource or to vacuum.
+	PermissionDelete Permission = "delete"
+)
+
+func (p Permission) validate() error {
+	switch p {
+	case PermissionRead, PermissionWrite, PermissionDelete:
+		return nil
+	default:
+		return errors.Errorf("unrecognized permission '%s'", p)
+	}
+}
+
+// Authorizer decides whether principals, e.g. the authenticated clients of
+// a server, may perform operations on keys.
+type Authorizer interface {
+	// Authorize returns an AuthorizationError, or any other error if the
+	// decision cannot be made, unless the principal has the permission
+	// on the key.
+	Authorize(ctx context.Context, principal string, perm Permission, key string) error
+}
+
+// AuthorizationError is returned when a principal lacks the permission
+// required by an operation.
+type AuthorizationError struct {
+	Principal  string
+	Permission Permission
+	Key        string
+}
+
+func (e *AuthorizationError) Error() string {
+	if e.Principal == "" {
+		return fmt.Sprintf("unauthenticated principal cannot %s '%s'", e.Permission, e.Key)
+	}
+
+	return fmt.Sprintf("principal '%s' cannot %s '%s'", e.Principal, e.Permission, e.Key)
+}
+
+// IsAuthorizationError returns whether the cause of the given error is an
+// AuthorizationError.
+func IsAuthorizationError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	_, ok := errors.Cause(err).(*AuthorizationError)
+	return ok
+}
+
+// CheckKeyPath returns an error if the key could resolve outside of the
+// prefixes it is nested under on backends that resolve keys as file paths,
+// e.g. local and SFTP buckets, i.e. if it is absolute or contains a
+// backslash or empty, "." or ".." elements. Prefixes are matched against
+// keys as strings, so authorizers must reject such keys first, since
+// "project/../secret" would otherwise be authorized as a key under
+// "project". The empty key is valid.
+func CheckKeyPath(key string) error {
+	if key == "" {
+		return nil
+	}
+	if strings.Contains(key, "\\") {
+		return errors.Errorf("key '%s' must not contain a backslash", key)
+	}
+	for _, elem := range strings.Split(key, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return errors.Errorf("key '%s' must not contain empty, '.', or '..' elements", key)
+		}
+	}
+
+	return nil
+}
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns a context whose operations on authorized
+// loggers are performed on behalf of the principal.
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set on the context with
+// ContextWithPrincipal, or an empty string if there is none.
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalContextKey{}).(string)
+	return principal
+}
+
+// PrefixPolicy grants principals permissions on the keys under prefixes.
+type PrefixPolicy struct {
+	// Principal is the ID of the principal the policy applies to, or "*"
+	// for every authenticated principal.
+	Principal string `bson:"principal" json:"principal" yaml:"principal"`
+	// Prefixes are the key prefixes the policy applies to. A prefix
+	// matches the key equal to it and the keys nested under it, e.g.
+	// "project" matches "project" and "project/task", but not
+	// "project2". An empty prefix matches every key.
+	Prefixes    []string     `bson:"prefixes" json:"prefixes" yaml:"prefixes"`
+	Permissions []Permission `bson:"permissions" json:"permissions" yaml:"permissions"`
+}
+
+type prefixAuthorizer struct {
+	policies []PrefixPolicy
+}
+
+// NewPrefixAuthorizer returns an authorizer granting principals the
+// permissions of the policies applying to them, and no others.
+func NewPrefixAuthorizer(policies ...PrefixPolicy) (Authorizer, error) {
+	for i, policy := range policies {
+		if policy.Principal == "" {
+			return nil, errors.Errorf("policy %d must specify a principal", i)
+		}
+		if len(policy.Prefixes) == 0 || len(policy.Permissions) == 0 {
+			return nil, errors.Errorf("policy %d must specify at least one prefix and permission", i)
+		}
+		for _, perm := range policy.Permissions {
+			if err := perm.validate(); err != nil {
+				return nil, errors.Wrapf(err, "policy %d", i)
+			}
+		}
+	}
+
+	return &prefixAuthorizer{policies: policies}, nil
+}
+
+func (a *prefixAuthorizer) Authorize(_ context.Context, principal string, perm Permission, key string) error {
+	if principal != "" && CheckKeyPath(key) == nil {
+		for _, policy := range a.policies {
+			if policy.Principal != "*" && policy.Principal != principal {
+				continue
+			}
+			if hasPermission(policy.Permissions, perm) && matchesAnyPrefix(policy.Prefixes, key) {
+				return nil
+			}
+		}
+	}
+
+	return &AuthorizationError{Principal: principal, Permission: perm, Key: key}
+}
+
+func hasPermission(perms []Permission, perm Permission) bool {
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAnyPrefix(prefixes []string, key string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.Trim(prefix, "/")
+		if prefix == "" || key == prefix || strings.HasPrefix(key, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authorizedLogger is a view of a logger that authorizes every operation
+// against the principal of its context.
+type authorizedLogger struct {
+	logger     Logger
+	authorizer Authorizer
+}
+
+// NewAuthorizedLogger returns a view of the logger that only performs
+// operations that the authorizer permits the principal of the operation's
+// context, see ContextWithPrincipal, to perform, returning an
+// AuthorizationError otherwise. Operations on keys failing CheckKeyPath are
+// never authorized. Besides the Logger methods, the view authorizes and
+// forwards Copy, Vacuum, Export, HeadLines, TailLines, Manifest,
+// CountLines, Exists, and Size to loggers that implement them, e.g.
+// bucket loggers, returning an error for loggers that do not.
+func NewAuthorizedLogger(l Logger, a Authorizer) (Logger, error) {
+	if l == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	if a == nil {
+		return nil, errors.New("authorizer cannot be nil")
+	}
+
+	return &authorizedLogger{logger: l, authorizer: a}, nil
+}
+
+func (l *authorizedLogger) authorize(ctx context.Context, perm Permission, key string) error {
+	principal := PrincipalFromContext(ctx)
+	if err := CheckKeyPath(key); err != nil {
+		return errors.Wrap(&AuthorizationError{Principal: principal, Permission: perm, Key: key}, err.Error())
+	}
+
+	return l.authorizer.Authorize(ctx, principal, perm, key)
+}
+
+func (l *authorizedLogger) AddMetadata(ctx context.Context, opts options.AddMetadata) error {
+	if err := l.authorize(ctx, PermissionWrite, opts.Key); err != nil {
+		return err
+	}
+	return l.logger.AddMetadata(ctx, opts)
+}
+
+func (l *authorizedLogger) Write(ctx context.Context, opts options.Write) error {
+	if err := l.authorize(ctx, PermissionWrite, opts.Key); err != nil {
+		return err
+	}
+	return l.logger.Write(ctx, opts)
+}
+
+func (l *authorizedLogger) WriteBytes(ctx context.Context, opts options.WriteBytes) (WriteResult, error) {
+	if err := l.authorize(ctx, PermissionWrite, opts.Key); err != nil {
+		return WriteResult{}, err
+	}
+	return l.logger.WriteBytes(ctx, opts)
+}
+
+func (l *authorizedLogger) WriteReader(ctx context.Context, opts options.WriteReader) error {
+	if err := l.authorize(ctx, PermissionWrite, opts.Key); err != nil {
+		return err
+	}
+	return l.logger.WriteReader(ctx, opts)
+}
+
+func (l *authorizedLogger) FollowFile(ctx context.Context, opts options.FollowFile) error {
+	if err := l.authorize(ctx, PermissionWrite, opts.Key); err != nil {
+		return err
+	}
+	return l.logger.FollowFile(ctx, opts)
+}
+
+func (l *authorizedLogger) NewReadCloser(ctx context.Context, opts options.Read) (ReadCloser, error) {
+	if err := l.authorize(ctx, PermissionRead, opts.Key); err != nil {
+		return nil, err
+	}
+	return l.logger.NewReadCloser(ctx, opts)
+}
+
+func (l *authorizedLogger) NewReverseReadCloser(ctx context.Context, opts options.Read) (ReadCloser, error) {
+	if err := l.authorize(ctx, PermissionRead, opts.Key); err != nil {
+		return nil, err
+	}
+	return l.logger.NewReverseReadCloser(ctx, opts)
+}
+
+func (l *authorizedLogger) ReadLines(ctx context.Context, opts options.Read) ([]LogLine, string, error) {
+	if err := l.authorize(ctx, PermissionRead, opts.Key); err != nil {
+		return nil, "", err
+	}
+	return l.logger.ReadLines(ctx, opts)
+}
+
+func (l *authorizedLogger) Search(ctx context.Context, opts options.Search) ([]SearchResult, error) {
+	if err := l.authorize(ctx, PermissionRead, opts.Key); err != nil {
+		return nil, err
+	}
+	return l.logger.Search(ctx, opts)
+}
+
+func (l *authorizedLogger) Check(ctx context.Context) error { return l.logger.Check(ctx) }
+
+func (l *authorizedLogger) Child(prefix string) Logger { return NewChildLogger(l, prefix) }
+
+func (l *authorizedLogger) unsupported(op string) error {
+	return errors.Errorf("logger does not support %s", op)
+}
+
+// Copy requires read permission on the source key, write permission on
+// the destination key, and, with DeleteSource, delete permission on the
+// source key.
+func (l *authorizedLogger) Copy(ctx context.Context, opts options.Copy) error {
+	c, ok := l.logger.(interface {
+		Copy(context.Context, options.Copy) error
+	})
+	if !ok {
+		return l.unsupported("Copy")
+	}
+	if err := l.authorize(ctx, PermissionRead, opts.SrcKey); err != nil {
+		return err
+	}
+	if err := l.authorize(ctx, PermissionWrite, opts.DstKey); err != nil {
+		return err
+	}
+	if opts.DeleteSource {
+		if err := l.authorize(ctx, PermissionDelete, opts.SrcKey); err != nil {
+			return err
+		}
+	}
+	return c.Copy(ctx, opts)
+}
+
+// Vacuum requires write permission on the prefix, to repair manifest
+// entries, and delete permission, to remove them.
+func (l *authorizedLogger) Vacuum(ctx context.Context, prefix string) (*VacuumReport, error) {
+	v, ok := l.logger.(interface {
+		Vacuum(context.Context, string) (*VacuumReport, error)
+	})
+	if !ok {
+		return nil, l.unsupported("Vacuum")
+	}
+	if err := l.authorize(ctx, PermissionWrite, prefix); err != nil {
+		return nil, err
+	}
+	if err := l.authorize(ctx, PermissionDelete, prefix); err != nil {
+		return nil, err
+	}
+	return v.Vacuum(ctx, prefix)
+}
+
+func (l *authorizedLogger) Export(ctx context.Context, opts options.Export) error {
+	e, ok := l.logger.(interface {
+		Export(context.Context, options.Export) error
+	})
+	if !ok {
+		return l.unsupported("Export")
+	}
+	if err := l.authorize(ctx, PermissionRead, opts.Key); err != nil {
+		return err
+	}
+	return e.Export(ctx, opts)
+}
+
+func (l *authorizedLogger) HeadLines(ctx context.Context, key string, n int) ([]LogLine, error) {
+	h, ok := l.logger.(interface {
+		HeadLines(context.Context, string, int) ([]LogLine, error)
+	})
+	if !ok {
+		return nil, l.unsupported("HeadLines")
+	}
+	if err := l.authorize(ctx, PermissionRead, key); err != nil {
+		return nil, err
+	}
+	return h.HeadLines(ctx, key, n)
+}
+
+func (l *authorizedLogger) TailLines(ctx context.Context, key string, n int) ([]LogLine, error) {
+	t, ok := l.logger.(interface {
+		TailLines(context.Context, string, int) ([]LogLine, error)
+	})
+	if !ok {
+		return nil, l.unsupported("TailLines")
+	}
+	if err := l.authorize(ctx, PermissionRead, key); err != nil {
+		return nil, err
+	}
+	return t.TailLines(ctx, key, n)
+}
+
+func (l *authorizedLogger) Manifest(ctx context.Context, key string) ([]ManifestEntry, error) {
+	m, ok := l.logger.(interface {
+		Manifest(context.Context, string) ([]ManifestEntry, error)
+	})
+	if !ok {
+		return nil, l.unsupported("Manifest")
+	}
+	if err := l.authorize(ctx, PermissionRead, key); err != nil {
+		return nil, err
+	}
+	return m.Manifest(ctx, key)
+}
+
+func (l *authorizedLogger) CountLines(ctx context.Context, opts options.Read) (int64, error) {
+	c, ok := l.logger.(interface {
+		CountLines(context.Context, options.Read) (int64, error)
+	})
+	if !ok {
+		return 0, l.unsupported("CountLines")
+	}
+	if err := l.authorize(ctx, PermissionRead, opts.Key); err != nil {
+		return 0, err
+	}
+	return c.CountLines(ctx, opts)
+}
+
+func (l *authorizedLogger) Exists(ctx context.Context, key string) (bool, error) {
+	e, ok := l.logger.(interface {
+		Exists(context.Context, string) (bool, error)
+	})
+	if !ok {
+		return false, l.unsupported("Exists")
+	}
+	if err := l.authorize(ctx, PermissionRead, key); err != nil {
+		return false, err
+	}
+	return e.Exists(ctx, key)
+}
+
+func (l *authorizedLogger) Size(ctx context.Context, key string) (int64, error) {
+	s, ok := l.logger.(interface {
+		Size(context.Context, string) (int64, error)
+	})
+	if !ok {
+		return 0, l.unsupported("Size")
+	}
+	if err := l.authorize(ctx, PermissionRead, key); err != nil {
+		return 0, err
+	}
+	return s.Size(ctx, key)
+}
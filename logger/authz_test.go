@@ -0,0 +1,243 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewPrefixAuthorizer(
+		PrefixPolicy{Principal: "ci", Prefixes: []string{"project"}, Permissions: []Permission{PermissionRead}},
+		PrefixPolicy{Principal: "*", Prefixes: []string{"public/"}, Permissions: []Permission{PermissionRead}},
+		PrefixPolicy{Principal: "admin", Prefixes: []string{""}, Permissions: []Permission{PermissionRead, PermissionWrite}},
+	)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name       string
+		principal  string
+		perm       Permission
+		key        string
+		authorized bool
+	}{
+		{name: "EqualKey", principal: "ci", perm: PermissionRead, key: "project", authorized: true},
+		{name: "NestedKey", principal: "ci", perm: PermissionRead, key: "project/task", authorized: true},
+		{name: "SiblingKey", principal: "ci", perm: PermissionRead, key: "project2", authorized: false},
+		{name: "SiblingNestedKey", principal: "ci", perm: PermissionRead, key: "project2/task", authorized: false},
+		{name: "MissingPermission", principal: "ci", perm: PermissionWrite, key: "project", authorized: false},
+		{name: "OtherPrincipal", principal: "other", perm: PermissionRead, key: "project", authorized: false},
+		{name: "Wildcard", principal: "other", perm: PermissionRead, key: "public/task", authorized: true},
+		{name: "Unauthenticated", principal: "", perm: PermissionRead, key: "public/task", authorized: false},
+		{name: "EmptyPrefix", principal: "admin", perm: PermissionWrite, key: "anything", authorized: true},
+		{name: "EmptyPrefixMissingPermission", principal: "admin", perm: PermissionDelete, key: "anything", authorized: false},
+		{name: "ParentElement", principal: "ci", perm: PermissionRead, key: "project/../secret", authorized: false},
+		{name: "CurrentElement", principal: "ci", perm: PermissionRead, key: "project/./task", authorized: false},
+		{name: "EmptyElement", principal: "ci", perm: PermissionRead, key: "project//task", authorized: false},
+		{name: "Backslash", principal: "ci", perm: PermissionRead, key: "project/..\\secret", authorized: false},
+		{name: "EmptyPrefixParentElement", principal: "admin", perm: PermissionRead, key: "../outside", authorized: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := a.Authorize(ctx, test.principal, test.perm, test.key)
+			if test.authorized {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, IsAuthorizationError(err))
+			}
+		})
+	}
+}
+
+func TestCheckKeyPath(t *testing.T) {
+	for _, test := range []struct {
+		key   string
+		valid bool
+	}{
+		{key: "", valid: true},
+		{key: "project", valid: true},
+		{key: "project/task.log", valid: true},
+		{key: "project/..task", valid: true},
+		{key: "..", valid: false},
+		{key: "project/../secret", valid: false},
+		{key: "project/./task", valid: false},
+		{key: "project//task", valid: false},
+		{key: "project/", valid: false},
+		{key: "/project", valid: false},
+		{key: "project\\task", valid: false},
+	} {
+		t.Run(test.key, func(t *testing.T) {
+			if test.valid {
+				assert.NoError(t, CheckKeyPath(test.key))
+			} else {
+				assert.Error(t, CheckKeyPath(test.key))
+			}
+		})
+	}
+}
+
+// allowAllAuthorizer authorizes every operation.
+type allowAllAuthorizer struct{}
+
+func (allowAllAuthorizer) Authorize(context.Context, string, Permission, string) error { return nil }
+
+func TestAuthorizedLoggerTraversalKeys(t *testing.T) {
+	ctx := ContextWithPrincipal(context.Background(), "ci")
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	// Traversal keys are rejected even by authorizers that do not check
+	// keys themselves.
+	al, err := NewAuthorizedLogger(l, allowAllAuthorizer{})
+	require.NoError(t, err)
+
+	for _, key := range []string{"project/../secret", "../secret", "project/./task", "project//task"} {
+		t.Run(key, func(t *testing.T) {
+			err := al.Write(ctx, options.Write{Key: key, Data: "line\n"})
+			assert.True(t, IsAuthorizationError(err))
+			_, err = al.WriteBytes(ctx, options.WriteBytes{Key: key, Data: []byte("line\n")})
+			assert.True(t, IsAuthorizationError(err))
+			_, err = al.NewReadCloser(ctx, options.Read{Key: key})
+			assert.True(t, IsAuthorizationError(err))
+		})
+	}
+}
+
+func TestNewPrefixAuthorizerValidation(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		policy PrefixPolicy
+	}{
+		{name: "MissingPrincipal", policy: PrefixPolicy{Prefixes: []string{"a"}, Permissions: []Permission{PermissionRead}}},
+		{name: "MissingPrefixes", policy: PrefixPolicy{Principal: "ci", Permissions: []Permission{PermissionRead}}},
+		{name: "MissingPermissions", policy: PrefixPolicy{Principal: "ci", Prefixes: []string{"a"}}},
+		{name: "UnrecognizedPermission", policy: PrefixPolicy{Principal: "ci", Prefixes: []string{"a"}, Permissions: []Permission{"admin"}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewPrefixAuthorizer(test.policy)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// stringPrefixBucket lists keys by string prefix, as S3 does, rather than
+// by directory, as local buckets do.
+type stringPrefixBucket struct {
+	pail.Bucket
+}
+
+func (b stringPrefixBucket) List(ctx context.Context, prefix string) (pail.BucketIterator, error) {
+	it, err := b.Bucket.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &stringPrefixIterator{BucketIterator: it, prefix: prefix}, nil
+}
+
+type stringPrefixIterator struct {
+	pail.BucketIterator
+	prefix string
+}
+
+func (it *stringPrefixIterator) Next(ctx context.Context) bool {
+	for it.BucketIterator.Next(ctx) {
+		if strings.HasPrefix(it.Item().Name(), it.prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestAuthorizedLoggerSiblingKeys(t *testing.T) {
+	ctx := context.Background()
+	metaBucket, err := pail.NewLocalBucket(pail.LocalOptions{Path: t.TempDir()})
+	require.NoError(t, err)
+	logsBucket, err := pail.NewLocalBucket(pail.LocalOptions{Path: t.TempDir()})
+	require.NoError(t, err)
+	l, err := NewBucketLoggerFromBuckets(metaBucket, stringPrefixBucket{Bucket: logsBucket})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "project", Data: "granted\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "project2", Data: "denied\n"}))
+
+	a, err := NewPrefixAuthorizer(PrefixPolicy{Principal: "ci", Prefixes: []string{"project"}, Permissions: []Permission{PermissionRead}})
+	require.NoError(t, err)
+	al, err := NewAuthorizedLogger(l, a)
+	require.NoError(t, err)
+	ctx = ContextWithPrincipal(ctx, "ci")
+
+	r, err := al.NewReadCloser(ctx, options.Read{Key: "project"})
+	require.NoError(t, err)
+	var data []byte
+	for {
+		page, err := r.ReadPage()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data = append(data, page...)
+	}
+	require.NoError(t, r.Close())
+	assert.Equal(t, "granted\n", string(data))
+
+	_, err = al.NewReadCloser(ctx, options.Read{Key: "project2"})
+	assert.True(t, IsAuthorizationError(err))
+
+	exists, err := l.Exists(ctx, "project")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "other2", Data: "x\n"}))
+	exists, err = l.Exists(ctx, "other")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestAuthorizedLoggerDelete(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "project/src", Data: "line\n"}))
+
+	a, err := NewPrefixAuthorizer(
+		PrefixPolicy{Principal: "ci", Prefixes: []string{"project"}, Permissions: []Permission{PermissionRead, PermissionWrite}},
+		PrefixPolicy{Principal: "admin", Prefixes: []string{"project"}, Permissions: []Permission{PermissionRead, PermissionWrite, PermissionDelete}},
+	)
+	require.NoError(t, err)
+	logger, err := NewAuthorizedLogger(l, a)
+	require.NoError(t, err)
+	al := logger.(*authorizedLogger)
+	ci := ContextWithPrincipal(ctx, "ci")
+	admin := ContextWithPrincipal(ctx, "admin")
+
+	err = al.Copy(ci, options.Copy{SrcKey: "project/src", DstKey: "project/moved", DeleteSource: true})
+	require.True(t, IsAuthorizationError(err))
+	assert.Equal(t, PermissionDelete, errors.Cause(err).(*AuthorizationError).Permission)
+	require.NoError(t, al.Copy(ci, options.Copy{SrcKey: "project/src", DstKey: "project/copy"}))
+	assert.True(t, IsAuthorizationError(al.Copy(ci, options.Copy{SrcKey: "project/src", DstKey: "other"})))
+	require.NoError(t, al.Copy(admin, options.Copy{SrcKey: "project/src", DstKey: "project/moved", DeleteSource: true}))
+	exists, err := al.Exists(ci, "project/src")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = al.Vacuum(ci, "project")
+	assert.True(t, IsAuthorizationError(err))
+	_, err = al.Vacuum(admin, "project")
+	assert.NoError(t, err)
+
+	lines, err := al.HeadLines(ci, "project/moved", 1)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	_, err = al.HeadLines(ctx, "project/moved", 1)
+	assert.True(t, IsAuthorizationError(err), "unauthenticated principals cannot read")
+
+	child, err := NewAuthorizedLogger(l.Child("project"), a)
+	require.NoError(t, err)
+	assert.Error(t, child.(*authorizedLogger).Copy(admin, options.Copy{SrcKey: "src", DstKey: "dst"}), "loggers without Copy do not support it")
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/julianedwards/cedar/options"
+)
+
+// defaultWriteBatchConcurrency is the maximum number of keys written
+// concurrently by WriteBatch for loggers without an upload pool.
+const defaultWriteBatchConcurrency = 16
+
+// WriteBatch writes each of the writes, returning the error of each write
+// in the same order, nil for writes that succeeded. Writes to different
+// keys are encoded and uploaded concurrently, by at most as many goroutines
+// as the logger's upload pool has slots, or 16 without a pool, while writes
+// to the same key are written in order, so that their chunks are sequenced
+// in the order given.
+func (l *bucketLogger) WriteBatch(ctx context.Context, writes []options.Write) []error {
+	groups := map[string][]int{}
+	var keys []string
+	for i, w := range writes {
+		if _, ok := groups[w.Key]; !ok {
+			keys = append(keys, w.Key)
+		}
+		groups[w.Key] = append(groups[w.Key], i)
+	}
+
+	workers := defaultWriteBatchConcurrency
+	if l.uploadPool != nil {
+		workers = l.uploadPool.Concurrency()
+	}
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+
+	queue := make(chan []int, len(keys))
+	for _, key := range keys {
+		queue <- groups[key]
+	}
+	close(queue)
+
+	errs := make([]error, len(writes))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for indexes := range queue {
+				for _, i := range indexes {
+					errs[i] = l.Write(ctx, writes[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
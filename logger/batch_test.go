@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyBucket records the maximum number of concurrent puts.
+type concurrencyBucket struct {
+	pail.Bucket
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (b *concurrencyBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	b.mu.Lock()
+	b.current++
+	if b.current > b.max {
+		b.max = b.current
+	}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.current--
+		b.mu.Unlock()
+	}()
+	time.Sleep(time.Millisecond)
+
+	return b.Bucket.Put(ctx, key, r)
+}
+
+func TestBucketLoggerWriteBatch(t *testing.T) {
+	ctx := context.Background()
+	newLogger := func(t *testing.T, opts ...BucketLoggerOption) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, opts...)
+		require.NoError(t, err)
+		return l
+	}
+
+	t.Run("OrderPerKey", func(t *testing.T) {
+		l := newLogger(t)
+		var writes []options.Write
+		for i := 0; i < 3; i++ {
+			for _, key := range []string{"a", "b"} {
+				writes = append(writes, options.Write{Key: key, Data: fmt.Sprintf("%s%d", key, i)})
+			}
+		}
+		for _, err := range l.WriteBatch(ctx, writes) {
+			require.NoError(t, err)
+		}
+
+		for _, key := range []string{"a", "b"} {
+			var token string
+			for i := 0; i < 3; i++ {
+				lines, next, err := l.ReadLines(ctx, options.Read{Key: key, PageToken: token})
+				require.NoError(t, err)
+				require.Len(t, lines, 1)
+				assert.Equal(t, fmt.Sprintf("%s%d", key, i), lines[0].Data)
+				token = next
+			}
+		}
+	})
+	t.Run("ErrorsInOrder", func(t *testing.T) {
+		l := newLogger(t)
+		errs := l.WriteBatch(ctx, []options.Write{
+			{Key: "a", Data: "a"},
+			{Key: "b"},
+			{Key: "c", Data: "c"},
+		})
+		require.Len(t, errs, 3)
+		assert.NoError(t, errs[0])
+		assert.Error(t, errs[1])
+		assert.NoError(t, errs[2])
+	})
+	t.Run("BoundedByUploadPool", func(t *testing.T) {
+		pool, err := NewUploadPool(2)
+		require.NoError(t, err)
+		l := newLogger(t, WithUploadPool(pool))
+		bucket := &concurrencyBucket{Bucket: l.logsBucket}
+		l.logsBucket = bucket
+
+		var writes []options.Write
+		for i := 0; i < 20; i++ {
+			writes = append(writes, options.Write{Key: fmt.Sprintf("key%d", i), Data: "message"})
+		}
+		for _, err := range l.WriteBatch(ctx, writes) {
+			require.NoError(t, err)
+		}
+		assert.LessOrEqual(t, bucket.max, 2)
+		assert.Zero(t, pool.InFlight())
+	})
+	t.Run("Empty", func(t *testing.T) {
+		assert.Empty(t, newLogger(t).WriteBatch(ctx, nil))
+	})
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// CircuitState is the state of an upload circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed allows uploads.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails uploads immediately until the cooldown elapses.
+	CircuitOpen
+	// CircuitHalfOpen allows a single trial upload, which closes the
+	// breaker if it succeeds and reopens it otherwise.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker stops uploads from being attempted after repeated
+// failures, so that loggers do not hammer a bucket during an outage.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	opts     options.CircuitBreaker
+	now      func() time.Time
+	report   func(*CircuitStateError)
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// allow returns a CircuitOpenError if uploads are short-circuited.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	var change *CircuitStateError
+	defer func() {
+		b.mu.Unlock()
+		if change != nil {
+			b.report(change)
+		}
+	}()
+
+	switch b.state {
+	case CircuitOpen:
+		retryAt := b.openedAt.Add(b.opts.Cooldown)
+		if b.now().Before(retryAt) {
+			return &CircuitOpenError{RetryAt: retryAt}
+		}
+		change = b.transition(CircuitHalfOpen, nil)
+		b.trial = true
+	case CircuitHalfOpen:
+		if b.trial {
+			return &CircuitOpenError{RetryAt: b.now()}
+		}
+		b.trial = true
+	}
+
+	return nil
+}
+
+// record records the outcome of an allowed upload.
+// A cancelled upload frees the trial slot without counting as an outcome.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	var change *CircuitStateError
+	defer func() {
+		b.mu.Unlock()
+		if change != nil {
+			b.report(change)
+		}
+	}()
+
+	b.trial = false
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if err == nil {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			change = b.transition(CircuitClosed, nil)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.opts.Threshold) {
+		b.openedAt = b.now()
+		change = b.transition(CircuitOpen, err)
+	}
+}
+
+func (b *circuitBreaker) transition(to CircuitState, err error) *CircuitStateError {
+	change := &CircuitStateError{From: b.state, To: to, Err: err}
+	b.state = to
+
+	return change
+}
+
+// withCircuitBreaker runs the upload unless the circuit breaker, if any, is
+// open.
+func (l *bucketLogger) withCircuitBreaker(upload func() error) error {
+	if l.breaker == nil {
+		return upload()
+	}
+	if err := l.breaker.allow(); err != nil {
+		return err
+	}
+
+	err := upload()
+	l.breaker.record(err)
+
+	return err
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	var changes []*CircuitStateError
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"},
+		WithClock(func() time.Time { return now }),
+		WithCircuitBreaker(options.CircuitBreaker{Threshold: 2, Cooldown: time.Minute}),
+		WithErrorHandler(func(op string, err error) {
+			assert.Equal(t, "circuit breaker", op)
+			change, ok := err.(*CircuitStateError)
+			require.True(t, ok)
+			changes = append(changes, change)
+		}),
+	)
+	require.NoError(t, err)
+	local := l.logsBucket
+	failing := &failingChunkBucket{Bucket: local, seqs: map[uint64]bool{0: true, 1: true, 2: true}}
+	l.logsBucket = failing
+	write := func() error { return l.Write(ctx, options.Write{Key: "key", Data: "data"}) }
+
+	// Trips after the threshold of consecutive failures.
+	assert.False(t, IsCircuitOpenError(write()))
+	assert.Empty(t, changes)
+	assert.False(t, IsCircuitOpenError(write()))
+	require.Len(t, changes, 1)
+	assert.Equal(t, CircuitClosed, changes[0].From)
+	assert.Equal(t, CircuitOpen, changes[0].To)
+	assert.Contains(t, changes[0].Err.Error(), "put failed")
+
+	// Short-circuits until the cooldown elapses.
+	err = write()
+	require.True(t, IsCircuitOpenError(err))
+	assert.Equal(t, now.Add(time.Minute), errors.Cause(err).(*CircuitOpenError).RetryAt)
+
+	// A failed trial upload reopens the breaker.
+	now = now.Add(time.Minute)
+	assert.False(t, IsCircuitOpenError(write()))
+	require.Len(t, changes, 3)
+	assert.Equal(t, CircuitHalfOpen, changes[1].To)
+	assert.Equal(t, CircuitHalfOpen, changes[2].From)
+	assert.Equal(t, CircuitOpen, changes[2].To)
+	assert.True(t, IsCircuitOpenError(write()))
+
+	// A successful trial upload closes it.
+	now = now.Add(time.Minute)
+	l.logsBucket = local
+	require.NoError(t, write())
+	require.Len(t, changes, 5)
+	assert.Equal(t, CircuitHalfOpen, changes[3].To)
+	assert.Equal(t, CircuitClosed, changes[4].To)
+	assert.NoError(t, changes[4].Err)
+	require.NoError(t, write())
+
+	_, err = NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithCircuitBreaker(options.CircuitBreaker{Threshold: -1}))
+	assert.Error(t, err)
+}
+
+func TestCircuitBreakerCanceledTrial(t *testing.T) {
+	now := time.Now()
+	var changes []*CircuitStateError
+	b := &circuitBreaker{
+		opts:   options.CircuitBreaker{Threshold: 1, Cooldown: time.Minute},
+		now:    func() time.Time { return now },
+		report: func(change *CircuitStateError) { changes = append(changes, change) },
+	}
+
+	require.NoError(t, b.allow())
+	b.record(errors.New("put failed"))
+	require.True(t, IsCircuitOpenError(b.allow()))
+
+	// A cancelled trial upload leaves the breaker half open for another
+	// trial.
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow())
+	b.record(context.Canceled)
+	assert.Equal(t, CircuitHalfOpen, b.state)
+	require.NoError(t, b.allow())
+	assert.True(t, IsCircuitOpenError(b.allow()))
+	b.record(nil)
+	assert.Equal(t, CircuitClosed, b.state)
+	require.Len(t, changes, 3)
+	assert.Equal(t, CircuitClosed, changes[2].To)
+}
@@ -3,9 +3,10 @@ package logger
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"io"
+	"path"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +15,9 @@ import (
 	"github.com/julianedwards/cedar/internal"
 	"github.com/julianedwards/cedar/options"
 	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
 	"github.com/papertrail/go-tail/follower"
 	"github.com/pkg/errors"
 )
@@ -21,80 +25,580 @@ import (
 const (
 	defaultMaxBufferSize int = 1e7
 	defaultFlushInterval     = time.Minute
+	defaultCloseTimeout      = time.Minute
+	defaultPollInterval      = 5 * time.Second
+	defaultFollowTimeout     = 5 * time.Minute
 )
 
 type bucketLogger struct {
-	mu               sync.Mutex
-	metaBucket       pail.Bucket
-	logsBucket       pail.Bucket
-	encodingRegistry encode.EncodingRegistry
+	// mu guards the lazily created content type buckets and key
+	// generation state. Writes are only serialized per key via metaLocks
+	// and logsLocks, since pail buckets are safe for concurrent use.
+	mu                 sync.Mutex
+	metaLocks          keyLocker
+	logsLocks          keyLocker
+	opts               options.Bucket
+	session            internal.BucketSession
+	metaBucket         pail.Bucket
+	logsBucket         pail.Bucket
+	manifestBucket     pail.Bucket
+	multipart          *internal.MultipartUploader
+	contentTypeBuckets map[string]pail.Bucket
+	dedupeBucket       pail.Bucket
+	dedupeManifests    map[string]*dedupeManifest
+	instanceID         string
+	lastKeyTime        int64
+	sequences          map[string]uint64
+	metaSequences      map[string]uint64
+	encodingRegistry   encode.EncodingRegistry
+	followers          map[*followGauges]struct{}
+	followersMu        sync.Mutex
+	keyGenerator       KeyGenerator
+	now                func() time.Time
+	retry              *options.Retry
+	uploadPool         *UploadPool
+	dryRun             send.Sender
+	readOnly           bool
+	redactor           *Redactor
+	middleware         []Middleware
+	traceExtractor     TraceExtractor
+	beforePut          []BeforePutHook
+	onError            []OnErrorHook
+	errorHandler       ErrorHandler
+	breaker            *circuitBreaker
+	chunkCache         *ChunkCache
+	diskCache          *DiskCache
+	// cacheNamespace identifies the logs bucket in the chunk caches.
+	cacheNamespace string
+	// auditing records reads, exports, and deletes as audit events.
+	auditing      bool
+	auditBucket   pail.Bucket
+	auditSequence uint64
+	// attributesBucket, if set, stores the tags and storage class of the
+	// chunks written to a spool, to be applied on upload.
+	attributesBucket pail.Bucket
+	// encryption, if set, encrypts log chunks and metadata client-side.
+	encryption KeyProvider
+	// compressEncrypted gzips encrypted objects before they are
+	// encrypted, unless compression is disabled.
+	compressEncrypted bool
+	// manifestSigner signs the manifest entries written and verifies
+	// those read.
+	manifestSigner ManifestSigner
 }
 
-func NewBucketLogger(ctx context.Context, opts options.Bucket) (*bucketLogger, error) {
-	metaBucket, err := internal.CreateBucket(ctx, opts.Prefix+"/"+"metadata", opts)
+func NewBucketLogger(ctx context.Context, opts options.Bucket, loggerOpts ...BucketLoggerOption) (*bucketLogger, error) {
+	session, err := NewBucketSession(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBucketLoggerWithSession(ctx, session, loggerOpts...)
+}
+
+// NewBucketLoggerWithSession returns a bucket logger whose buckets are
+// created by the given session under the session's prefix.
+func NewBucketLoggerWithSession(ctx context.Context, session *BucketSession, loggerOpts ...BucketLoggerOption) (*bucketLogger, error) {
+	if session == nil {
+		return nil, errors.New("must specify bucket session")
+	}
+
+	l, err := newBucketLogger(session.opts, loggerOpts)
+	if err != nil {
+		return nil, err
+	}
+	l.session = session.session
+
+	opts := session.opts
+	manifestBucket, err := session.session.Create(ctx, bucketPrefix(opts, "manifest"))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating manifest bucket")
+	}
+	// Encrypted objects are compressed before they are encrypted, so the
+	// backend stores them as is.
+	storage := session.session
+	if l.encryption != nil && opts.Type == options.PailS3 && !opts.S3.DisableCompression {
+		s3 := *opts.S3
+		s3.DisableCompression = true
+		opts.S3 = &s3
+		l.opts = opts
+		if storage, err = internal.NewBucketSession(ctx, opts); err != nil {
+			return nil, errors.Wrap(err, "creating encrypted bucket session")
+		}
+	}
+	metaBucket, err := storage.Create(ctx, bucketPrefix(opts, "metadata"))
 	if err != nil {
 		return nil, errors.Wrap(err, "creating metadata bucket")
 	}
-	logsBucket, err := internal.CreateBucket(ctx, opts.Prefix+"/"+"logs", opts)
+	logsBucket, err := storage.Create(ctx, bucketPrefix(opts, "logs"))
 	if err != nil {
 		return nil, errors.Wrap(err, "creating logs bucket")
 	}
+	multipart, err := internal.NewMultipartUploader(bucketPrefix(opts, "logs"), opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating multipart uploader")
+	}
+	l.setBuckets(metaBucket, logsBucket, manifestBucket, multipart)
+
+	return l, nil
+}
+
+// bucketRoot returns the key prefix under which all of the logger's buckets
+// are stored. A tenant's buckets are stored under their own root, so that
+// no key of one tenant addresses an object of another.
+func bucketRoot(opts options.Bucket) string {
+	if opts.Tenant == "" {
+		return opts.Prefix
+	}
+
+	return opts.Prefix + "/tenants/" + opts.Tenant
+}
+
+// bucketPrefix returns the key prefix of the logger's bucket with the given
+// name, e.g. "logs".
+func bucketPrefix(opts options.Bucket, name string) string {
+	return bucketRoot(opts) + "/" + name
+}
+
+// NewBucketLoggerFromBuckets returns a bucket logger that stores metadata
+// and log chunks in the given buckets, for applications that construct
+// their own pail buckets, e.g. with custom credentials or wrappers. Chunk
+// manifests are only written if a manifest bucket is set with
+// WithManifestBucket; without one, reads filtered by time or priority scan
+// every chunk. Deduplicated writes require WithDedupeBucket and auditing
+// requires WithAuditBucket.
+func NewBucketLoggerFromBuckets(metaBucket, logsBucket pail.Bucket, loggerOpts ...BucketLoggerOption) (*bucketLogger, error) {
+	if metaBucket == nil || logsBucket == nil {
+		return nil, errors.New("must specify metadata and logs buckets")
+	}
+
+	l, err := newBucketLogger(options.Bucket{}, loggerOpts)
+	if err != nil {
+		return nil, err
+	}
+	l.setBuckets(metaBucket, logsBucket, nil, nil)
+
+	return l, nil
+}
+
+// newBucketLogger returns a bucket logger with the logger options applied,
+// whose buckets must then be set with setBuckets.
+func newBucketLogger(opts options.Bucket, loggerOpts []BucketLoggerOption) (*bucketLogger, error) {
+	instanceID, err := newInstanceID()
+	if err != nil {
+		return nil, err
+	}
 
 	l := &bucketLogger{
-		metaBucket:       metaBucket,
-		logsBucket:       logsBucket,
-		encodingRegistry: encode.GetGlobalRegistry(),
+		opts:               opts,
+		contentTypeBuckets: map[string]pail.Bucket{},
+		dedupeManifests:    map[string]*dedupeManifest{},
+		instanceID:         instanceID,
+		sequences:          map[string]uint64{},
+		metaSequences:      map[string]uint64{},
+		followers:          map[*followGauges]struct{}{},
+		encodingRegistry:   encode.GetGlobalRegistry(),
+		keyGenerator:       DefaultKeyGenerator,
+		now:                time.Now,
+		cacheNamespace:     instanceID,
+		compressEncrypted:  opts.S3 == nil || !opts.S3.DisableCompression,
+	}
+	for _, opt := range loggerOpts {
+		if err := opt(l); err != nil {
+			return nil, errors.Wrap(err, "applying logger option")
+		}
+	}
+	// The caches hold decrypted chunks, so they are only shared between
+	// loggers reading the same bucket without encryption.
+	if opts.Type != "" && l.encryption == nil {
+		l.cacheNamespace = string(opts.Type) + "://" + opts.Name + "/" + bucketPrefix(opts, "logs")
+	}
+
+	return l, nil
+}
+
+// setBuckets sets the logger's buckets, wrapping them with its retries and
+// encryption. A manifest bucket set with WithManifestBucket takes
+// precedence over the given one.
+func (l *bucketLogger) setBuckets(metaBucket, logsBucket, manifestBucket pail.Bucket, multipart *internal.MultipartUploader) {
+	l.metaBucket = metaBucket
+	l.logsBucket = logsBucket
+	if l.manifestBucket == nil {
+		l.manifestBucket = manifestBucket
+	}
+	l.multipart = multipart
+	if l.retry != nil {
+		// Uploads are retried by withRetry.
+		l.metaBucket = internal.NewRetryBucket(l.metaBucket, *l.retry)
+		l.logsBucket = internal.NewRetryBucket(l.logsBucket, *l.retry)
+		if l.manifestBucket != nil {
+			l.manifestBucket = internal.NewRetryBucket(l.manifestBucket, *l.retry)
+		}
+		if l.dedupeBucket != nil {
+			l.dedupeBucket = internal.NewRetryBucket(l.dedupeBucket, *l.retry)
+		}
+		if l.auditBucket != nil {
+			l.auditBucket = internal.NewRetryBucket(l.auditBucket, *l.retry)
+		}
 	}
+	if l.encryption != nil {
+		l.metaBucket = newEncryptedBucket(l.metaBucket, l.encryption, l.compressEncrypted)
+		l.logsBucket = newEncryptedBucket(l.logsBucket, l.encryption, l.compressEncrypted)
+	}
+}
+
+// NewBucketReader returns a bucket logger that only reads from the bucket,
+// so that it only requires read and list permissions. All of its write
+// methods return a ReadOnlyError. Readers that audit must record events in
+// a separate bucket set with WithAuditBucket.
+func NewBucketReader(ctx context.Context, opts options.Bucket, loggerOpts ...BucketLoggerOption) (*bucketLogger, error) {
+	l, err := NewBucketLogger(ctx, opts, loggerOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if l.auditing && l.auditBucket == nil {
+		return nil, errors.New("auditing readers must record events in a separate bucket set with WithAuditBucket")
+	}
+	l.readOnly = true
 
 	return l, nil
+}
+
+// checkWritable returns a ReadOnlyError if the logger is read-only.
+func (l *bucketLogger) checkWritable(op string) error {
+	if l.readOnly {
+		return &ReadOnlyError{Op: op}
+	}
 
+	return nil
 }
 
 func (l *bucketLogger) AddMetadata(ctx context.Context, opts options.AddMetadata) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	if err := l.checkWritable("AddMetadata"); err != nil {
+		return err
+	}
+
+	defer l.metaLocks.lock(opts.Key)()
+
+	e, byteData, release, err := l.encode(opts.Data, opts.Key, opts.Encoding)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	keyWithExt, byteData, err := l.encode(opts.Data, opts.Key, opts.Encoding)
+	bucket, err := l.getContentTypeBucket(ctx, true, encodingContentType(e))
 	if err != nil {
 		return err
 	}
 
-	return errors.Wrap(l.metaBucket.Put(ctx, keyWithExt, bytes.NewReader(byteData)), "uploading metadata")
+	return errors.Wrap(l.put(ctx, bucket, l.newMetadataKey(KeyInfo{Prefix: opts.Key, Extension: e.Extension(), Size: len(byteData)}), byteData), "uploading metadata")
 }
 
 func (l *bucketLogger) Write(ctx context.Context, opts options.Write) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	_, err := l.writeWithChunks(ctx, opts)
+	return err
+}
 
+// writeWithChunks is Write, returning the manifest entries of the chunks it
+// uploaded in key order.
+func (l *bucketLogger) writeWithChunks(ctx context.Context, opts options.Write) ([]ManifestEntry, error) {
+	if err := l.checkWritable("Write"); err != nil {
+		return nil, err
+	}
 	if err := opts.Validate(); err != nil {
-		return err
+		return nil, err
+	}
+	if l.traceExtractor != nil {
+		opts.Data = withTraceIDs(ctx, l.traceExtractor, opts.Data)
 	}
+	if len(l.middleware) > 0 {
+		opts.Data = applyMiddlewareData(l.middleware, opts.Data)
+		if lines, ok := opts.Data.([]LogLine); ok && len(lines) == 0 {
+			return nil, nil
+		}
+	}
+	if l.redactor != nil {
+		opts.Data = l.redactor.redactData(opts.Data)
+	}
+
+	defer l.logsLocks.lock(opts.Key)()
 
-	keyWithExt, byteData, err := l.encode(opts.Data, opts.Key, opts.Encoding)
+	e, byteData, release, err := l.encode(opts.Data, opts.Key, opts.Encoding)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if opts.TargetChunkSize > 0 && len(byteData) > opts.TargetChunkSize {
+		chunks, err := l.splitChunks(encodedChunk{data: opts.Data, encoded: byteData, release: release}, opts.Key, opts.Encoding, opts.TargetChunkSize)
+		if err != nil {
+			return nil, err
+		}
+
+		return l.writeChunks(ctx, opts, e, chunks)
+	}
+	defer release()
+
+	contentType := encodingContentType(e)
+	bucket, err := l.getContentTypeBucket(ctx, false, contentType)
+	if err != nil {
+		return nil, err
+	}
+	key, releaseKey := l.newKey(KeyInfo{
+		Prefix:    opts.Key,
+		Extension: e.Extension(),
+		Size:      len(byteData),
+		Lines:     countLines(opts.Data),
+	})
+	attrs := chunkAttributes(contentType, opts.Tags, opts.StorageClass)
+	if key, err = l.putChunk(ctx, bucket, attrs, key, byteData); err != nil {
+		releaseKey()
+		return nil, errors.Wrap(err, "uploading data")
+	}
+
+	entry := l.newManifestEntry(key, opts.Data, byteData)
+	if err = l.putManifestEntry(ctx, entry); err != nil {
+		return nil, err
 	}
 
-	return errors.Wrap(l.logsBucket.Put(ctx, keyWithExt, bytes.NewReader(byteData)), "uploading data")
+	return []ManifestEntry{entry}, nil
 }
 
-func (l *bucketLogger) WriteBytes(ctx context.Context, opts options.WriteBytes) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+func (l *bucketLogger) WriteBytes(ctx context.Context, opts options.WriteBytes) (WriteResult, error) {
+	if err := l.checkWritable("WriteBytes"); err != nil {
+		return WriteResult{}, err
+	}
+	if err := opts.Validate(); err != nil {
+		return WriteResult{}, err
+	}
+	if l.redactor != nil && isRedactableText(opts.Data) {
+		opts.Data = []byte(l.redactor.Redact(string(opts.Data)))
+	}
+
+	return l.putBytes(ctx, opts)
+}
+
+// putBytes uploads the data of WriteBytes, which must already be validated
+// and redacted.
+func (l *bucketLogger) putBytes(ctx context.Context, opts options.WriteBytes) (WriteResult, error) {
+	defer l.logsLocks.lock(opts.Key)()
+
+	var (
+		manifest *dedupeManifest
+		hash     string
+	)
+	if opts.Dedupe {
+		var err error
+		manifest, err = l.getDedupeManifest(ctx, opts.Key)
+		if err != nil {
+			return WriteResult{}, err
+		}
+		hash = hashChunk(opts.Data)
+		if key, ok := manifest.Hashes[hash]; ok {
+			exists, err := l.logsBucket.Exists(ctx, key)
+			if err != nil {
+				return WriteResult{}, errors.Wrapf(err, "checking for deduplicated chunk '%s'", key)
+			}
+			if exists {
+				return WriteResult{Key: key, Deduplicated: true}, nil
+			}
+			// The chunk was removed, e.g. expired by a retention rule
+			// that the dedupe manifest outlived, so the data is written
+			// again.
+			manifest.remove(hash)
+		}
+	}
+
+	var (
+		key string
+		err error
+	)
+	if opts.SniffContentType {
+		key, err = l.writeSniffedBytes(ctx, opts)
+	} else {
+		key, err = l.writeBytes(ctx, opts)
+	}
+	if err != nil {
+		return WriteResult{}, err
+	}
+
+	if manifest != nil {
+		manifest.add(hash, key)
+		if err = l.putDedupeManifest(ctx, opts.Key, manifest); err != nil {
+			return WriteResult{}, err
+		}
+	}
+
+	return WriteResult{Key: key}, nil
+}
+
+func (l *bucketLogger) writeBytes(ctx context.Context, opts options.WriteBytes) (string, error) {
+	e, err := l.getEncoding(opts.Encoding)
+	if err != nil {
+		return "", err
+	}
+
+	contentType := encodingContentType(e)
+	bucket, err := l.getContentTypeBucket(ctx, false, contentType)
+	if err != nil {
+		return "", err
+	}
+	key, releaseKey := l.newKey(KeyInfo{
+		Prefix:    opts.Key,
+		Extension: e.Extension(),
+		Size:      len(opts.Data),
+		Lines:     countLines(opts.Data),
+	})
+	if key, err = l.putChunk(ctx, bucket, chunkAttributes(contentType, opts.Tags, opts.StorageClass), key, opts.Data); err != nil {
+		releaseKey()
+		return "", errors.Wrap(err, "uploading data")
+	}
 
+	return key, l.putManifestEntry(ctx, l.newManifestEntry(key, opts.Data, opts.Data))
+}
+
+// WriteReader streams the contents of the reader into the logs bucket,
+// uploading a new chunk every time ChunkSize bytes have been read so that the
+// full payload is never held in memory.
+func (l *bucketLogger) WriteReader(ctx context.Context, opts options.WriteReader) error {
+	if err := l.checkWritable("WriteReader"); err != nil {
+		return err
+	}
 	if err := opts.Validate(); err != nil {
 		return err
 	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = defaultMaxBufferSize
+	}
 
 	e, err := l.getEncoding(opts.Encoding)
 	if err != nil {
 		return err
 	}
+	contentType := encodingContentType(e)
+	bucket, err := l.getContentTypeBucket(ctx, false, contentType)
+	if err != nil {
+		return err
+	}
+	attrs := chunkAttributes(contentType, opts.Tags, opts.StorageClass)
+
+	defer l.logsLocks.lock(opts.Key)()
+
+	var redactor *lineRedactor
+	if l.redactor != nil {
+		redactor = newLineRedactor(l.redactor)
+	}
+	buffer := make([]byte, opts.ChunkSize)
+	var filled int
+	for {
+		n, err := io.ReadFull(opts.Reader, buffer[filled:])
+		filled += n
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			return errors.Wrap(err, "reading data")
+		}
+
+		// Chunks end after their last complete line, so that lines are
+		// not split across chunks, unless a single line does not fit.
+		size := filled
+		if !eof {
+			if i := bytes.LastIndexByte(buffer[:filled], '\n'); i >= 0 {
+				size = i + 1
+			}
+		}
+		chunk := buffer[:size]
+		if redactor != nil && isRedactableText(chunk) {
+			// Text is redacted line by line, holding the lines of a
+			// private key block for a later chunk until it ends.
+			chunk = redactor.redact(chunk)
+			if eof {
+				chunk = redactor.flush(chunk)
+			}
+		}
+		if len(chunk) > 0 {
+			key, releaseKey := l.newKey(KeyInfo{
+				Prefix:    opts.Key,
+				Extension: e.Extension(),
+				Size:      len(chunk),
+				Lines:     countLines(chunk),
+			})
+			key, putErr := l.putChunk(ctx, bucket, attrs, key, chunk)
+			if putErr != nil {
+				releaseKey()
+				return errors.Wrap(putErr, "uploading data")
+			}
+			if putErr := l.putManifestEntry(ctx, l.newManifestEntry(key, chunk, chunk)); putErr != nil {
+				return putErr
+			}
+		}
+		if eof {
+			return nil
+		}
+		filled = copy(buffer, buffer[size:filled])
+	}
+}
+
+// writeSniffedBytes uploads the data as a blob, keeping the extension of its
+// detected content type ahead of the blob extension, e.g. "<key>.png.blob".
+// Blobs are not log lines, so readers can tell them apart by extension.
+func (l *bucketLogger) writeSniffedBytes(ctx context.Context, opts options.WriteBytes) (string, error) {
+	contentType, ext := sniffContentType(opts.Data)
+	ext += "." + encode.BLOB
+
+	bucket, err := l.getContentTypeBucket(ctx, false, contentType)
+	if err != nil {
+		return "", err
+	}
+
+	key, releaseKey := l.newKey(KeyInfo{
+		Prefix:    opts.Key,
+		Extension: ext,
+		Size:      len(opts.Data),
+	})
+	if key, err = l.putChunk(ctx, bucket, chunkAttributes(contentType, opts.Tags, opts.StorageClass), key, opts.Data); err != nil {
+		releaseKey()
+		return "", errors.Wrap(err, "uploading data")
+	}
 
-	return errors.Wrap(l.logsBucket.Put(ctx, l.newKey(opts.Key, e.Extension()), bytes.NewReader(opts.Data)), "uploading data")
+	return key, l.putManifestEntry(ctx, l.newManifestEntry(key, opts.Data, opts.Data))
+}
+
+// getContentTypeBucket returns a bucket, either the logs or the metadata
+// bucket, that stores the given content type with each uploaded object.
+// Buckets are created lazily and cached since pail only supports setting the
+// content type per bucket. Only S3 stores content types, so the default
+// buckets are returned for other backends.
+func (l *bucketLogger) getContentTypeBucket(ctx context.Context, metadata bool, contentType string) (pail.Bucket, error) {
+	name, defaultBucket := "logs", l.logsBucket
+	if metadata {
+		name, defaultBucket = "metadata", l.metaBucket
+	}
+	if l.opts.Type != options.PailS3 || contentType == "" {
+		return defaultBucket, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cacheKey := name + "/" + contentType
+	if bucket, ok := l.contentTypeBuckets[cacheKey]; ok {
+		return bucket, nil
+	}
+
+	bucket, err := internal.CreateBucketWithContentType(ctx, bucketPrefix(l.opts, name), contentType, l.opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating %s bucket for content type '%s'", name, contentType)
+	}
+	if l.encryption != nil {
+		bucket = newEncryptedBucket(bucket, l.encryption, l.compressEncrypted)
+	}
+	l.contentTypeBuckets[cacheKey] = bucket
+
+	return bucket, nil
 }
 
 func (l *bucketLogger) FollowFile(ctx context.Context, opts options.FollowFile) error {
+	if err := l.checkWritable("FollowFile"); err != nil {
+		return err
+	}
 	if err := opts.Validate(); err != nil {
 		return err
 	}
@@ -113,33 +617,90 @@ func (l *bucketLogger) FollowFile(ctx context.Context, opts options.FollowFile)
 	}
 	defer t.Close()
 
-	var buffer []byte
+	gauges := l.registerFollower(opts.Key, opts.Filename)
+	defer l.unregisterFollower(gauges)
+
+	var deadline <-chan time.Time
+	if opts.MaxDuration > 0 {
+		timer := time.NewTimer(opts.MaxDuration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var (
+		buffer    []byte
+		totalSize int64
+		redactor  *lineRedactor
+	)
+	if l.redactor != nil {
+		redactor = newLineRedactor(l.redactor)
+	}
 	lines := t.Lines()
 	catcher := grip.NewBasicCatcher()
+	flush := func(final bool) {
+		if final && redactor != nil {
+			buffer = redactor.flush(buffer)
+		}
+		if len(buffer) == 0 {
+			return
+		}
+
+		// The lines are already redacted.
+		_, err := l.putBytes(ctx, options.WriteBytes{
+			Key:      opts.Key,
+			Data:     buffer,
+			Encoding: opts.Encoding,
+		})
+		l.handleError("FollowFile", errors.Wrapf(err, "uploading lines of '%s'", opts.Filename))
+		catcher.Add(err)
+		if catcher.HasErrors() {
+			return
+		}
+
+		gauges.uploaded(l.now())
+		buffer = []byte{}
+	}
+followLoop:
 	for {
 		select {
-		case line := <-lines:
-			buffer = append(buffer, line.Bytes()...)
+		case line, ok := <-lines:
+			if !ok {
+				break followLoop
+			}
+
+			if redactor != nil {
+				buffer = redactor.write(buffer, append(line.Bytes(), '\n'))
+			} else {
+				buffer = append(buffer, line.Bytes()...)
+				buffer = append(buffer, '\n')
+			}
+			gauges.add(len(line.Bytes())+1, l.now())
+			totalSize += int64(len(line.Bytes()) + 1)
+			if opts.MaxBytes > 0 && totalSize >= opts.MaxBytes {
+				flush(true)
+				break followLoop
+			}
 			if len(buffer) >= opts.MaxBufferSize {
-				catcher.Add(l.WriteBytes(ctx, options.WriteBytes{
-					Key:      opts.Key,
-					Data:     buffer,
-					Encoding: opts.Encoding,
-				}))
+				flush(false)
 				if catcher.HasErrors() {
-					break
+					break followLoop
 				}
-
-				buffer = []byte{}
 			}
 		case <-opts.Exit:
-			break
+			flush(true)
+			break followLoop
+		case <-deadline:
+			flush(true)
+			break followLoop
 		case <-ctx.Done():
 			catcher.Add(ctx.Err())
-			break
+			break followLoop
 		}
 	}
-	catcher.Wrap(t.Err(), "following log file")
+	if err = t.Err(); err != nil {
+		l.handleError("FollowFile", errors.Wrapf(err, "following log file '%s'", opts.Filename))
+		catcher.Wrap(err, "following log file")
+	}
 
 	return catcher.Resolve()
 }
@@ -156,32 +717,147 @@ func (l *bucketLogger) newReadCloser(ctx context.Context, opts options.Read, rev
 	if err := opts.Validate(); err != nil {
 		return nil, err
 	}
+	if err := l.audit(ctx, AuditRead, opts.Key); err != nil {
+		return nil, err
+	}
 
 	bucket := l.logsBucket
 	if opts.Metadata {
 		bucket = l.metaBucket
 	}
 
-	r := &bucketReader{ctx: ctx, bucket: bucket}
-	return r, r.getAndSortKeys(opts.Key, reverse)
+	r := &bucketReader{
+		ctx:        ctx,
+		bucket:     bucket,
+		registry:   l.encodingRegistry,
+		offset:     opts.Offset,
+		bufferSize: opts.BufferSize,
+		startKey:   opts.StartKey,
+		endKey:     opts.EndKey,
+	}
+	r.chunks = chunkReader{bucket: bucket}
+	if opts.Follow {
+		if reverse {
+			return nil, errors.New("cannot follow reverse reads")
+		}
+		r.follower = &readFollower{
+			metaBucket:   l.metaBucket,
+			key:          opts.Key,
+			pollInterval: opts.PollInterval,
+			timeout:      opts.FollowTimeout,
+		}
+		if r.follower.pollInterval == 0 {
+			r.follower.pollInterval = defaultPollInterval
+		}
+		if r.follower.timeout == 0 {
+			r.follower.timeout = defaultFollowTimeout
+		}
+	}
+	if opts.PrefetchCount > 0 {
+		r.prefetch = newChunkPrefetcher(ctx, opts.PrefetchCount, opts.ChunkConcurrency)
+	}
+	if !opts.Metadata {
+		r.chunks = l.logsChunkReader()
+	}
+	if opts.VerifyChunks {
+		if l.manifestSigner == nil || l.manifestBucket == nil {
+			return r, errors.New("verifying chunks requires a logger that signs manifests")
+		}
+		r.chunks.verify = l.verifyChunk
+	}
+	var entries []ManifestEntry
+	if opts.StartSequence > 0 || (opts.Strict && !opts.Metadata && l.manifestBucket != nil) {
+		if opts.StartSequence > 0 && reverse {
+			return r, errors.New("cannot start reverse reads at a sequence number")
+		}
+		if opts.StartSequence > 0 && l.manifestBucket == nil {
+			return r, errors.New("reading from a sequence number requires a manifest")
+		}
+		var err error
+		if entries, err = l.Manifest(ctx, opts.Key); err != nil {
+			return r, err
+		}
+		if opts.StartSequence > 0 {
+			r.startKey = sequenceStartKey(entries, opts.StartSequence)
+		}
+	}
+	if err := r.getAndSortKeys(opts.Key, reverse); err != nil {
+		return r, err
+	}
+	if opts.Strict {
+		if err := validateChunkSequence(opts.Key, r.keys, reverse); err != nil {
+			return r, err
+		}
+		if err := validateLineSequence(opts.Key, entries); err != nil {
+			return r, err
+		}
+	}
+	if opts.PageToken != "" {
+		idx := -1
+		for i, key := range r.keys {
+			if key == opts.PageToken {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return r, errors.Errorf("page token '%s' does not match any chunk of key '%s'", opts.PageToken, opts.Key)
+		}
+		r.keyIdx = idx
+	}
+
+	return r, nil
 }
 
-func (l *bucketLogger) encode(data interface{}, prefix, encoding string) (string, []byte, error) {
+// encode marshals the data with the given encoding. Encodings that support
+// streaming are written to a pooled buffer; the returned release function
+// must be called once the encoded data is no longer in use.
+func (l *bucketLogger) encode(data interface{}, prefix, encoding string) (encode.Encoding, []byte, func(), error) {
 	if prefix == "" {
-		return "", nil, errors.New("must provide a key prefix")
+		return nil, nil, nil, errors.New("must provide a key prefix")
 	}
 
 	e, err := l.getEncoding(encoding)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, nil, err
+	}
+
+	if se, ok := e.(encode.SchemaEncoding); ok {
+		out, err := se.MarshalKey(prefix, data)
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "marshaling data to '%s'", e)
+		}
+
+		return e, out, func() {}, nil
+	}
+	if se, ok := e.(encode.StreamEncoding); ok {
+		buf := getEncodeBuffer()
+		if err = se.Encode(buf, data); err != nil {
+			putEncodeBuffer(buf)
+			return nil, nil, nil, errors.Wrapf(err, "encoding data to '%s'", e)
+		}
+
+		return e, buf.Bytes(), func() { putEncodeBuffer(buf) }, nil
 	}
 
 	out, err := e.Marshal(data)
 	if err != nil {
-		return "", nil, errors.Wrapf(err, "marshaling data to '%s'", e)
+		return nil, nil, nil, errors.Wrapf(err, "marshaling data to '%s'", e)
 	}
 
-	return l.newKey(prefix, e.Extension()), out, nil
+	return e, out, func() {}, nil
+}
+
+// detectEncoding returns the encoding of the chunk with the given key based on
+// its extension, ignoring any compression extension.
+func detectEncoding(registry encode.EncodingRegistry, key string) (encode.Encoding, error) {
+	ext := strings.TrimPrefix(path.Ext(internal.TrimCompressionExtension(key)), ".")
+	e, ok := registry.GetByExtension(ext)
+	if !ok {
+		return nil, errors.Errorf("no encoding registered for extension '%s' of chunk '%s'", ext, key)
+	}
+
+	return e, nil
 }
 
 func (l *bucketLogger) getEncoding(encoding string) (encode.Encoding, error) {
@@ -197,72 +873,300 @@ func (l *bucketLogger) getEncoding(encoding string) (encode.Encoding, error) {
 	return e, nil
 }
 
-func (l *bucketLogger) newKey(prefix, ext string) string {
-	key := fmt.Sprintf("%d", time.Now().UnixNano())
-	if prefix != "" {
-		key = prefix + "/" + key
+// put uploads the data to the given bucket, retrying on failure if a retry
+// policy is configured.
+func (l *bucketLogger) put(ctx context.Context, bucket pail.Bucket, key string, data []byte) error {
+	if l.dryRun != nil {
+		l.logDryRun(key, data)
+		return nil
+	}
+
+	return l.withRetry(ctx, key, func() error {
+		return bucket.Put(ctx, key, bytes.NewReader(data))
+	})
+}
+
+// chunkAttributes returns the attributes of a chunk with the given content
+// type, written with the given tags and storage class. Every write path
+// builds its chunks' attributes with it.
+func chunkAttributes(contentType string, tags map[string]string, storageClass options.S3StorageClass) internal.ObjectAttributes {
+	return internal.ObjectAttributes{ContentType: contentType, Tags: tags, StorageClass: storageClass}
+}
+
+// putChunk runs the before put hooks on a log chunk and uploads it, returning
+// the key it was uploaded to. The error hooks are run if the upload fails.
+func (l *bucketLogger) putChunk(ctx context.Context, bucket pail.Bucket, attrs internal.ObjectAttributes, key string, data []byte) (string, error) {
+	key, err := l.runBeforePut(ctx, key, data)
+	if err != nil {
+		return "", err
+	}
+	if err = l.putSpooledAttributes(ctx, key, attrs); err != nil {
+		return "", err
+	}
+	if err = l.uploadChunk(ctx, bucket, attrs, key, data); err != nil {
+		l.runOnError(ctx, key, err)
+		return "", err
+	}
+
+	return key, nil
+}
+
+// uploadChunk uploads a log chunk to the given logs bucket, switching to the
+// S3 uploader for chunks at or above the configured multipart threshold and
+// for tagged chunks.
+func (l *bucketLogger) uploadChunk(ctx context.Context, bucket pail.Bucket, attrs internal.ObjectAttributes, key string, data []byte) error {
+	if l.dryRun != nil || !l.multipart.ShouldUpload(len(data), attrs) {
+		return l.put(ctx, bucket, key, data)
 	}
-	if ext != "" {
-		key += "." + ext
+	// The S3 uploader bypasses the logs bucket, so it encrypts the chunk
+	// if the bucket would have.
+	if _, ok := bucket.(*encryptedBucket); ok {
+		var err error
+		if data, err = encrypt(ctx, l.encryption, data, l.compressEncrypted); err != nil {
+			return errors.Wrapf(err, "encrypting '%s'", key)
+		}
 	}
 
+	return l.withRetry(ctx, key, func() error {
+		return l.multipart.Upload(ctx, key, attrs, bytes.NewReader(data))
+	})
+}
+
+// logDryRun logs an upload skipped in dry run mode.
+func (l *bucketLogger) logDryRun(key string, data []byte) {
+	l.dryRun.Send(message.NewFields(level.Info, message.Fields{
+		"message": "dry run: skipping upload",
+		"prefix":  bucketRoot(l.opts),
+		"key":     key,
+		"size":    len(data),
+	}))
+}
+
+// withRetry runs the upload operation through the upload pool, retrying on
+// failure if a retry policy is configured, unless the circuit breaker is
+// open.
+func (l *bucketLogger) withRetry(ctx context.Context, key string, upload func() error) error {
+	op := func() error { return l.uploadPool.Do(ctx, upload) }
+	if l.retry == nil {
+		return l.withCircuitBreaker(op)
+	}
+
+	return errors.Wrapf(l.withCircuitBreaker(func() error {
+		return internal.Retry(ctx, *l.retry, op)
+	}), "uploading '%s'", key)
+}
+
+// newKey returns a new log chunk key for the object described by info and
+// a function releasing its sequence number, which must be called if the
+// chunk is not uploaded so that strict reads do not report a gap. The caller
+// must hold the key's lock.
+func (l *bucketLogger) newKey(info KeyInfo) (string, func()) {
+	return l.generateKey(l.sequences, info)
+}
+
+// newMetadataKey returns a new metadata key for the object described by
+// info. Metadata keys have their own sequence numbers so that adding
+// metadata does not leave gaps in the sequence of log chunks.
+func (l *bucketLogger) newMetadataKey(info KeyInfo) string {
+	info.Metadata = true
+	key, _ := l.generateKey(l.metaSequences, info)
+
 	return key
 }
 
+// generateKey fills in the time, instance ID, and sequence number of the
+// key info and passes it to the key generator. Times are strictly
+// increasing per logger, so keys sort in write order even if the clock steps
+// backwards, and the instance ID keeps keys written by different loggers in
+// the same nanosecond from colliding. The returned function releases the
+// sequence number unless a later one has been taken since.
+func (l *bucketLogger) generateKey(sequences map[string]uint64, info KeyInfo) (string, func()) {
+	l.mu.Lock()
+	ts := l.now().UnixNano()
+	if ts <= l.lastKeyTime {
+		ts = l.lastKeyTime + 1
+	}
+	l.lastKeyTime = ts
+	info.Sequence = sequences[info.Prefix]
+	sequences[info.Prefix]++
+	l.mu.Unlock()
+
+	info.Time = time.Unix(0, ts)
+	info.Instance = l.instanceID
+
+	return l.keyGenerator.Key(info), func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if sequences[info.Prefix] == info.Sequence+1 {
+			sequences[info.Prefix] = info.Sequence
+		}
+	}
+}
+
+// countLines returns the number of log lines in the data, if known.
+func countLines(data interface{}) int {
+	switch d := data.(type) {
+	case []LogLine:
+		return len(d)
+	case []byte:
+		return bytes.Count(d, []byte{'\n'})
+	default:
+		return 0
+	}
+}
+
 type bucketReader struct {
-	ctx    context.Context
-	reader io.ReadCloser
-	bucket pail.Bucket
-	keys   []string
-	keyIdx int
+	ctx      context.Context
+	reader   io.ReadCloser
+	bucket   pail.Bucket
+	registry encode.EncodingRegistry
+	keys     []string
+	keyIdx   int
+	// offset is the byte offset at which to start reading the next chunk.
+	offset int64
+	// pos is the byte offset within the current chunk.
+	pos int64
+	// etags are the ETags of the chunks, where the backend provides them.
+	etags map[string]string
+	// chunks downloads the chunks, if set, and otherwise the bucket.
+	chunks chunkReader
+	// prefetch, if set, downloads the chunks after the current one in the
+	// background.
+	prefetch   *chunkPrefetcher
+	bufferSize int
+	// startKey and endKey, if set, bound the listed chunk keys to those
+	// after startKey and at or before endKey.
+	startKey string
+	endKey   string
+	// follower, if set, waits for new chunks once all chunks are read.
+	follower *readFollower
 }
 
+// ReadPage returns the remaining contents of the current chunk, or of the
+// next chunk if there is no partially read chunk.
 func (r *bucketReader) ReadPage() ([]byte, error) {
-	if r.keyIdx == 0 {
+	if r.reader == nil {
 		if err := r.getNextChunk(); err != nil {
 			return nil, err
 		}
+		if r.reader == nil {
+			return nil, io.EOF
+		}
 	}
 
-	if r.reader == nil {
-		return nil, io.EOF
+	data, err := io.ReadAll(r.reader)
+	r.pos += int64(len(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading next log page")
 	}
 
-	data, err := io.ReadAll(r.reader)
-	return data, errors.Wrap(err, "reading next log page")
+	return data, r.closeChunk()
 }
 
-func (r *bucketReader) Read(p []byte) (int, error) {
-	if r.keyIdx == 0 {
+// DecodePage reads the next page and unmarshals it into v using the encoding
+// registered for the chunk's key extension, so prefixes containing chunks of
+// different encodings can be decoded. Plain text pages decoded into a
+// *[]LogLine are parsed line by line, as by ReadLines. The page must not
+// have been partially consumed by Read.
+func (r *bucketReader) DecodePage(v interface{}) error {
+	// The next chunk is fetched before taking its key, since followed
+	// readers only learn of chunks written since the last page here.
+	if r.reader == nil {
 		if err := r.getNextChunk(); err != nil {
-			return 0, err
+			return err
+		}
+		if r.reader == nil {
+			return io.EOF
 		}
 	}
+	key := r.keys[r.keyIdx-1]
+	data, err := r.ReadPage()
+	if err != nil {
+		return err
+	}
 
-	if r.reader == nil {
-		return 0, io.EOF
+	if lines, ok := v.(*[]LogLine); ok {
+		*lines, err = decodeLines(r.registry, key, data)
+		return err
 	}
 
-	var (
-		n      int
-		offset int
-		err    error
-	)
+	e, err := detectEncoding(r.registry, key)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(e.Unmarshal(data, v), "decoding chunk '%s' from '%s'", key, e)
+}
+
+// PageToken returns the key of the chunk that the next page starts at, or an
+// empty string if all chunks have been read. A partially read chunk is
+// returned again in full when resuming from its token.
+func (r *bucketReader) PageToken() string {
+	switch {
+	case r.reader != nil:
+		return r.keys[r.keyIdx-1]
+	case r.keyIdx < len(r.keys):
+		return r.keys[r.keyIdx]
+	default:
+		return ""
+	}
+}
+
+func (r *bucketReader) Read(p []byte) (int, error) {
+	var offset int
 	for offset < len(p) {
-		n, err = r.reader.Read(p[offset:])
+		if r.reader == nil {
+			if err := r.getNextChunk(); err != nil {
+				return offset, err
+			}
+			if r.reader == nil {
+				return offset, io.EOF
+			}
+		}
+
+		n, err := r.reader.Read(p[offset:])
 		offset += n
+		r.pos += int64(n)
 		if err == io.EOF {
-			err = r.getNextChunk()
+			err = r.closeChunk()
 		}
 		if err != nil {
-			break
+			return offset, err
 		}
 	}
 
-	return offset, err
+	return offset, nil
+}
+
+// WriteTo implements io.WriterTo, copying the remaining contents of each
+// chunk directly to the writer without going through Read.
+func (r *bucketReader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for {
+		if r.reader == nil {
+			if err := r.getNextChunk(); err != nil {
+				return total, err
+			}
+			if r.reader == nil {
+				return total, nil
+			}
+		}
+
+		n, err := io.Copy(w, r.reader)
+		total += n
+		r.pos += n
+		if err != nil {
+			return total, errors.Wrap(err, "copying log chunk")
+		}
+		if err = r.closeChunk(); err != nil {
+			return total, err
+		}
+	}
 }
 
 func (r *bucketReader) Close() error {
+	r.prefetch.stop()
 	if r.reader == nil {
 		return nil
 	}
@@ -270,14 +1174,27 @@ func (r *bucketReader) Close() error {
 	return errors.WithStack(r.reader.Close())
 }
 
-func (r *bucketReader) getAndSortKeys(prefix string, reverse bool) error {
-	it, err := r.bucket.List(r.ctx, prefix)
+func (r *bucketReader) getAndSortKeys(key string, reverse bool) error {
+	prefix := keyListPrefix(key)
+	if r.startKey != "" && r.endKey != "" {
+		if common := commonKeyDir(r.startKey, r.endKey); len(common) > len(prefix) {
+			prefix = common
+		}
+	}
+	it, err := internal.ListRange(r.ctx, r.bucket, prefix, r.startKey, r.endKey)
 	if err != nil {
 		return errors.Wrap(err, "listing log chunk keys")
 	}
 
 	for it.Next(r.ctx) {
-		r.keys = append(r.keys, it.Item().Name())
+		item := it.Item()
+		r.keys = append(r.keys, item.Name())
+		if hash := item.Hash(); hash != "" {
+			if r.etags == nil {
+				r.etags = map[string]string{}
+			}
+			r.etags[item.Name()] = hash
+		}
 	}
 	if err = it.Err(); err != nil {
 		return errors.Wrap(err, "iterating log chunk keys")
@@ -292,20 +1209,122 @@ func (r *bucketReader) getAndSortKeys(prefix string, reverse bool) error {
 	return nil
 }
 
-func (r *bucketReader) getNextChunk() error {
-	if err := r.Close(); err != nil {
-		return errors.Wrap(err, "closing previous ReadCloser")
+// commonKeyDir returns the longest common "directory" of the keys, e.g.
+// "a/2021/11" for "a/2021/11/19/x" and "a/2021/11/20/y", which may be
+// listed in place of a shorter prefix of both keys.
+func commonKeyDir(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	if idx := strings.LastIndex(a[:n], "/"); idx >= 0 {
+		return a[:idx]
+	}
+
+	return ""
+}
+
+// validateChunkSequence checks that the sequence numbers of the given chunk
+// keys, sorted by time, are contiguous for each logger instance that wrote
+// to the key. Every instance starts its sequence at zero. Keys not in the
+// default chunk key format, e.g. those written by older versions or by a
+// custom key generator, carry no sequence number and are skipped.
+func validateChunkSequence(key string, keys []string, reverse bool) error {
+	if reverse {
+		ordered := make([]string, len(keys))
+		for i := range keys {
+			ordered[len(keys)-1-i] = keys[i]
+		}
+		keys = ordered
+	}
+
+	type lastChunk struct {
+		key string
+		seq uint64
+	}
+	last := map[string]lastChunk{}
+	for _, chunk := range keys {
+		ck, err := parseChunkKey(chunk)
+		if err != nil {
+			continue
+		}
+
+		prev, ok := last[ck.instance]
+		switch {
+		case !ok && ck.seq != 0:
+			return &GapError{Key: key, Instance: ck.instance, From: 0, To: ck.seq - 1}
+		case !ok || ck.seq == prev.seq+1:
+		case ck.seq <= prev.seq:
+			return errors.Errorf("chunk '%s' has sequence number %d which is out of order with chunk '%s'", chunk, ck.seq, prev.key)
+		default:
+			return &GapError{Key: key, Instance: ck.instance, After: prev.key, From: prev.seq + 1, To: ck.seq - 1}
+		}
+
+		last[ck.instance] = lastChunk{key: chunk, seq: ck.seq}
+	}
+
+	return nil
+}
+
+// get returns the contents of the chunk.
+func (r *bucketReader) get(key string) ([]byte, error) {
+	if r.chunks.bucket == nil {
+		return getChunk(r.ctx, r.bucket, key)
+	}
+
+	return r.chunks.get(r.ctx, key, r.etags[key])
+}
+
+// getRange returns a reader of the chunk starting at the byte offset.
+func (r *bucketReader) getRange(key string, offset int64) (io.ReadCloser, error) {
+	if r.chunks.bucket == nil {
+		return internal.GetRange(r.ctx, r.bucket, key, offset)
+	}
+
+	return r.chunks.getRange(r.ctx, key, r.etags[key], offset)
+}
+
+// closeChunk closes the reader of the current chunk, if any.
+func (r *bucketReader) closeChunk() error {
+	var err error
+	if r.reader != nil {
+		err = r.reader.Close()
 	}
 	r.reader = nil
 
+	return errors.Wrap(err, "closing log chunk")
+}
+
+func (r *bucketReader) getNextChunk() error {
+	if err := r.closeChunk(); err != nil {
+		return err
+	}
+
+	if r.keyIdx == len(r.keys) && r.follower != nil {
+		if err := r.follow(); err != nil {
+			return errors.Wrap(err, "following log")
+		}
+	}
 	if r.keyIdx == len(r.keys) {
 		return nil
 	}
 
-	reader, err := r.bucket.Get(r.ctx, r.keys[r.keyIdx])
+	var reader io.ReadCloser
+	var err error
+	if r.prefetch != nil && r.offset == 0 {
+		reader, err = r.prefetch.take(r, r.keyIdx)
+	} else {
+		r.prefetch.fill(r, r.keyIdx+1)
+		reader, err = r.getRange(r.keys[r.keyIdx], r.offset)
+	}
 	if err != nil {
 		return errors.Wrap(err, "getting next log chunk")
 	}
+	if r.bufferSize > 0 {
+		reader = newBufferedReadCloser(reader, r.bufferSize)
+	}
+	r.pos = r.offset
+	r.offset = 0
 
 	r.reader = reader
 	r.keyIdx++
@@ -1,12 +1,445 @@
 package logger
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBucketLoggerImplementation(t *testing.T) {
 	assert.Implements(t, (*ReadCloser)(nil), &bucketReader{})
 	assert.Implements(t, (*Logger)(nil), &bucketLogger{})
 }
+
+func TestBucketLoggerEncodeJSON(t *testing.T) {
+	l, err := NewBucketLogger(context.Background(), options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name string
+		data interface{}
+	}{
+		{name: "LogLines", data: []LogLine{{Timestamp: time.Unix(1, 0).UTC(), Data: "a"}, {Timestamp: time.Unix(2, 0).UTC(), Data: "b"}}},
+		{name: "Map", data: map[string]interface{}{"a": 1}},
+		{name: "EscapedHTML", data: "<a href=\"x\">&</a>"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			expected, err := json.Marshal(test.data)
+			require.NoError(t, err)
+
+			_, out, release, err := l.encode(test.data, "key", encode.JSON)
+			require.NoError(t, err)
+			defer release()
+			assert.Equal(t, string(expected), string(out))
+		})
+	}
+}
+
+// toggledPutBucket is a bucket whose puts fail while fail is set.
+type toggledPutBucket struct {
+	pail.Bucket
+	fail bool
+}
+
+func (b *toggledPutBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	if b.fail {
+		return errors.New("put failed")
+	}
+
+	return b.Bucket.Put(ctx, key, r)
+}
+
+func TestBucketLoggerStrictRead(t *testing.T) {
+	ctx := context.Background()
+	newLogger := func(t *testing.T, opts ...BucketLoggerOption) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, opts...)
+		require.NoError(t, err)
+		return l
+	}
+	write := func(t *testing.T, l Logger, data string) {
+		_, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte(data)})
+		require.NoError(t, err)
+	}
+	readStrict := func(l Logger) error {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key", Strict: true})
+		if r != nil {
+			r.Close()
+		}
+		return err
+	}
+	listKeys := func(t *testing.T, l *bucketLogger) []string {
+		it, err := l.logsBucket.List(ctx, "key")
+		require.NoError(t, err)
+		var keys []string
+		for it.Next(ctx) {
+			keys = append(keys, it.Item().Name())
+		}
+		require.NoError(t, it.Err())
+		return keys
+	}
+
+	t.Run("Contiguous", func(t *testing.T) {
+		l := newLogger(t)
+		write(t, l, "a\n")
+		write(t, l, "b\n")
+
+		assert.NoError(t, readStrict(l))
+	})
+	t.Run("MissingChunk", func(t *testing.T) {
+		l := newLogger(t)
+		write(t, l, "a\n")
+		write(t, l, "b\n")
+		keys := listKeys(t, l)
+		require.Len(t, keys, 2)
+		write(t, l, "c\n")
+		require.NoError(t, l.logsBucket.Remove(ctx, keys[1]))
+
+		assert.True(t, IsGapError(readStrict(l)))
+	})
+	t.Run("FailedPut", func(t *testing.T) {
+		l := newLogger(t)
+		bucket := &toggledPutBucket{Bucket: l.logsBucket}
+		l.logsBucket = bucket
+		write(t, l, "a\n")
+		bucket.fail = true
+		_, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("lost\n")})
+		assert.Error(t, err)
+		bucket.fail = false
+		write(t, l, "b\n")
+
+		assert.NoError(t, readStrict(l))
+	})
+	t.Run("Metadata", func(t *testing.T) {
+		l := newLogger(t)
+		write(t, l, "a\n")
+		require.NoError(t, l.AddMetadata(ctx, options.AddMetadata{Key: "key", Data: "meta"}))
+		write(t, l, "b\n")
+
+		assert.NoError(t, readStrict(l))
+	})
+	t.Run("NonChunkKeys", func(t *testing.T) {
+		opts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+		baseline, err := NewBucketLogger(ctx, opts, WithKeyGenerator(KeyGeneratorFunc(func(info KeyInfo) string {
+			return JoinKey(info.Prefix, "0000000000000000000_baseline", info.Extension)
+		})))
+		require.NoError(t, err)
+		write(t, baseline, "baseline\n")
+		l, err := NewBucketLogger(ctx, opts)
+		require.NoError(t, err)
+		write(t, l, "a\n")
+
+		assert.NoError(t, readStrict(l))
+	})
+}
+
+func BenchmarkBucketLoggerConcurrentWrites(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := make([]byte, 4096)
+	for _, test := range []struct {
+		name string
+		key  func(int64) string
+	}{
+		{
+			name: "SameKey",
+			key:  func(int64) string { return "key" },
+		},
+		{
+			name: "DistinctKeys",
+			key:  func(i int64) string { return fmt.Sprintf("key%d", i) },
+		},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			l, err := NewBucketLogger(ctx, options.Bucket{
+				Type:   options.PailLocal,
+				Name:   b.TempDir(),
+				Prefix: "bench",
+			})
+			require.NoError(b, err)
+
+			var worker int64
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				key := test.key(atomic.AddInt64(&worker, 1))
+				for pb.Next() {
+					if _, err := l.WriteBytes(ctx, options.WriteBytes{Key: key, Data: data}); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestBucketLoggerPageToken(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	for _, data := range []string{"a\n", "b\n", "c\n"} {
+		_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte(data)})
+		require.NoError(t, err)
+	}
+
+	t.Run("OneChunkPerPage", func(t *testing.T) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		defer r.Close()
+
+		for _, expected := range []string{"a\n", "b\n", "c\n"} {
+			assert.NotEmpty(t, r.PageToken())
+			page, err := r.ReadPage()
+			require.NoError(t, err)
+			assert.Equal(t, expected, string(page))
+		}
+		assert.Empty(t, r.PageToken())
+		_, err = r.ReadPage()
+		assert.Equal(t, io.EOF, err)
+	})
+	t.Run("Resume", func(t *testing.T) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		_, err = r.ReadPage()
+		require.NoError(t, err)
+		token := r.PageToken()
+		require.NoError(t, r.Close())
+
+		r, err = l.NewReadCloser(ctx, options.Read{Key: "key", PageToken: token})
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "b\nc\n", string(data))
+	})
+	t.Run("PartiallyReadChunk", func(t *testing.T) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		defer r.Close()
+		p := make([]byte, 1)
+		_, err = r.Read(p)
+		require.NoError(t, err)
+		token := r.PageToken()
+
+		resumed, err := l.NewReadCloser(ctx, options.Read{Key: "key", PageToken: token})
+		require.NoError(t, err)
+		defer resumed.Close()
+		page, err := resumed.ReadPage()
+		require.NoError(t, err)
+		assert.Equal(t, "a\n", string(page))
+	})
+	t.Run("InvalidToken", func(t *testing.T) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key", PageToken: "key/unknown"})
+		if r != nil {
+			r.Close()
+		}
+		assert.Error(t, err)
+	})
+}
+
+func TestBucketReader(t *testing.T) {
+	ctx := context.Background()
+	opts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+	l, err := NewBucketLogger(ctx, opts)
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "message"}))
+
+	r, err := NewBucketReader(ctx, opts)
+	require.NoError(t, err)
+
+	t.Run("Reads", func(t *testing.T) {
+		lines, _, err := r.ReadLines(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "message", lines[0].Data)
+		assert.NoError(t, r.Check(ctx))
+	})
+	t.Run("RefusesWrites", func(t *testing.T) {
+		for op, err := range map[string]error{
+			"Write":       r.Write(ctx, options.Write{Key: "key", Data: "message"}),
+			"AddMetadata": r.AddMetadata(ctx, options.AddMetadata{Key: "key", Data: "metadata"}),
+			"WriteReader": r.WriteReader(ctx, options.WriteReader{Key: "key"}),
+		} {
+			require.Error(t, err, op)
+			assert.True(t, IsReadOnlyError(err), op)
+		}
+		_, err := r.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("message")})
+		assert.True(t, IsReadOnlyError(err))
+		assert.False(t, IsReadOnlyError(nil))
+	})
+}
+
+func TestNewBucketLoggerFromBuckets(t *testing.T) {
+	ctx := context.Background()
+	newBucket := func(t *testing.T) pail.Bucket {
+		b, err := pail.NewLocalBucket(pail.LocalOptions{Path: t.TempDir()})
+		require.NoError(t, err)
+		return b
+	}
+
+	t.Run("InvalidArguments", func(t *testing.T) {
+		_, err := NewBucketLoggerFromBuckets(nil, newBucket(t))
+		assert.Error(t, err)
+		_, err = NewBucketLoggerFromBuckets(newBucket(t), newBucket(t), WithManifestBucket(nil))
+		assert.Error(t, err)
+	})
+	t.Run("WithoutManifest", func(t *testing.T) {
+		l, err := NewBucketLoggerFromBuckets(newBucket(t), newBucket(t))
+		require.NoError(t, err)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "message"}))
+		require.NoError(t, l.AddMetadata(ctx, options.AddMetadata{Key: "key", Data: "metadata"}))
+
+		lines, _, err := l.ReadLines(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "message", lines[0].Data)
+
+		entries, err := l.Manifest(ctx, "key")
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+
+		_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("line\n"), Dedupe: true})
+		assert.Error(t, err, "deduplicated writes require a dedupe bucket")
+	})
+	t.Run("WithManifestAndDedupe", func(t *testing.T) {
+		l, err := NewBucketLoggerFromBuckets(newBucket(t), newBucket(t), WithManifestBucket(newBucket(t)), WithDedupeBucket(newBucket(t)))
+		require.NoError(t, err)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "message"}))
+
+		entries, err := l.Manifest(ctx, "key")
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+
+		for _, deduplicated := range []bool{false, true} {
+			res, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("line\n"), Dedupe: true})
+			require.NoError(t, err)
+			assert.Equal(t, deduplicated, res.Deduplicated)
+		}
+	})
+}
+
+func TestNewBucketLoggerWithSession(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewBucketLoggerWithSession(ctx, nil)
+	assert.Error(t, err)
+	_, err = NewBucketSession(ctx, options.Bucket{Type: options.PailLocal})
+	assert.Error(t, err)
+
+	session, err := NewBucketSession(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	writer, err := NewBucketLoggerWithSession(ctx, session)
+	require.NoError(t, err)
+	reader, err := NewBucketLoggerWithSession(ctx, session)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(ctx, options.Write{Key: "key", Data: "message"}))
+	lines, _, err := reader.ReadLines(ctx, options.Read{Key: "key"})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "message", lines[0].Data)
+}
+
+func TestBucketLoggerReadOffset(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line0\nline1\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line2\n"}))
+
+	r, err := l.NewReadCloser(ctx, options.Read{Key: "key", Offset: 6})
+	require.NoError(t, err)
+	defer r.Close()
+	page, err := r.ReadPage()
+	require.NoError(t, err)
+	assert.Equal(t, "line1\n", string(page), "the first chunk is read from the offset")
+	page, err = r.ReadPage()
+	require.NoError(t, err)
+	assert.Equal(t, "line2\n", string(page), "later chunks are read in full")
+
+	_, err = l.NewReadCloser(ctx, options.Read{Key: "key", Offset: -1})
+	assert.Error(t, err)
+}
+
+func TestBucketReaderWriteTo(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line0\nline1\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line2\n"}))
+
+	r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+	require.NoError(t, err)
+	defer r.Close()
+	_, ok := r.(io.WriterTo)
+	require.True(t, ok)
+
+	// WriteTo continues from the current position.
+	buf := make([]byte, 6)
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	n, err := io.Copy(&out, r)
+	require.NoError(t, err)
+	assert.EqualValues(t, 12, n)
+	assert.Equal(t, "line1\nline2\n", out.String())
+	assert.Empty(t, r.Checkpoint())
+
+	n, err = io.Copy(&out, r)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestBucketLoggerReadKeyRange(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: fmt.Sprintf("line%d\n", i)}))
+	}
+	entries, err := l.Manifest(ctx, "key")
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	read := func(t *testing.T, opts options.Read) string {
+		opts.Key = "key"
+		r, err := l.NewReadCloser(ctx, opts)
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		return string(data)
+	}
+
+	assert.Equal(t, "line1\nline2\nline3\n", read(t, options.Read{StartKey: entries[0].Key}))
+	assert.Equal(t, "line0\nline1\nline2\n", read(t, options.Read{EndKey: entries[2].Key}))
+	assert.Equal(t, "line2\n", read(t, options.Read{StartKey: entries[1].Key, EndKey: entries[2].Key}))
+
+	for _, opts := range []options.Read{
+		{Key: "key", StartKey: "other/chunk"},
+		{Key: "key", EndKey: "other/chunk"},
+		{Key: "key", StartKey: entries[2].Key, EndKey: entries[1].Key},
+	} {
+		_, err = l.NewReadCloser(ctx, opts)
+		assert.Error(t, err)
+	}
+}
+
+func TestCommonKeyDir(t *testing.T) {
+	assert.Equal(t, "a/2021/11", commonKeyDir("a/2021/11/19/x", "a/2021/11/20/y"))
+	assert.Equal(t, "a/2021/11", commonKeyDir("a/2021/11/x", "a/2021/11/y"))
+	assert.Equal(t, "", commonKeyDir("a/x", "b/y"))
+	assert.Equal(t, "", commonKeyDir("ax", "ay"))
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the largest buffer capacity returned to the pools,
+// so that a single unusually large flush does not pin memory indefinitely.
+const maxPooledBufferSize = 4 * defaultMaxBufferSize
+
+var (
+	encodeBufferPool = sync.Pool{
+		New: func() interface{} { return &bytes.Buffer{} },
+	}
+	logLinePool = sync.Pool{
+		New: func() interface{} {
+			lines := make([]LogLine, 0, 128)
+			return &lines
+		},
+	}
+)
+
+func getEncodeBuffer() *bytes.Buffer {
+	buf := encodeBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	return buf
+}
+
+func putEncodeBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	encodeBufferPool.Put(buf)
+}
+
+func getLogLines() []LogLine {
+	return (*logLinePool.Get().(*[]LogLine))[:0]
+}
+
+// putLogLines returns the log lines to the pool. The lines are zeroed first
+// so that pooled buffers do not keep message data alive.
+func putLogLines(lines []LogLine) {
+	if cap(lines) == 0 {
+		return
+	}
+
+	lines = lines[:cap(lines)]
+	for i := range lines {
+		lines[i] = LogLine{}
+	}
+	lines = lines[:0]
+	logLinePool.Put(&lines)
+}
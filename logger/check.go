@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// checkKeyPrefix is the prefix of the probe keys written by Check, within
+// the metadata bucket.
+const checkKeyPrefix = "_check"
+
+// Check verifies that the bucket is reachable and that the logger's
+// credentials can write, read, and delete objects by round tripping a small
+// probe object through the metadata bucket. Read-only loggers instead only
+// verify that the logs bucket can be listed, as do dry run loggers, which
+// log the probe object they would have written.
+func (l *bucketLogger) Check(ctx context.Context) error {
+	key := checkKeyPrefix + "/" + l.instanceID
+	probe := []byte(l.instanceID)
+	if l.dryRun != nil && !l.readOnly {
+		l.logDryRun(key, probe)
+	}
+	if l.readOnly || l.dryRun != nil {
+		it, err := l.logsBucket.List(ctx, "")
+		if err != nil {
+			return errors.Wrap(err, "listing logs bucket")
+		}
+		it.Next(ctx)
+
+		return errors.Wrap(it.Err(), "listing logs bucket")
+	}
+
+	if err := l.metaBucket.Put(ctx, key, bytes.NewReader(probe)); err != nil {
+		return errors.Wrap(err, "writing probe object")
+	}
+
+	r, err := l.metaBucket.Get(ctx, key)
+	if err != nil {
+		return errors.Wrap(err, "reading probe object")
+	}
+	data, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		return errors.Wrap(err, "reading probe object")
+	}
+	if !bytes.Equal(data, probe) {
+		return errors.New("probe object read back does not match the object written")
+	}
+
+	return errors.Wrap(l.metaBucket.Remove(ctx, key), "removing probe object")
+}
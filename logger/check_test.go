@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableBucket is a bucket whose puts always fail.
+type unreachableBucket struct {
+	pail.Bucket
+}
+
+func (b unreachableBucket) Put(context.Context, string, io.Reader) error {
+	return errors.New("connection refused")
+}
+
+func TestBucketLoggerCheck(t *testing.T) {
+	ctx := context.Background()
+	newLogger := func(t *testing.T) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		return l
+	}
+
+	t.Run("Reachable", func(t *testing.T) {
+		l := newLogger(t)
+		require.NoError(t, l.Check(ctx))
+
+		it, err := l.metaBucket.List(ctx, checkKeyPrefix)
+		require.NoError(t, err)
+		assert.False(t, it.Next(ctx), "the probe object is removed")
+	})
+	t.Run("Unreachable", func(t *testing.T) {
+		l := newLogger(t)
+		l.metaBucket = unreachableBucket{Bucket: l.metaBucket}
+		assert.Error(t, l.Check(ctx))
+	})
+	t.Run("DryRun", func(t *testing.T) {
+		local := send.MakeInternalLogger()
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithDryRun(local))
+		require.NoError(t, err)
+		l.metaBucket = unreachableBucket{Bucket: l.metaBucket}
+		require.NoError(t, l.Check(ctx), "the probe object is not written")
+		require.True(t, local.HasMessage())
+		fields, ok := local.GetMessage().Message.Raw().(message.Fields)
+		require.True(t, ok)
+		assert.Equal(t, checkKeyPrefix+"/"+l.instanceID, fields["key"])
+	})
+	t.Run("Multi", func(t *testing.T) {
+		unreachable := newLogger(t)
+		unreachable.metaBucket = unreachableBucket{Bucket: unreachable.metaBucket}
+
+		m, err := NewMultiLogger(MultiRequireAny, newLogger(t), unreachable)
+		require.NoError(t, err)
+		assert.NoError(t, m.Check(ctx))
+
+		m, err = NewMultiLogger(MultiRequireAll, newLogger(t), unreachable)
+		require.NoError(t, err)
+		assert.Error(t, m.Check(ctx))
+	})
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// readCheckpoint is the decoded form of a reader checkpoint token.
+type readCheckpoint struct {
+	Key    string `json:"key"`
+	Offset int64  `json:"offset"`
+}
+
+// Checkpoint returns an opaque token identifying the exact position of the
+// reader, i.e. the chunk and byte offset within it that the next Read
+// starts at, or an empty string once all chunks have been read.
+func (r *bucketReader) Checkpoint() string {
+	var checkpoint readCheckpoint
+	switch {
+	case r.reader != nil:
+		checkpoint = readCheckpoint{Key: r.keys[r.keyIdx-1], Offset: r.pos}
+	case r.keyIdx < len(r.keys):
+		checkpoint = readCheckpoint{Key: r.keys[r.keyIdx], Offset: r.offset}
+	default:
+		return ""
+	}
+
+	data, _ := json.Marshal(checkpoint)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Resume moves the reader to the position identified by a token previously
+// returned by Checkpoint, e.g. by a reader of the same key that was
+// interrupted. Only the remainder of the checkpointed chunk is fetched from
+// backends supporting ranged fetches.
+func (r *bucketReader) Resume(token string) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return errors.Wrap(err, "decoding checkpoint token")
+	}
+	var checkpoint readCheckpoint
+	if err = json.Unmarshal(data, &checkpoint); err != nil {
+		return errors.Wrap(err, "decoding checkpoint token")
+	}
+	if checkpoint.Offset < 0 {
+		return errors.Errorf("checkpoint offset %d cannot be negative", checkpoint.Offset)
+	}
+
+	idx := -1
+	for i, key := range r.keys {
+		if key == checkpoint.Key {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return errors.Errorf("checkpoint chunk '%s' does not match any chunk of the reader", checkpoint.Key)
+	}
+
+	if err = r.closeChunk(); err != nil {
+		return err
+	}
+	r.keyIdx = idx
+	r.offset = checkpoint.Offset
+
+	return nil
+}
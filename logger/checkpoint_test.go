@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketReaderCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line0\nline1\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line2\nline3\n"}))
+	newReader := func(t *testing.T) ReadCloser {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = r.Close() })
+		return r
+	}
+	all := "line0\nline1\nline2\nline3\n"
+
+	for _, n := range []int{0, 3, 12, 15} {
+		r := newReader(t)
+		buf := make([]byte, n)
+		_, err := io.ReadFull(r, buf)
+		require.NoError(t, err)
+		token := r.Checkpoint()
+		require.NotEmpty(t, token)
+
+		resumed := newReader(t)
+		require.NoError(t, resumed.Resume(token))
+		rest, err := io.ReadAll(resumed)
+		require.NoError(t, err)
+		assert.Equal(t, all[n:], string(rest), "resumed after %d bytes", n)
+	}
+
+	r := newReader(t)
+	_, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Empty(t, r.Checkpoint(), "fully read readers have no checkpoint")
+
+	r = newReader(t)
+	assert.Error(t, r.Resume("not a token"))
+	assert.Error(t, r.Resume("eyJrZXkiOiJtaXNzaW5nIn0"), "unknown chunks cannot be resumed")
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"context"
+	"strings"
+
+	"github.com/julianedwards/cedar/options"
+)
+
+// childLogger is a view of a parent logger that nests every key under a
+// prefix.
+type childLogger struct {
+	parent Logger
+	prefix string
+}
+
+// childKeysSuffix is appended to the prefix of a child to name the
+// directory its keys are stored under. Listing a key only lists the objects
+// under "<key>/", so the keys of a child never mix with the chunks of the
+// parent's key of the same name, which may nest directories of its own,
+// e.g. with a Hive key template.
+const childKeysSuffix = ".children"
+
+// NewChildLogger returns a view of the parent logger that reads and writes
+// the key "<prefix>.children/<key>" of the parent for each non-empty key it
+// is passed, and the key "<prefix>" for the empty key. It is provided for
+// implementations of Logger.Child.
+func NewChildLogger(parent Logger, prefix string) Logger {
+	prefix = strings.Trim(prefix, "/")
+	if child, ok := parent.(*childLogger); ok {
+		return &childLogger{parent: child.parent, prefix: child.key(prefix)}
+	}
+
+	return &childLogger{parent: parent, prefix: prefix}
+}
+
+func (l *childLogger) key(key string) string {
+	return childKey(l.prefix, key)
+}
+
+// childKey returns the key of the parent that a child with the given prefix
+// reads and writes for the key.
+func childKey(prefix, key string) string {
+	if key == "" {
+		return prefix
+	}
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + childKeysSuffix + "/" + key
+}
+
+func (l *childLogger) AddMetadata(ctx context.Context, opts options.AddMetadata) error {
+	opts.Key = l.key(opts.Key)
+	return l.parent.AddMetadata(ctx, opts)
+}
+
+func (l *childLogger) Write(ctx context.Context, opts options.Write) error {
+	opts.Key = l.key(opts.Key)
+	return l.parent.Write(ctx, opts)
+}
+
+func (l *childLogger) writeWithChunks(ctx context.Context, opts options.Write) ([]ManifestEntry, error) {
+	cw, ok := l.parent.(chunkWriter)
+	if !ok {
+		return nil, l.Write(ctx, opts)
+	}
+
+	opts.Key = l.key(opts.Key)
+	return cw.writeWithChunks(ctx, opts)
+}
+
+func (l *childLogger) WriteBytes(ctx context.Context, opts options.WriteBytes) (WriteResult, error) {
+	opts.Key = l.key(opts.Key)
+	return l.parent.WriteBytes(ctx, opts)
+}
+
+func (l *childLogger) WriteReader(ctx context.Context, opts options.WriteReader) error {
+	opts.Key = l.key(opts.Key)
+	return l.parent.WriteReader(ctx, opts)
+}
+
+func (l *childLogger) FollowFile(ctx context.Context, opts options.FollowFile) error {
+	opts.Key = l.key(opts.Key)
+	return l.parent.FollowFile(ctx, opts)
+}
+
+func (l *childLogger) NewReadCloser(ctx context.Context, opts options.Read) (ReadCloser, error) {
+	opts.Key = l.key(opts.Key)
+	return l.parent.NewReadCloser(ctx, opts)
+}
+
+func (l *childLogger) NewReverseReadCloser(ctx context.Context, opts options.Read) (ReadCloser, error) {
+	opts.Key = l.key(opts.Key)
+	return l.parent.NewReverseReadCloser(ctx, opts)
+}
+
+func (l *childLogger) ReadLines(ctx context.Context, opts options.Read) ([]LogLine, string, error) {
+	opts.Key = l.key(opts.Key)
+	return l.parent.ReadLines(ctx, opts)
+}
+
+func (l *childLogger) Search(ctx context.Context, opts options.Search) ([]SearchResult, error) {
+	opts.Key = l.key(opts.Key)
+	return l.parent.Search(ctx, opts)
+}
+
+func (l *childLogger) Check(ctx context.Context) error { return l.parent.Check(ctx) }
+
+func (l *childLogger) Child(prefix string) Logger { return NewChildLogger(l, prefix) }
+
+func (l *bucketLogger) Child(prefix string) Logger { return NewChildLogger(l, prefix) }
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChildLogger(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	readLines := func(t *testing.T, l Logger, key string) []LogLine {
+		lines, _, err := l.ReadLines(ctx, options.Read{Key: key})
+		require.NoError(t, err)
+		return lines
+	}
+
+	child := l.Child("/task/")
+	require.NoError(t, child.Write(ctx, options.Write{Key: "step1", Data: "message"}))
+
+	lines := readLines(t, l, "task.children/step1")
+	require.Len(t, lines, 1)
+	assert.Equal(t, "message", lines[0].Data)
+	assert.Len(t, readLines(t, child, "step1"), 1)
+
+	t.Run("Nested", func(t *testing.T) {
+		grandchild := child.Child("step2")
+		require.NoError(t, grandchild.Write(ctx, options.Write{Key: "0", Data: "nested"}))
+
+		assert.Len(t, readLines(t, l, "task.children/step2.children/0"), 1)
+		assert.Len(t, readLines(t, child.Child("step2"), "0"), 1)
+		assert.Equal(t, l, grandchild.(*childLogger).parent, "nested children wrap the root logger")
+	})
+	t.Run("EmptyKey", func(t *testing.T) {
+		require.NoError(t, l.Child("empty").Write(ctx, options.Write{Data: "message"}))
+		assert.Len(t, readLines(t, l, "empty"), 1)
+	})
+	t.Run("ParentWithChildren", func(t *testing.T) {
+		require.NoError(t, l.Write(ctx, options.Write{Key: "parent", Data: "parent"}))
+		parent := l.Child("parent")
+		require.NoError(t, parent.Write(ctx, options.Write{Key: "step1", Data: "child"}))
+		require.NoError(t, parent.Child("step1").Write(ctx, options.Write{Key: "0", Data: "grandchild"}))
+
+		for _, strict := range []bool{false, true} {
+			lines, _, err := l.ReadLines(ctx, options.Read{Key: "parent", Strict: strict})
+			require.NoError(t, err)
+			require.Len(t, lines, 1, "the keys of children are not read with the parent")
+			assert.Equal(t, "parent", lines[0].Data)
+
+			lines, _, err = parent.ReadLines(ctx, options.Read{Key: "step1", Strict: strict})
+			require.NoError(t, err)
+			require.Len(t, lines, 1)
+			assert.Equal(t, "child", lines[0].Data)
+		}
+	})
+	t.Run("Check", func(t *testing.T) {
+		assert.NoError(t, child.Check(ctx))
+	})
+}
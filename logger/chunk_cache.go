@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ChunkCache is an in-memory cache of the decompressed contents of recently
+// read log chunks, bounded by their total size and evicting the least
+// recently read chunks first. A single cache may be shared by any number of
+// bucket loggers, and therefore readers, so that a service serving many
+// readers of the same logs downloads each chunk once. Chunks are cached in
+// full, so reads of cached loggers do not use ranged fetches.
+type ChunkCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	lru      *list.List
+	entries  map[chunkCacheKey]*list.Element
+	loading  map[chunkCacheKey]*chunkLoad
+	hits     int64
+	misses   int64
+}
+
+// chunkCacheKey identifies a chunk of a logs bucket. The namespace
+// identifies the bucket, so that loggers of the same bucket share entries.
+type chunkCacheKey struct {
+	namespace string
+	key       string
+	etag      string
+}
+
+type chunkCacheEntry struct {
+	key  chunkCacheKey
+	data []byte
+}
+
+// chunkLoad is a download of a chunk in progress, shared by concurrent
+// readers of the chunk.
+type chunkLoad struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// NewChunkCache returns a new chunk cache holding at most maxBytes of chunk
+// data. Chunks larger than maxBytes are not cached.
+func NewChunkCache(maxBytes int64) (*ChunkCache, error) {
+	if maxBytes <= 0 {
+		return nil, errors.New("chunk cache size must be positive")
+	}
+
+	return &ChunkCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  map[chunkCacheKey]*list.Element{},
+		loading:  map[chunkCacheKey]*chunkLoad{},
+	}, nil
+}
+
+// Size returns the total size in bytes of the cached chunks.
+func (c *ChunkCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// Len returns the number of cached chunks.
+func (c *ChunkCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Hits and Misses return the number of chunk reads served from the cache
+// and downloaded, respectively.
+func (c *ChunkCache) Hits() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits
+}
+
+func (c *ChunkCache) Misses() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.misses
+}
+
+func (c *ChunkCache) load(key chunkCacheKey, fetch func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(el)
+		c.hits++
+		c.mu.Unlock()
+		return el.Value.(*chunkCacheEntry).data, nil
+	}
+	if load, ok := c.loading[key]; ok {
+		c.mu.Unlock()
+		<-load.done
+		return load.data, load.err
+	}
+	load := &chunkLoad{done: make(chan struct{})}
+	c.loading[key] = load
+	c.misses++
+	c.mu.Unlock()
+
+	load.data, load.err = fetch()
+
+	c.mu.Lock()
+	delete(c.loading, key)
+	if load.err == nil {
+		c.add(key, load.data)
+	}
+	c.mu.Unlock()
+	close(load.done)
+
+	return load.data, load.err
+}
+
+// add caches the chunk, evicting the least recently read chunks as needed.
+// The cache must be locked.
+func (c *ChunkCache) add(key chunkCacheKey, data []byte) {
+	if int64(len(data)) > c.maxBytes {
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&chunkCacheEntry{key: key, data: data})
+	c.size += int64(len(data))
+	for c.size > c.maxBytes {
+		oldest := c.lru.Back()
+		entry := oldest.Value.(*chunkCacheEntry)
+		c.lru.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= int64(len(entry.data))
+	}
+}
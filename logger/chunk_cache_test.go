@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SharedByLoggers", func(t *testing.T) {
+		cache, err := NewChunkCache(1024)
+		require.NoError(t, err)
+		opts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+		readers := make([]*bucketLogger, 2)
+		for i := range readers {
+			readers[i], err = NewBucketLogger(ctx, opts, WithChunkCache(cache))
+			require.NoError(t, err)
+		}
+		require.NoError(t, readers[0].Write(ctx, options.Write{Key: "key", Data: "line0\n"}))
+		require.NoError(t, readers[0].Write(ctx, options.Write{Key: "key", Data: "line1\n"}))
+
+		for _, l := range readers {
+			r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, "line0\nline1\n", string(data))
+		}
+		assert.EqualValues(t, 2, cache.Misses())
+		assert.EqualValues(t, 2, cache.Hits())
+		assert.Equal(t, 2, cache.Len())
+		assert.EqualValues(t, 12, cache.Size())
+
+		r, err := readers[1].NewReadCloser(ctx, options.Read{Key: "key", Offset: 2})
+		require.NoError(t, err)
+		page, err := r.ReadPage()
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, "ne0\n", string(page), "offsets apply to cached chunks")
+	})
+	t.Run("Evicts", func(t *testing.T) {
+		cache, err := NewChunkCache(10)
+		require.NoError(t, err)
+		fetch := func(data string) func() ([]byte, error) {
+			return func() ([]byte, error) { return []byte(data), nil }
+		}
+		key := func(k string) chunkCacheKey { return chunkCacheKey{namespace: "ns", key: k} }
+
+		_, err = cache.load(key("a"), fetch("aaaa"))
+		require.NoError(t, err)
+		_, err = cache.load(key("b"), fetch("bbbb"))
+		require.NoError(t, err)
+		_, err = cache.load(key("a"), fetch("aaaa"))
+		require.NoError(t, err)
+		_, err = cache.load(key("c"), fetch("cccc"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, cache.Len())
+		assert.EqualValues(t, 8, cache.Size())
+		assert.Contains(t, cache.entries, key("a"))
+		assert.NotContains(t, cache.entries, key("b"), "the least recently read chunk is evicted")
+
+		_, err = cache.load(key("big"), fetch("too big to cache"))
+		require.NoError(t, err)
+		assert.NotContains(t, cache.entries, key("big"))
+		assert.Equal(t, 2, cache.Len())
+	})
+	t.Run("ErrorsNotCached", func(t *testing.T) {
+		cache, err := NewChunkCache(10)
+		require.NoError(t, err)
+		key := chunkCacheKey{key: "key"}
+		_, err = cache.load(key, func() ([]byte, error) { return nil, errors.New("failed") })
+		assert.Error(t, err)
+		data, err := cache.load(key, func() ([]byte, error) { return []byte("data"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+	})
+	t.Run("ConcurrentLoads", func(t *testing.T) {
+		cache, err := NewChunkCache(10)
+		require.NoError(t, err)
+		release := make(chan struct{})
+		var fetches int
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				data, err := cache.load(chunkCacheKey{key: "key"}, func() ([]byte, error) {
+					fetches++
+					<-release
+					return []byte("data"), nil
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, "data", string(data))
+			}()
+		}
+		require.Eventually(t, func() bool {
+			cache.mu.Lock()
+			defer cache.mu.Unlock()
+			return cache.misses+cache.hits > 0 && len(cache.loading) == 1
+		}, time.Second, time.Millisecond)
+		close(release)
+		wg.Wait()
+		assert.Equal(t, 1, fetches, "concurrent reads of a chunk share a download")
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := NewChunkCache(0)
+		assert.Error(t, err)
+		_, err = NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithChunkCache(nil))
+		assert.Error(t, err)
+	})
+}
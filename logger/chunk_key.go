@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// chunkKey is the parsed form of the object keys generated by
+// bucketLogger.newKey, i.e.
+// "<prefix>/<unix nanos>_<instance ID>_<sequence>.<ext>".
+type chunkKey struct {
+	ts       int64
+	instance string
+	seq      uint64
+}
+
+// String returns the key name without its prefix or extension. The
+// timestamp and sequence number are zero-padded so that keys sort
+// lexicographically in time order.
+func (k chunkKey) String() string {
+	return leftPad(strconv.FormatInt(k.ts, 10), 19) + "_" + k.instance + "_" + leftPad(strconv.FormatUint(k.seq, 10), 10)
+}
+
+func parseChunkKey(key string) (chunkKey, error) {
+	name := path.Base(key)
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+
+	parts := strings.Split(name, "_")
+	if len(parts) != 3 {
+		return chunkKey{}, errors.Errorf("chunk key '%s' does not contain an instance ID and sequence number", key)
+	}
+
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return chunkKey{}, errors.Wrapf(err, "parsing timestamp of chunk key '%s'", key)
+	}
+	seq, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return chunkKey{}, errors.Wrapf(err, "parsing sequence number of chunk key '%s'", key)
+	}
+
+	return chunkKey{ts: ts, instance: parts[1], seq: seq}, nil
+}
+
+// keyListPrefix returns the prefix that lists the objects written to the
+// key, i.e. "<key>/", so that listing a key does not also list the keys it
+// is a string prefix of, e.g. "project2" for "project".
+func keyListPrefix(key string) string {
+	if key == "" || strings.HasSuffix(key, "/") {
+		return key
+	}
+
+	return key + "/"
+}
+
+// newInstanceID returns a random ID identifying a single logger instance.
+func newInstanceID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.Wrap(err, "generating logger instance ID")
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+func leftPad(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+
+	return strings.Repeat("0", width-len(s)) + s
+}
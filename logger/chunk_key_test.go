@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkKey(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		ck := chunkKey{ts: 42, instance: "abcdef0123456789", seq: 7}
+		parsed, err := parseChunkKey("prefix/" + ck.String() + ".json")
+		require.NoError(t, err)
+		assert.Equal(t, ck, parsed)
+	})
+	t.Run("SortsByTime", func(t *testing.T) {
+		keys := []string{
+			chunkKey{ts: 1000, instance: "b", seq: 1}.String(),
+			chunkKey{ts: 999, instance: "a", seq: 0}.String(),
+		}
+		sort.Strings(keys)
+		first, err := parseChunkKey(keys[0])
+		require.NoError(t, err)
+		assert.EqualValues(t, 999, first.ts)
+	})
+	t.Run("InvalidKey", func(t *testing.T) {
+		_, err := parseChunkKey("prefix/1234.txt")
+		assert.Error(t, err)
+		_, err = parseChunkKey("prefix/abc_def_0.txt")
+		assert.Error(t, err)
+	})
+}
+
+func TestBucketLoggerGenerateKey(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(100, 0)
+	clock := func() time.Time { return now }
+	newLogger := func(t *testing.T) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithClock(clock))
+		require.NoError(t, err)
+		return l
+	}
+
+	t.Run("MonotonicWithBackwardsClock", func(t *testing.T) {
+		l := newLogger(t)
+		first, _ := l.newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		now = now.Add(-time.Hour)
+		second, _ := l.newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		assert.Less(t, first, second)
+
+		ck, err := parseChunkKey(second)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, ck.seq)
+	})
+	t.Run("DistinctInstances", func(t *testing.T) {
+		a, _ := newLogger(t).newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		b, _ := newLogger(t).newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		assert.NotEqual(t, a, b)
+	})
+	t.Run("ReleaseSequence", func(t *testing.T) {
+		l := newLogger(t)
+		_, release := l.newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		release()
+		key, _ := l.newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		ck, err := parseChunkKey(key)
+		require.NoError(t, err)
+		assert.Zero(t, ck.seq)
+
+		_, release = l.newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		_, _ = l.newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		release()
+		key, _ = l.newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		ck, err = parseChunkKey(key)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, ck.seq)
+	})
+	t.Run("MetadataSequence", func(t *testing.T) {
+		l := newLogger(t)
+		l.newMetadataKey(KeyInfo{Prefix: "key", Extension: "json"})
+		key, _ := l.newKey(KeyInfo{Prefix: "key", Extension: "txt"})
+		ck, err := parseChunkKey(key)
+		require.NoError(t, err)
+		assert.Zero(t, ck.seq)
+	})
+}
+
+func TestValidateChunkSequence(t *testing.T) {
+	key := func(ts int64, instance string, seq uint64) string {
+		return "key/" + chunkKey{ts: ts, instance: instance, seq: seq}.String() + ".txt"
+	}
+
+	assert.NoError(t, validateChunkSequence("key", []string{key(1, "a", 0), key(2, "b", 0), key(3, "a", 1), key(4, "b", 1)}, false))
+	assert.NoError(t, validateChunkSequence("key", []string{key(2, "a", 1), key(1, "a", 0)}, true))
+
+	err := validateChunkSequence("key", []string{key(1, "a", 0), key(2, "b", 0), key(3, "a", 2)}, false)
+	require.True(t, IsGapError(err))
+	gap := err.(*GapError)
+	assert.Equal(t, "a", gap.Instance)
+	assert.EqualValues(t, 1, gap.From)
+	assert.EqualValues(t, 1, gap.To)
+
+	assert.True(t, IsGapError(validateChunkSequence("key", []string{key(1, "a", 1)}, false)))
+	assert.Error(t, validateChunkSequence("key", []string{key(1, "a", 1), key(2, "a", 0)}, false))
+}
+
+func TestKeyListPrefix(t *testing.T) {
+	assert.Equal(t, "key/", keyListPrefix("key"))
+	assert.Equal(t, "key/", keyListPrefix("key/"))
+	assert.Equal(t, "", keyListPrefix(""))
+}
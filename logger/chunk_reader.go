@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/internal"
+)
+
+// chunkReader downloads the decompressed contents of log chunks through the
+// logger's chunk caches, if any, checking the in-memory cache before the
+// disk cache.
+type chunkReader struct {
+	bucket pail.Bucket
+	memory *ChunkCache
+	disk   *DiskCache
+	// namespace identifies the bucket in the caches.
+	namespace string
+	// verify, if set, verifies the contents of every chunk returned, which
+	// are then always downloaded in full.
+	verify func(ctx context.Context, key string, data []byte) error
+}
+
+// logsChunkReader returns a chunk reader of the logger's logs bucket.
+func (l *bucketLogger) logsChunkReader() chunkReader {
+	return chunkReader{bucket: l.logsBucket, memory: l.chunkCache, disk: l.diskCache, namespace: l.cacheNamespace}
+}
+
+func (c chunkReader) cached() bool { return c.memory != nil || c.disk != nil }
+
+// get returns the contents of the chunk with the given ETag, which may be
+// empty if unknown.
+func (c chunkReader) get(ctx context.Context, key, etag string) ([]byte, error) {
+	fetch := func() ([]byte, error) { return getChunk(ctx, c.bucket, key) }
+	if c.disk != nil {
+		download := fetch
+		fetch = func() ([]byte, error) { return c.disk.get(c.namespace, key, etag, download) }
+	}
+	var data []byte
+	var err error
+	if c.memory != nil {
+		data, err = c.memory.load(chunkCacheKey{namespace: c.namespace, key: key, etag: etag}, fetch)
+	} else {
+		data, err = fetch()
+	}
+	if err != nil || c.verify == nil {
+		return data, err
+	}
+
+	return data, c.verify(ctx, key, data)
+}
+
+// getRange is the same as get but returns a reader of the chunk starting at
+// the byte offset. Only the needed range of the chunk is downloaded from
+// buckets supporting ranged fetches if it is not cached.
+func (c chunkReader) getRange(ctx context.Context, key, etag string, offset int64) (io.ReadCloser, error) {
+	if !c.cached() && c.verify == nil {
+		return internal.GetRange(ctx, c.bucket, key, offset)
+	}
+	if c.memory == nil && c.disk != nil && c.verify == nil {
+		if f := c.disk.open(c.namespace, key, etag); f != nil {
+			if _, err := f.Seek(offset, io.SeekStart); err == nil {
+				return f, nil
+			}
+			_ = f.Close()
+		}
+	}
+
+	data, err := c.get(ctx, key, etag)
+	if err != nil {
+		return nil, err
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return io.NopCloser(bytes.NewReader(data[offset:])), nil
+}
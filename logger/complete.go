@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/pkg/errors"
+)
+
+// completionMarkerName is the name of the metadata object, under a key,
+// recording that the key's log is complete.
+const completionMarkerName = "_complete.json"
+
+// completionMarker records when a log was marked complete.
+type completionMarker struct {
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// MarkComplete records that no more chunks will be written to the key, e.g.
+// once a task finishes, so that followed reads of the key stop at its end.
+// It must only be called once every chunk of the key is uploaded.
+func (l *bucketLogger) MarkComplete(ctx context.Context, key string) error {
+	if err := l.checkWritable("MarkComplete"); err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("must specify a key")
+	}
+
+	marker, err := json.Marshal(completionMarker{CompletedAt: l.now()})
+	if err != nil {
+		return errors.Wrap(err, "encoding completion marker")
+	}
+
+	return errors.Wrapf(l.put(ctx, l.metaBucket, JoinKey(key, completionMarkerName, ""), marker), "marking '%s' complete", key)
+}
+
+// IsComplete returns whether the key was marked complete with MarkComplete.
+func (l *bucketLogger) IsComplete(ctx context.Context, key string) (bool, error) {
+	return isComplete(ctx, l.metaBucket, key)
+}
+
+func isComplete(ctx context.Context, metaBucket pail.Bucket, key string) (bool, error) {
+	r, err := metaBucket.Get(ctx, JoinKey(key, completionMarkerName, ""))
+	switch {
+	case err == nil:
+		return true, r.Close()
+	case pail.IsKeyNotFoundError(err):
+		return false, nil
+	default:
+		return false, errors.Wrapf(err, "checking completion of '%s'", key)
+	}
+}
+
+// readFollower waits for new chunks of a log that is not yet complete once
+// a reader reaches the end of its chunks.
+type readFollower struct {
+	metaBucket   pail.Bucket
+	key          string
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+// follow waits for chunks written to the reader's key after its last chunk
+// until the key is marked complete or no chunk is written for the follow
+// timeout, adding any new chunks to the reader's keys. Chunks whose keys
+// sort before the reader's last chunk, e.g. of writers with skewed clocks,
+// are not read.
+func (r *bucketReader) follow() error {
+	timeout := time.NewTimer(r.follower.timeout)
+	defer timeout.Stop()
+
+	for {
+		// The log is only marked complete once all of its chunks are
+		// written, so checking before listing does not miss any.
+		complete, err := isComplete(r.ctx, r.follower.metaBucket, r.follower.key)
+		if err != nil {
+			return err
+		}
+
+		var startAfter string
+		if len(r.keys) > 0 {
+			startAfter = r.keys[len(r.keys)-1]
+		}
+		n := len(r.keys)
+		r.startKey = startAfter
+		err = r.getAndSortKeys(r.follower.key, false)
+		r.startKey = ""
+		if err != nil {
+			return err
+		}
+		if len(r.keys) > n || complete {
+			return nil
+		}
+
+		poll := time.NewTimer(r.follower.pollInterval)
+		select {
+		case <-poll.C:
+		case <-timeout.C:
+			poll.Stop()
+			return nil
+		case <-r.ctx.Done():
+			poll.Stop()
+			return r.ctx.Err()
+		}
+	}
+}
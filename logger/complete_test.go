@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerMarkComplete(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+
+	complete, err := l.IsComplete(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, complete)
+	require.NoError(t, l.MarkComplete(ctx, "key"))
+	complete, err = l.IsComplete(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, complete)
+	complete, err = l.IsComplete(ctx, "other")
+	require.NoError(t, err)
+	assert.False(t, complete)
+
+	assert.Error(t, l.MarkComplete(ctx, ""))
+}
+
+// atomicPutBucket is a bucket whose listings do not include objects that
+// are still being written, as is the case for S3 but not for local buckets.
+type atomicPutBucket struct {
+	pail.Bucket
+	mu sync.Mutex
+}
+
+func (b *atomicPutBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.Bucket.Put(ctx, key, r)
+}
+
+func (b *atomicPutBucket) List(ctx context.Context, prefix string) (pail.BucketIterator, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.Bucket.List(ctx, prefix)
+}
+
+func TestBucketLoggerFollowRead(t *testing.T) {
+	ctx := context.Background()
+	newLogger := func(t *testing.T) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		l.logsBucket = &atomicPutBucket{Bucket: l.logsBucket}
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line0\n"}))
+		return l
+	}
+
+	t.Run("UntilComplete", func(t *testing.T) {
+		l := newLogger(t)
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key", Follow: true, PollInterval: 10 * time.Millisecond, FollowTimeout: time.Minute})
+		require.NoError(t, err)
+		defer r.Close()
+		page, err := r.ReadPage()
+		require.NoError(t, err)
+		assert.Equal(t, "line0\n", string(page))
+
+		errs := make(chan error, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			if err := l.Write(ctx, options.Write{Key: "key", Data: "line1\n"}); err != nil {
+				errs <- err
+				return
+			}
+			errs <- l.MarkComplete(ctx, "key")
+		}()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, <-errs)
+		assert.Equal(t, "line1\n", string(data), "new chunks are read once written")
+	})
+	t.Run("Complete", func(t *testing.T) {
+		l := newLogger(t)
+		require.NoError(t, l.MarkComplete(ctx, "key"))
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key", Follow: true, FollowTimeout: time.Minute})
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "line0\n", string(data))
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		l := newLogger(t)
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key", Follow: true, PollInterval: 10 * time.Millisecond, FollowTimeout: 50 * time.Millisecond})
+		require.NoError(t, err)
+		defer r.Close()
+		start := time.Now()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "line0\n", string(data))
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(50*time.Millisecond))
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		l := newLogger(t)
+		cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		r, err := l.NewReadCloser(cctx, options.Read{Key: "key", Follow: true, PollInterval: 10 * time.Millisecond, FollowTimeout: time.Minute})
+		require.NoError(t, err)
+		defer r.Close()
+		_, err = io.ReadAll(r)
+		assert.Error(t, err)
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		l := newLogger(t)
+		for _, opts := range []options.Read{
+			{Key: "key", Follow: true, Metadata: true},
+			{Key: "key", Follow: true, EndKey: "key/chunk"},
+			{Key: "key", Follow: true, PollInterval: -1},
+			{Key: "key", Follow: true, FollowTimeout: -1},
+		} {
+			_, err := l.NewReadCloser(ctx, opts)
+			assert.Error(t, err)
+		}
+	})
+}
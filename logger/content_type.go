@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/julianedwards/cedar/encode"
+)
+
+const defaultContentTypeExtension = "bin"
+
+// contentTypeExtensions maps the MIME types returned by
+// http.DetectContentType to the key extension used when uploading. The
+// system MIME tables are not consistent across hosts so common types are
+// pinned here.
+var contentTypeExtensions = map[string]string{
+	"text/plain":                   "txt",
+	"text/html":                    "html",
+	"text/xml":                     "xml",
+	"application/json":             "json",
+	"application/pdf":              "pdf",
+	"application/zip":              "zip",
+	"application/x-gzip":           "gz",
+	"application/x-rar-compressed": "rar",
+	"application/ogg":              "ogg",
+	"application/wasm":             "wasm",
+	"application/octet-stream":     defaultContentTypeExtension,
+	"image/png":                    "png",
+	"image/jpeg":                   "jpg",
+	"image/gif":                    "gif",
+	"image/webp":                   "webp",
+	"image/bmp":                    "bmp",
+	"image/x-icon":                 "ico",
+	"audio/mpeg":                   "mp3",
+	"audio/wave":                   "wav",
+	"video/mp4":                    "mp4",
+	"video/webm":                   "webm",
+	"font/woff":                    "woff",
+	"font/woff2":                   "woff2",
+}
+
+// sniffContentType returns the detected MIME type of the given data along
+// with the extension that should be used for its key.
+func sniffContentType(data []byte) (string, string) {
+	contentType := http.DetectContentType(data)
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType, defaultContentTypeExtension
+	}
+	if ext, ok := contentTypeExtensions[mediaType]; ok {
+		return contentType, ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return contentType, strings.TrimPrefix(exts[0], ".")
+	}
+
+	return contentType, defaultContentTypeExtension
+}
+
+// encodingContentType returns the MIME type of data in the given encoding,
+// falling back to the system MIME tables by extension.
+func encodingContentType(e encode.Encoding) string {
+	if ce, ok := e.(encode.ContentTypeEncoding); ok {
+		return ce.ContentType()
+	}
+	if contentType := mime.TypeByExtension("." + e.Extension()); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffContentType(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		data        []byte
+		contentType string
+		ext         string
+	}{
+		{name: "Text", data: []byte("hello world\n"), contentType: "text/plain; charset=utf-8", ext: "txt"},
+		{name: "JSON", data: []byte(`{"a": 1}`), contentType: "text/plain; charset=utf-8", ext: "txt"},
+		{name: "PNG", data: []byte("\x89PNG\x0D\x0A\x1A\x0A"), contentType: "image/png", ext: "png"},
+		{name: "PDF", data: []byte("%PDF-1.4"), contentType: "application/pdf", ext: "pdf"},
+		{name: "Binary", data: []byte{0x00, 0x01, 0x02}, contentType: "application/octet-stream", ext: defaultContentTypeExtension},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			contentType, ext := sniffContentType(test.data)
+			assert.Equal(t, test.contentType, contentType)
+			assert.Equal(t, test.ext, ext)
+		})
+	}
+}
+
+func TestWriteSniffedBytes(t *testing.T) {
+	ctx := context.Background()
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err := gw.Write([]byte("archived\n"))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	for _, test := range []struct {
+		name string
+		data []byte
+		ext  string
+	}{
+		{name: "PNG", data: []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR"), ext: "png"},
+		{name: "Gzip", data: gz.Bytes(), ext: "gz"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+			require.NoError(t, err)
+
+			res, err := l.WriteBytes(ctx, options.WriteBytes{Key: "artifacts", Data: test.data, SniffContentType: true})
+			require.NoError(t, err)
+
+			it, err := l.logsBucket.List(ctx, "artifacts")
+			require.NoError(t, err)
+			require.True(t, it.Next(ctx))
+			key := it.Item().Name()
+			assert.True(t, strings.HasSuffix(key, "."+test.ext+"."+encode.BLOB), key)
+			assert.Equal(t, key, res.Key)
+			assert.False(t, it.Next(ctx))
+
+			r, err := l.NewReadCloser(ctx, options.Read{Key: "artifacts"})
+			require.NoError(t, err)
+			defer r.Close()
+			page, err := r.ReadPage()
+			require.NoError(t, err)
+			assert.Equal(t, test.data, page, "artifacts are read back as written")
+
+			e, ok := encode.GetGlobalRegistry().Get(encode.BLOB)
+			require.True(t, ok)
+			var out []byte
+			require.NoError(t, e.Unmarshal(page, &out))
+			assert.Equal(t, test.data, out)
+		})
+	}
+}
+
+// extensionEncoding is an encoding without a known content type.
+type extensionEncoding struct {
+	encode.Encoding
+	ext string
+}
+
+func (e extensionEncoding) Extension() string { return e.ext }
+
+func TestEncodingContentType(t *testing.T) {
+	for _, test := range []struct {
+		encoding    string
+		contentType string
+	}{
+		{encoding: encode.TEXT, contentType: "text/plain; charset=utf-8"},
+		{encoding: encode.JSON, contentType: "application/json"},
+		{encoding: encode.NDJSON, contentType: "application/x-ndjson"},
+		{encoding: encode.CSV, contentType: "text/csv; charset=utf-8"},
+		{encoding: encode.GOB, contentType: "application/octet-stream"},
+	} {
+		t.Run(test.encoding, func(t *testing.T) {
+			e, ok := encode.GetGlobalRegistry().Get(test.encoding)
+			require.True(t, ok)
+			assert.Equal(t, test.contentType, encodingContentType(e))
+		})
+	}
+	t.Run("ByExtension", func(t *testing.T) {
+		assert.Equal(t, "image/png", encodingContentType(extensionEncoding{ext: "png"}))
+		assert.Equal(t, "application/octet-stream", encodingContentType(extensionEncoding{ext: "unknown-extension"}))
+	})
+	t.Run("LocalBucket", func(t *testing.T) {
+		l, err := NewBucketLogger(context.Background(), options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		bucket, err := l.getContentTypeBucket(context.Background(), false, "application/json")
+		require.NoError(t, err)
+		assert.Equal(t, l.logsBucket, bucket)
+		bucket, err = l.getContentTypeBucket(context.Background(), true, "application/json")
+		require.NoError(t, err)
+		assert.Equal(t, l.metaBucket, bucket)
+	})
+}
+
+func TestBucketReaderDecodePage(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: map[string]string{"a": "b"}, Encoding: encode.JSON}))
+	_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: png, SniffContentType: true})
+	require.NoError(t, err)
+	_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("text\n")})
+	require.NoError(t, err)
+
+	r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+	require.NoError(t, err)
+	defer r.Close()
+
+	var m map[string]string
+	require.NoError(t, r.DecodePage(&m))
+	assert.Equal(t, map[string]string{"a": "b"}, m)
+	var blob []byte
+	require.NoError(t, r.DecodePage(&blob))
+	assert.Equal(t, png, blob)
+	var text string
+	require.NoError(t, r.DecodePage(&text))
+	assert.Equal(t, "text\n", text)
+	assert.Equal(t, io.EOF, r.DecodePage(&text))
+}
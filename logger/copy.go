@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"context"
+	"strings"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// Copy copies all chunks, metadata, and manifest entries of a key to
+// another key, of the same or another bucket, e.g. to archive the logs of
+// finished tasks to a cold storage bucket. Chunks are copied server-side
+// when both buckets are S3 buckets. The source is only removed, if
+// requested, once everything has been copied.
+func (l *bucketLogger) Copy(ctx context.Context, opts options.Copy) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.DstBucket == nil {
+		return l.copyTo(ctx, l, opts)
+	}
+
+	dst, err := NewBucketLogger(ctx, *opts.DstBucket)
+	if err != nil {
+		return errors.Wrap(err, "creating destination bucket logger")
+	}
+
+	return l.copyTo(ctx, dst, opts)
+}
+
+// CopyTo is the same as Copy but copies to the given bucket logger, e.g. one
+// shared by every copy to a cold storage bucket, rather than to a logger
+// created for the copy. The copy's destination bucket must not be set.
+func (l *bucketLogger) CopyTo(ctx context.Context, dst *bucketLogger, opts options.Copy) error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(dst == nil, "must specify a destination logger")
+	catcher.NewWhen(opts.DstBucket != nil, "cannot specify a destination bucket when copying to a logger")
+	catcher.NewWhen(opts.SrcKey == "", "must specify a source key")
+	catcher.NewWhen(opts.DstKey == "", "must specify a destination key")
+	catcher.NewWhen(dst == l && opts.SrcKey == opts.DstKey, "source and destination cannot be the same")
+	if catcher.HasErrors() {
+		return catcher.Resolve()
+	}
+
+	return l.copyTo(ctx, dst, opts)
+}
+
+// copyTo copies the source key of the logger to the destination key of the
+// given logger.
+func (l *bucketLogger) copyTo(ctx context.Context, dst *bucketLogger, opts options.Copy) error {
+	if err := dst.checkWritable("Copy"); err != nil {
+		return err
+	}
+	if err := l.audit(ctx, AuditExport, opts.SrcKey); err != nil {
+		return err
+	}
+	serverSide := l.opts.Type == options.PailS3 && dst.opts.Type == options.PailS3 && dst.dryRun == nil
+	rename := func(key string) string {
+		return opts.DstKey + strings.TrimPrefix(key, opts.SrcKey)
+	}
+
+	chunks, err := dst.copyObjects(ctx, l.logsBucket, dst.logsBucket, opts.SrcKey, rename, serverSide)
+	if err != nil {
+		return errors.Wrap(err, "copying log chunks")
+	}
+	metadata, err := dst.copyObjects(ctx, l.metaBucket, dst.metaBucket, opts.SrcKey, rename, serverSide)
+	if err != nil {
+		return errors.Wrap(err, "copying metadata")
+	}
+	entries, err := l.Manifest(ctx, opts.SrcKey)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		srcKey := entry.Key
+		entry.Key = rename(entry.Key)
+		if err = dst.putManifestEntry(ctx, entry); err != nil {
+			return errors.Wrapf(err, "copying manifest entry for chunk '%s'", srcKey)
+		}
+	}
+
+	if !opts.DeleteSource {
+		return nil
+	}
+	if err = l.checkWritable("Copy"); err != nil {
+		return err
+	}
+	if err = l.audit(ctx, AuditDelete, opts.SrcKey); err != nil {
+		return err
+	}
+	if err = removeKeys(ctx, l.logsBucket, chunks); err != nil {
+		return errors.Wrap(err, "removing source log chunks")
+	}
+	if err = removeKeys(ctx, l.metaBucket, metadata); err != nil {
+		return errors.Wrap(err, "removing source metadata")
+	}
+	if l.manifestBucket != nil {
+		keys := make([]string, len(entries))
+		for i, entry := range entries {
+			keys[i] = entry.Key + manifestExtension
+		}
+		if err = removeKeys(ctx, l.manifestBucket, keys); err != nil {
+			return errors.Wrap(err, "removing source manifest entries")
+		}
+	}
+
+	return nil
+}
+
+// copyObjects copies every object under the prefix of the source bucket to
+// the renamed key of the destination bucket, one of the logger's buckets,
+// returning the copied keys.
+func (l *bucketLogger) copyObjects(ctx context.Context, src, dst pail.Bucket, prefix string, rename func(string) string, serverSide bool) ([]string, error) {
+	r := &bucketReader{ctx: ctx, bucket: src}
+	if err := r.getAndSortKeys(prefix, false); err != nil {
+		return nil, err
+	}
+
+	for _, key := range r.keys {
+		if serverSide {
+			if err := src.Copy(ctx, pail.CopyOptions{
+				SourceKey:         key,
+				DestinationKey:    rename(key),
+				DestinationBucket: dst,
+			}); err != nil {
+				return nil, errors.Wrapf(err, "copying '%s'", key)
+			}
+			continue
+		}
+
+		data, err := getObject(ctx, src, key)
+		if err != nil {
+			return nil, err
+		}
+		if err = l.put(ctx, dst, rename(key), data); err != nil {
+			return nil, errors.Wrapf(err, "putting '%s'", rename(key))
+		}
+	}
+
+	return r.keys, nil
+}
+
+// removeKeys removes the keys, if any, from the bucket.
+func removeKeys(ctx context.Context, bucket pail.Bucket, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return bucket.RemoveMany(ctx, keys...)
+}
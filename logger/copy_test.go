@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerCopy(t *testing.T) {
+	ctx := context.Background()
+	newOpts := func(t *testing.T) options.Bucket {
+		return options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+	}
+	newLogger := func(t *testing.T, opts options.Bucket) *bucketLogger {
+		l, err := NewBucketLogger(ctx, opts)
+		require.NoError(t, err)
+		return l
+	}
+	newSource := func(t *testing.T) *bucketLogger {
+		l := newLogger(t, newOpts(t))
+		require.NoError(t, l.Write(ctx, options.Write{Key: "src", Data: "line0\n"}))
+		require.NoError(t, l.Write(ctx, options.Write{Key: "src", Data: "line1\n"}))
+		return l
+	}
+	read := func(t *testing.T, l Logger, key string) []string {
+		var data []string
+		var token string
+		for {
+			lines, next, err := l.ReadLines(ctx, options.Read{Key: key, PageToken: token})
+			require.NoError(t, err)
+			for _, line := range lines {
+				data = append(data, line.Data.(string))
+			}
+			if next == "" {
+				return data
+			}
+			token = next
+		}
+	}
+	expected := []string{"line0", "line1"}
+
+	t.Run("SameBucket", func(t *testing.T) {
+		l := newSource(t)
+		require.NoError(t, l.Copy(ctx, options.Copy{SrcKey: "src", DstKey: "dst"}))
+		assert.Equal(t, expected, read(t, l, "dst"))
+		assert.Equal(t, expected, read(t, l, "src"))
+		entries, err := l.Manifest(ctx, "dst")
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+	t.Run("OtherBucket", func(t *testing.T) {
+		l := newSource(t)
+		dstOpts := newOpts(t)
+		require.NoError(t, l.Copy(ctx, options.Copy{SrcKey: "src", DstKey: "src", DstBucket: &dstOpts, DeleteSource: true}))
+		assert.Equal(t, expected, read(t, newLogger(t, dstOpts), "src"))
+
+		exists, err := l.Exists(ctx, "src")
+		require.NoError(t, err)
+		assert.False(t, exists, "the source is removed")
+		entries, err := l.Manifest(ctx, "src")
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+	t.Run("CopyTo", func(t *testing.T) {
+		dst := newLogger(t, newOpts(t))
+		for _, key := range []string{"task0", "task1"} {
+			l := newSource(t)
+			require.NoError(t, l.CopyTo(ctx, dst, options.Copy{SrcKey: "src", DstKey: key}))
+			assert.Equal(t, expected, read(t, dst, key))
+		}
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		l := newSource(t)
+		dstOpts := newOpts(t)
+		assert.Error(t, l.Copy(ctx, options.Copy{SrcKey: "src", DstKey: "src"}))
+		assert.Error(t, l.Copy(ctx, options.Copy{DstKey: "dst"}))
+		assert.Error(t, l.CopyTo(ctx, l, options.Copy{SrcKey: "src", DstKey: "src"}))
+		assert.Error(t, l.CopyTo(ctx, nil, options.Copy{SrcKey: "src", DstKey: "dst"}))
+		assert.Error(t, l.CopyTo(ctx, newLogger(t, dstOpts), options.Copy{SrcKey: "src", DstKey: "dst", DstBucket: &dstOpts}))
+	})
+}
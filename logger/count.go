@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/julianedwards/cedar/options"
+)
+
+// CountLines returns the number of lines in the chunks read with the given
+// options, starting at the page token, if any. Counts are taken from the
+// manifest where possible, so only chunks without a manifest entry, or
+// whose entry has no line count, are downloaded and counted.
+func (l *bucketLogger) CountLines(ctx context.Context, opts options.Read) (int64, error) {
+	rc, err := l.newReadCloser(ctx, opts, false)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	r := rc.(*bucketReader)
+
+	counts := map[string]int{}
+	if !opts.Metadata {
+		entries, err := l.Manifest(ctx, opts.Key)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			if entry.Lines > 0 || entry.Size == 0 {
+				counts[entry.Key] = entry.Lines
+			}
+		}
+	}
+
+	var total int64
+	for _, key := range r.keys[r.keyIdx:] {
+		if count, ok := counts[key]; ok {
+			total += int64(count)
+			continue
+		}
+
+		data, err := r.get(key)
+		if err != nil {
+			return 0, err
+		}
+		total += int64(l.countChunkLines(key, data))
+	}
+
+	return total, nil
+}
+
+// countChunkLines returns the number of log lines in the chunk, falling
+// back to counting newline-terminated lines if the chunk cannot be decoded
+// as log lines.
+func (l *bucketLogger) countChunkLines(key string, data []byte) int {
+	if lines, err := decodeLines(l.encodingRegistry, key, data); err == nil {
+		return len(lines)
+	}
+
+	count := bytes.Count(data, []byte{'\n'})
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		count++
+	}
+
+	return count
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerCountLines(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+
+	count, err := l.CountLines(ctx, options.Read{Key: "key"})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "a\nb\nc\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{{Priority: level.Info, Data: "d"}, {Priority: level.Info, Data: "e"}}, Encoding: encode.JSON}))
+	count, err = l.CountLines(ctx, options.Read{Key: "key"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, count)
+
+	// Chunks without a manifest entry are downloaded and counted.
+	require.NoError(t, l.logsBucket.Put(ctx, "key/unmanifested", strings.NewReader("f\ng")))
+	count, err = l.CountLines(ctx, options.Read{Key: "key"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, count)
+
+	// Counting starts at the page token.
+	_, token, err := l.ReadLines(ctx, options.Read{Key: "key"})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	count, err = l.CountLines(ctx, options.Read{Key: "key", PageToken: token})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, count)
+}
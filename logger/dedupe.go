@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/internal"
+	"github.com/pkg/errors"
+)
+
+// maxDedupeManifestSize is the maximum number of chunk hashes recorded per
+// key, after which the oldest hashes are evicted.
+const maxDedupeManifestSize = 1000
+
+// dedupeManifest records the content hashes of the chunks written to a key
+// with deduplication enabled. It is stored in its own bucket, next to the
+// logs and metadata buckets, so that writes from a new logger instance
+// (e.g. a retried task) are also deduplicated.
+type dedupeManifest struct {
+	// Hashes maps the hex-encoded SHA-256 of each chunk to its key.
+	Hashes map[string]string `json:"hashes"`
+	// Order is the hashes in write order, used for eviction.
+	Order []string `json:"order"`
+}
+
+func (m *dedupeManifest) add(hash, key string) {
+	m.Hashes[hash] = key
+	m.Order = append(m.Order, hash)
+	for len(m.Order) > maxDedupeManifestSize {
+		delete(m.Hashes, m.Order[0])
+		m.Order = m.Order[1:]
+	}
+}
+
+func (m *dedupeManifest) remove(hash string) {
+	delete(m.Hashes, hash)
+	for i, h := range m.Order {
+		if h == hash {
+			m.Order = append(m.Order[:i], m.Order[i+1:]...)
+			break
+		}
+	}
+}
+
+func hashChunk(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// getDedupeManifest returns the dedupe manifest for the key, downloading it
+// on first use. The caller must hold the key's logs lock.
+func (l *bucketLogger) getDedupeManifest(ctx context.Context, key string) (*dedupeManifest, error) {
+	l.mu.Lock()
+	manifest, ok := l.dedupeManifests[key]
+	l.mu.Unlock()
+	if ok {
+		return manifest, nil
+	}
+
+	bucket, err := l.getDedupeBucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest = &dedupeManifest{Hashes: map[string]string{}}
+	r, err := bucket.Get(ctx, key+".json")
+	switch {
+	case pail.IsKeyNotFoundError(err):
+	case err != nil:
+		return nil, errors.Wrapf(err, "getting dedupe manifest for key '%s'", key)
+	default:
+		defer r.Close()
+		if err = json.NewDecoder(r).Decode(manifest); err != nil {
+			return nil, errors.Wrapf(err, "decoding dedupe manifest for key '%s'", key)
+		}
+		if manifest.Hashes == nil {
+			manifest.Hashes = map[string]string{}
+		}
+	}
+
+	l.mu.Lock()
+	l.dedupeManifests[key] = manifest
+	l.mu.Unlock()
+
+	return manifest, nil
+}
+
+// putDedupeManifest uploads the dedupe manifest for the key. The caller must
+// hold the key's logs lock.
+func (l *bucketLogger) putDedupeManifest(ctx context.Context, key string, manifest *dedupeManifest) error {
+	bucket, err := l.getDedupeBucket(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "encoding dedupe manifest")
+	}
+
+	return errors.Wrapf(l.put(ctx, bucket, key+".json", data), "uploading dedupe manifest for key '%s'", key)
+}
+
+// getDedupeBucket returns the bucket storing dedupe manifests, creating it
+// on first use.
+func (l *bucketLogger) getDedupeBucket(ctx context.Context) (pail.Bucket, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.dedupeBucket != nil {
+		return l.dedupeBucket, nil
+	}
+	if l.session == nil {
+		return nil, errors.New("deduplicated writes require a dedupe bucket")
+	}
+
+	bucket, err := l.session.Create(ctx, bucketPrefix(l.opts, "dedupe"))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating dedupe bucket")
+	}
+	if l.retry != nil {
+		bucket = internal.NewRetryBucket(bucket, *l.retry)
+	}
+	l.dedupeBucket = bucket
+
+	return bucket, nil
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerDedupe(t *testing.T) {
+	ctx := context.Background()
+	newOpts := func(t *testing.T) options.Bucket {
+		return options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+	}
+	write := func(t *testing.T, l Logger, key, data string, dedupe bool) WriteResult {
+		res, err := l.WriteBytes(ctx, options.WriteBytes{Key: key, Data: []byte(data), Dedupe: dedupe})
+		require.NoError(t, err)
+		return res
+	}
+
+	for _, test := range []struct {
+		name    string
+		key     string
+		data    string
+		dedupe  bool
+		skipped bool
+	}{
+		{name: "SameData", key: "key", data: "line\n", dedupe: true, skipped: true},
+		{name: "DifferentData", key: "key", data: "other\n", dedupe: true, skipped: false},
+		{name: "DifferentKey", key: "key2", data: "line\n", dedupe: true, skipped: false},
+		{name: "Disabled", key: "key", data: "line\n", dedupe: false, skipped: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := NewBucketLogger(ctx, newOpts(t))
+			require.NoError(t, err)
+			first := write(t, l, "key", "line\n", true)
+			assert.False(t, first.Deduplicated)
+
+			res := write(t, l, test.key, test.data, test.dedupe)
+			assert.Equal(t, test.skipped, res.Deduplicated)
+			if test.skipped {
+				assert.Equal(t, first.Key, res.Key)
+			} else {
+				assert.NotEqual(t, first.Key, res.Key)
+			}
+		})
+	}
+
+	t.Run("AcrossInstances", func(t *testing.T) {
+		opts := newOpts(t)
+		l, err := NewBucketLogger(ctx, opts)
+		require.NoError(t, err)
+		first := write(t, l, "key", "line\n", true)
+
+		retried, err := NewBucketLogger(ctx, opts)
+		require.NoError(t, err)
+		res := write(t, retried, "key", "line\n", true)
+		assert.True(t, res.Deduplicated)
+		assert.Equal(t, first.Key, res.Key)
+
+		it, err := retried.logsBucket.List(ctx, "key")
+		require.NoError(t, err)
+		var chunks int
+		for it.Next(ctx) {
+			chunks++
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, 1, chunks)
+	})
+	t.Run("RemovedChunk", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, newOpts(t))
+		require.NoError(t, err)
+		first := write(t, l, "key", "line\n", true)
+		require.NoError(t, l.logsBucket.Remove(ctx, first.Key))
+
+		res := write(t, l, "key", "line\n", true)
+		assert.False(t, res.Deduplicated, "removed chunks are not deduplicated against")
+		assert.NotEqual(t, first.Key, res.Key)
+		exists, err := l.logsBucket.Exists(ctx, res.Key)
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		res = write(t, l, "key", "line\n", true)
+		assert.True(t, res.Deduplicated)
+		manifest, err := l.getDedupeManifest(ctx, "key")
+		require.NoError(t, err)
+		assert.Len(t, manifest.Order, 1)
+	})
+}
+
+func TestDedupeManifestEviction(t *testing.T) {
+	m := &dedupeManifest{Hashes: map[string]string{}}
+	for i := 0; i < maxDedupeManifestSize+1; i++ {
+		m.add(fmt.Sprint(i), fmt.Sprintf("key/%d", i))
+	}
+
+	assert.Len(t, m.Hashes, maxDedupeManifestSize)
+	assert.Len(t, m.Order, maxDedupeManifestSize)
+	assert.NotContains(t, m.Hashes, "0")
+	assert.Contains(t, m.Hashes, fmt.Sprint(maxDedupeManifestSize))
+}
@@ -0,0 +1,450 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// DiffOp is the kind of a diff edit.
+type DiffOp int
+
+const (
+	// DiffEqual lines are in both logs.
+	DiffEqual DiffOp = iota
+	// DiffDelete lines are only in the first log.
+	DiffDelete
+	// DiffInsert lines are only in the second log.
+	DiffInsert
+)
+
+// DiffEdit is a single line of a diff between two logs.
+type DiffEdit struct {
+	Op DiffOp
+	// Line is the line of the first log for equal and deleted lines, and
+	// of the second log for inserted lines.
+	Line LogLine
+	// Text is the normalized text the line was compared by.
+	Text string
+}
+
+var diffTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+
+// diffText returns the text two lines are compared by: their level and
+// message, or data, with any embedded timestamps replaced, since
+// timestamps differ between otherwise identical runs.
+func diffText(line LogLine) string {
+	var text string
+	switch {
+	case line.Message != "":
+		text = line.Message
+	case line.Data != nil:
+		text = fmt.Sprint(line.Data)
+	}
+	if line.Priority != 0 {
+		text = "[" + line.Priority.String() + "] " + text
+	}
+
+	return diffTimestamp.ReplaceAllString(text, "<timestamp>")
+}
+
+// diffWindow is the number of lines of each log that are compared at a
+// time, which bounds the memory of a diff. Changes are aligned within the
+// window, so a change spanning more lines than half the window may not
+// produce the shortest diff.
+const diffWindow = 10000
+
+// Diff compares the lines of two logs, e.g. of a passing and a failing run,
+// ignoring timestamps, and returns the deleted and inserted lines of the
+// edits turning the first into the second. The logs are streamed rather
+// than read in full, and a unified diff is written to the options' writer,
+// if set, as the logs are compared.
+func (l *bucketLogger) Diff(ctx context.Context, opts options.Diff) ([]DiffEdit, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	a, err := l.diffLineReader(ctx, opts.KeyA)
+	if err != nil {
+		return nil, err
+	}
+	defer a.r.Close()
+	b, err := l.diffLineReader(ctx, opts.KeyB)
+	if err != nil {
+		return nil, err
+	}
+	defer b.r.Close()
+
+	var (
+		changes []DiffEdit
+		unified *unifiedWriter
+	)
+	if opts.Writer != nil {
+		unified = newUnifiedWriter(opts.Writer, opts.KeyA, opts.KeyB, *opts.Context)
+	}
+	err = diffStream(a, b, diffWindow, func(edit DiffEdit) error {
+		if edit.Op != DiffEqual {
+			changes = append(changes, edit)
+		}
+		if unified != nil {
+			return unified.add(edit)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unified != nil {
+		if err = unified.close(); err != nil {
+			return nil, errors.Wrap(err, "writing unified diff")
+		}
+	}
+
+	return changes, nil
+}
+
+// diffLineReader returns an iterator over the lines of the key.
+func (l *bucketLogger) diffLineReader(ctx context.Context, key string) (*diffReader, error) {
+	r, err := l.newReadCloser(ctx, options.Read{Key: key}, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading key '%s'", key)
+	}
+
+	return &diffReader{key: key, lineIterator: lineIterator{r: r, registry: l.encodingRegistry}}, nil
+}
+
+// diffReader reads the lines of one side of a diff.
+type diffReader struct {
+	key string
+	lineIterator
+}
+
+// fill appends lines to the buffer until it holds n lines, returning false
+// once the key has no more lines.
+func (r *diffReader) fill(buffer []diffLine, n int) ([]diffLine, bool, error) {
+	for len(buffer) < n {
+		ok, err := r.next()
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "reading key '%s'", r.key)
+		}
+		if !ok {
+			return buffer, false, nil
+		}
+		buffer = append(buffer, diffLine{line: r.line, text: diffText(r.line)})
+	}
+
+	return buffer, true, nil
+}
+
+// diffLine is a line and the text it is compared by.
+type diffLine struct {
+	line LogLine
+	text string
+}
+
+// diffStream passes the edits turning the lines of a into those of b to
+// emit in order, comparing up to window lines of each at a time. Only the
+// edits of the first half of a window are emitted before it is refilled,
+// so that the edits near its end, which may align differently with the
+// lines that follow, are compared again.
+func diffStream(a, b *diffReader, window int, emit func(DiffEdit) error) error {
+	var (
+		bufA, bufB   []diffLine
+		moreA, moreB = true, true
+		err          error
+	)
+	for {
+		if moreA {
+			if bufA, moreA, err = a.fill(bufA, window); err != nil {
+				return err
+			}
+		}
+		if moreB {
+			if bufB, moreB, err = b.fill(bufB, window); err != nil {
+				return err
+			}
+		}
+		if len(bufA) == 0 && len(bufB) == 0 {
+			return nil
+		}
+
+		var ia, ib int
+		for _, edit := range diffLineSlices(bufA, bufB) {
+			if (moreA && ia >= len(bufA)/2) || (moreB && ib >= len(bufB)/2) {
+				break
+			}
+			if err = emit(edit); err != nil {
+				return err
+			}
+			if edit.Op != DiffInsert {
+				ia++
+			}
+			if edit.Op != DiffDelete {
+				ib++
+			}
+		}
+		bufA = append(bufA[:0], bufA[ia:]...)
+		bufB = append(bufB[:0], bufB[ib:]...)
+	}
+}
+
+// diffLines returns the shortest edit script between the lines, compared by
+// their normalized text.
+func diffLines(a, b []LogLine) []DiffEdit {
+	al := make([]diffLine, len(a))
+	for i := range a {
+		al[i] = diffLine{line: a[i], text: diffText(a[i])}
+	}
+	bl := make([]diffLine, len(b))
+	for i := range b {
+		bl[i] = diffLine{line: b[i], text: diffText(b[i])}
+	}
+
+	return diffLineSlices(al, bl)
+}
+
+// diffLineSlices returns the shortest edit script between the lines using
+// the linear space variant of Myers' algorithm.
+func diffLineSlices(a, b []diffLine) []DiffEdit {
+	var edits []DiffEdit
+	var diff func(a, b []diffLine)
+	diff = func(a, b []diffLine) {
+		var prefix int
+		for prefix < len(a) && prefix < len(b) && a[prefix].text == b[prefix].text {
+			edits = append(edits, DiffEdit{Op: DiffEqual, Line: a[prefix].line, Text: a[prefix].text})
+			prefix++
+		}
+		a, b = a[prefix:], b[prefix:]
+		var suffix int
+		for suffix < len(a) && suffix < len(b) && a[len(a)-suffix-1].text == b[len(b)-suffix-1].text {
+			suffix++
+		}
+		equal := a[len(a)-suffix:]
+		a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+		if x, y, ok := diffMiddle(a, b); ok {
+			diff(a[:x], b[:y])
+			diff(a[x:], b[y:])
+		} else {
+			for _, line := range a {
+				edits = append(edits, DiffEdit{Op: DiffDelete, Line: line.line, Text: line.text})
+			}
+			for _, line := range b {
+				edits = append(edits, DiffEdit{Op: DiffInsert, Line: line.line, Text: line.text})
+			}
+		}
+		for _, line := range equal {
+			edits = append(edits, DiffEdit{Op: DiffEqual, Line: line.line, Text: line.text})
+		}
+	}
+	diff(a, b)
+
+	return edits
+}
+
+// diffMiddle returns a point on a shortest edit path between lines that
+// share no prefix or suffix, found by searching from both ends until the
+// paths overlap, using space linear in the number of lines. It returns
+// false if the lines have nothing in common.
+func diffMiddle(a, b []diffLine) (int, int, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	reverse := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		reverse[i] = -1
+	}
+	forward[offset+1] = 0
+	reverse[offset+1] = 0
+	delta := n - m
+	// The paths overlap on a forward step if delta is odd, and on a
+	// reverse step if it is even.
+	front := delta%2 != 0
+	var kStart, kEnd, rkStart, rkEnd int
+	for d := 0; d < maxD; d++ {
+		for k := -d + kStart; k <= d-kEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x].text == b[y].text {
+				x++
+				y++
+			}
+			forward[i] = x
+			switch {
+			case x > n:
+				kEnd += 2
+			case y > m:
+				kStart += 2
+			case front:
+				ri := offset + delta - k
+				if ri >= 0 && ri < len(reverse) && reverse[ri] != -1 && x >= n-reverse[ri] {
+					return x, y, true
+				}
+			}
+		}
+		for k := -d + rkStart; k <= d-rkEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && reverse[i-1] < reverse[i+1]) {
+				x = reverse[i+1]
+			} else {
+				x = reverse[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1].text == b[m-y-1].text {
+				x++
+				y++
+			}
+			reverse[i] = x
+			switch {
+			case x > n:
+				rkEnd += 2
+			case y > m:
+				rkStart += 2
+			case !front:
+				fi := offset + delta - k
+				if fi >= 0 && fi < len(forward) && forward[fi] != -1 {
+					fx := forward[fi]
+					fy := offset + fx - fi
+					if fx >= n-x {
+						return fx, fy, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// unifiedWriter writes edits as a unified diff with the given number of
+// context lines around each change, holding only the edits of the current
+// hunk.
+type unifiedWriter struct {
+	w            *bufio.Writer
+	nameA, nameB string
+	context      int
+	header       bool
+	// lineA and lineB are the 1-based line numbers of the next edit.
+	lineA, lineB int
+	// before are the equal edits preceding the next hunk, and hunk the
+	// edits of the current hunk, which starts at hunkA and hunkB and ends
+	// with trailing equal edits.
+	before       []DiffEdit
+	hunk         []DiffEdit
+	hunkA, hunkB int
+	trailing     int
+}
+
+func newUnifiedWriter(w io.Writer, nameA, nameB string, context int) *unifiedWriter {
+	return &unifiedWriter{w: bufio.NewWriter(w), nameA: nameA, nameB: nameB, context: context, lineA: 1, lineB: 1}
+}
+
+func (u *unifiedWriter) add(edit DiffEdit) error {
+	switch {
+	case edit.Op != DiffEqual:
+		if u.hunk == nil {
+			u.hunk = append([]DiffEdit(nil), u.before...)
+			u.hunkA, u.hunkB = u.lineA-len(u.before), u.lineB-len(u.before)
+			u.before = u.before[:0]
+		}
+		u.hunk = append(u.hunk, edit)
+		u.trailing = 0
+	case u.hunk != nil:
+		// The hunk extends until more than twice the context of unchanged
+		// lines separate it from the next change.
+		u.hunk = append(u.hunk, edit)
+		u.trailing++
+		if u.trailing > 2*u.context {
+			end := len(u.hunk) - u.trailing + u.context
+			u.before = append(u.before[:0], u.hunk[len(u.hunk)-u.context:]...)
+			if err := u.writeHunk(u.hunk[:end]); err != nil {
+				return err
+			}
+			u.hunk = nil
+		}
+	case u.context > 0:
+		if len(u.before) == u.context {
+			u.before = append(u.before[:0], u.before[1:]...)
+		}
+		u.before = append(u.before, edit)
+	}
+
+	if edit.Op != DiffInsert {
+		u.lineA++
+	}
+	if edit.Op != DiffDelete {
+		u.lineB++
+	}
+
+	return nil
+}
+
+// close writes the last hunk, if any, and flushes the diff.
+func (u *unifiedWriter) close() error {
+	if u.hunk != nil {
+		end := len(u.hunk)
+		if u.trailing > u.context {
+			end -= u.trailing - u.context
+		}
+		if err := u.writeHunk(u.hunk[:end]); err != nil {
+			return err
+		}
+		u.hunk = nil
+	}
+
+	return u.w.Flush()
+}
+
+func (u *unifiedWriter) writeHunk(hunk []DiffEdit) error {
+	if !u.header {
+		if _, err := fmt.Fprintf(u.w, "--- %s\n+++ %s\n", u.nameA, u.nameB); err != nil {
+			return err
+		}
+		u.header = true
+	}
+
+	var linesA, linesB int
+	for _, edit := range hunk {
+		if edit.Op != DiffInsert {
+			linesA++
+		}
+		if edit.Op != DiffDelete {
+			linesB++
+		}
+	}
+	if _, err := fmt.Fprintf(u.w, "@@ -%d,%d +%d,%d @@\n", u.hunkA, linesA, u.hunkB, linesB); err != nil {
+		return err
+	}
+	for _, edit := range hunk {
+		prefix := " "
+		switch edit.Op {
+		case DiffDelete:
+			prefix = "-"
+		case DiffInsert:
+			prefix = "+"
+		}
+		if _, err := fmt.Fprintf(u.w, "%s%s\n", prefix, edit.Text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	lines := func(data ...string) []LogLine {
+		var lines []LogLine
+		for _, d := range data {
+			lines = append(lines, LogLine{Data: d})
+		}
+		return lines
+	}
+	ops := func(edits []DiffEdit) []DiffOp {
+		var ops []DiffOp
+		for _, edit := range edits {
+			ops = append(ops, edit.Op)
+		}
+		return ops
+	}
+
+	assert.Empty(t, diffLines(nil, nil))
+	assert.Equal(t, []DiffOp{DiffEqual, DiffEqual}, ops(diffLines(lines("a", "b"), lines("a", "b"))))
+	assert.Equal(t, []DiffOp{DiffInsert, DiffInsert}, ops(diffLines(nil, lines("a", "b"))))
+	assert.Equal(t, []DiffOp{DiffDelete, DiffDelete}, ops(diffLines(lines("a", "b"), nil)))
+	assert.Equal(t, []DiffOp{DiffEqual, DiffDelete, DiffInsert, DiffEqual}, ops(diffLines(lines("a", "b", "c"), lines("a", "x", "c"))))
+	assert.Equal(t, []DiffOp{DiffEqual}, ops(diffLines(
+		lines("started at 2021-01-01T00:00:00Z"),
+		lines("started at 2021-02-03 10:11:12.123+05:00"),
+	)), "timestamps are ignored")
+}
+
+func TestBucketLoggerDiff(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "pass", Data: "started at 2021-01-01T00:00:00Z\nstep\nok\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "fail", Data: "started at 2021-01-02T00:00:00Z\nstep\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "fail", Data: "failed\n"}))
+
+	contextLines := func(n int) *int { return &n }
+
+	var unified bytes.Buffer
+	edits, err := l.Diff(ctx, options.Diff{KeyA: "pass", KeyB: "fail", Writer: &unified, Context: contextLines(1)})
+	require.NoError(t, err)
+	require.Len(t, edits, 2, "only changes are returned")
+	assert.Equal(t, DiffDelete, edits[0].Op)
+	assert.Equal(t, "ok", edits[0].Text)
+	assert.Equal(t, DiffInsert, edits[1].Op)
+	assert.Equal(t, "failed", edits[1].Text)
+	assert.Equal(t, "--- pass\n+++ fail\n@@ -2,2 +2,2 @@\n step\n-ok\n+failed\n", unified.String())
+
+	unified.Reset()
+	_, err = l.Diff(ctx, options.Diff{KeyA: "pass", KeyB: "fail", Writer: &unified, Context: contextLines(0)})
+	require.NoError(t, err)
+	assert.Equal(t, "--- pass\n+++ fail\n@@ -3,1 +3,1 @@\n-ok\n+failed\n", unified.String())
+
+	unified.Reset()
+	edits, err = l.Diff(ctx, options.Diff{KeyA: "pass", KeyB: "pass", Writer: &unified})
+	require.NoError(t, err)
+	assert.Empty(t, edits)
+	assert.Empty(t, unified.String(), "identical logs have an empty unified diff")
+
+	_, err = l.Diff(ctx, options.Diff{KeyA: "pass"})
+	assert.Error(t, err)
+}
+
+func TestUnifiedWriter(t *testing.T) {
+	var edits []DiffEdit
+	for i, op := range []DiffOp{DiffDelete, DiffEqual, DiffEqual, DiffEqual, DiffEqual, DiffEqual, DiffInsert, DiffEqual, DiffEqual, DiffEqual} {
+		edits = append(edits, DiffEdit{Op: op, Text: strconv.Itoa(i)})
+	}
+	write := func(context int) string {
+		var out bytes.Buffer
+		u := newUnifiedWriter(&out, "a", "b", context)
+		for _, edit := range edits {
+			require.NoError(t, u.add(edit))
+		}
+		require.NoError(t, u.close())
+		return out.String()
+	}
+
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,3 +1,2 @@\n-0\n 1\n 2\n@@ -5,4 +4,5 @@\n 4\n 5\n+6\n 7\n 8\n", write(2))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,1 +1,0 @@\n-0\n@@ -7,0 +6,1 @@\n+6\n", write(0))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,9 +1,9 @@\n-0\n 1\n 2\n 3\n 4\n 5\n+6\n 7\n 8\n 9\n", write(3), "hunks separated by at most twice the context are merged")
+}
+
+func TestDiffStream(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	random := rand.New(rand.NewSource(1))
+	var a, b strings.Builder
+	for i := 0; i < 500; i++ {
+		line := strconv.Itoa(i) + "\n"
+		switch random.Intn(10) {
+		case 0:
+			a.WriteString(line)
+		case 1:
+			b.WriteString(line)
+		case 2:
+			a.WriteString(line)
+			b.WriteString("changed " + line)
+		default:
+			a.WriteString(line)
+			b.WriteString(line)
+		}
+	}
+	require.NoError(t, l.Write(ctx, options.Write{Key: "a", Data: a.String()}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "b", Data: b.String()}))
+
+	for _, window := range []int{2, 7, 64, diffWindow} {
+		t.Run(strconv.Itoa(window), func(t *testing.T) {
+			ra, err := l.diffLineReader(ctx, "a")
+			require.NoError(t, err)
+			defer ra.r.Close()
+			rb, err := l.diffLineReader(ctx, "b")
+			require.NoError(t, err)
+			defer rb.r.Close()
+
+			var gotA, gotB strings.Builder
+			require.NoError(t, diffStream(ra, rb, window, func(edit DiffEdit) error {
+				if edit.Op != DiffInsert {
+					gotA.WriteString(edit.Text + "\n")
+				}
+				if edit.Op != DiffDelete {
+					gotB.WriteString(edit.Text + "\n")
+				}
+				return nil
+			}))
+			assert.Equal(t, a.String(), gotA.String())
+			assert.Equal(t, b.String(), gotB.String())
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+const diskCacheTempSuffix = ".tmp"
+
+// DiskCache is a persistent read-through cache of the decompressed
+// contents of log chunks in a local directory, bounded by their total size
+// and evicting the least recently read chunks first. Chunks are keyed by
+// their bucket, key, and, where the backend provides one, ETag, so that a
+// directory may be reused across runs and processes, e.g. of repeated
+// analyses on the same workstation, and by loggers of different buckets.
+type DiskCache struct {
+	mu      sync.Mutex
+	opts    options.DiskCache
+	size    int64
+	entries map[string]*diskCacheEntry
+}
+
+type diskCacheEntry struct {
+	size int64
+	used time.Time
+}
+
+// NewDiskCache returns a disk cache storing chunks in the options'
+// directory, indexing, and evicting as needed, the chunks already there.
+func NewDiskCache(opts options.DiskCache) (*DiskCache, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid disk cache options")
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "creating cache directory '%s'", opts.Dir)
+	}
+
+	files, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading cache directory '%s'", opts.Dir)
+	}
+	c := &DiskCache{opts: opts, entries: map[string]*diskCacheEntry{}}
+	for _, file := range files {
+		if file.IsDir() || strings.HasSuffix(file.Name(), diskCacheTempSuffix) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		c.entries[file.Name()] = &diskCacheEntry{size: info.Size(), used: info.ModTime()}
+		c.size += info.Size()
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+
+	return c, nil
+}
+
+// Size returns the total size in bytes of the cached chunks.
+func (c *DiskCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// Len returns the number of cached chunks.
+func (c *DiskCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// diskCacheName returns the name of the file caching the chunk.
+func diskCacheName(namespace, key, etag string) string {
+	sum := sha256.Sum256([]byte(namespace + "\x00" + key + "\x00" + etag))
+	return hex.EncodeToString(sum[:])
+}
+
+// open returns the cached file of the chunk, or nil if it is not cached.
+func (c *DiskCache) open(namespace, key, etag string) *os.File {
+	name := diskCacheName(namespace, key, etag)
+	f, err := os.Open(filepath.Join(c.opts.Dir, name))
+	if err != nil {
+		// The file may have been evicted by another process.
+		c.mu.Lock()
+		c.remove(name)
+		c.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(f.Name(), now, now)
+	c.mu.Lock()
+	if entry, ok := c.entries[name]; ok {
+		entry.used = now
+	} else if info, err := f.Stat(); err == nil {
+		c.entries[name] = &diskCacheEntry{size: info.Size(), used: now}
+		c.size += info.Size()
+	}
+	c.mu.Unlock()
+
+	return f
+}
+
+// get returns the contents of the chunk, fetching and caching it on a miss.
+func (c *DiskCache) get(namespace, key, etag string, fetch func() ([]byte, error)) ([]byte, error) {
+	if f := c.open(namespace, key, etag); f != nil {
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		return data, errors.Wrapf(err, "reading cached chunk '%s'", key)
+	}
+
+	data, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if err = c.add(diskCacheName(namespace, key, etag), data); err != nil {
+		return nil, errors.Wrapf(err, "caching chunk '%s'", key)
+	}
+
+	return data, nil
+}
+
+// add writes the chunk to the cache, evicting the least recently read
+// chunks as needed.
+func (c *DiskCache) add(name string, data []byte) error {
+	size := int64(len(data))
+	if size > c.opts.MaxSize {
+		return nil
+	}
+
+	path := filepath.Join(c.opts.Dir, name)
+	tmp, err := os.CreateTemp(c.opts.Dir, name+"-*"+diskCacheTempSuffix)
+	if err != nil {
+		return errors.Wrap(err, "creating cache file")
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrap(err, "writing cache file")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(name)
+	c.entries[name] = &diskCacheEntry{size: size, used: time.Now()}
+	c.size += size
+	c.evict()
+
+	return nil
+}
+
+// remove drops the chunk from the index. The cache must be locked.
+func (c *DiskCache) remove(name string) {
+	if entry, ok := c.entries[name]; ok {
+		c.size -= entry.size
+		delete(c.entries, name)
+	}
+}
+
+// evict deletes the least recently read chunks until the cache fits its
+// maximum size. The cache must be locked.
+func (c *DiskCache) evict() {
+	if c.size <= c.opts.MaxSize {
+		return
+	}
+
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return c.entries[names[i]].used.Before(c.entries[names[j]].used) })
+	for _, name := range names {
+		if c.size <= c.opts.MaxSize {
+			return
+		}
+		_ = os.Remove(filepath.Join(c.opts.Dir, name))
+		c.remove(name)
+	}
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getCountingBucket counts the objects downloaded from the wrapped bucket.
+type getCountingBucket struct {
+	pail.Bucket
+	gets int
+}
+
+func (b *getCountingBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b.gets++
+	return b.Bucket.Get(ctx, key)
+}
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	read := func(t *testing.T, l Logger, opts options.Read) string {
+		r, err := l.NewReadCloser(ctx, opts)
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("ReadThrough", func(t *testing.T) {
+		dir := t.TempDir()
+		cache, err := NewDiskCache(options.DiskCache{Dir: dir})
+		require.NoError(t, err)
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithDiskCache(cache))
+		require.NoError(t, err)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line0\n"}))
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line1\n"}))
+
+		assert.Equal(t, "line0\nline1\n", read(t, l, options.Read{Key: "key"}))
+		assert.Equal(t, 2, cache.Len())
+		assert.EqualValues(t, 12, cache.Size())
+
+		// Cached chunks are read from disk, even by a new cache of the
+		// same directory.
+		reopened, err := NewDiskCache(options.DiskCache{Dir: dir})
+		require.NoError(t, err)
+		assert.Equal(t, 2, reopened.Len())
+		l.diskCache = reopened
+		gets := &getCountingBucket{Bucket: l.logsBucket}
+		l.logsBucket = gets
+		assert.Equal(t, "ine0\nline1\n", read(t, l, options.Read{Key: "key", Offset: 1}))
+		assert.Zero(t, gets.gets)
+	})
+	t.Run("Evicts", func(t *testing.T) {
+		dir := t.TempDir()
+		cache, err := NewDiskCache(options.DiskCache{Dir: dir, MaxSize: 10})
+		require.NoError(t, err)
+		fetch := func(data string) func() ([]byte, error) {
+			return func() ([]byte, error) { return []byte(data), nil }
+		}
+
+		_, err = cache.get("ns", "a", "", fetch("aaaa"))
+		require.NoError(t, err)
+		_, err = cache.get("ns", "b", "", fetch("bbbb"))
+		require.NoError(t, err)
+		_, err = cache.get("ns", "c", "", fetch("cccc"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, cache.Len())
+		assert.EqualValues(t, 8, cache.Size())
+		assert.Nil(t, cache.open("ns", "a", ""), "the least recently read chunk is evicted")
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, files, 2)
+
+		_, err = cache.get("ns", "big", "", fetch("too big to cache"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, cache.Len())
+
+		shrunk, err := NewDiskCache(options.DiskCache{Dir: dir, MaxSize: 4})
+		require.NoError(t, err)
+		assert.Equal(t, 1, shrunk.Len(), "existing chunks are evicted to fit")
+	})
+	t.Run("ETag", func(t *testing.T) {
+		cache, err := NewDiskCache(options.DiskCache{Dir: t.TempDir()})
+		require.NoError(t, err)
+		data, err := cache.get("ns", "key", "v1", func() ([]byte, error) { return []byte("old"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(data))
+		data, err = cache.get("ns", "key", "v2", func() ([]byte, error) { return []byte("new"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data), "changed chunks are refetched")
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := NewDiskCache(options.DiskCache{})
+		assert.Error(t, err)
+		_, err = NewDiskCache(options.DiskCache{Dir: t.TempDir(), MaxSize: -1})
+		assert.Error(t, err)
+		_, err = NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithDiskCache(nil))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/pkg/errors"
+)
+
+// Download writes the log lines of a key at or above a minimum priority,
+// e.g. warnings and errors, to a single writer or local file as plain text,
+// in log order. Chunks whose manifest entry records a lower maximum priority
+// are skipped without being downloaded.
+func (l *bucketLogger) Download(ctx context.Context, opts options.Download) (err error) {
+	if err = opts.Validate(); err != nil {
+		return err
+	}
+	if err = l.audit(ctx, AuditRead, opts.Key); err != nil {
+		return err
+	}
+	if opts.MinPriority == 0 {
+		opts.MinPriority = level.Warning
+	}
+
+	w := opts.Writer
+	if opts.Filename != "" {
+		f, createErr := os.Create(opts.Filename)
+		if createErr != nil {
+			return errors.Wrapf(createErr, "creating file '%s'", opts.Filename)
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = errors.Wrapf(closeErr, "closing file '%s'", opts.Filename)
+			}
+		}()
+		w = f
+	}
+
+	keys, etags, err := l.downloadKeys(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, key := range keys {
+		lines, err := l.readChunkLines(ctx, key, etags[key])
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if line.Priority < opts.MinPriority {
+				continue
+			}
+			if err = writeLine(bw, line); err != nil {
+				return errors.Wrap(err, "writing log line")
+			}
+		}
+	}
+
+	return errors.Wrap(bw.Flush(), "flushing log lines")
+}
+
+// downloadKeys returns the keys, and ETags where known, of the chunks that
+// may contain lines at or above the minimum priority.
+func (l *bucketLogger) downloadKeys(ctx context.Context, opts options.Download) ([]string, map[string]string, error) {
+	r := &bucketReader{ctx: ctx, bucket: l.logsBucket}
+	if err := r.getAndSortKeys(opts.Key, false); err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := l.Manifest(ctx, opts.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	skip := map[string]bool{}
+	for _, entry := range entries {
+		skip[entry.Key] = entry.MaxPriority != 0 && entry.MaxPriority < opts.MinPriority
+	}
+
+	var keys []string
+	for _, key := range r.keys {
+		if !skip[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, r.etags, nil
+}
+
+func writeLine(w io.Writer, line LogLine) error {
+	_, err := io.WriteString(w, line.String()+"\n")
+	return err
+}
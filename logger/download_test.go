@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerDownload(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+
+	ts := time.Unix(10, 0).UTC()
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{
+		{Timestamp: ts, Priority: level.Info, PriorityString: "info", Data: "info"},
+		{Timestamp: ts, Priority: level.Error, PriorityString: "error", Data: "error"},
+	}, Encoding: encode.JSON}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{
+		{Timestamp: ts, Priority: level.Debug, PriorityString: "debug", Data: "debug"},
+	}, Encoding: encode.JSON}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{
+		{Timestamp: ts, Priority: level.Warning, PriorityString: "warning", Data: "warning"},
+	}, Encoding: encode.JSON}))
+
+	t.Run("Writer", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, l.Download(ctx, options.Download{Key: "key", Writer: &buf}))
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasSuffix(lines[0], "[error] error"))
+		assert.True(t, strings.HasSuffix(lines[1], "[warning] warning"))
+	})
+	t.Run("File", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "out.log")
+		require.NoError(t, l.Download(ctx, options.Download{Key: "key", Filename: filename, MinPriority: level.Debug}))
+		data, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, 4, strings.Count(string(data), "\n"))
+	})
+	t.Run("SkipsChunksBelowMinPriority", func(t *testing.T) {
+		keys, _, err := l.downloadKeys(ctx, options.Download{Key: "key", MinPriority: level.Warning})
+		require.NoError(t, err)
+		assert.Len(t, keys, 2)
+
+		entries, err := l.Manifest(ctx, "key")
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, level.Error, entries[0].MaxPriority)
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		assert.Error(t, l.Download(ctx, options.Download{Key: "key"}))
+		assert.Error(t, l.Download(ctx, options.Download{Key: "key", Writer: &bytes.Buffer{}, Filename: "out.log"}))
+		assert.Error(t, l.Download(ctx, options.Download{Key: "key", Writer: &bytes.Buffer{}, MinPriority: 1000}))
+		assert.Error(t, l.Download(ctx, options.Download{Key: "key", Filename: filepath.Join(t.TempDir(), "missing", "out.log")}))
+	})
+}
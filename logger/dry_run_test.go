@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerDryRun(t *testing.T) {
+	ctx := context.Background()
+	opts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+
+	t.Run("NilSender", func(t *testing.T) {
+		_, err := NewBucketLogger(ctx, opts, WithDryRun(nil))
+		assert.Error(t, err)
+	})
+	t.Run("SkipsUploads", func(t *testing.T) {
+		local := send.MakeInternalLogger()
+		l, err := NewBucketLogger(ctx, opts, WithDryRun(local))
+		require.NoError(t, err)
+
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "message"}))
+
+		var keys []string
+		for local.HasMessage() {
+			m := local.GetMessage()
+			fields, ok := m.Message.Raw().(message.Fields)
+			require.True(t, ok)
+			keys = append(keys, fields["key"].(string))
+		}
+		require.Len(t, keys, 2, "the chunk and its manifest entry are logged")
+		assert.Contains(t, keys[0], "key")
+
+		err = filepath.WalkDir(opts.Name, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				t.Errorf("unexpected upload '%s'", path)
+			}
+			return err
+		})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,305 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"os"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/internal"
+	"github.com/pkg/errors"
+)
+
+// KeyProvider resolves the AES keys used to encrypt log chunks and metadata
+// client-side. Each encrypted object records the ID of its key in its
+// header, so keys can be rotated by changing the current key as long as the
+// provider can still resolve the keys of existing objects by ID.
+type KeyProvider interface {
+	// CurrentKey returns the ID and the key new objects are encrypted
+	// with.
+	CurrentKey(ctx context.Context) (string, []byte, error)
+	// Key returns the key with the given ID, which may be a retired
+	// key.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// maxKeyIDLength is the maximum length of a key ID, which is stored in a
+// single byte of the encrypted object header.
+const maxKeyIDLength = 255
+
+// encryptedMagic starts the header of every encrypted object. It is
+// followed by the flags, the length of the key ID, the key ID, the nonce,
+// and the sealed data.
+var encryptedMagic = []byte("CEDARENC1")
+
+// encryptedGzip flags encrypted objects whose data was gzipped before it
+// was sealed.
+const encryptedGzip = 1 << 0
+
+// KeyRing is a KeyProvider of a fixed set of keys, e.g. loaded from a
+// secrets manager on startup.
+type KeyRing struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyRing returns a key ring encrypting with the key of the current ID
+// and decrypting with any of the keys. Keys must be 16, 24, or 32 bytes,
+// selecting AES-128, AES-192, or AES-256.
+func NewKeyRing(current string, keys map[string][]byte) (*KeyRing, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, errors.Errorf("current key '%s' is not in the key ring", current)
+	}
+
+	ring := &KeyRing{current: current, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if id == "" || len(id) > maxKeyIDLength {
+			return nil, errors.Errorf("key ID '%s' must be between 1 and %d bytes", id, maxKeyIDLength)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, errors.Wrapf(err, "invalid key '%s'", id)
+		}
+		ring.keys[id] = append([]byte(nil), key...)
+	}
+
+	return ring, nil
+}
+
+func (r *KeyRing) CurrentKey(_ context.Context) (string, []byte, error) {
+	return r.current, r.keys[r.current], nil
+}
+
+func (r *KeyRing) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, errors.Errorf("unknown encryption key '%s'", id)
+	}
+
+	return key, nil
+}
+
+// encrypt seals the data with the provider's current key, gzipping it
+// first if compress is set, since ciphertext does not compress.
+func encrypt(ctx context.Context, keys KeyProvider, data []byte, compress bool) ([]byte, error) {
+	id, key, err := keys.CurrentKey(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting current encryption key")
+	}
+	if id == "" || len(id) > maxKeyIDLength {
+		return nil, errors.Errorf("key ID '%s' must be between 1 and %d bytes", id, maxKeyIDLength)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid encryption key '%s'", id)
+	}
+
+	var flags byte
+	if compress {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err = gz.Write(data); err != nil {
+			return nil, errors.Wrap(err, "compressing data")
+		}
+		if err = gz.Close(); err != nil {
+			return nil, errors.Wrap(err, "compressing data")
+		}
+		data = buf.Bytes()
+		flags |= encryptedGzip
+	}
+
+	header := make([]byte, 0, len(encryptedMagic)+2+len(id)+gcm.NonceSize())
+	header = append(header, encryptedMagic...)
+	header = append(header, flags, byte(len(id)))
+	header = append(header, id...)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "generating nonce")
+	}
+
+	// The header is authenticated so that neither the flags nor the key
+	// ID can be altered.
+	return gcm.Seal(append(header, nonce...), nonce, data, header), nil
+}
+
+// decrypt opens data sealed by encrypt with the key recorded in its header.
+// Data that is not encrypted, e.g. written before encryption was enabled,
+// is returned unchanged.
+func decrypt(ctx context.Context, keys KeyProvider, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedMagic) {
+		return data, nil
+	}
+
+	rest := data[len(encryptedMagic):]
+	if len(rest) < 2 || len(rest) < 2+int(rest[1]) {
+		return nil, errors.New("truncated encryption header")
+	}
+	flags := rest[0]
+	id := string(rest[2 : 2+rest[1]])
+	header := data[:len(encryptedMagic)+2+len(id)]
+	key, err := keys.Key(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting encryption key '%s'", id)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid encryption key '%s'", id)
+	}
+
+	sealed := data[len(header):]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("truncated encryption header")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decrypting with key '%s'", id)
+	}
+	if flags&encryptedGzip == 0 {
+		return plaintext, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(plaintext))
+	if err != nil {
+		return nil, errors.Wrap(err, "decompressing decrypted data")
+	}
+	defer gz.Close()
+	plaintext, err = io.ReadAll(gz)
+
+	return plaintext, errors.Wrap(err, "decompressing decrypted data")
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encryptedBucket encrypts the objects written to the wrapped bucket and
+// decrypts the objects read from it. Objects are read in full to be
+// decrypted, so ranged fetches download the whole object.
+type encryptedBucket struct {
+	pail.Bucket
+	keys     KeyProvider
+	compress bool
+}
+
+func newEncryptedBucket(bucket pail.Bucket, keys KeyProvider, compress bool) pail.Bucket {
+	return &encryptedBucket{Bucket: bucket, keys: keys, compress: compress}
+}
+
+// unencrypted returns the bucket wrapped by an encrypted bucket, to upload
+// objects that are already encrypted, e.g. read raw from a spool, as is.
+// Other buckets are returned unchanged.
+func unencrypted(bucket pail.Bucket) pail.Bucket {
+	if b, ok := bucket.(*encryptedBucket); ok {
+		return b.Bucket
+	}
+
+	return bucket
+}
+
+func (b *encryptedBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return errors.Wrapf(err, "reading '%s'", key)
+	}
+	if data, err = encrypt(ctx, b.keys, data, b.compress); err != nil {
+		return errors.Wrapf(err, "encrypting '%s'", key)
+	}
+
+	return b.Bucket.Put(ctx, key, bytes.NewReader(data))
+}
+
+func (b *encryptedBucket) Upload(ctx context.Context, key, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "opening '%s'", path)
+	}
+	defer f.Close()
+
+	return b.Put(ctx, key, f)
+}
+
+func (b *encryptedBucket) Writer(ctx context.Context, key string) (io.WriteCloser, error) {
+	return &encryptedWriter{ctx: ctx, bucket: b, key: key}, nil
+}
+
+func (b *encryptedBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, err := b.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *encryptedBucket) Reader(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.Get(ctx, key)
+}
+
+func (b *encryptedBucket) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	data, err := b.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return io.NopCloser(bytes.NewReader(data[offset:])), nil
+}
+
+func (b *encryptedBucket) Download(ctx context.Context, key, path string) error {
+	data, err := b.get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(os.WriteFile(path, data, 0644), "writing '%s'", path)
+}
+
+func (b *encryptedBucket) ListRange(ctx context.Context, prefix, startAfter, end string) (pail.BucketIterator, error) {
+	return internal.ListRange(ctx, b.Bucket, prefix, startAfter, end)
+}
+
+// get returns the decompressed and decrypted contents of the object.
+func (b *encryptedBucket) get(ctx context.Context, key string) ([]byte, error) {
+	r, err := internal.GetRange(ctx, b.Bucket, key, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading '%s'", key)
+	}
+	data, err = decrypt(ctx, b.keys, data)
+
+	return data, errors.Wrapf(err, "decrypting '%s'", key)
+}
+
+// encryptedWriter buffers the data written to it and uploads it encrypted
+// once closed.
+type encryptedWriter struct {
+	ctx    context.Context
+	bucket *encryptedBucket
+	key    string
+	buf    bytes.Buffer
+}
+
+func (w *encryptedWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *encryptedWriter) Close() error {
+	return w.bucket.Put(w.ctx, w.key, &w.buf)
+}
@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyRing(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	for _, test := range []struct {
+		name    string
+		current string
+		keys    map[string][]byte
+	}{
+		{name: "MissingCurrent", current: "k2", keys: map[string][]byte{"k1": key}},
+		{name: "EmptyID", current: "k1", keys: map[string][]byte{"k1": key, "": key}},
+		{name: "LongID", current: "k1", keys: map[string][]byte{"k1": key, string(bytes.Repeat([]byte("k"), 256)): key}},
+		{name: "InvalidKeySize", current: "k1", keys: map[string][]byte{"k1": key[:10]}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewKeyRing(test.current, test.keys)
+			assert.Error(t, err)
+		})
+	}
+	t.Run("Valid", func(t *testing.T) {
+		ring, err := NewKeyRing("k1", map[string][]byte{"k1": key, "k0": key[:16]})
+		require.NoError(t, err)
+		id, current, err := ring.CurrentKey(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "k1", id)
+		assert.Equal(t, key, current)
+		_, err = ring.Key(context.Background(), "k0")
+		assert.NoError(t, err)
+		_, err = ring.Key(context.Background(), "k2")
+		assert.Error(t, err)
+	})
+}
+
+func TestBucketLoggerEncryption(t *testing.T) {
+	ctx := context.Background()
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	oldRing, err := NewKeyRing("old", map[string][]byte{"old": oldKey})
+	require.NoError(t, err)
+	rotatedRing, err := NewKeyRing("new", map[string][]byte{"old": oldKey, "new": newKey})
+	require.NoError(t, err)
+	newRing, err := NewKeyRing("new", map[string][]byte{"new": newKey})
+	require.NoError(t, err)
+
+	opts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+	read := func(t *testing.T, l *bucketLogger, key string) (string, error) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: key})
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		return string(data), err
+	}
+	storedChunks := func(t *testing.T, key string) [][]byte {
+		var chunks [][]byte
+		err := filepath.Walk(filepath.Join(opts.Name, "test", "logs"), func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Base(filepath.Dir(path)) != key {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			chunks = append(chunks, data)
+			return err
+		})
+		require.NoError(t, err)
+		return chunks
+	}
+
+	plain, err := NewBucketLogger(ctx, opts)
+	require.NoError(t, err)
+	require.NoError(t, plain.Write(ctx, options.Write{Key: "plain", Data: "unencrypted\n"}))
+
+	before, err := NewBucketLogger(ctx, opts, WithEncryption(oldRing))
+	require.NoError(t, err)
+	require.NoError(t, before.Write(ctx, options.Write{Key: "rotated", Data: "secret1\n"}))
+	require.NoError(t, before.AddMetadata(ctx, options.AddMetadata{Key: "rotated", Data: "metadata"}))
+	after, err := NewBucketLogger(ctx, opts, WithEncryption(rotatedRing))
+	require.NoError(t, err)
+	require.NoError(t, after.Write(ctx, options.Write{Key: "rotated", Data: "secret2\n"}))
+
+	t.Run("StoredEncrypted", func(t *testing.T) {
+		chunks := storedChunks(t, "rotated")
+		require.Len(t, chunks, 2)
+		for _, chunk := range chunks {
+			assert.True(t, bytes.HasPrefix(chunk, encryptedMagic))
+			assert.NotContains(t, string(chunk), "secret")
+		}
+		assert.Contains(t, string(chunks[0][:len(encryptedMagic)+5]), "old", "the key ID is in the header")
+		assert.Contains(t, string(chunks[1][:len(encryptedMagic)+5]), "new", "the key ID is in the header")
+	})
+	t.Run("ReadAcrossRotation", func(t *testing.T) {
+		data, err := read(t, after, "rotated")
+		require.NoError(t, err)
+		assert.Equal(t, "secret1\nsecret2\n", data)
+	})
+	t.Run("Metadata", func(t *testing.T) {
+		r, err := after.NewReadCloser(ctx, options.Read{Key: "rotated", Metadata: true})
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "metadata")
+	})
+	t.Run("UnencryptedChunks", func(t *testing.T) {
+		data, err := read(t, after, "plain")
+		require.NoError(t, err)
+		assert.Equal(t, "unencrypted\n", data)
+	})
+	t.Run("RetiredKey", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, opts, WithEncryption(newRing))
+		require.NoError(t, err)
+		_, err = read(t, l, "rotated")
+		assert.Error(t, err, "chunks encrypted with a key the provider cannot resolve are unreadable")
+	})
+	t.Run("Tampered", func(t *testing.T) {
+		data, err := encrypt(ctx, oldRing, []byte("secret"), false)
+		require.NoError(t, err)
+		data[len(data)-1] ^= 1
+		_, err = decrypt(ctx, oldRing, data)
+		assert.Error(t, err)
+
+		data, err = encrypt(ctx, rotatedRing, []byte("secret"), false)
+		require.NoError(t, err)
+		copy(data[len(encryptedMagic)+2:], "old")
+		_, err = decrypt(ctx, rotatedRing, data)
+		assert.Error(t, err, "the key ID is authenticated")
+
+		data, err = encrypt(ctx, oldRing, []byte("secret"), false)
+		require.NoError(t, err)
+		data[len(encryptedMagic)] |= encryptedGzip
+		_, err = decrypt(ctx, oldRing, data)
+		assert.Error(t, err, "the flags are authenticated")
+
+		_, err = decrypt(ctx, oldRing, encryptedMagic)
+		assert.Error(t, err)
+	})
+	t.Run("DataWithHeaderPrefix", func(t *testing.T) {
+		data := string(encryptedMagic) + "secret\n"
+		require.NoError(t, after.Write(ctx, options.Write{Key: "prefixed", Data: data}))
+		chunks := storedChunks(t, "prefixed")
+		require.Len(t, chunks, 1)
+		assert.NotContains(t, string(chunks[0]), "secret", "data starting with the header is encrypted")
+
+		read, err := read(t, after, "prefixed")
+		require.NoError(t, err)
+		assert.Equal(t, data, read)
+	})
+	t.Run("Compressed", func(t *testing.T) {
+		data := bytes.Repeat([]byte("compressible secret\n"), 100)
+		compressed, err := encrypt(ctx, oldRing, data, true)
+		require.NoError(t, err)
+		uncompressed, err := encrypt(ctx, oldRing, data, false)
+		require.NoError(t, err)
+		assert.Less(t, len(compressed), len(data)/10, "the data is compressed before it is encrypted")
+		assert.Greater(t, len(uncompressed), len(data))
+
+		for _, sealed := range [][]byte{compressed, uncompressed} {
+			opened, err := decrypt(ctx, oldRing, sealed)
+			require.NoError(t, err)
+			assert.Equal(t, data, opened)
+		}
+	})
+	t.Run("CachesAreNotShared", func(t *testing.T) {
+		cache, err := NewChunkCache(1024)
+		require.NoError(t, err)
+		encrypted, err := NewBucketLogger(ctx, opts, WithEncryption(rotatedRing), WithChunkCache(cache))
+		require.NoError(t, err)
+		_, err = read(t, encrypted, "rotated")
+		require.NoError(t, err)
+
+		for _, l := range []*bucketLogger{plain, after} {
+			assert.NotEqual(t, encrypted.cacheNamespace, l.cacheNamespace)
+		}
+		unencrypted, err := NewBucketLogger(ctx, opts, WithChunkCache(cache))
+		require.NoError(t, err)
+		data, err := read(t, unencrypted, "rotated")
+		require.NoError(t, err)
+		assert.NotContains(t, data, "secret", "decrypted chunks are not served from the cache to loggers without the keys")
+	})
+	t.Run("Spool", func(t *testing.T) {
+		l, err := NewSpoolLogger(ctx, opts, options.Spool{Dir: t.TempDir(), Interval: time.Hour}, WithEncryption(oldRing))
+		require.NoError(t, err)
+		defer func() { assert.NoError(t, l.Close(ctx)) }()
+
+		require.NoError(t, l.Write(ctx, options.Write{Key: "spooled", Data: "secret\n"}))
+		require.NoError(t, l.AddMetadata(ctx, options.AddMetadata{Key: "spooled", Data: "metadata"}))
+		require.NoError(t, l.Flush(ctx))
+		chunks := storedChunks(t, "spooled")
+		require.Len(t, chunks, 1)
+		assert.NotContains(t, string(chunks[0]), "secret")
+
+		data, err := read(t, after, "spooled")
+		require.NoError(t, err)
+		assert.Equal(t, "secret\n", data, "spooled chunks are not encrypted twice")
+		r, err := after.NewReadCloser(ctx, options.Read{Key: "spooled", Metadata: true})
+		require.NoError(t, err)
+		defer r.Close()
+		meta, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Contains(t, string(meta), "metadata")
+	})
+	t.Run("NilProvider", func(t *testing.T) {
+		_, err := NewBucketLogger(ctx, opts, WithEncryption(nil))
+		assert.Error(t, err)
+	})
+}
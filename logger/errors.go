@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// GapError is returned by strict reads when the sequence numbers of the
+// chunks stored under a key are not contiguous.
+type GapError struct {
+	Key string
+	// Instance is the ID of the logger instance that wrote the chunks.
+	Instance string
+	// After is the key of the last chunk before the gap.
+	After string
+	// From and To are the first and last missing sequence numbers,
+	// inclusive.
+	From uint64
+	To   uint64
+	// Lines is whether the missing sequence numbers are those of log
+	// lines, recorded in the manifest, rather than of chunks.
+	Lines bool
+}
+
+func (e *GapError) Error() string {
+	if e.Lines {
+		return fmt.Sprintf("log '%s' is missing lines with sequence numbers %d through %d after chunk '%s'", e.Key, e.From, e.To, e.After)
+	}
+
+	return fmt.Sprintf("log '%s' is missing chunks with sequence numbers %d through %d after chunk '%s'", e.Key, e.From, e.To, e.After)
+}
+
+// IsGapError returns whether the cause of the given error is a GapError.
+func IsGapError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	_, ok := errors.Cause(err).(*GapError)
+	return ok
+}
+
+// ReadOnlyError is returned by the write methods of a read-only logger.
+type ReadOnlyError struct {
+	// Op is the name of the refused method.
+	Op string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("cannot call %s on a read-only logger", e.Op)
+}
+
+// IsReadOnlyError returns whether the cause of the given error is a
+// ReadOnlyError.
+func IsReadOnlyError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	_, ok := errors.Cause(err).(*ReadOnlyError)
+	return ok
+}
+
+// CircuitOpenError is returned by uploads that are not attempted because
+// the logger's circuit breaker is open.
+type CircuitOpenError struct {
+	// RetryAt is when a trial upload will next be allowed.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open until %s", e.RetryAt.Format(time.RFC3339))
+}
+
+// IsCircuitOpenError returns whether the cause of the given error is a
+// CircuitOpenError.
+func IsCircuitOpenError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	_, ok := errors.Cause(err).(*CircuitOpenError)
+	return ok
+}
+
+// CircuitStateError is reported to the logger's error handler whenever its
+// circuit breaker changes state.
+type CircuitStateError struct {
+	From CircuitState
+	To   CircuitState
+	// Err is the upload failure that opened the breaker, if any.
+	Err error
+}
+
+func (e *CircuitStateError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("circuit breaker changed from %s to %s: %s", e.From, e.To, e.Err)
+	}
+
+	return fmt.Sprintf("circuit breaker changed from %s to %s", e.From, e.To)
+}
+
+// ManifestVerificationError is returned when a manifest entry or its chunk
+// fails verification against the entry's signature.
+type ManifestVerificationError struct {
+	// Key is the key of the chunk.
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+func (e *ManifestVerificationError) Error() string {
+	return fmt.Sprintf("verifying manifest entry for chunk '%s': %s", e.Key, e.Reason)
+}
+
+// IsManifestVerificationError returns whether the cause of the given error
+// is a ManifestVerificationError.
+func IsManifestVerificationError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	_, ok := errors.Cause(err).(*ManifestVerificationError)
+	return ok
+}
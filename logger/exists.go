@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"context"
+	"io"
+
+	"github.com/julianedwards/cedar/internal"
+	"github.com/pkg/errors"
+)
+
+// Exists returns whether any log chunks are stored under the key. It stops
+// listing at the first chunk.
+func (l *bucketLogger) Exists(ctx context.Context, key string) (bool, error) {
+	it, err := l.logsBucket.List(ctx, keyListPrefix(key))
+	if err != nil {
+		return false, errors.Wrap(err, "listing log chunk keys")
+	}
+	if it.Next(ctx) {
+		return true, nil
+	}
+
+	return false, errors.Wrap(it.Err(), "iterating log chunk keys")
+}
+
+// Size returns the total decompressed size in bytes of the log chunks
+// stored under the key. Sizes are taken from the manifest, so only chunks without a
+// manifest entry are downloaded to be measured.
+func (l *bucketLogger) Size(ctx context.Context, key string) (int64, error) {
+	chunks, err := l.chunkSizes(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, chunk := range chunks {
+		total += chunk.size
+	}
+
+	return total, nil
+}
+
+// chunkSize is the size in bytes of a log chunk.
+type chunkSize struct {
+	key  string
+	etag string
+	size int64
+}
+
+// chunkSizes returns the decompressed size of each log chunk stored under
+// the key, in order.
+func (l *bucketLogger) chunkSizes(ctx context.Context, key string) ([]chunkSize, error) {
+	r := &bucketReader{ctx: ctx, bucket: l.logsBucket}
+	if err := r.getAndSortKeys(key, false); err != nil {
+		return nil, err
+	}
+
+	entries, err := l.Manifest(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	sizes := map[string]int{}
+	for _, entry := range entries {
+		sizes[entry.Key] = entry.Size
+	}
+
+	chunks := make([]chunkSize, 0, len(r.keys))
+	for _, chunkKey := range r.keys {
+		// The manifest records the compressed size of compressed chunks.
+		if size, ok := sizes[chunkKey]; ok && internal.TrimCompressionExtension(chunkKey) == chunkKey {
+			chunks = append(chunks, chunkSize{key: chunkKey, etag: r.etags[chunkKey], size: int64(size)})
+			continue
+		}
+
+		size, err := l.measureChunk(ctx, chunkKey)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunkSize{key: chunkKey, etag: r.etags[chunkKey], size: size})
+	}
+
+	return chunks, nil
+}
+
+// measureChunk returns the decompressed size of a chunk by downloading it.
+func (l *bucketLogger) measureChunk(ctx context.Context, key string) (int64, error) {
+	r, err := internal.GetRange(ctx, l.logsBucket, key, 0)
+	if err != nil {
+		return 0, errors.Wrapf(err, "getting chunk '%s'", key)
+	}
+	defer r.Close()
+
+	size, err := io.Copy(io.Discard, r)
+	return size, errors.Wrapf(err, "reading chunk '%s'", key)
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerExistsSize(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+
+	exists, err := l.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	size, err := l.Size(ctx, "key")
+	require.NoError(t, err)
+	assert.Zero(t, size)
+
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "hello"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "world!"}))
+	exists, err = l.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	entries, err := l.Manifest(ctx, "key")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	size, err = l.Size(ctx, "key")
+	require.NoError(t, err)
+	assert.EqualValues(t, entries[0].Size+entries[1].Size, size)
+
+	// Chunks without a manifest entry are measured.
+	require.NoError(t, l.logsBucket.Put(ctx, "key/unmanifested", strings.NewReader("12345")))
+	unmanifested, err := l.Size(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, size+5, unmanifested)
+
+	t.Run("SiblingKey", func(t *testing.T) {
+		require.NoError(t, l.Write(ctx, options.Write{Key: "sibling2", Data: "data"}))
+		exists, err := l.Exists(ctx, "sibling")
+		require.NoError(t, err)
+		assert.False(t, exists, "keys the key is a string prefix of do not match")
+		size, err := l.Size(ctx, "sibling")
+		require.NoError(t, err)
+		assert.Zero(t, size)
+		entries, err := l.Manifest(ctx, "sibling")
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"path"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/internal"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// archiveWriter adds files to an archive.
+type archiveWriter interface {
+	add(name string, data []byte) error
+	io.Closer
+}
+
+// Export streams all chunks of a key, and optionally its metadata, into a
+// tar.gz or zip archive written to the given writer. Chunks are stored under
+// "logs/" and metadata under "metadata/", followed by their keys.
+func (l *bucketLogger) Export(ctx context.Context, opts options.Export) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if err := l.audit(ctx, AuditExport, opts.Key); err != nil {
+		return err
+	}
+
+	var aw archiveWriter
+	switch opts.Format {
+	case options.ExportZip:
+		aw = &zipArchiveWriter{zw: zip.NewWriter(opts.Writer)}
+	default:
+		gw := gzip.NewWriter(opts.Writer)
+		aw = &tarArchiveWriter{gw: gw, tw: tar.NewWriter(gw), now: l.now}
+	}
+
+	catcher := grip.NewBasicCatcher()
+	catcher.Add(l.exportBucket(ctx, aw, l.logsBucket, "logs", opts.Key))
+	if opts.IncludeMetadata && !catcher.HasErrors() {
+		catcher.Add(l.exportBucket(ctx, aw, l.metaBucket, "metadata", opts.Key))
+	}
+	catcher.Wrap(aw.Close(), "closing archive")
+
+	return catcher.Resolve()
+}
+
+func (l *bucketLogger) exportBucket(ctx context.Context, aw archiveWriter, bucket pail.Bucket, dir, key string) error {
+	r := &bucketReader{ctx: ctx, bucket: bucket}
+	if err := r.getAndSortKeys(key, false); err != nil {
+		return err
+	}
+
+	for _, name := range r.keys {
+		data, err := getObject(ctx, bucket, name)
+		if err != nil {
+			return err
+		}
+		if err = aw.add(path.Join(dir, name), data); err != nil {
+			return errors.Wrapf(err, "adding '%s' to archive", name)
+		}
+	}
+
+	return nil
+}
+
+// getObject downloads the object with the given key.
+func getObject(ctx context.Context, bucket pail.Bucket, key string) ([]byte, error) {
+	r, err := bucket.Get(ctx, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting '%s'", key)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	return data, errors.Wrapf(err, "reading '%s'", key)
+}
+
+// getChunk downloads the decompressed contents of a log chunk.
+func getChunk(ctx context.Context, bucket pail.Bucket, key string) ([]byte, error) {
+	r, err := internal.GetRange(ctx, bucket, key, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting '%s'", key)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	return data, errors.Wrapf(err, "reading '%s'", key)
+}
+
+type tarArchiveWriter struct {
+	gw  *gzip.Writer
+	tw  *tar.Writer
+	now func() time.Time
+}
+
+func (w *tarArchiveWriter) add(name string, data []byte) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: w.now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.tw.Write(data)
+	return err
+}
+
+func (w *tarArchiveWriter) Close() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.Add(w.tw.Close())
+	catcher.Add(w.gw.Close())
+
+	return catcher.Resolve()
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipArchiveWriter) add(name string, data []byte) error {
+	f, err := w.zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	return err
+}
+
+func (w *zipArchiveWriter) Close() error { return w.zw.Close() }
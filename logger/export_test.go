@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerExport(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	for _, data := range []string{"a\n", "b\n"} {
+		_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte(data)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, l.AddMetadata(ctx, options.AddMetadata{Key: "key", Data: "meta"}))
+
+	// contents returns the data of each archived file whose name starts
+	// with the given directory, in archive order.
+	contents := func(files map[string]string, order []string, dir string) []string {
+		var out []string
+		for _, name := range order {
+			if strings.HasPrefix(name, dir+"/key/") {
+				out = append(out, files[name])
+			}
+		}
+		return out
+	}
+
+	t.Run("TarGz", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, l.Export(ctx, options.Export{Key: "key", Writer: &buf}))
+
+		gr, err := gzip.NewReader(&buf)
+		require.NoError(t, err)
+		tr := tar.NewReader(gr)
+		files := map[string]string{}
+		var order []string
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			data, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			files[hdr.Name] = string(data)
+			order = append(order, hdr.Name)
+		}
+		assert.Equal(t, []string{"a\n", "b\n"}, contents(files, order, "logs"))
+		assert.Empty(t, contents(files, order, "metadata"))
+	})
+	t.Run("ZipWithMetadata", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, l.Export(ctx, options.Export{Key: "key", Writer: &buf, Format: options.ExportZip, IncludeMetadata: true}))
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		files := map[string]string{}
+		var order []string
+		for _, f := range zr.File {
+			r, err := f.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			files[f.Name] = string(data)
+			order = append(order, f.Name)
+		}
+		assert.Equal(t, []string{"a\n", "b\n"}, contents(files, order, "logs"))
+		assert.Equal(t, []string{"meta"}, contents(files, order, "metadata"))
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		assert.Error(t, l.Export(ctx, options.Export{Key: "key"}))
+		assert.Error(t, l.Export(ctx, options.Export{Key: "key", Writer: &bytes.Buffer{}, Format: "rar"}))
+	})
+}
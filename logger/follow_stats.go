@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// FollowerStats is a point-in-time snapshot of the gauges for a single
+// FollowFile session.
+type FollowerStats struct {
+	Key      string
+	Filename string
+	// PendingLines is the number of lines read from the file that have not
+	// yet been uploaded.
+	PendingLines int64
+	// BufferedBytes is the number of bytes read from the file that have
+	// not yet been uploaded.
+	BufferedBytes int64
+	// UploadLag is the time since the oldest pending line was read from
+	// the file, or zero if there are no pending lines.
+	UploadLag time.Duration
+	// LastUploadLag is the time between the oldest line of the most
+	// recently uploaded chunk being read and the chunk being uploaded.
+	LastUploadLag time.Duration
+}
+
+// followGauges tracks the state of a single FollowFile session. The gauges
+// are updated by the following goroutine and may be read concurrently.
+type followGauges struct {
+	key           string
+	filename      string
+	pendingLines  int64
+	bufferedBytes int64
+	// oldestPending is the time, in Unix nanoseconds, at which the oldest
+	// pending line was read.
+	oldestPending int64
+	lastUploadLag int64
+}
+
+func (g *followGauges) add(size int, ts time.Time) {
+	if atomic.AddInt64(&g.pendingLines, 1) == 1 {
+		atomic.StoreInt64(&g.oldestPending, ts.UnixNano())
+	}
+	atomic.AddInt64(&g.bufferedBytes, int64(size))
+}
+
+func (g *followGauges) uploaded(ts time.Time) {
+	if oldest := atomic.LoadInt64(&g.oldestPending); oldest > 0 {
+		atomic.StoreInt64(&g.lastUploadLag, ts.UnixNano()-oldest)
+	}
+	atomic.StoreInt64(&g.pendingLines, 0)
+	atomic.StoreInt64(&g.bufferedBytes, 0)
+	atomic.StoreInt64(&g.oldestPending, 0)
+}
+
+func (g *followGauges) stats(now time.Time) FollowerStats {
+	stats := FollowerStats{
+		Key:           g.key,
+		Filename:      g.filename,
+		PendingLines:  atomic.LoadInt64(&g.pendingLines),
+		BufferedBytes: atomic.LoadInt64(&g.bufferedBytes),
+		LastUploadLag: time.Duration(atomic.LoadInt64(&g.lastUploadLag)),
+	}
+	if oldest := atomic.LoadInt64(&g.oldestPending); oldest > 0 && stats.PendingLines > 0 {
+		stats.UploadLag = time.Duration(now.UnixNano() - oldest)
+	}
+
+	return stats
+}
+
+// FollowerStats returns the current gauges of every active FollowFile
+// session, sorted by key and filename.
+func (l *bucketLogger) FollowerStats() []FollowerStats {
+	l.followersMu.Lock()
+	defer l.followersMu.Unlock()
+
+	now := l.now()
+	stats := make([]FollowerStats, 0, len(l.followers))
+	for g := range l.followers {
+		stats = append(stats, g.stats(now))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Key == stats[j].Key {
+			return stats[i].Filename < stats[j].Filename
+		}
+		return stats[i].Key < stats[j].Key
+	})
+
+	return stats
+}
+
+func (l *bucketLogger) registerFollower(key, filename string) *followGauges {
+	l.followersMu.Lock()
+	defer l.followersMu.Unlock()
+
+	g := &followGauges{key: key, filename: filename}
+	l.followers[g] = struct{}{}
+
+	return g
+}
+
+func (l *bucketLogger) unregisterFollower(g *followGauges) {
+	l.followersMu.Lock()
+	defer l.followersMu.Unlock()
+
+	delete(l.followers, g)
+}
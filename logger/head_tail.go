@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// HeadLines returns the first n log lines of the key, or all of its lines
+// if it has fewer.
+func (l *bucketLogger) HeadLines(ctx context.Context, key string, n int) ([]LogLine, error) {
+	return l.edgeLines(ctx, key, n, false)
+}
+
+// TailLines returns the last n log lines of the key, or all of its lines if
+// it has fewer.
+func (l *bucketLogger) TailLines(ctx context.Context, key string, n int) ([]LogLine, error) {
+	return l.edgeLines(ctx, key, n, true)
+}
+
+// edgeLines returns the first, or, if tail is set, last n log lines of the
+// key. Chunks are read from the start, or end, of the key, and the line
+// counts of the manifest are used to download only, and concurrently, the
+// chunks holding the lines, skipping empty ones. Chunks without a count
+// are downloaded before deciding whether more are needed.
+func (l *bucketLogger) edgeLines(ctx context.Context, key string, n int, tail bool) ([]LogLine, error) {
+	if key == "" {
+		return nil, errors.New("must specify a key")
+	}
+	if n <= 0 {
+		return nil, errors.New("number of lines must be positive")
+	}
+	if err := l.audit(ctx, AuditRead, key); err != nil {
+		return nil, err
+	}
+
+	r := &bucketReader{ctx: ctx, bucket: l.logsBucket}
+	if err := r.getAndSortKeys(key, tail); err != nil {
+		return nil, err
+	}
+	entries, err := l.Manifest(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, entry := range entries {
+		if entry.Lines > 0 || entry.Size == 0 {
+			counts[entry.Key] = entry.Lines
+		}
+	}
+
+	// chunks holds the lines of each chunk read, in the order read.
+	var chunks [][]LogLine
+	var total int
+	for idx := 0; total < n && idx < len(r.keys); {
+		var batch []string
+		for expected := total; expected < n && idx < len(r.keys); idx++ {
+			count, ok := counts[r.keys[idx]]
+			if ok && count == 0 {
+				continue
+			}
+			batch = append(batch, r.keys[idx])
+			if !ok {
+				idx++
+				break
+			}
+			expected += count
+		}
+
+		lines, err := l.readChunksLines(ctx, batch, r.etags)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range lines {
+			chunks = append(chunks, chunk)
+			total += len(chunk)
+		}
+	}
+
+	var out []LogLine
+	if !tail {
+		for _, chunk := range chunks {
+			out = append(out, chunk...)
+		}
+		if len(out) > n {
+			out = out[:n]
+		}
+		return out, nil
+	}
+
+	for i := len(chunks) - 1; i >= 0; i-- {
+		out = append(out, chunks[i]...)
+	}
+	if len(out) > n {
+		out = out[len(out)-n:]
+	}
+
+	return out, nil
+}
+
+// readChunksLines downloads and decodes the log lines of the chunks
+// concurrently, returning the lines of each chunk in the order given.
+func (l *bucketLogger) readChunksLines(ctx context.Context, keys []string, etags map[string]string) ([][]LogLine, error) {
+	lines := make([][]LogLine, len(keys))
+	slots := make(chan struct{}, defaultSearchConcurrency)
+	catcher := grip.NewBasicCatcher()
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, key string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			var err error
+			lines[i], err = l.readChunkLines(ctx, key, etags[key])
+			catcher.Add(err)
+		}(i, key)
+	}
+	wg.Wait()
+
+	return lines, catcher.Resolve()
+}
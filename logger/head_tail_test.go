@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerHeadTailLines(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	var expected []string
+	for i := 0; i < 4; i++ {
+		var lines []LogLine
+		for j := 0; j < 3; j++ {
+			data := fmt.Sprintf("line%d", len(expected))
+			expected = append(expected, data)
+			lines = append(lines, LogLine{Priority: level.Info, Timestamp: start.Add(time.Duration(len(expected)) * time.Second), Data: data})
+		}
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: lines, Encoding: encode.JSON}))
+	}
+	data := func(lines []LogLine) []string {
+		var out []string
+		for _, line := range lines {
+			out = append(out, line.Data.(string))
+		}
+		return out
+	}
+
+	t.Run("Head", func(t *testing.T) {
+		for _, n := range []int{1, 3, 4, 12} {
+			lines, err := l.HeadLines(ctx, "key", n)
+			require.NoError(t, err)
+			assert.Equal(t, expected[:n], data(lines))
+		}
+	})
+	t.Run("Tail", func(t *testing.T) {
+		for _, n := range []int{1, 3, 5, 12} {
+			lines, err := l.TailLines(ctx, "key", n)
+			require.NoError(t, err)
+			assert.Equal(t, expected[len(expected)-n:], data(lines))
+		}
+	})
+	t.Run("FewerLines", func(t *testing.T) {
+		lines, err := l.HeadLines(ctx, "key", 100)
+		require.NoError(t, err)
+		assert.Equal(t, expected, data(lines))
+		lines, err = l.TailLines(ctx, "key", 100)
+		require.NoError(t, err)
+		assert.Equal(t, expected, data(lines))
+	})
+	t.Run("Missing", func(t *testing.T) {
+		lines, err := l.TailLines(ctx, "missing", 3)
+		require.NoError(t, err)
+		assert.Empty(t, lines)
+	})
+	t.Run("InvalidArguments", func(t *testing.T) {
+		_, err := l.HeadLines(ctx, "", 3)
+		assert.Error(t, err)
+		_, err = l.TailLines(ctx, "key", 0)
+		assert.Error(t, err)
+	})
+}
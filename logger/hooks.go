@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// BeforePutHook is called before each log chunk is uploaded with the chunk's
+// key and encoded data. It returns the key to upload the chunk to, which
+// may be a different key, or an error to veto the upload. Renamed keys must
+// keep the original key's prefix for the chunk to be read back. The data
+// must not be modified.
+type BeforePutHook func(ctx context.Context, key string, data []byte) (string, error)
+
+// OnErrorHook is called with the key of each log chunk that failed to
+// upload, after any retries, and the error.
+type OnErrorHook func(ctx context.Context, key string, err error)
+
+// ErrorHandler is called with the errors of operations that may run in the
+// background, where returned errors are easily lost, e.g. FollowFile and
+// spool uploads. The operation is named by op.
+type ErrorHandler func(op string, err error)
+
+// runBeforePut runs the before put hooks in order, returning the final key
+// of the chunk.
+func (l *bucketLogger) runBeforePut(ctx context.Context, key string, data []byte) (string, error) {
+	for _, hook := range l.beforePut {
+		newKey, err := hook(ctx, key, data)
+		if err != nil {
+			return "", errors.Wrapf(err, "upload of '%s' vetoed", key)
+		}
+		if newKey == "" {
+			return "", errors.Errorf("before put hook returned an empty key for '%s'", key)
+		}
+		key = newKey
+	}
+
+	return key, nil
+}
+
+// runOnError runs the error hooks in order.
+func (l *bucketLogger) runOnError(ctx context.Context, key string, err error) {
+	for _, hook := range l.onError {
+		hook(ctx, key, err)
+	}
+}
+
+// handleError reports the error, if any, to the error handler, if set.
+func (l *bucketLogger) handleError(op string, err error) {
+	if err != nil && l.errorHandler != nil {
+		l.errorHandler(op, err)
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerHooks(t *testing.T) {
+	ctx := context.Background()
+	newLogger := func(t *testing.T, opts ...BucketLoggerOption) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, opts...)
+		require.NoError(t, err)
+		return l
+	}
+
+	t.Run("BeforePut", func(t *testing.T) {
+		var keys []string
+		var data []string
+		l := newLogger(t, WithBeforePut(func(_ context.Context, key string, b []byte) (string, error) {
+			keys = append(keys, key)
+			data = append(data, string(b))
+			return key, nil
+		}))
+
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "hello"}))
+		require.Len(t, keys, 1)
+		assert.Equal(t, []string{"hello"}, data)
+
+		entries, err := l.Manifest(ctx, "key")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, keys[0], entries[0].Key)
+	})
+	t.Run("Veto", func(t *testing.T) {
+		var failed []string
+		l := newLogger(t,
+			WithBeforePut(func(context.Context, string, []byte) (string, error) { return "", errors.New("vetoed") }),
+			WithOnError(func(_ context.Context, key string, _ error) { failed = append(failed, key) }),
+		)
+
+		err := l.Write(ctx, options.Write{Key: "key", Data: "hello"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "vetoed")
+		assert.Empty(t, failed, "vetoed uploads are not failures")
+
+		entries, err := l.Manifest(ctx, "key")
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+	t.Run("EmptyKey", func(t *testing.T) {
+		l := newLogger(t, WithBeforePut(func(context.Context, string, []byte) (string, error) { return "", nil }))
+		assert.Error(t, l.Write(ctx, options.Write{Key: "key", Data: "hello"}))
+	})
+	t.Run("OnError", func(t *testing.T) {
+		var failed []string
+		var errs []error
+		l := newLogger(t, WithOnError(func(_ context.Context, key string, err error) {
+			failed = append(failed, key)
+			errs = append(errs, err)
+		}))
+		l.logsBucket = &failingChunkBucket{Bucket: l.logsBucket, seqs: map[uint64]bool{0: true}}
+
+		require.Error(t, l.Write(ctx, options.Write{Key: "key", Data: "hello"}))
+		require.Len(t, failed, 1)
+		assert.Contains(t, failed[0], "key")
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "put failed")
+
+		l.logsBucket = l.logsBucket.(*failingChunkBucket).Bucket
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "hello"}))
+		assert.Len(t, failed, 1, "successful uploads do not run the error hooks")
+	})
+	t.Run("Nil", func(t *testing.T) {
+		_, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithBeforePut(nil))
+		assert.Error(t, err)
+		_, err = NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithOnError(nil))
+		assert.Error(t, err)
+	})
+}
@@ -9,14 +9,62 @@ import (
 
 type Logger interface {
 	AddMetadata(context.Context, options.AddMetadata) error
+	// Write encodes and uploads the data. Implementations must not retain
+	// the data once Write returns since callers, such as the sender, reuse
+	// it.
 	Write(context.Context, options.Write) error
-	WriteBytes(context.Context, options.WriteBytes) error
+	WriteBytes(context.Context, options.WriteBytes) (WriteResult, error)
+	WriteReader(context.Context, options.WriteReader) error
 	FollowFile(context.Context, options.FollowFile) error
 	NewReadCloser(context.Context, options.Read) (ReadCloser, error)
 	NewReverseReadCloser(context.Context, options.Read) (ReadCloser, error)
+	// ReadLines returns the decoded log lines of the page identified by
+	// the read options' page token, along with the token of the next
+	// page, which is empty once all pages have been read.
+	ReadLines(context.Context, options.Read) ([]LogLine, string, error)
+	// Search returns the log lines of a key matching a regular expression.
+	Search(context.Context, options.Search) ([]SearchResult, error)
+	// Check returns an error if the logger's backing storage is not
+	// reachable with the logger's credentials.
+	Check(context.Context) error
+	// Child returns a view of the logger that nests every key under the
+	// given prefix, e.g. the key "step1" of the child "task" is the key
+	// "task.children/step1" of the logger, and the empty key of the child
+	// is the key "task". Reading the key "task" does not read the keys of
+	// its child.
+	Child(prefix string) Logger
 }
 
+// ReadCloser reads the chunks of a log key in order. Each page is a single
+// chunk.
 type ReadCloser interface {
+	// ReadPage returns the next page, or io.EOF once all pages have been
+	// read.
 	ReadPage() ([]byte, error)
+	// DecodePage reads the next page and unmarshals it into v using the
+	// encoding detected from the page's chunk, returning io.EOF once all
+	// pages have been read.
+	DecodePage(v interface{}) error
+	// PageToken returns an opaque token identifying the next page, or an
+	// empty string once all pages have been read. Passing the token to a
+	// new reader via options.Read.PageToken resumes reading at that page.
+	PageToken() string
+	// Checkpoint returns an opaque token identifying the exact position
+	// of the reader, or an empty string once all pages have been read.
+	// Passing the token to Resume, e.g. of a new reader of the same key,
+	// continues reading at that position.
+	Checkpoint() string
+	// Resume moves the reader to the position identified by a token
+	// returned by Checkpoint.
+	Resume(token string) error
 	io.ReadCloser
 }
+
+// WriteResult describes the outcome of a successful write.
+type WriteResult struct {
+	// Key is the key of the chunk within the logs bucket.
+	Key string
+	// Deduplicated is whether the upload was skipped because an identical
+	// chunk already exists at Key.
+	Deduplicated bool
+}
@@ -0,0 +1,64 @@
+package logger
+
+import "time"
+
+// KeyInfo describes an object about to be uploaded by a bucket logger and is
+// passed to its KeyGenerator.
+type KeyInfo struct {
+	// Prefix is the caller-provided key, e.g. options.Write.Key.
+	Prefix string
+	// Extension is the extension of the object's encoding, without a
+	// leading dot.
+	Extension string
+	// Metadata is whether the object is uploaded to the metadata bucket.
+	Metadata bool
+	// Time is the time of the write. Times are strictly increasing per
+	// logger.
+	Time time.Time
+	// Instance is the random ID of the logger writing the object.
+	Instance string
+	// Sequence is the sequence number of the object for the prefix,
+	// starting at zero for each logger.
+	Sequence uint64
+	// Size is the size of the object in bytes.
+	Size int
+	// Lines is the number of log lines in the object, if known.
+	Lines int
+}
+
+// KeyGenerator determines the key of each object uploaded by a bucket
+// logger. Keys generated for the same prefix should sort lexicographically
+// in write order, since readers return chunks in key order. Strict reads
+// additionally require the default key format.
+type KeyGenerator interface {
+	Key(KeyInfo) string
+}
+
+// KeyGeneratorFunc adapts a function to the KeyGenerator interface.
+type KeyGeneratorFunc func(KeyInfo) string
+
+func (f KeyGeneratorFunc) Key(info KeyInfo) string { return f(info) }
+
+// DefaultKeyGenerator generates keys of the form
+// "<prefix>/<unix nanos>_<instance ID>_<sequence>.<ext>".
+var DefaultKeyGenerator KeyGenerator = KeyGeneratorFunc(func(info KeyInfo) string {
+	return JoinKey(info.Prefix, chunkKey{
+		ts:       info.Time.UnixNano(),
+		instance: info.Instance,
+		seq:      info.Sequence,
+	}.String(), info.Extension)
+})
+
+// JoinKey joins a key prefix, name, and extension, skipping any empty
+// prefix or extension.
+func JoinKey(prefix, name, ext string) string {
+	key := name
+	if prefix != "" {
+		key = prefix + "/" + key
+	}
+	if ext != "" {
+		key += "." + ext
+	}
+
+	return key
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinKey(t *testing.T) {
+	assert.Equal(t, "prefix/name.txt", JoinKey("prefix", "name", "txt"))
+	assert.Equal(t, "name.txt", JoinKey("", "name", "txt"))
+	assert.Equal(t, "prefix/name", JoinKey("prefix", "name", ""))
+}
+
+func TestDefaultKeyGenerator(t *testing.T) {
+	key := DefaultKeyGenerator.Key(KeyInfo{
+		Prefix:    "prefix",
+		Extension: "json",
+		Time:      time.Unix(0, 42),
+		Instance:  "abcdef",
+		Sequence:  3,
+	})
+	assert.Equal(t, "prefix/0000000000000000042_abcdef_0000000003.json", key)
+
+	ck, err := parseChunkKey(key)
+	require.NoError(t, err)
+	assert.Equal(t, chunkKey{ts: 42, instance: "abcdef", seq: 3}, ck)
+}
+
+func TestBucketLoggerKeyGenerator(t *testing.T) {
+	ctx := context.Background()
+	var infos []KeyInfo
+	gen := KeyGeneratorFunc(func(info KeyInfo) string {
+		infos = append(infos, info)
+		return JoinKey(info.Prefix, fmt.Sprintf("custom-%d", len(infos)), info.Extension)
+	})
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithKeyGenerator(gen))
+	require.NoError(t, err)
+
+	_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("a\nb\n")})
+	require.NoError(t, err)
+	require.NoError(t, l.AddMetadata(ctx, options.AddMetadata{Key: "key", Data: "meta"}))
+
+	require.Len(t, infos, 2)
+	assert.Equal(t, "key", infos[0].Prefix)
+	assert.Equal(t, "txt", infos[0].Extension)
+	assert.Equal(t, 4, infos[0].Size)
+	assert.Equal(t, 2, infos[0].Lines)
+	assert.False(t, infos[0].Metadata)
+	assert.NotEmpty(t, infos[0].Instance)
+	assert.True(t, infos[1].Metadata)
+	assert.Zero(t, infos[1].Sequence)
+
+	exists, err := l.logsBucket.Exists(ctx, "key/custom-1.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithKeyGenerator(nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package logger
+
+import "sync"
+
+// keyLocker provides mutual exclusion per key so that writes to the same key
+// stay ordered while writes to different keys proceed concurrently.
+type keyLocker struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock acquires the lock for the given key and returns the function that
+// releases it.
+func (k *keyLocker) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = map[string]*keyLock{}
+	}
+	kl, ok := k.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		k.locks[key] = kl
+	}
+	kl.refs++
+	k.mu.Unlock()
+
+	kl.mu.Lock()
+
+	return func() {
+		kl.mu.Unlock()
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		kl.refs--
+		if kl.refs == 0 {
+			delete(k.locks, key)
+		}
+	}
+}
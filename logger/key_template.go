@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HiveKeyTemplate lays keys out in Hive-style date and hour partitions, e.g.
+// "<prefix>/dt=2021-11-19/hour=05/<name>.ndjson", so that a bucket prefix
+// can be registered as an external table partitioned by dt and hour in
+// Athena or Presto.
+const HiveKeyTemplate = "{prefix}/dt={yyyy}-{mm}-{dd}/hour={hh}/{name}"
+
+var keyTemplatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// keyTemplateFields returns the value of each key template placeholder for
+// the key info. Dates are in UTC.
+func keyTemplateFields(info KeyInfo) map[string]string {
+	t := info.Time.UTC()
+	name := chunkKey{ts: info.Time.UnixNano(), instance: info.Instance, seq: info.Sequence}
+
+	return map[string]string{
+		"{prefix}":   info.Prefix,
+		"{yyyy}":     leftPad(strconv.Itoa(t.Year()), 4),
+		"{mm}":       leftPad(strconv.Itoa(int(t.Month())), 2),
+		"{dd}":       leftPad(strconv.Itoa(t.Day()), 2),
+		"{hh}":       leftPad(strconv.Itoa(t.Hour()), 2),
+		"{name}":     name.String(),
+		"{ts}":       leftPad(strconv.FormatInt(name.ts, 10), 19),
+		"{instance}": info.Instance,
+		"{seq}":      leftPad(strconv.FormatUint(info.Sequence, 10), 10),
+	}
+}
+
+// NewKeyTemplate returns a key generator that lays keys out according to
+// the template, e.g. "{prefix}/{yyyy}/{mm}/{dd}/{name}", followed by the
+// object's extension. The placeholders are:
+//
+//	{prefix}    the caller-provided key
+//	{yyyy}      the UTC year of the write
+//	{mm}        the UTC month of the write, 01-12
+//	{dd}        the UTC day of the write, 01-31
+//	{hh}        the UTC hour of the write, 00-23
+//	{name}      the default chunk name, "<unix nanos>_<instance ID>_<sequence>"
+//	{ts}        the zero-padded unix nanos of the write
+//	{instance}  the logger instance ID
+//	{seq}       the zero-padded sequence number
+//
+// The template must begin with "{prefix}/", since readers list a key's
+// chunks by prefix, so that, e.g., a single day's logs are read by reading
+// the key "<prefix>/<yyyy>/<mm>/<dd>". It must also contain {name}, or
+// both {instance} and {seq}, so that keys are unique across loggers, whose
+// sequence numbers all start at zero. Strict reads additionally require
+// {name}.
+func NewKeyTemplate(template string) (KeyGenerator, error) {
+	if !strings.HasPrefix(template, "{prefix}/") {
+		return nil, errors.New("key template must begin with '{prefix}/'")
+	}
+	if !strings.Contains(template, "{name}") && (!strings.Contains(template, "{instance}") || !strings.Contains(template, "{seq}")) {
+		return nil, errors.New("key template must contain '{name}', or both '{instance}' and '{seq}'")
+	}
+	fields := keyTemplateFields(KeyInfo{})
+	for _, placeholder := range keyTemplatePlaceholder.FindAllString(template, -1) {
+		if _, ok := fields[placeholder]; !ok {
+			return nil, errors.Errorf("unrecognized key template placeholder '%s'", placeholder)
+		}
+	}
+
+	return KeyGeneratorFunc(func(info KeyInfo) string {
+		fields := keyTemplateFields(info)
+		key := keyTemplatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+			return fields[placeholder]
+		})
+
+		return JoinKey("", key, info.Extension)
+	}), nil
+}
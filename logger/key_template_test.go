@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyTemplate(t *testing.T) {
+	info := KeyInfo{
+		Prefix:    "project",
+		Extension: "txt",
+		Time:      time.Date(2021, 11, 19, 5, 4, 3, 2, time.UTC),
+		Instance:  "abc",
+		Sequence:  7,
+	}
+
+	for _, test := range []struct {
+		name     string
+		template string
+		key      string
+		invalid  bool
+	}{
+		{
+			name:     "Name",
+			template: "{prefix}/{yyyy}/{mm}/{dd}/{name}",
+			key:      "project/2021/11/19/1637298243000000002_abc_0000000007.txt",
+		},
+		{
+			name:     "Hive",
+			template: HiveKeyTemplate,
+			key:      "project/dt=2021-11-19/hour=05/1637298243000000002_abc_0000000007.txt",
+		},
+		{
+			name:     "InstanceAndSequence",
+			template: "{prefix}/{instance}/{seq}",
+			key:      "project/abc/0000000007.txt",
+		},
+		{name: "SequenceOnly", template: "{prefix}/{seq}", invalid: true},
+		{name: "InstanceOnly", template: "{prefix}/{instance}", invalid: true},
+		{name: "TimestampOnly", template: "{prefix}/{ts}", invalid: true},
+		{name: "MissingPrefix", template: "{yyyy}/{prefix}/{name}", invalid: true},
+		{name: "UnrecognizedPlaceholder", template: "{prefix}/{host}/{name}", invalid: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			gen, err := NewKeyTemplate(test.template)
+			if test.invalid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.key, gen.Key(info))
+		})
+	}
+}
+
+func TestBucketLoggerKeyTemplate(t *testing.T) {
+	ctx := context.Background()
+	days := []time.Time{
+		time.Date(2021, 11, 19, 23, 0, 0, 0, time.UTC),
+		time.Date(2021, 11, 20, 1, 0, 0, 0, time.UTC),
+	}
+	now := days[0]
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"},
+		WithKeyTemplate("{prefix}/{yyyy}/{mm}/{dd}/{name}"),
+		WithClock(func() time.Time { return now }),
+	)
+	require.NoError(t, err)
+	for _, day := range days {
+		now = day
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: day.Format("2006-01-02")}))
+	}
+
+	lines, _, err := l.ReadLines(ctx, options.Read{Key: "key/2021/11/20"})
+	require.NoError(t, err)
+	require.Len(t, lines, 1, "a single day's logs are read by reading its key")
+	assert.Equal(t, "2021-11-20", lines[0].Data)
+
+	lines, token, err := l.ReadLines(ctx, options.Read{Key: "key"})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "2021-11-19", lines[0].Data)
+	lines, _, err = l.ReadLines(ctx, options.Read{Key: "key", PageToken: token})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "2021-11-20", lines[0].Data)
+
+	_, err = NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithKeyTemplate("{prefix}/{seq}"))
+	assert.Error(t, err)
+}
+
+func TestBucketLoggerHiveLayout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 11, 19, 5, 4, 3, 2, time.UTC)
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"},
+		WithHiveLayout(),
+		WithClock(func() time.Time { return now }),
+	)
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "message"}))
+
+	lines, _, err := l.ReadLines(ctx, options.Read{Key: "key/dt=2021-11-19/hour=05"})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "message", lines[0].Data)
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/pkg/errors"
+)
+
+// ReadLines returns the decoded log lines of a single page, starting at
+// opts.PageToken, along with the token of the following page. The returned
+// token is empty once all pages have been read.
+func (l *bucketLogger) ReadLines(ctx context.Context, opts options.Read) ([]LogLine, string, error) {
+	r, err := l.newReadCloser(ctx, opts, false)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+
+	key := r.PageToken()
+	if key == "" {
+		return nil, "", nil
+	}
+	data, err := r.ReadPage()
+	if err != nil {
+		return nil, "", err
+	}
+
+	lines, err := decodeLines(l.encodingRegistry, key, data)
+	if err != nil {
+		return nil, "", err
+	}
+	if opts.StartSequence > 0 {
+		start := 0
+		for start < len(lines) && lines[start].Sequence != 0 && lines[start].Sequence < opts.StartSequence {
+			start++
+		}
+		lines = lines[start:]
+	}
+
+	return lines, r.PageToken(), nil
+}
+
+// decodeLines decodes the log lines of the chunk with the given key.
+// Plain text chunks are parsed line by line, see parseTextLine. Blobs, e.g.
+// artifacts written with content-type sniffing, contain no log lines.
+func decodeLines(registry encode.EncodingRegistry, key string, data []byte) ([]LogLine, error) {
+	e, err := detectEncoding(registry, key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.String() {
+	case encode.BLOB:
+		return nil, nil
+	case encode.TEXT:
+		var lines []LogLine
+		for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+			if len(line) > 0 {
+				lines = append(lines, parseTextLine(string(line)))
+			}
+		}
+		return lines, nil
+	}
+
+	var lines []LogLine
+	if err = e.Unmarshal(data, &lines); err != nil {
+		return nil, errors.Wrapf(err, "decoding log lines of chunk '%s' from '%s'", key, e)
+	}
+
+	return lines, nil
+}
+
+// parseTextLine parses a line in the format written by LogLine.String. Lines
+// in any other format, e.g. raw bytes written via WriteBytes, are returned as
+// the data of a log line without a timestamp or priority.
+func parseTextLine(line string) LogLine {
+	parts := strings.SplitN(line, " ", 2)
+	ts, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil || len(parts) < 2 {
+		return LogLine{Data: line}
+	}
+
+	out := LogLine{Timestamp: ts, Data: parts[1]}
+	if rest := parts[1]; strings.HasPrefix(rest, "[") {
+		if end := strings.Index(rest, "] "); end > 0 {
+			if p := level.FromString(rest[1:end]); p != level.Invalid {
+				out.Priority = p
+				out.PriorityString = rest[1:end]
+				out.Data = rest[end+2:]
+			}
+		}
+	}
+
+	return out
+}
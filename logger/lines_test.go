@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerReadLines(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+
+	ts := time.Unix(10, 0).UTC()
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{{Timestamp: ts, Priority: level.Info, PriorityString: "info", Data: "text"}}}))
+	_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), SniffContentType: true})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{{Timestamp: ts, Data: "json"}}, Encoding: encode.JSON}))
+
+	var pages [][]LogLine
+	var token string
+	for {
+		lines, next, err := l.ReadLines(ctx, options.Read{Key: "key", PageToken: token})
+		require.NoError(t, err)
+		pages = append(pages, lines)
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	require.Len(t, pages, 3)
+
+	require.Len(t, pages[0], 1)
+	assert.True(t, ts.Equal(pages[0][0].Timestamp))
+	assert.Equal(t, level.Info, pages[0][0].Priority)
+	assert.Equal(t, "text", pages[0][0].Data)
+	assert.Empty(t, pages[1], "blob chunks contain no log lines")
+	require.Len(t, pages[2], 1)
+	assert.Equal(t, "json", pages[2][0].Data)
+
+	lines, next, err := l.ReadLines(ctx, options.Read{Key: "empty"})
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+	assert.Empty(t, next)
+}
+
+func TestParseTextLine(t *testing.T) {
+	ts := time.Unix(10, 5).UTC()
+	for _, test := range []struct {
+		name     string
+		line     string
+		expected LogLine
+	}{
+		{name: "Priority", line: LogLine{Timestamp: ts, PriorityString: "error", Data: "msg"}.String(), expected: LogLine{Timestamp: ts, Priority: level.Error, PriorityString: "error", Data: "msg"}},
+		{name: "NoPriority", line: LogLine{Timestamp: ts, Data: "msg"}.String(), expected: LogLine{Timestamp: ts, Data: "msg"}},
+		{name: "UnknownPriority", line: ts.Format(time.RFC3339Nano) + " [other] msg", expected: LogLine{Timestamp: ts, Data: "[other] msg"}},
+		{name: "Raw", line: "raw line", expected: LogLine{Data: "raw line"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, parseTextLine(test.line))
+		})
+	}
+}
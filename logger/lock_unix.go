@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes an exclusive lock on the file without blocking,
+// returning false if another open file holds it. The lock is released when
+// the file is closed, including when its process exits.
+func tryLockFile(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+
+	return err == nil, err
+}
@@ -0,0 +1,240 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/pkg/errors"
+)
+
+// Manager lazily creates and caches a child logger and a sender per
+// logical ID, e.g. a task or job ID, on top of a single shared logger, so
+// that buckets are not recreated per ID. Entries neither requested nor sent
+// to within the idle timeout are evicted and their senders closed, unless a
+// reference to the sender acquired with Sender is still held.
+type Manager struct {
+	mu         sync.Mutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	logger     Logger
+	opts       options.Manager
+	senderOpts []SenderOption
+	entries    map[string]*managerEntry
+	closed     bool
+	now        func() time.Time
+}
+
+type managerEntry struct {
+	logger Logger
+	sender *sender
+	// refs is the number of references to the sender that have not been
+	// released with Done.
+	refs int
+	// lastUsed is the Unix time in nanoseconds the entry was last requested
+	// or sent to, which is updated atomically by the sender.
+	lastUsed int64
+}
+
+// minEvictInterval bounds how often idle entries are evicted for short
+// idle timeouts.
+const minEvictInterval = 10 * time.Millisecond
+
+// NewManager returns a manager of the children of the given logger. The
+// sender options are applied to every sender the manager creates. Close
+// must be called to stop idle eviction and close the remaining senders.
+func NewManager(ctx context.Context, l Logger, opts options.Manager, senderOpts ...SenderOption) (*Manager, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid manager options")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	m := &Manager{
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		logger:     l,
+		opts:       opts,
+		senderOpts: senderOpts,
+		entries:    map[string]*managerEntry{},
+		now:        time.Now,
+	}
+	go m.evictLoop()
+
+	return m, nil
+}
+
+// entry returns the cached entry for the ID, creating it if necessary. The
+// manager's lock must be held.
+func (m *Manager) entry(id string) (*managerEntry, error) {
+	if m.closed {
+		return nil, errors.New("manager is closed")
+	}
+	if id == "" {
+		return nil, errors.New("must specify an ID")
+	}
+
+	e, ok := m.entries[id]
+	if !ok {
+		e = &managerEntry{logger: m.logger.Child(id)}
+		m.entries[id] = e
+	}
+	m.touch(e)
+
+	return e, nil
+}
+
+// touch marks the entry as used.
+func (m *Manager) touch(e *managerEntry) {
+	atomic.StoreInt64(&e.lastUsed, m.now().UnixNano())
+}
+
+// Logger returns the child logger for the ID, whose keys are nested under
+// the ID.
+func (m *Manager) Logger(id string) (Logger, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, err := m.entry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.logger, nil
+}
+
+// Sender returns the sender for the ID, creating it from the manager's
+// sender template on first use, and acquires a reference to it. The sender
+// is not evicted, even if idle, until every reference is released with Done.
+func (m *Manager) Sender(id string) (*sender, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, err := m.entry(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.sender == nil {
+		opts := m.opts.Sender
+		opts.Key = childKey(id, m.opts.Sender.Key)
+		s, err := NewSender(m.ctx, m.logger, opts, m.senderOpts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating sender for '%s'", id)
+		}
+		s.onSend = func() { m.touch(e) }
+		e.sender = s
+	}
+	e.refs++
+
+	return e.sender, nil
+}
+
+// Done releases a reference to the ID's sender acquired with Sender.
+func (m *Manager) Done(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[id]; ok && e.refs > 0 {
+		e.refs--
+		m.touch(e)
+	}
+}
+
+// Release evicts the ID, closing its sender, if any, with the given
+// context. The ID's logger and sender are recreated if requested again. It
+// returns an error, without evicting the ID, if references to the sender
+// acquired with Sender have not been released.
+func (m *Manager) Release(ctx context.Context, id string) error {
+	m.mu.Lock()
+	e, ok := m.entries[id]
+	if ok && e.refs > 0 {
+		m.mu.Unlock()
+		return errors.Errorf("sender for '%s' is still in use", id)
+	}
+	delete(m.entries, id)
+	m.mu.Unlock()
+
+	if !ok || e.sender == nil {
+		return nil
+	}
+
+	return errors.Wrapf(e.sender.CloseContext(ctx), "closing sender for '%s'", id)
+}
+
+// Close stops idle eviction and closes every cached sender with the given
+// context. The shared logger is not closed.
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	entries := m.entries
+	m.entries = map[string]*managerEntry{}
+	m.mu.Unlock()
+
+	m.cancel()
+	<-m.done
+
+	catcher := grip.NewBasicCatcher()
+	for id, e := range entries {
+		if e.sender != nil {
+			catcher.Wrapf(e.sender.CloseContext(ctx), "closing sender for '%s'", id)
+		}
+	}
+
+	return catcher.Resolve()
+}
+
+// evictLoop periodically evicts idle IDs until the manager is closed.
+func (m *Manager) evictLoop() {
+	defer close(m.done)
+
+	interval := m.opts.IdleTimeout / 2
+	if interval < minEvictInterval {
+		interval = minEvictInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.evictIdle()
+		}
+	}
+}
+
+// evictIdle closes the senders of, and forgets, the IDs that have been
+// neither requested nor sent to within the idle timeout and whose senders
+// have no references.
+func (m *Manager) evictIdle() {
+	m.mu.Lock()
+	var idle []*managerEntry
+	for id, e := range m.entries {
+		lastUsed := time.Unix(0, atomic.LoadInt64(&e.lastUsed))
+		if e.refs == 0 && m.now().Sub(lastUsed) >= m.opts.IdleTimeout {
+			idle = append(idle, e)
+			delete(m.entries, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range idle {
+		if e.sender == nil {
+			continue
+		}
+		if err := e.sender.CloseWithTimeout(defaultCloseTimeout); err != nil && e.sender.opts.Local != nil {
+			e.sender.opts.Local.Send(message.NewErrorMessage(level.Error, errors.Wrap(err, "closing idle sender")))
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	newManager := func(t *testing.T, senderKey string) (*Manager, *bucketLogger) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		m, err := NewManager(ctx, l, options.Manager{
+			Sender: options.Sender{
+				Key:           senderKey,
+				Local:         send.MakeInternalLogger(),
+				LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+				FlushInterval: -1,
+			},
+			IdleTimeout: time.Hour,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { assert.NoError(t, m.Close(ctx)) })
+		return m, l
+	}
+	read := func(t *testing.T, l Logger, key string) []LogLine {
+		lines, _, err := l.ReadLines(ctx, options.Read{Key: key})
+		require.NoError(t, err)
+		return lines
+	}
+
+	t.Run("Caches", func(t *testing.T) {
+		m, _ := newManager(t, "")
+		l1, err := m.Logger("task")
+		require.NoError(t, err)
+		l2, err := m.Logger("task")
+		require.NoError(t, err)
+		assert.True(t, l1 == l2)
+		s1, err := m.Sender("task")
+		require.NoError(t, err)
+		s2, err := m.Sender("task")
+		require.NoError(t, err)
+		assert.True(t, s1 == s2)
+		s3, err := m.Sender("other")
+		require.NoError(t, err)
+		assert.False(t, s1 == s3)
+
+		_, err = m.Logger("")
+		assert.Error(t, err)
+	})
+	t.Run("SenderKeys", func(t *testing.T) {
+		for _, test := range []struct {
+			name      string
+			senderKey string
+			key       string
+		}{
+			{name: "ID", key: "task"},
+			{name: "Nested", senderKey: "agent", key: "task.children/agent"},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				m, l := newManager(t, test.senderKey)
+				s, err := m.Sender("task")
+				require.NoError(t, err)
+				s.Send(message.NewDefaultMessage(level.Info, "message"))
+				m.Done("task")
+				require.NoError(t, m.Release(ctx, "task"))
+				assert.True(t, s.closed)
+
+				lines := read(t, l, test.key)
+				require.Len(t, lines, 1)
+			})
+		}
+	})
+	t.Run("SenderKeysMatchChildKeys", func(t *testing.T) {
+		m, l := newManager(t, "agent")
+		s, err := m.Sender("task")
+		require.NoError(t, err)
+		s.Send(message.NewDefaultMessage(level.Info, "sender"))
+		child, err := m.Logger("task")
+		require.NoError(t, err)
+		require.NoError(t, child.Write(ctx, options.Write{Key: "", Data: "child\n"}))
+		m.Done("task")
+		require.NoError(t, m.Release(ctx, "task"))
+
+		lines := read(t, child, "agent")
+		require.Len(t, lines, 1)
+		data, ok := lines[0].Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "sender", data["message"])
+		lines = read(t, l, "task")
+		require.Len(t, lines, 1)
+		assert.Equal(t, "child", lines[0].Data)
+	})
+	t.Run("EvictsIdle", func(t *testing.T) {
+		m, l := newManager(t, "")
+		now := time.Now()
+		m.now = func() time.Time { return now }
+		idle, err := m.Sender("idle")
+		require.NoError(t, err)
+		idle.Send(message.NewDefaultMessage(level.Info, "message"))
+		m.Done("idle")
+		now = now.Add(30 * time.Minute)
+		active, err := m.Sender("active")
+		require.NoError(t, err)
+		m.Done("active")
+
+		now = now.Add(30 * time.Minute)
+		m.evictIdle()
+		assert.True(t, idle.closed, "evicted senders are closed")
+		assert.False(t, active.closed)
+		assert.Len(t, read(t, l, "idle"), 1, "evicted senders are flushed")
+
+		recreated, err := m.Sender("idle")
+		require.NoError(t, err)
+		assert.False(t, recreated == idle)
+	})
+	t.Run("SendRefreshesIdle", func(t *testing.T) {
+		m, _ := newManager(t, "")
+		now := time.Now()
+		m.now = func() time.Time { return now }
+		s, err := m.Sender("task")
+		require.NoError(t, err)
+		m.Done("task")
+
+		now = now.Add(30 * time.Minute)
+		s.Send(message.NewDefaultMessage(level.Info, "message"))
+		now = now.Add(30 * time.Minute)
+		m.evictIdle()
+		assert.False(t, s.closed)
+
+		now = now.Add(30 * time.Minute)
+		m.evictIdle()
+		assert.True(t, s.closed)
+	})
+	t.Run("ReferencedSendersAreNotEvicted", func(t *testing.T) {
+		m, _ := newManager(t, "")
+		now := time.Now()
+		m.now = func() time.Time { return now }
+		s, err := m.Sender("task")
+		require.NoError(t, err)
+		_, err = m.Sender("task")
+		require.NoError(t, err)
+
+		now = now.Add(time.Hour)
+		m.evictIdle()
+		assert.False(t, s.closed)
+		assert.Error(t, m.Release(ctx, "task"))
+		assert.False(t, s.closed)
+
+		m.Done("task")
+		m.evictIdle()
+		assert.False(t, s.closed)
+		m.Done("task")
+		now = now.Add(time.Hour)
+		m.evictIdle()
+		assert.True(t, s.closed)
+	})
+	t.Run("ShortIdleTimeout", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		m, err := NewManager(ctx, l, options.Manager{
+			Sender:      options.Sender{Local: send.MakeInternalLogger(), FlushInterval: -1},
+			IdleTimeout: time.Nanosecond,
+		})
+		require.NoError(t, err)
+		defer func() { assert.NoError(t, m.Close(ctx)) }()
+
+		s, err := m.Sender("task")
+		require.NoError(t, err)
+		m.Done("task")
+		assert.Eventually(t, func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.closed
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("Close", func(t *testing.T) {
+		m, _ := newManager(t, "")
+		s, err := m.Sender("task")
+		require.NoError(t, err)
+		require.NoError(t, m.Close(ctx))
+		assert.True(t, s.closed)
+		require.NoError(t, m.Close(ctx))
+
+		_, err = m.Sender("task")
+		assert.Error(t, err)
+		_, err = m.Logger("task")
+		assert.Error(t, err)
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := NewManager(ctx, &copyingLogger{}, options.Manager{IdleTimeout: -time.Second})
+		assert.Error(t, err)
+	})
+}
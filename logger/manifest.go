@@ -0,0 +1,273 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/mongodb/grip/level"
+	"github.com/pkg/errors"
+)
+
+const (
+	manifestExtension     = ".json"
+	manifestTempExtension = ".json.tmp"
+)
+
+// ManifestEntry describes a single log chunk. An entry is written to the
+// manifest bucket, next to the logs and metadata buckets, every time a chunk
+// is flushed, so that chunks can be located by size, line count, and time
+// without downloading them.
+type ManifestEntry struct {
+	// Key is the key of the chunk within the logs bucket.
+	Key string `json:"key"`
+	// Size is the size of the chunk in bytes.
+	Size int `json:"size"`
+	// Lines is the number of log lines in the chunk.
+	Lines int `json:"lines"`
+	// Start and End are the earliest and latest log line timestamps in
+	// the chunk. If the chunk's data has no timestamps, both are the time
+	// of the flush.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// MaxPriority is the highest priority of the log lines in the chunk,
+	// if the chunk's data has priorities.
+	MaxPriority level.Priority `json:"max_priority,omitempty"`
+	// FirstSequence and LastSequence are the lowest and highest sequence
+	// numbers of the log lines in the chunk, if the lines have sequence
+	// numbers.
+	FirstSequence uint64 `json:"first_seq,omitempty"`
+	LastSequence  uint64 `json:"last_seq,omitempty"`
+	// SHA256 is the hex encoded SHA-256 hash of the chunk's uncompressed
+	// contents and Signature the signature of the entry, if the logger
+	// that wrote it signs manifests.
+	SHA256    string `json:"sha256,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// newManifestEntry returns the manifest entry for a chunk written from the
+// given data, encoded as the chunk's contents.
+func (l *bucketLogger) newManifestEntry(key string, data interface{}, encoded []byte) ManifestEntry {
+	entry := ManifestEntry{Key: key, Size: len(encoded), Lines: countLines(data)}
+	if l.manifestSigner != nil {
+		entry.SHA256 = chunkHash(encoded)
+	}
+
+	if lines, ok := data.([]LogLine); ok && len(lines) > 0 {
+		entry.Start, entry.End = lines[0].Timestamp, lines[0].Timestamp
+		for _, line := range lines {
+			if line.Timestamp.Before(entry.Start) {
+				entry.Start = line.Timestamp
+			}
+			if line.Timestamp.After(entry.End) {
+				entry.End = line.Timestamp
+			}
+			if line.Priority > entry.MaxPriority {
+				entry.MaxPriority = line.Priority
+			}
+			if line.Sequence != 0 && (entry.FirstSequence == 0 || line.Sequence < entry.FirstSequence) {
+				entry.FirstSequence = line.Sequence
+			}
+			if line.Sequence > entry.LastSequence {
+				entry.LastSequence = line.Sequence
+			}
+		}
+	} else {
+		entry.Start = l.now()
+		entry.End = entry.Start
+	}
+
+	return entry
+}
+
+// putManifestEntry atomically uploads the manifest entry by writing it to a
+// temporary key and copying it into place, so that readers never observe a
+// partially written entry.
+func (l *bucketLogger) putManifestEntry(ctx context.Context, entry ManifestEntry) error {
+	if l.manifestBucket == nil {
+		return nil
+	}
+	entry.Signature = nil
+	if l.manifestSigner != nil {
+		if err := l.signManifestEntry(&entry); err != nil {
+			return errors.Wrapf(err, "signing manifest entry for chunk '%s'", entry.Key)
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "encoding manifest entry")
+	}
+
+	if l.dryRun != nil {
+		return l.put(ctx, l.manifestBucket, entry.Key+manifestExtension, data)
+	}
+
+	tmpKey := entry.Key + manifestTempExtension
+	if err = l.put(ctx, l.manifestBucket, tmpKey, data); err != nil {
+		return errors.Wrapf(err, "uploading temporary manifest entry for chunk '%s'", entry.Key)
+	}
+	err = l.withRetry(ctx, entry.Key, func() error {
+		return l.manifestBucket.Copy(ctx, pail.CopyOptions{
+			SourceKey:         tmpKey,
+			DestinationKey:    entry.Key + manifestExtension,
+			DestinationBucket: l.manifestBucket,
+		})
+	})
+	if err != nil {
+		return errors.Wrapf(err, "copying manifest entry for chunk '%s' into place", entry.Key)
+	}
+
+	return errors.Wrapf(l.manifestBucket.Remove(ctx, tmpKey), "removing temporary manifest entry for chunk '%s'", entry.Key)
+}
+
+// repairManifestEntry rebuilds the manifest entry of an existing chunk from
+// its data, e.g. for a chunk whose writer crashed before writing the entry,
+// returning false if the chunk has since been removed. The log lines are
+// decoded, if possible, to recover their time range and priorities; chunks
+// that cannot be decoded are described by their raw data.
+func (l *bucketLogger) repairManifestEntry(ctx context.Context, key string) (bool, error) {
+	data, err := l.logsChunkReader().get(ctx, key, "")
+	if pail.IsKeyNotFoundError(errors.Cause(err)) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	entry := l.newManifestEntry(key, data, data)
+	if lines, err := decodeLines(l.encodingRegistry, key, data); err == nil && len(lines) > 0 && !lines[0].Timestamp.IsZero() {
+		entry = l.newManifestEntry(key, lines, data)
+	}
+
+	return true, l.putManifestEntry(ctx, entry)
+}
+
+// Manifest returns the manifest entries of the chunks written to the given
+// key, sorted by chunk key. Loggers without a manifest bucket return no
+// entries. Loggers that sign manifests return a ManifestVerificationError
+// if an entry's signature is not valid.
+func (l *bucketLogger) Manifest(ctx context.Context, key string) ([]ManifestEntry, error) {
+	if l.manifestBucket == nil {
+		return nil, nil
+	}
+
+	it, err := l.manifestBucket.List(ctx, keyListPrefix(key))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing manifest entries")
+	}
+
+	var entries []ManifestEntry
+	for it.Next(ctx) {
+		name := it.Item().Name()
+		if !strings.HasSuffix(name, manifestExtension) {
+			continue
+		}
+
+		entry, err := l.getManifestEntry(ctx, it.Item())
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting manifest entry '%s'", name)
+		}
+		if err = l.verifyManifestEntry(strings.TrimSuffix(name, manifestExtension), entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err = it.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating manifest entries")
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	return entries, nil
+}
+
+// sequenceResumer is implemented by loggers that can return the last line
+// sequence number written to a key, which senders continue from.
+type sequenceResumer interface {
+	lastSequence(ctx context.Context, key string) (uint64, error)
+}
+
+// lastSequence returns the highest line sequence number recorded in the
+// manifest of the key, or 0 if the logger has no manifest.
+func (l *bucketLogger) lastSequence(ctx context.Context, key string) (uint64, error) {
+	entries, err := l.Manifest(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	var last uint64
+	for _, entry := range entries {
+		if entry.LastSequence > last {
+			last = entry.LastSequence
+		}
+	}
+
+	return last, nil
+}
+
+func (l *childLogger) lastSequence(ctx context.Context, key string) (uint64, error) {
+	r, ok := l.parent.(sequenceResumer)
+	if !ok {
+		return 0, nil
+	}
+
+	return r.lastSequence(ctx, l.key(key))
+}
+
+// validateLineSequence returns a GapError if the line sequence numbers
+// recorded in the manifest entries, ordered by chunk key, skip numbers.
+// Entries without sequence numbers are ignored, and overlapping ranges,
+// e.g. of retried flushes, are not gaps.
+func validateLineSequence(key string, entries []ManifestEntry) error {
+	var prev *ManifestEntry
+	for i := range entries {
+		entry := &entries[i]
+		if entry.LastSequence == 0 {
+			continue
+		}
+		if prev != nil && entry.FirstSequence > prev.LastSequence+1 {
+			return &GapError{Key: key, After: prev.Key, From: prev.LastSequence + 1, To: entry.FirstSequence - 1, Lines: true}
+		}
+		if prev == nil || entry.LastSequence > prev.LastSequence {
+			prev = entry
+		}
+	}
+
+	return nil
+}
+
+// sequenceStartKey returns the start key of a read from the line with the
+// given sequence number, i.e. the key of the chunk before the first chunk
+// whose lines end at or after the sequence number, found in the manifest
+// entries ordered by chunk key. If no chunk has the line, the start key is
+// the last chunk's key, so that nothing is read.
+func sequenceStartKey(entries []ManifestEntry, seq uint64) string {
+	for i, entry := range entries {
+		if entry.LastSequence >= seq {
+			if i == 0 {
+				return ""
+			}
+			return entries[i-1].Key
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+
+	return entries[len(entries)-1].Key
+}
+
+func (l *bucketLogger) getManifestEntry(ctx context.Context, item pail.BucketItem) (ManifestEntry, error) {
+	r, err := item.Get(ctx)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	defer r.Close()
+
+	var entry ManifestEntry
+	return entry, errors.Wrap(json.NewDecoder(r).Decode(&entry), "decoding manifest entry")
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerManifest(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0).UTC()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	res, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("a\nb\nc\n")})
+	require.NoError(t, err)
+	lines := []LogLine{
+		{Timestamp: time.Unix(20, 0).UTC(), Data: "second"},
+		{Timestamp: time.Unix(10, 0).UTC(), Data: "first"},
+	}
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: lines, Encoding: encode.JSON}))
+
+	entries, err := l.Manifest(ctx, "key")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, res.Key, entries[0].Key)
+	assert.Equal(t, 6, entries[0].Size)
+	assert.Equal(t, 3, entries[0].Lines)
+	assert.True(t, now.Equal(entries[0].Start))
+	assert.True(t, now.Equal(entries[0].End))
+
+	assert.Equal(t, 2, entries[1].Lines)
+	assert.True(t, lines[1].Timestamp.Equal(entries[1].Start))
+	assert.True(t, lines[0].Timestamp.Equal(entries[1].End))
+
+	it, err := l.manifestBucket.List(ctx, "key")
+	require.NoError(t, err)
+	for it.Next(ctx) {
+		assert.False(t, strings.HasSuffix(it.Item().Name(), manifestTempExtension), "temporary manifest entry %s was not removed", it.Item().Name())
+	}
+	require.NoError(t, it.Err())
+}
@@ -0,0 +1,39 @@
+package logger
+
+// Middleware transforms a log line before it is flushed. It returns the
+// line to log, which may be modified or enriched, and false to drop the
+// line instead. The line's Attrs may be shared with other lines, so they
+// must be copied rather than modified in place.
+type Middleware func(LogLine) (LogLine, bool)
+
+// applyMiddleware runs the line through each middleware in order, stopping
+// at the first that drops it.
+func applyMiddleware(mws []Middleware, line LogLine) (LogLine, bool) {
+	for _, mw := range mws {
+		var ok bool
+		if line, ok = mw(line); !ok {
+			return line, false
+		}
+	}
+
+	return line, true
+}
+
+// applyMiddlewareData runs each line of log line data through the
+// middleware, returning the lines that were not dropped. Data of any other
+// type is returned unchanged.
+func applyMiddlewareData(mws []Middleware, data interface{}) interface{} {
+	lines, ok := data.([]LogLine)
+	if !ok {
+		return data
+	}
+
+	out := make([]LogLine, 0, len(lines))
+	for _, line := range lines {
+		if line, ok = applyMiddleware(mws, line); ok {
+			out = append(out, line)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	dropDebug := func(line LogLine) (LogLine, bool) { return line, line.Priority > level.Debug }
+	upper := func(line LogLine) (LogLine, bool) {
+		line.Data = strings.ToUpper(fmt.Sprint(line.Data))
+		return line, true
+	}
+
+	t.Run("Sender", func(t *testing.T) {
+		l := &copyingLogger{}
+		s, err := NewSender(ctx, l, options.Sender{
+			Key:           "key",
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Trace},
+			FlushInterval: -1,
+		}, WithSenderMiddleware(dropDebug, upper))
+		require.NoError(t, err)
+
+		s.Send(message.NewDefaultMessage(level.Debug, "dropped"))
+		s.Send(message.NewDefaultMessage(level.Info, "kept"))
+		require.NoError(t, s.Close())
+
+		require.Len(t, l.writes, 1)
+		require.Len(t, l.writes[0], 1)
+		assert.Equal(t, "KEPT", l.writes[0][0].Data)
+		assert.EqualValues(t, 1, l.writes[0][0].Sequence, "dropped lines do not leave sequence gaps")
+	})
+	t.Run("BucketLogger", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithMiddleware(dropDebug, upper))
+		require.NoError(t, err)
+
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{
+			{Priority: level.Debug, Data: "dropped"},
+			{Priority: level.Info, Data: "kept"},
+		}}))
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{{Priority: level.Debug, Data: "dropped"}}}))
+		require.NoError(t, l.Write(ctx, options.Write{Key: "other", Data: "not lines"}))
+
+		entries, err := l.Manifest(ctx, "key")
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "writes whose lines are all dropped upload nothing")
+
+		lines, _, err := l.ReadLines(ctx, options.Read{Key: "other"})
+		require.NoError(t, err)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "not lines", lines[0].Data, "other data is unaffected")
+	})
+	t.Run("Nil", func(t *testing.T) {
+		_, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithMiddleware(upper, nil))
+		assert.Error(t, err)
+		_, err = NewSender(ctx, &copyingLogger{}, options.Sender{Key: "key", Local: send.MakeInternalLogger()}, WithSenderMiddleware(nil))
+		assert.Error(t, err)
+	})
+}
@@ -1,14 +1,209 @@
 package logger
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
 )
 
+// LogLineVersion is the version of the log line schema written by this
+// package. Version 1 lines, which have no version field and store their
+// level as both a number and a name, are still decoded.
+const LogLineVersion = 2
+
+// TraceIDKey and SpanIDKey are the fields of structured messages, e.g.
+// message.Fields, or the annotations of any message, see
+// message.Composer.Annotate, that senders take the trace and span IDs of a
+// line from.
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// TraceExtractor returns the trace and span IDs of the span active in the
+// context, if any. For OpenTelemetry, e.g.:
+//
+//	func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	}
+type TraceExtractor func(context.Context) (traceID, spanID string)
+
+// messageField returns the non-empty string value of the field of a
+// structured message, i.e. one whose raw form is a map, or of the
+// annotation of a message, with the given name.
+func messageField(m message.Composer, name string) (string, bool) {
+	var fields map[string]interface{}
+	switch raw := m.Raw().(type) {
+	case message.Fields:
+		fields = raw
+	case map[string]interface{}:
+		fields = raw
+	}
+	if id, ok := fields[name].(string); ok && id != "" {
+		return id, true
+	}
+
+	// Annotations are stored in the context of the message.Base that
+	// composers embed, which is not otherwise exposed.
+	v := reflect.Indirect(reflect.ValueOf(m))
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+	annotated := v.FieldByName("Context")
+	if !annotated.IsValid() || !annotated.CanInterface() {
+		return "", false
+	}
+	annotations, _ := annotated.Interface().(message.Fields)
+	id, ok := annotations[name].(string)
+
+	return id, ok && id != ""
+}
+
+// withTraceIDs returns a copy of the data, if it is log lines, with the trace
+// and span IDs of the span active in the context set on the lines that have
+// none.
+func withTraceIDs(ctx context.Context, extract TraceExtractor, data interface{}) interface{} {
+	lines, ok := data.([]LogLine)
+	if !ok {
+		return data
+	}
+	traceID, spanID := extract(ctx)
+	if traceID == "" && spanID == "" {
+		return data
+	}
+
+	out := make([]LogLine, len(lines))
+	for i, line := range lines {
+		if line.TraceID == "" && line.SpanID == "" {
+			line.TraceID, line.SpanID = traceID, spanID
+		}
+		out[i] = line
+	}
+
+	return out
+}
+
 type LogLine struct {
-	Timestamp      time.Time      `json:"ts"`
-	Priority       level.Priority `json:"priority,omitempty"`
-	PriorityString string         `json:"priority_string,omitempty"`
-	Data           interface{}    `json:"data"`
+	Timestamp time.Time `json:"ts" csv:"ts"`
+	// Sequence is the position of the line among the lines sent by a
+	// single sender, starting at 1, so that consumers can detect missing
+	// or duplicated lines. It is zero for lines not sent by a sender.
+	Sequence uint64 `json:"seq,omitempty" csv:"seq"`
+	// Priority is the level of the line.
+	Priority level.Priority `json:"priority,omitempty" csv:"priority"`
+	// PriorityString is the name of the line's level.
+	//
+	// Deprecated: Use Priority. PriorityString is no longer encoded and
+	// is only set on decoded lines for compatibility.
+	PriorityString string `json:"priority_string,omitempty" csv:"-"`
+	// Message is the human readable message of the line, if any.
+	Message string `json:"msg,omitempty" csv:"msg"`
+	// TraceID and SpanID identify the trace span, e.g. an OpenTelemetry
+	// span, that the line was logged in, if any.
+	TraceID string `json:"trace_id,omitempty" csv:"trace_id"`
+	SpanID  string `json:"span_id,omitempty" csv:"span_id"`
+	// Attrs are the structured attributes of the line, if any.
+	Attrs map[string]interface{} `json:"attrs,omitempty" csv:"attrs"`
+	Data  interface{}            `json:"data" csv:"data"`
+}
+
+// logLineJSON is the encoded form of a log line. It includes the fields of
+// every schema version so that any version can be decoded.
+type logLineJSON struct {
+	Version        int                    `json:"v,omitempty"`
+	Timestamp      time.Time              `json:"ts"`
+	Sequence       uint64                 `json:"seq,omitempty"`
+	Level          string                 `json:"level,omitempty"`
+	Priority       level.Priority         `json:"priority,omitempty"`
+	PriorityString string                 `json:"priority_string,omitempty"`
+	Message        string                 `json:"msg,omitempty"`
+	TraceID        string                 `json:"trace_id,omitempty"`
+	SpanID         string                 `json:"span_id,omitempty"`
+	Attrs          map[string]interface{} `json:"attrs,omitempty"`
+	Data           interface{}            `json:"data"`
+}
+
+// MarshalJSON encodes the line with the current schema version, storing its
+// level by number and, for the standard levels, by name.
+func (l LogLine) MarshalJSON() ([]byte, error) {
+	out := logLineJSON{
+		Version:   LogLineVersion,
+		Timestamp: l.Timestamp,
+		Sequence:  l.Sequence,
+		Message:   l.Message,
+		TraceID:   l.TraceID,
+		SpanID:    l.SpanID,
+		Attrs:     l.Attrs,
+		Data:      l.Data,
+	}
+	if l.Priority != 0 {
+		out.Priority = l.Priority
+		if name := l.Priority.String(); level.FromString(name) == l.Priority {
+			out.Level = name
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a line of any schema version. The level is taken
+// from its number if present, since only the standard levels have names.
+func (l *LogLine) UnmarshalJSON(data []byte) error {
+	var in logLineJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*l = LogLine{
+		Timestamp: in.Timestamp,
+		Sequence:  in.Sequence,
+		Message:   in.Message,
+		TraceID:   in.TraceID,
+		SpanID:    in.SpanID,
+		Attrs:     in.Attrs,
+		Data:      in.Data,
+	}
+	switch {
+	case in.Priority != 0:
+		l.Priority = in.Priority
+	case in.Version >= 2 && in.Level != "":
+		l.Priority = level.FromString(in.Level)
+	case in.PriorityString != "":
+		l.Priority = level.FromString(in.PriorityString)
+	}
+	if l.Priority != 0 {
+		l.PriorityString = l.Priority.String()
+	}
+
+	return nil
+}
+
+// String renders the log line as plain text, e.g.
+// "2006-01-02T15:04:05.999999999Z [info] message". Lines without data are
+// rendered with their message.
+func (l LogLine) String() string {
+	ts := l.Timestamp.Format(time.RFC3339Nano)
+	var data interface{} = l.Data
+	if data == nil && l.Message != "" {
+		data = l.Message
+	}
+
+	name := l.PriorityString
+	if name == "" && l.Priority != 0 {
+		name = l.Priority.String()
+	}
+	if name == "" {
+		return fmt.Sprintf("%s %v", ts, data)
+	}
+
+	return fmt.Sprintf("%s [%s] %v", ts, name, data)
 }
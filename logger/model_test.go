@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLineJSON(t *testing.T) {
+	ts := time.Date(2021, 11, 19, 5, 4, 3, 0, time.UTC)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		line := LogLine{
+			Timestamp: ts,
+			Priority:  level.Warning,
+			Message:   "message",
+			Attrs:     map[string]interface{}{"task": "compile"},
+			Data:      "data",
+		}
+		data, err := json.Marshal(line)
+		require.NoError(t, err)
+
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &fields))
+		assert.EqualValues(t, LogLineVersion, fields["v"])
+		assert.Equal(t, "warning", fields["level"])
+		assert.EqualValues(t, level.Warning, fields["priority"])
+		assert.NotContains(t, fields, "priority_string")
+
+		var decoded LogLine
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		line.PriorityString = "warning"
+		assert.Equal(t, line, decoded)
+	})
+	t.Run("NonStandardPriority", func(t *testing.T) {
+		data, err := json.Marshal(LogLine{Timestamp: ts, Priority: 45, Data: "data"})
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "level", "only standard levels have names")
+
+		var decoded LogLine
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, level.Priority(45), decoded.Priority)
+	})
+	t.Run("Version1", func(t *testing.T) {
+		var decoded LogLine
+		require.NoError(t, json.Unmarshal([]byte(`{"ts":"2021-11-19T05:04:03Z","priority":70,"priority_string":"error","data":"data"}`), &decoded))
+		assert.Equal(t, LogLine{Timestamp: ts, Priority: level.Error, PriorityString: "error", Data: "data"}, decoded)
+
+		require.NoError(t, json.Unmarshal([]byte(`{"ts":"2021-11-19T05:04:03Z","priority_string":"info","data":"data"}`), &decoded))
+		assert.Equal(t, level.Info, decoded.Priority)
+	})
+	t.Run("NoLevel", func(t *testing.T) {
+		data, err := json.Marshal(LogLine{Timestamp: ts, Data: "data"})
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "level")
+
+		var decoded LogLine
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Zero(t, decoded.Priority)
+		assert.Empty(t, decoded.PriorityString)
+	})
+}
+
+func TestLogLineCSV(t *testing.T) {
+	e, ok := encode.GetGlobalRegistry().Get(encode.CSV)
+	require.True(t, ok)
+	lines := []LogLine{
+		{
+			Timestamp: time.Date(2021, 11, 19, 5, 4, 3, 0, time.UTC),
+			Sequence:  7,
+			Priority:  45,
+			Message:   "message",
+			TraceID:   "trace",
+			SpanID:    "span",
+			Attrs:     map[string]interface{}{"task": "compile"},
+			Data:      "data",
+		},
+	}
+	data, err := e.Marshal(lines)
+	require.NoError(t, err)
+
+	var decoded []LogLine
+	require.NoError(t, e.Unmarshal(data, &decoded))
+	assert.Equal(t, lines, decoded)
+}
+
+func TestLogLineString(t *testing.T) {
+	ts := time.Date(2021, 11, 19, 5, 4, 3, 0, time.UTC)
+	for _, test := range []struct {
+		name     string
+		line     LogLine
+		expected string
+	}{
+		{name: "Data", line: LogLine{Timestamp: ts, Data: "data"}, expected: "2021-11-19T05:04:03Z data"},
+		{name: "Priority", line: LogLine{Timestamp: ts, Priority: level.Info, Data: "data"}, expected: "2021-11-19T05:04:03Z [info] data"},
+		{name: "MessageOnly", line: LogLine{Timestamp: ts, Message: "message"}, expected: "2021-11-19T05:04:03Z message"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.line.String())
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// MultiPolicy determines which of a multi logger's targets must succeed for
+// a write to succeed.
+type MultiPolicy int
+
+const (
+	// MultiRequireAll requires every target to succeed.
+	MultiRequireAll MultiPolicy = iota
+	// MultiRequireAny requires at least one target to succeed; errors from
+	// the remaining targets are ignored.
+	MultiRequireAny
+)
+
+type multiLogger struct {
+	loggers []Logger
+	policy  MultiPolicy
+}
+
+// NewMultiLogger returns a Logger that duplicates writes to all of the given
+// loggers concurrently, e.g. a local bucket logger for fast access and an S3
+// bucket logger for durability. Reads are served by the first logger.
+func NewMultiLogger(policy MultiPolicy, loggers ...Logger) (Logger, error) {
+	if len(loggers) == 0 {
+		return nil, errors.New("must specify at least one logger")
+	}
+	if policy != MultiRequireAll && policy != MultiRequireAny {
+		return nil, errors.Errorf("unrecognized multi logger policy %d", policy)
+	}
+
+	return &multiLogger{loggers: loggers, policy: policy}, nil
+}
+
+func (l *multiLogger) AddMetadata(ctx context.Context, opts options.AddMetadata) error {
+	return l.each(func(logger Logger, _ int) error { return logger.AddMetadata(ctx, opts) })
+}
+
+func (l *multiLogger) Write(ctx context.Context, opts options.Write) error {
+	return l.each(func(logger Logger, _ int) error { return logger.Write(ctx, opts) })
+}
+
+// WriteBytes returns the result of the first logger that succeeded.
+func (l *multiLogger) WriteBytes(ctx context.Context, opts options.WriteBytes) (WriteResult, error) {
+	results := make([]*WriteResult, len(l.loggers))
+	err := l.each(func(logger Logger, i int) error {
+		result, err := logger.WriteBytes(ctx, opts)
+		if err == nil {
+			results[i] = &result
+		}
+		return err
+	})
+	if err != nil {
+		return WriteResult{}, err
+	}
+
+	for _, result := range results {
+		if result != nil {
+			return *result, nil
+		}
+	}
+
+	return WriteResult{}, nil
+}
+
+// WriteReader streams the reader to all loggers at once. A logger that fails
+// stops receiving data without blocking the others.
+func (l *multiLogger) WriteReader(ctx context.Context, opts options.WriteReader) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	w := &multiPipeWriter{}
+	readers := make([]*io.PipeReader, len(l.loggers))
+	for i := range l.loggers {
+		pr, pw := io.Pipe()
+		readers[i] = pr
+		w.writers = append(w.writers, pw)
+	}
+
+	var copyErr error
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		_, copyErr = io.Copy(w, opts.Reader)
+		w.close(copyErr)
+	}()
+
+	err := l.each(func(logger Logger, i int) error {
+		loggerOpts := opts
+		loggerOpts.Reader = readers[i]
+		err := logger.WriteReader(ctx, loggerOpts)
+		_ = readers[i].CloseWithError(errors.New("logger stopped reading"))
+		return err
+	})
+	<-copied
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(copyErr, "reading data")
+}
+
+func (l *multiLogger) FollowFile(ctx context.Context, opts options.FollowFile) error {
+	return l.each(func(logger Logger, _ int) error { return logger.FollowFile(ctx, opts) })
+}
+
+func (l *multiLogger) NewReadCloser(ctx context.Context, opts options.Read) (ReadCloser, error) {
+	return l.loggers[0].NewReadCloser(ctx, opts)
+}
+
+func (l *multiLogger) NewReverseReadCloser(ctx context.Context, opts options.Read) (ReadCloser, error) {
+	return l.loggers[0].NewReverseReadCloser(ctx, opts)
+}
+
+func (l *multiLogger) ReadLines(ctx context.Context, opts options.Read) ([]LogLine, string, error) {
+	return l.loggers[0].ReadLines(ctx, opts)
+}
+
+func (l *multiLogger) Search(ctx context.Context, opts options.Search) ([]SearchResult, error) {
+	return l.loggers[0].Search(ctx, opts)
+}
+
+// Check checks every logger, resolving the errors according to the policy.
+func (l *multiLogger) Check(ctx context.Context) error {
+	return l.each(func(logger Logger, _ int) error { return logger.Check(ctx) })
+}
+
+func (l *multiLogger) Child(prefix string) Logger { return NewChildLogger(l, prefix) }
+
+// each calls fn concurrently for every logger and resolves the errors
+// according to the policy.
+func (l *multiLogger) each(fn func(Logger, int) error) error {
+	errs := make([]error, len(l.loggers))
+	var wg sync.WaitGroup
+	for i, logger := range l.loggers {
+		wg.Add(1)
+		go func(i int, logger Logger) {
+			defer wg.Done()
+			errs[i] = fn(logger, i)
+		}(i, logger)
+	}
+	wg.Wait()
+
+	catcher := grip.NewBasicCatcher()
+	for i, err := range errs {
+		catcher.Wrapf(err, "logger %d", i)
+	}
+	if l.policy == MultiRequireAny && catcher.Len() < len(l.loggers) {
+		return nil
+	}
+
+	return catcher.Resolve()
+}
+
+// multiPipeWriter writes to every pipe that is still being read, dropping
+// pipes whose reader has been closed.
+type multiPipeWriter struct {
+	writers []*io.PipeWriter
+}
+
+func (w *multiPipeWriter) Write(p []byte) (int, error) {
+	active := w.writers[:0]
+	for _, pw := range w.writers {
+		if _, err := pw.Write(p); err == nil {
+			active = append(active, pw)
+		}
+	}
+	w.writers = active
+	if len(w.writers) == 0 {
+		return 0, errors.New("no loggers are reading")
+	}
+
+	return len(p), nil
+}
+
+func (w *multiPipeWriter) close(err error) {
+	for _, pw := range w.writers {
+		_ = pw.CloseWithError(err)
+	}
+}
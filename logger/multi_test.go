@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingLogger is a logger whose writes always fail.
+type failingLogger struct {
+	Logger
+}
+
+func (l failingLogger) Write(context.Context, options.Write) error {
+	return errors.New("write failed")
+}
+
+func (l failingLogger) WriteBytes(context.Context, options.WriteBytes) (WriteResult, error) {
+	return WriteResult{}, errors.New("write failed")
+}
+
+func (l failingLogger) WriteReader(_ context.Context, opts options.WriteReader) error {
+	buf := make([]byte, 1)
+	_, _ = opts.Reader.Read(buf)
+	return errors.New("write failed")
+}
+
+func TestMultiLogger(t *testing.T) {
+	ctx := context.Background()
+	newLogger := func(t *testing.T) Logger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		return l
+	}
+	read := func(t *testing.T, l Logger) string {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("InvalidArguments", func(t *testing.T) {
+		_, err := NewMultiLogger(MultiRequireAll)
+		assert.Error(t, err)
+		_, err = NewMultiLogger(MultiPolicy(10), newLogger(t))
+		assert.Error(t, err)
+	})
+	t.Run("WritesToAllLoggers", func(t *testing.T) {
+		a, b := newLogger(t), newLogger(t)
+		m, err := NewMultiLogger(MultiRequireAll, a, b)
+		require.NoError(t, err)
+
+		require.NoError(t, m.Write(ctx, options.Write{Key: "key", Data: "a\n"}))
+		res, err := m.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("b\n")})
+		require.NoError(t, err)
+		assert.NotEmpty(t, res.Key)
+		long := strings.Repeat("c", 100) + "\n"
+		require.NoError(t, m.WriteReader(ctx, options.WriteReader{Key: "key", Reader: strings.NewReader(long), ChunkSize: 16}))
+
+		for _, l := range []Logger{a, b, m} {
+			assert.Equal(t, "a\nb\n"+long, read(t, l))
+		}
+	})
+	t.Run("RequireAll", func(t *testing.T) {
+		a := newLogger(t)
+		m, err := NewMultiLogger(MultiRequireAll, a, failingLogger{Logger: newLogger(t)})
+		require.NoError(t, err)
+
+		assert.Error(t, m.Write(ctx, options.Write{Key: "key", Data: "a\n"}))
+		_, err = m.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("b\n")})
+		assert.Error(t, err)
+		assert.Error(t, m.WriteReader(ctx, options.WriteReader{Key: "key", Reader: strings.NewReader("c\n")}))
+		assert.Equal(t, "a\nb\nc\n", read(t, a), "successful loggers still receive writes")
+	})
+	t.Run("RequireAny", func(t *testing.T) {
+		a := newLogger(t)
+		m, err := NewMultiLogger(MultiRequireAny, failingLogger{Logger: newLogger(t)}, a)
+		require.NoError(t, err)
+
+		assert.NoError(t, m.Write(ctx, options.Write{Key: "key", Data: "a\n"}))
+		res, err := m.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("b\n")})
+		require.NoError(t, err)
+		assert.NotEmpty(t, res.Key)
+		assert.NoError(t, m.WriteReader(ctx, options.WriteReader{Key: "key", Reader: strings.NewReader(strings.Repeat("c", 100)), ChunkSize: 16}))
+		assert.Equal(t, "a\nb\n"+strings.Repeat("c", 100), read(t, a))
+
+		all, err := NewMultiLogger(MultiRequireAny, failingLogger{Logger: newLogger(t)}, failingLogger{Logger: newLogger(t)})
+		require.NoError(t, err)
+		assert.Error(t, all.Write(ctx, options.Write{Key: "key", Data: "a\n"}))
+	})
+}
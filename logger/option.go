@@ -0,0 +1,493 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/send"
+	"github.com/pkg/errors"
+)
+
+// BucketLoggerOption configures optional behavior of a bucket logger
+// returned by NewBucketLogger.
+type BucketLoggerOption func(*bucketLogger) error
+
+// WithEncodingRegistry sets the registry used to look up encodings. Defaults
+// to the global encoding registry.
+func WithEncodingRegistry(registry encode.EncodingRegistry) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if registry == nil {
+			return errors.New("encoding registry cannot be nil")
+		}
+		l.encodingRegistry = registry
+		return nil
+	}
+}
+
+// WithKeyGenerator sets the generator used to determine the key of each
+// uploaded object. Defaults to DefaultKeyGenerator.
+func WithKeyGenerator(gen KeyGenerator) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if gen == nil {
+			return errors.New("key generator cannot be nil")
+		}
+		l.keyGenerator = gen
+		return nil
+	}
+}
+
+// WithKeyTemplate sets the key generator to one laying keys out according
+// to the template; see NewKeyTemplate.
+func WithKeyTemplate(template string) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		gen, err := NewKeyTemplate(template)
+		if err != nil {
+			return errors.Wrap(err, "invalid key template")
+		}
+		l.keyGenerator = gen
+		return nil
+	}
+}
+
+// WithHiveLayout lays keys out according to HiveKeyTemplate. Chunks should
+// be written with an encoding a query engine can read, e.g. NDJSON or
+// Parquet, and each table should cover a single key prefix.
+func WithHiveLayout() BucketLoggerOption {
+	return WithKeyTemplate(HiveKeyTemplate)
+}
+
+// WithClock sets the function used to get the current time. Defaults to
+// time.Now.
+func WithClock(now func() time.Time) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if now == nil {
+			return errors.New("clock cannot be nil")
+		}
+		l.now = now
+		return nil
+	}
+}
+
+// WithManifestBucket sets the bucket storing chunk manifest entries of a
+// logger created with NewBucketLoggerFromBuckets.
+func WithManifestBucket(bucket pail.Bucket) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if bucket == nil {
+			return errors.New("manifest bucket cannot be nil")
+		}
+		l.manifestBucket = bucket
+		return nil
+	}
+}
+
+// WithManifestSigner signs every manifest entry written, along with the
+// SHA-256 hash of its chunk, and verifies the signature of every entry
+// read, so that modified chunks and entries can be detected; see
+// VerifyManifest and options.Read.VerifyChunks. Entries without a valid
+// signature, including those written before signing was enabled, fail to
+// be read, and Vacuum does not sign entries for chunks without one.
+func WithManifestSigner(signer ManifestSigner) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if signer == nil {
+			return errors.New("manifest signer cannot be nil")
+		}
+		l.manifestSigner = signer
+		return nil
+	}
+}
+
+// WithDedupeBucket sets the bucket storing the dedupe manifests of
+// deduplicated writes. Defaults to a "dedupe" bucket alongside the logs
+// bucket for loggers created from bucket options.
+func WithDedupeBucket(bucket pail.Bucket) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if bucket == nil {
+			return errors.New("dedupe bucket cannot be nil")
+		}
+		l.dedupeBucket = bucket
+		return nil
+	}
+}
+
+// WithRedactor removes secrets from the data of Write, the text data of
+// WriteBytes, WriteReader, and UploadFile, and the lines of FollowFile
+// before they are uploaded or spooled. Binary data is uploaded unchanged.
+func WithRedactor(r *Redactor) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if r == nil {
+			return errors.New("redactor cannot be nil")
+		}
+		l.redactor = r
+		return nil
+	}
+}
+
+// WithTraceExtractor sets the function Write uses to take the trace and
+// span IDs of the log lines it writes, that have none, from its context.
+func WithTraceExtractor(extract TraceExtractor) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if extract == nil {
+			return errors.New("trace extractor cannot be nil")
+		}
+		l.traceExtractor = extract
+		return nil
+	}
+}
+
+// WithMiddleware adds middleware, run in order, to the log lines written
+// with Write. Writes of data other than log lines are unaffected, and
+// writes whose lines are all dropped upload nothing.
+func WithMiddleware(mws ...Middleware) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		for _, mw := range mws {
+			if mw == nil {
+				return errors.New("middleware cannot be nil")
+			}
+		}
+		l.middleware = append(l.middleware, mws...)
+		return nil
+	}
+}
+
+// WithBeforePut adds hooks, run in order, before each log chunk upload, that
+// may rename the chunk's key or veto the upload. Spooling loggers run the
+// hooks when chunks are written to the spool.
+func WithBeforePut(hooks ...BeforePutHook) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		for _, hook := range hooks {
+			if hook == nil {
+				return errors.New("before put hook cannot be nil")
+			}
+		}
+		l.beforePut = append(l.beforePut, hooks...)
+		return nil
+	}
+}
+
+// WithOnError adds hooks, run in order, after each failed log chunk upload.
+func WithOnError(hooks ...OnErrorHook) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		for _, hook := range hooks {
+			if hook == nil {
+				return errors.New("error hook cannot be nil")
+			}
+		}
+		l.onError = append(l.onError, hooks...)
+		return nil
+	}
+}
+
+// WithErrorHandler reports the errors of operations that may run in the
+// background, e.g. FollowFile and spool uploads, to the handler as they
+// occur.
+func WithErrorHandler(handler ErrorHandler) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if handler == nil {
+			return errors.New("error handler cannot be nil")
+		}
+		l.errorHandler = handler
+		return nil
+	}
+}
+
+// WithRetry enables retrying failed uploads, reads, listings, and removals
+// of the logger's buckets.
+func WithRetry(opts options.Retry) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if err := opts.Validate(); err != nil {
+			return errors.Wrap(err, "invalid retry options")
+		}
+		l.retry = &opts
+		return nil
+	}
+}
+
+// WithCircuitBreaker stops attempting uploads for a cooldown period after
+// repeated failures, failing them immediately with a CircuitOpenError
+// instead. Senders keep failed lines buffered, or spill them to their
+// overflow directory, in the meantime. State changes are reported to the
+// error handler as CircuitStateErrors.
+func WithCircuitBreaker(opts options.CircuitBreaker) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if err := opts.Validate(); err != nil {
+			return errors.Wrap(err, "invalid circuit breaker options")
+		}
+		l.breaker = &circuitBreaker{
+			opts:   opts,
+			now:    func() time.Time { return l.now() },
+			report: func(change *CircuitStateError) { l.handleError("circuit breaker", change) },
+		}
+		return nil
+	}
+}
+
+// WithChunkCache caches the log chunks read by the logger's readers, e.g.
+// ReadCloser, ReaderAt, Search, and CountLines, in the given cache, which
+// may be shared with other loggers. Metadata reads are not cached.
+func WithChunkCache(cache *ChunkCache) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if cache == nil {
+			return errors.New("chunk cache cannot be nil")
+		}
+		l.chunkCache = cache
+		return nil
+	}
+}
+
+// WithDiskCache caches the log chunks read by the logger's readers in the
+// given disk cache, which may be shared with other loggers. Reads check the
+// chunk cache, if any, before the disk cache. Metadata reads are not
+// cached.
+func WithDiskCache(cache *DiskCache) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if cache == nil {
+			return errors.New("disk cache cannot be nil")
+		}
+		l.diskCache = cache
+		return nil
+	}
+}
+
+// WithAudit records an AuditEvent for every read, export, and delete of a
+// key, along with the principal of the operation's context, in an "audit"
+// bucket alongside the logs bucket, which no key written through the
+// logger can address. Operations that cannot be recorded are refused.
+// Since recording events writes, readers must set a separate audit bucket
+// with WithAuditBucket.
+func WithAudit() BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		l.auditing = true
+		return nil
+	}
+}
+
+// WithAuditBucket records audit events, as WithAudit does, in the given
+// bucket, e.g. one returned by NewAuditBucket for credentials that may only
+// write audit events, so that the logger's own credentials may stay read
+// only.
+func WithAuditBucket(bucket pail.Bucket) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if bucket == nil {
+			return errors.New("audit bucket cannot be nil")
+		}
+		l.auditing = true
+		l.auditBucket = bucket
+		return nil
+	}
+}
+
+// WithEncryption encrypts the log chunks and metadata written by the logger
+// with AES-GCM, using the provider's current key, and decrypts them on read
+// with the key whose ID is recorded in each object's header, so keys may be
+// rotated without making existing logs unreadable. Objects written without
+// encryption are read unchanged. Objects are gzipped before they are
+// encrypted, unless compression is disabled, and stored as is. Manifest
+// entries are not encrypted, and ranged reads of encrypted chunks download
+// the whole chunk. Chunk caches hold decrypted chunks, so an encrypted
+// logger does not share its cache entries with other loggers.
+func WithEncryption(keys KeyProvider) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if keys == nil {
+			return errors.New("key provider cannot be nil")
+		}
+		l.encryption = keys
+		return nil
+	}
+}
+
+// WithUploadPool bounds the logger's concurrent uploads with the given pool,
+// which may be shared with other loggers.
+func WithUploadPool(pool *UploadPool) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if pool == nil {
+			return errors.New("upload pool cannot be nil")
+		}
+		l.uploadPool = pool
+		return nil
+	}
+}
+
+// WithDryRun encodes data and generates keys as usual but skips all
+// uploads, instead logging each object that would have been uploaded to the
+// given sender. This is useful for validating logging configuration without
+// writing to the bucket. Reads are unaffected, and Check only verifies that
+// the logs bucket can be listed.
+func WithDryRun(local send.Sender) BucketLoggerOption {
+	return func(l *bucketLogger) error {
+		if local == nil {
+			return errors.New("dry run sender cannot be nil")
+		}
+		l.dryRun = local
+		return nil
+	}
+}
+
+// SenderOption configures optional behavior of a sender returned by
+// NewSender. Sender options are applied after the options.Sender struct so
+// they take precedence over any fields set there.
+type SenderOption func(*sender) error
+
+// WithSenderLocal sets the local sender used for "fallback" operations.
+func WithSenderLocal(local send.Sender) SenderOption {
+	return func(s *sender) error {
+		if local == nil {
+			return errors.New("local sender cannot be nil")
+		}
+		s.opts.Local = local
+		return errors.Wrap(s.SetErrorHandler(send.ErrorHandlerFromSender(local)), "setting default error handler")
+	}
+}
+
+// WithSenderLevelInfo sets the default and threshold logging levels.
+func WithSenderLevelInfo(info send.LevelInfo) SenderOption {
+	return func(s *sender) error {
+		s.opts.LevelInfo = &info
+		return errors.Wrap(s.SetLevel(info), "setting level")
+	}
+}
+
+// WithSenderMaxBufferSize sets the maximum number of bytes to buffer before
+// flushing data.
+func WithSenderMaxBufferSize(size int) SenderOption {
+	return func(s *sender) error {
+		s.opts.MaxBufferSize = size
+		return nil
+	}
+}
+
+// WithSenderFlushInterval sets the interval at which to flush data. A
+// negative interval disables timed flushes.
+func WithSenderFlushInterval(interval time.Duration) SenderOption {
+	return func(s *sender) error {
+		s.opts.FlushInterval = interval
+		return nil
+	}
+}
+
+// WithSenderFlushTimeout bounds each flush by the given timeout.
+func WithSenderFlushTimeout(timeout time.Duration) SenderOption {
+	return func(s *sender) error {
+		if timeout < 0 {
+			return errors.New("flush timeout cannot be negative")
+		}
+		s.opts.FlushTimeout = timeout
+		return nil
+	}
+}
+
+// WithSenderOverflow spills buffered lines to a bounded queue in the given
+// local directory while flushes are failing.
+func WithSenderOverflow(dir string, maxSize int64) SenderOption {
+	return func(s *sender) error {
+		if dir == "" {
+			return errors.New("overflow directory cannot be empty")
+		}
+		if maxSize < 0 {
+			return errors.New("max overflow size cannot be negative")
+		}
+		s.opts.OverflowDir = dir
+		s.opts.MaxOverflowSize = maxSize
+		return nil
+	}
+}
+
+// WithSenderWAL appends each line to a write-ahead log in the given local
+// directory before buffering it.
+func WithSenderWAL(dir string) SenderOption {
+	return func(s *sender) error {
+		if dir == "" {
+			return errors.New("write-ahead log directory cannot be empty")
+		}
+		s.opts.WALDir = dir
+		return nil
+	}
+}
+
+// WithSenderEnrichment stamps source metadata and the given fields onto
+// every line.
+func WithSenderEnrichment(fields map[string]interface{}) SenderOption {
+	return func(s *sender) error {
+		s.opts.Enrich = true
+		s.opts.EnrichFields = fields
+		return nil
+	}
+}
+
+// WithSenderTraceExtractor sets the function SendContext uses to take the
+// trace and span IDs of each line from its context.
+func WithSenderTraceExtractor(extract TraceExtractor) SenderOption {
+	return func(s *sender) error {
+		if extract == nil {
+			return errors.New("trace extractor cannot be nil")
+		}
+		s.traceExtractor = extract
+		return nil
+	}
+}
+
+// WithSenderRedactor removes secrets from each line before it is buffered,
+// or appended to the write-ahead log.
+func WithSenderRedactor(r *Redactor) SenderOption {
+	return func(s *sender) error {
+		if r == nil {
+			return errors.New("redactor cannot be nil")
+		}
+		s.redactor = r
+		return nil
+	}
+}
+
+// WithSenderMiddleware adds middleware, run in order, to each line sent,
+// before it is redacted and buffered.
+func WithSenderMiddleware(mws ...Middleware) SenderOption {
+	return func(s *sender) error {
+		for _, mw := range mws {
+			if mw == nil {
+				return errors.New("middleware cannot be nil")
+			}
+		}
+		s.middleware = append(s.middleware, mws...)
+		return nil
+	}
+}
+
+// WithSenderOnFlush calls the function with each chunk uploaded by a
+// successful flush.
+func WithSenderOnFlush(onFlush func(options.FlushInfo)) SenderOption {
+	return func(s *sender) error {
+		if onFlush == nil {
+			return errors.New("flush callback cannot be nil")
+		}
+		s.opts.OnFlush = onFlush
+		return nil
+	}
+}
+
+// WithSenderStartSequence sets the sequence number of the first line sent.
+// Defaults to 1 after the last sequence number in the manifest of the
+// sender's key, if the logger has a manifest, so that a restarted process
+// continues the numbering, and to 1 otherwise.
+func WithSenderStartSequence(seq uint64) SenderOption {
+	return func(s *sender) error {
+		if seq == 0 {
+			return errors.New("start sequence must be positive")
+		}
+		s.seq = seq - 1
+		s.seqSet = true
+		return nil
+	}
+}
+
+// WithSenderClock sets the function used to timestamp buffered log lines.
+// Defaults to time.Now.
+func WithSenderClock(now func() time.Time) SenderOption {
+	return func(s *sender) error {
+		if now == nil {
+			return errors.New("clock cannot be nil")
+		}
+		s.now = now
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxOverflowSize int64 = 1 << 30
+	overflowExtension            = ".json"
+)
+
+// overflowQueue is a bounded, on-disk FIFO queue of buffered log lines that
+// could not be flushed. Each push is stored as a single segment file whose
+// name sorts in push order.
+type overflowQueue struct {
+	dir      string
+	maxSize  int64
+	size     int64
+	segments []string
+	seq      uint64
+}
+
+// newOverflowQueue returns an overflow queue for the sender for the given
+// key, stored in the given directory, which is created if it does not
+// exist. Segments left in the directory by a previous process are queued
+// ahead of any new segments. The directory must not belong to a sender for
+// a different key.
+func newOverflowQueue(dir, key string, maxSize int64) (*overflowQueue, error) {
+	if err := claimSenderDir(dir, key); err != nil {
+		return nil, errors.Wrap(err, "claiming overflow directory")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading overflow directory '%s'", dir)
+	}
+
+	q := &overflowQueue{dir: dir, maxSize: maxSize}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), overflowExtension) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting file info for overflow segment '%s'", entry.Name())
+		}
+		q.segments = append(q.segments, filepath.Join(dir, entry.Name()))
+		q.size += info.Size()
+	}
+	sort.Strings(q.segments)
+	if len(q.segments) > 0 {
+		var last uint64
+		_, _ = fmt.Sscanf(filepath.Base(q.segments[len(q.segments)-1]), "%020d", &last)
+		q.seq = last + 1
+	}
+
+	return q, nil
+}
+
+// push stores the lines as a new segment, returning false without storing
+// them if the queue would exceed its maximum size.
+func (q *overflowQueue) push(lines []LogLine) (bool, error) {
+	data, err := json.Marshal(lines)
+	if err != nil {
+		return false, errors.Wrap(err, "encoding overflow segment")
+	}
+	if q.size+int64(len(data)) > q.maxSize {
+		return false, nil
+	}
+
+	fn := filepath.Join(q.dir, fmt.Sprintf("%020d%s", q.seq, overflowExtension))
+	if err = os.WriteFile(fn, data, 0644); err != nil {
+		_ = os.Remove(fn)
+		return false, errors.Wrapf(err, "writing overflow segment '%s'", fn)
+	}
+	q.seq++
+	q.segments = append(q.segments, fn)
+	q.size += int64(len(data))
+
+	return true, nil
+}
+
+// drain passes each segment, oldest first, to write and removes it once
+// written, stopping at the first error.
+func (q *overflowQueue) drain(write func([]LogLine) error) error {
+	for len(q.segments) > 0 {
+		fn := q.segments[0]
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			return errors.Wrapf(err, "reading overflow segment '%s'", fn)
+		}
+
+		var lines []LogLine
+		if err = json.Unmarshal(data, &lines); err != nil {
+			return errors.Wrapf(err, "decoding overflow segment '%s'", fn)
+		}
+		if err = write(lines); err != nil {
+			return err
+		}
+
+		if err = os.Remove(fn); err != nil {
+			return errors.Wrapf(err, "removing overflow segment '%s'", fn)
+		}
+		q.segments = q.segments[1:]
+		q.size -= int64(len(data))
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+)
+
+// chunkPrefetcher downloads the chunks after the current chunk of a reader
+// in the background, so that reading a chunk overlaps with downloading the
+// next ones.
+type chunkPrefetcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	count  int
+	slots  chan struct{}
+	// chunks are the prefetched chunks by their index in the reader's
+	// keys.
+	chunks map[int]*prefetchedChunk
+}
+
+type prefetchedChunk struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+func newChunkPrefetcher(ctx context.Context, count, concurrency int) *chunkPrefetcher {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &chunkPrefetcher{
+		ctx:    ctx,
+		cancel: cancel,
+		count:  count,
+		slots:  make(chan struct{}, concurrency),
+		chunks: map[int]*prefetchedChunk{},
+	}
+}
+
+// take returns a reader of the reader's chunk at the index, waiting for its
+// download, and starts prefetching the chunks after it.
+func (p *chunkPrefetcher) take(r *bucketReader, idx int) (io.ReadCloser, error) {
+	p.fill(r, idx)
+	chunk := p.chunks[idx]
+	delete(p.chunks, idx)
+
+	select {
+	case <-chunk.done:
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	}
+	if chunk.err != nil {
+		return nil, chunk.err
+	}
+
+	return io.NopCloser(bytes.NewReader(chunk.data)), nil
+}
+
+// fill starts downloading the reader's chunks from the index through the
+// prefetch count after it, if not already started, and drops prefetched
+// chunks outside that window, e.g. after the reader resumed elsewhere. It is
+// safe to call on a nil prefetcher.
+func (p *chunkPrefetcher) fill(r *bucketReader, from int) {
+	if p == nil {
+		return
+	}
+
+	for idx := range p.chunks {
+		if idx < from || idx > from+p.count {
+			delete(p.chunks, idx)
+		}
+	}
+	for idx := from; idx < len(r.keys) && idx <= from+p.count; idx++ {
+		if _, ok := p.chunks[idx]; ok {
+			continue
+		}
+
+		chunk := &prefetchedChunk{done: make(chan struct{})}
+		p.chunks[idx] = chunk
+		key := r.keys[idx]
+		etag := r.etags[key]
+		go func() {
+			defer close(chunk.done)
+			select {
+			case p.slots <- struct{}{}:
+			case <-p.ctx.Done():
+				chunk.err = p.ctx.Err()
+				return
+			}
+			defer func() { <-p.slots }()
+
+			chunk.data, chunk.err = r.chunks.get(p.ctx, key, etag)
+		}()
+	}
+}
+
+// stop cancels all downloads in progress. It is safe to call on a nil
+// prefetcher.
+func (p *chunkPrefetcher) stop() {
+	if p != nil {
+		p.cancel()
+	}
+}
+
+// bufferedReadCloser reads the wrapped reader through a buffer.
+type bufferedReadCloser struct {
+	*bufio.Reader
+	io.Closer
+}
+
+func newBufferedReadCloser(rc io.ReadCloser, size int) io.ReadCloser {
+	return &bufferedReadCloser{Reader: bufio.NewReaderSize(rc, size), Closer: rc}
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketReaderPrefetch(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	var expected strings.Builder
+	for i := 0; i < 8; i++ {
+		line := fmt.Sprintf("line%d\n", i)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: line}))
+		expected.WriteString(line)
+	}
+
+	for _, test := range []struct {
+		name string
+		opts options.Read
+	}{
+		{name: "Prefetch", opts: options.Read{PrefetchCount: 2}},
+		{name: "Concurrent", opts: options.Read{PrefetchCount: 4, ChunkConcurrency: 3}},
+		{name: "PastEnd", opts: options.Read{PrefetchCount: 20, ChunkConcurrency: 20}},
+		{name: "Buffered", opts: options.Read{BufferSize: 16}},
+		{name: "All", opts: options.Read{PrefetchCount: 3, ChunkConcurrency: 2, BufferSize: 16}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := test.opts
+			opts.Key = "key"
+			r, err := l.NewReadCloser(ctx, opts)
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, expected.String(), string(data))
+		})
+	}
+	t.Run("Offset", func(t *testing.T) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key", Offset: 2, PrefetchCount: 2})
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, expected.String()[2:], string(data))
+	})
+	t.Run("CloseEarly", func(t *testing.T) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key", PrefetchCount: 4, ChunkConcurrency: 2})
+		require.NoError(t, err)
+		page, err := r.ReadPage()
+		require.NoError(t, err)
+		assert.Equal(t, "line0\n", string(page))
+		require.NoError(t, r.Close())
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		for _, opts := range []options.Read{
+			{Key: "key", PrefetchCount: -1},
+			{Key: "key", ChunkConcurrency: -1},
+			{Key: "key", BufferSize: -1},
+		} {
+			_, err := l.NewReadCloser(ctx, opts)
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ReaderAt provides random access to the contents of the log chunks stored
+// under a key, as if they were concatenated in order. Each read fetches only
+// the byte ranges of the chunks it covers from backends supporting ranged
+// fetches. The view is of the chunks stored when it was created.
+type ReaderAt struct {
+	ctx         context.Context
+	chunkReader chunkReader
+	chunks      []chunkSize
+	// starts are the offsets of each chunk within the log.
+	starts []int64
+	size   int64
+}
+
+// NewReaderAt returns a random access view of the log chunks stored under
+// the key. Chunk sizes are taken from the manifest, so only chunks without
+// a manifest entry are downloaded to index them.
+func (l *bucketLogger) NewReaderAt(ctx context.Context, key string) (*ReaderAt, error) {
+	if err := l.audit(ctx, AuditRead, key); err != nil {
+		return nil, err
+	}
+	chunks, err := l.chunkSizes(ctx, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexing log chunks")
+	}
+
+	r := &ReaderAt{
+		ctx:         ctx,
+		chunkReader: l.logsChunkReader(),
+		chunks:      chunks,
+		starts:      make([]int64, len(chunks)),
+	}
+	for i, chunk := range chunks {
+		r.starts[i] = r.size
+		r.size += chunk.size
+	}
+
+	return r, nil
+}
+
+// Size returns the total size in bytes of the log.
+func (r *ReaderAt) Size() int64 { return r.size }
+
+// ReadAt implements io.ReaderAt.
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("offset cannot be negative")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	idx := sort.Search(len(r.starts), func(i int) bool { return r.starts[i] > off }) - 1
+	var n int
+	for ; n < len(p) && idx < len(r.chunks); idx++ {
+		chunk := r.chunks[idx]
+		chunkOff := off + int64(n) - r.starts[idx]
+		if chunkOff >= chunk.size {
+			continue
+		}
+
+		want := len(p) - n
+		if remaining := chunk.size - chunkOff; int64(want) > remaining {
+			want = int(remaining)
+		}
+		read, err := r.readChunk(chunk, chunkOff, p[n:n+want])
+		n += read
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readChunk fills p with the contents of the chunk at the offset.
+func (r *ReaderAt) readChunk(chunk chunkSize, off int64, p []byte) (int, error) {
+	key := chunk.key
+	rc, err := r.chunkReader.getRange(r.ctx, key, chunk.etag, off)
+	if err != nil {
+		return 0, errors.Wrapf(err, "getting chunk '%s'", key)
+	}
+	defer rc.Close()
+
+	n, err := io.ReadFull(rc, p)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return n, errors.Errorf("chunk '%s' is shorter than its indexed size", key)
+	}
+
+	return n, errors.Wrapf(err, "reading chunk '%s'", key)
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerReaderAt(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	all := "line0\nline1\nline2\nline3\n"
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: all[:12]}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: all[12:18]}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: all[18:]}))
+
+	r, err := l.NewReaderAt(ctx, "key")
+	require.NoError(t, err)
+	require.EqualValues(t, len(all), r.Size())
+
+	for _, test := range []struct {
+		name string
+		off  int64
+		n    int
+	}{
+		{name: "WithinChunk", off: 1, n: 4},
+		{name: "ChunkStart", off: 12, n: 6},
+		{name: "AcrossChunks", off: 10, n: 12},
+		{name: "Whole", off: 0, n: len(all)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p := make([]byte, test.n)
+			n, err := r.ReadAt(p, test.off)
+			require.NoError(t, err)
+			assert.Equal(t, test.n, n)
+			assert.Equal(t, all[test.off:test.off+int64(test.n)], string(p))
+		})
+	}
+	t.Run("PastEnd", func(t *testing.T) {
+		p := make([]byte, 10)
+		n, err := r.ReadAt(p, 20)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, 4, n)
+		assert.Equal(t, all[20:], string(p[:n]))
+
+		n, err = r.ReadAt(p, r.Size())
+		assert.Equal(t, io.EOF, err)
+		assert.Zero(t, n)
+
+		_, err = r.ReadAt(p, -1)
+		assert.Error(t, err)
+	})
+	t.Run("SectionReader", func(t *testing.T) {
+		data, err := io.ReadAll(io.NewSectionReader(r, 6, 12))
+		require.NoError(t, err)
+		assert.Equal(t, all[6:18], string(data))
+	})
+}
@@ -0,0 +1,430 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mongodb/grip/message"
+)
+
+// RedactedText replaces the secrets matched by the default redaction rules.
+const RedactedText = "[REDACTED]"
+
+// privateKeyRule is the name of the default rule matching private key
+// blocks, which span multiple lines.
+const privateKeyRule = "private-key"
+
+// maxRedactedDepth is the depth up to which structured values are redacted
+// field by field, which also stops the recursion on cyclic values.
+const maxRedactedDepth = 32
+
+// RedactionRule replaces every match of a pattern in log text.
+type RedactionRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Replacement is expanded as in regexp.Regexp.ReplaceAllString, so
+	// it may refer to submatches, e.g. "${1}[REDACTED]".
+	Replacement string
+}
+
+// DefaultRedactionRules returns rules matching common credential formats:
+// private key blocks, AWS access key IDs and secret keys, GitHub and Slack
+// tokens, JWTs, bearer tokens, credentials in URLs, and password, secret,
+// token, and API key assignments.
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{
+		{
+			Name:        privateKeyRule,
+			Pattern:     regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+			Replacement: RedactedText,
+		},
+		{
+			Name:        "aws-access-key-id",
+			Pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+			Replacement: RedactedText,
+		},
+		{
+			Name:        "aws-secret-access-key",
+			Pattern:     regexp.MustCompile(`(?i)(aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?)[A-Za-z0-9/+=]{40}`),
+			Replacement: "${1}" + RedactedText,
+		},
+		{
+			Name:        "github-token",
+			Pattern:     regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+			Replacement: RedactedText,
+		},
+		{
+			Name:        "slack-token",
+			Pattern:     regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+			Replacement: RedactedText,
+		},
+		{
+			Name:        "jwt",
+			Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+			Replacement: RedactedText,
+		},
+		{
+			Name:        "bearer-token",
+			Pattern:     regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+			Replacement: "${1}" + RedactedText,
+		},
+		{
+			Name:        "url-credentials",
+			Pattern:     regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`),
+			Replacement: "${1}" + RedactedText + "@",
+		},
+		{
+			Name:        "assignment",
+			Pattern:     regexp.MustCompile(`(?i)((?:password|passwd|pwd|secret|token|api[_-]?key)["']?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,;]+)`),
+			Replacement: "${1}" + RedactedText,
+		},
+	}
+}
+
+// DefaultRedactedKeys matches the names of structured fields, e.g. of
+// message.Fields, whose values are secrets.
+var DefaultRedactedKeys = regexp.MustCompile(`(?i)^(?:password|passwd|pwd|.*secret.*|.*token|api[_-]?key|authorization|credentials?)$`)
+
+// Redactor removes secrets from log text before it is buffered or
+// uploaded. The rules are applied in order, followed by Func, if set.
+// Values of structured fields whose names match Keys are replaced
+// entirely.
+type Redactor struct {
+	Rules []RedactionRule
+	Func  func(string) string
+	Keys  *regexp.Regexp
+}
+
+// NewDefaultRedactor returns a redactor applying the default redaction
+// rules and keys.
+func NewDefaultRedactor() *Redactor {
+	return &Redactor{Rules: DefaultRedactionRules(), Keys: DefaultRedactedKeys}
+}
+
+// Redact returns the text with all secrets replaced.
+func (r *Redactor) Redact(s string) string {
+	for _, rule := range r.Rules {
+		s = rule.Pattern.ReplaceAllString(s, rule.Replacement)
+	}
+	if r.Func != nil {
+		s = r.Func(s)
+	}
+
+	return s
+}
+
+// RedactLine returns the line with secrets replaced in its message, data,
+// and attributes. Strings and byte slices are redacted as text, and maps,
+// structs, slices, and pointers field by field and element by element, in
+// copies of the values that contain secrets. Values with no secrets
+// reachable this way, whose text rendering contains secrets, e.g. in
+// unexported fields, are replaced by their redacted text rendering.
+func (r *Redactor) RedactLine(line LogLine) LogLine {
+	line.Message = r.Redact(line.Message)
+	line.Data = r.redactValue(line.Data)
+	if attrs, ok := r.redactMap(line.Attrs); ok {
+		line.Attrs = attrs
+	}
+
+	return line
+}
+
+// redactData returns the data of a write with secrets replaced.
+func (r *Redactor) redactData(data interface{}) interface{} {
+	lines, ok := data.([]LogLine)
+	if !ok {
+		return r.redactValue(data)
+	}
+
+	out := make([]LogLine, len(lines))
+	for i, line := range lines {
+		out[i] = r.RedactLine(line)
+	}
+
+	return out
+}
+
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.Redact(t)
+	case []byte:
+		return []byte(r.Redact(string(t)))
+	case message.Fields:
+		if out, ok := r.redactMap(t); ok {
+			return message.Fields(out)
+		}
+		return t
+	case map[string]interface{}:
+		if out, ok := r.redactMap(t); ok {
+			return out
+		}
+		return t
+	}
+
+	if redacted, ok := r.redactReflect(reflect.ValueOf(v), 0); ok {
+		return redacted.Interface()
+	}
+	s := fmt.Sprint(v)
+	if redacted := r.Redact(s); redacted != s {
+		return redacted
+	}
+
+	return v
+}
+
+// redactReflect returns a redacted copy of the value and true if any of its
+// strings, exported fields, elements, or map values contained a secret.
+// Exported struct fields and map values of keys matching the redactor's
+// keys are replaced entirely if they are strings.
+func (r *Redactor) redactReflect(v reflect.Value, depth int) (reflect.Value, bool) {
+	if depth > maxRedactedDepth || !v.IsValid() {
+		return v, false
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		redacted := r.Redact(v.String())
+		if redacted == v.String() {
+			return v, false
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(redacted)
+		return out, true
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, ok := r.redactReflect(v.Elem(), depth+1)
+		if !ok {
+			return v, false
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(elem)
+		return out, true
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, ok := r.redactReflect(v.Elem(), depth+1)
+		if !ok {
+			return v, false
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out, true
+	case reflect.Struct:
+		var out reflect.Value
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			redacted, ok := r.redactField(field.Name, v.Field(i), depth)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.New(v.Type()).Elem()
+				out.Set(v)
+			}
+			out.Field(i).Set(redacted)
+		}
+		return out, out.IsValid()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v, false
+		}
+		var out reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			redacted, ok := r.redactReflect(v.Index(i), depth+1)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				if v.Kind() == reflect.Slice {
+					out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+					reflect.Copy(out, v)
+				} else {
+					out = reflect.New(v.Type()).Elem()
+					out.Set(v)
+				}
+			}
+			out.Index(i).Set(redacted)
+		}
+		return out, out.IsValid()
+	case reflect.Map:
+		if v.IsNil() {
+			return v, false
+		}
+		var out reflect.Value
+		iter := v.MapRange()
+		for iter.Next() {
+			name := fmt.Sprint(iter.Key().Interface())
+			redacted, ok := r.redactField(name, iter.Value(), depth)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeMapWithSize(v.Type(), v.Len())
+				copyIter := v.MapRange()
+				for copyIter.Next() {
+					out.SetMapIndex(copyIter.Key(), copyIter.Value())
+				}
+			}
+			out.SetMapIndex(iter.Key(), redacted)
+		}
+		return out, out.IsValid()
+	}
+
+	return v, false
+}
+
+// redactField redacts the value of a struct field or map entry, replacing
+// it entirely if it is a non-empty string, or an interface holding a
+// string, and its name matches the redactor's keys.
+func (r *Redactor) redactField(name string, v reflect.Value, depth int) (reflect.Value, bool) {
+	if r.Keys != nil && r.Keys.MatchString(name) {
+		if v.Kind() == reflect.String && v.String() != "" {
+			out := reflect.New(v.Type()).Elem()
+			out.SetString(RedactedText)
+			return out, v.String() != RedactedText
+		}
+		if v.Kind() == reflect.Interface && !v.IsNil() && v.Elem().Kind() == reflect.String {
+			out := reflect.New(v.Type()).Elem()
+			out.Set(reflect.ValueOf(RedactedText))
+			return out, v.Elem().String() != RedactedText
+		}
+	}
+
+	return r.redactReflect(v, depth+1)
+}
+
+// redactMap returns a redacted copy of the map and true if any of its
+// values contained a secret, so that maps shared between lines, e.g.
+// enrichment attributes, are only copied when needed.
+func (r *Redactor) redactMap(m map[string]interface{}) (map[string]interface{}, bool) {
+	var out map[string]interface{}
+	for k, v := range m {
+		var redacted interface{} = RedactedText
+		if r.Keys == nil || !r.Keys.MatchString(k) {
+			redacted = r.redactValue(v)
+		}
+		if fmt.Sprint(redacted) == fmt.Sprint(v) {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+		out[k] = redacted
+	}
+
+	return out, out != nil
+}
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// maxPrivateKeyBlockSize is the size up to which the lines of a private key
+// block are held to be redacted together. Larger blocks are dropped.
+const maxPrivateKeyBlockSize = 64 << 10
+
+// isRedactableText returns whether data written as bytes is text, which is
+// redacted, rather than binary data, which is uploaded unchanged.
+func isRedactableText(data []byte) bool {
+	return utf8.Valid(data) && strings.HasPrefix(http.DetectContentType(data), "text/")
+}
+
+// lineRedactor redacts a stream of lines. If the redactor has the
+// private key rule, the lines of a private key block are held until the
+// end of the block, so that the block, which the rule only matches whole,
+// is redacted although it spans multiple lines.
+type lineRedactor struct {
+	redactor *Redactor
+	blocks   bool
+	block    []byte
+	dropping bool
+}
+
+func newLineRedactor(r *Redactor) *lineRedactor {
+	lr := &lineRedactor{redactor: r}
+	for _, rule := range r.Rules {
+		lr.blocks = lr.blocks || rule.Name == privateKeyRule
+	}
+
+	return lr
+}
+
+// write appends the redacted line, which includes its newline, if any, to
+// dst. Lines of a private key block are appended once the block ends.
+func (lr *lineRedactor) write(dst, line []byte) []byte {
+	text := bytes.TrimSuffix(line, []byte("\n"))
+	newline := line[len(text):]
+	switch {
+	case lr.dropping:
+		if privateKeyEnd.Match(text) {
+			lr.dropping = false
+			dst = append(dst, RedactedText...)
+			dst = append(dst, newline...)
+		}
+		return dst
+	case lr.block != nil:
+		lr.block = append(lr.block, line...)
+		if privateKeyEnd.Match(text) {
+			dst = append(dst, lr.redactor.Redact(string(lr.block))...)
+			lr.block = nil
+		} else if len(lr.block) > maxPrivateKeyBlockSize {
+			lr.block = nil
+			lr.dropping = true
+		}
+		return dst
+	}
+
+	if lr.blocks {
+		if begin := privateKeyBegin.FindIndex(text); begin != nil && !privateKeyEnd.Match(text[begin[1]:]) {
+			lr.block = append([]byte{}, line...)
+			return dst
+		}
+	}
+
+	dst = append(dst, lr.redactor.Redact(string(text))...)
+	return append(dst, newline...)
+}
+
+// flush appends the held lines of a private key block that did not end,
+// replaced entirely, to dst.
+func (lr *lineRedactor) flush(dst []byte) []byte {
+	if lr.block == nil && !lr.dropping {
+		return dst
+	}
+	lr.block, lr.dropping = nil, false
+
+	return append(dst, RedactedText+"\n"...)
+}
+
+// redact returns the redacted lines of the data, see write.
+func (lr *lineRedactor) redact(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i+1]
+		}
+		out = lr.write(out, line)
+		data = data[len(line):]
+	}
+
+	return out
+}
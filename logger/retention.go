@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/julianedwards/cedar/internal"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// ApplyRetention creates or updates the lifecycle rules of an S3 bucket so
+// that S3 enforces the retention policy on the objects of the loggers with
+// the given bucket options. Each retention rule becomes a lifecycle rule
+// for each of the logs, metadata, manifest, and dedupe prefixes of the keys
+// it applies to, or just the logs prefix for rules with tags. Rules previously
+// applied for the same bucket prefix but missing from the policy are
+// removed, while rules of other prefixes and rules not created by
+// ApplyRetention are kept.
+func ApplyRetention(ctx context.Context, opts options.Bucket, retention options.Retention) error {
+	if err := retention.Validate(); err != nil {
+		return errors.Wrap(err, "invalid retention policy")
+	}
+
+	rules := retentionLifecycleRules(opts, retention)
+	for _, rule := range rules {
+		if len(rule.ID) > internal.MaxLifecycleRuleIDLength {
+			return errors.Errorf("invalid retention policy: lifecycle rule ID '%s' is longer than %d characters", rule.ID, internal.MaxLifecycleRuleIDLength)
+		}
+	}
+
+	return errors.Wrap(internal.ReplaceLifecycleRules(ctx, opts, retentionRuleIDPrefix(opts), rules), "applying lifecycle rules")
+}
+
+// retentionRuleIDPrefix returns the prefix of the IDs of the lifecycle rules
+// applied for the bucket options.
+func retentionRuleIDPrefix(opts options.Bucket) string {
+	return "cedar:" + bucketRoot(opts) + ":"
+}
+
+// retentionLifecycleRules returns the lifecycle rules enforcing the
+// retention policy for the bucket options.
+func retentionLifecycleRules(opts options.Bucket, retention options.Retention) []internal.LifecycleRule {
+	idPrefix := retentionRuleIDPrefix(opts)
+	var rules []internal.LifecycleRule
+	for _, rule := range retention.Rules {
+		names := []string{"logs", "metadata", "manifest", "dedupe"}
+		if len(rule.Tags) > 0 {
+			names = names[:1]
+		}
+		for _, name := range names {
+			rules = append(rules, internal.LifecycleRule{
+				ID:              idPrefix + rule.ID + ":" + name,
+				Prefix:          bucketPrefix(opts, name) + "/" + rule.Prefix,
+				Tags:            rule.Tags,
+				ExpireAfterDays: rule.ExpireAfterDays,
+				Transitions:     rule.Transitions,
+			})
+		}
+	}
+
+	return rules
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionLifecycleRules(t *testing.T) {
+	opts := options.Bucket{Type: options.PailS3, Name: "bucket", Prefix: "logs"}
+	transitions := []options.RetentionTransition{{AfterDays: 30, StorageClass: options.S3StorageClassStandardIA}}
+	rules := retentionLifecycleRules(opts, options.Retention{Rules: []options.RetentionRule{
+		{ID: "project", Prefix: "project/", ExpireAfterDays: 90, Transitions: transitions},
+		{ID: "short", Tags: map[string]string{"ttl": "short"}, ExpireAfterDays: 7},
+	}})
+	require.Len(t, rules, 5)
+	for i, name := range []string{"logs", "metadata", "manifest", "dedupe"} {
+		assert.Equal(t, "cedar:logs:project:"+name, rules[i].ID)
+		assert.Equal(t, "logs/"+name+"/project/", rules[i].Prefix)
+		assert.Equal(t, 90, rules[i].ExpireAfterDays)
+		assert.Equal(t, transitions, rules[i].Transitions)
+	}
+	assert.Equal(t, "cedar:logs:short:logs", rules[4].ID, "tagged rules only apply to log chunks")
+	assert.Equal(t, "logs/logs/", rules[4].Prefix)
+	assert.Equal(t, map[string]string{"ttl": "short"}, rules[4].Tags)
+
+	opts.Tenant = "tenant"
+	rules = retentionLifecycleRules(opts, options.Retention{Rules: []options.RetentionRule{{ID: "all", ExpireAfterDays: 30}}})
+	require.Len(t, rules, 4)
+	assert.Equal(t, "cedar:logs/tenants/tenant:all:logs", rules[0].ID)
+	assert.Equal(t, "logs/tenants/tenant/logs/", rules[0].Prefix)
+}
+
+func TestApplyRetentionValidation(t *testing.T) {
+	ctx := context.Background()
+	opts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "logs"}
+
+	for _, test := range []struct {
+		name string
+		rule options.RetentionRule
+	}{
+		{name: "MissingID", rule: options.RetentionRule{ExpireAfterDays: 30}},
+		{name: "NoAction", rule: options.RetentionRule{ID: "rule"}},
+		{name: "NegativeExpiry", rule: options.RetentionRule{ID: "rule", ExpireAfterDays: -1}},
+		{name: "TransitionAfterExpiry", rule: options.RetentionRule{ID: "rule", ExpireAfterDays: 30, Transitions: []options.RetentionTransition{{AfterDays: 30, StorageClass: options.S3StorageClassStandardIA}}}},
+		{name: "StandardTransition", rule: options.RetentionRule{ID: "rule", Transitions: []options.RetentionTransition{{AfterDays: 30, StorageClass: options.S3StorageClassStandard}}}},
+		{name: "UnsupportedTransition", rule: options.RetentionRule{ID: "rule", Transitions: []options.RetentionTransition{{AfterDays: 30, StorageClass: "GLACIER"}}}},
+		{name: "EarlyIATransition", rule: options.RetentionRule{ID: "rule", Transitions: []options.RetentionTransition{{AfterDays: 29, StorageClass: options.S3StorageClassStandardIA}}}},
+		{name: "EarlyOneZoneIATransition", rule: options.RetentionRule{ID: "rule", Transitions: []options.RetentionTransition{{AfterDays: 7, StorageClass: options.S3StorageClassOneZoneIA}}}},
+		{name: "LongID", rule: options.RetentionRule{ID: strings.Repeat("a", 250), ExpireAfterDays: 30}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := ApplyRetention(ctx, opts, options.Retention{Rules: []options.RetentionRule{test.rule}})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid retention policy")
+		})
+	}
+	t.Run("DuplicateID", func(t *testing.T) {
+		rule := options.RetentionRule{ID: "rule", ExpireAfterDays: 30}
+		err := ApplyRetention(ctx, opts, options.Retention{Rules: []options.RetentionRule{rule, rule}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "duplicate rule ID")
+	})
+	t.Run("LocalBucket", func(t *testing.T) {
+		err := ApplyRetention(ctx, opts, options.Retention{Rules: []options.RetentionRule{
+			{ID: "rule", ExpireAfterDays: 30},
+			{ID: "tiered", Transitions: []options.RetentionTransition{{AfterDays: 1, StorageClass: options.S3StorageClassGlacierIR}}},
+		}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only supported by S3 buckets")
+	})
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// Scanner yields the lines of a log read from a reader, e.g. a ReadCloser,
+// whose chunks are concatenated, so that lines spanning two chunks, e.g.
+// of WriteBytes or FollowFile flushes, are yielded whole. Line endings,
+// "\n" or "\r\n", are stripped, and a final line without one is yielded.
+type Scanner struct {
+	r    *bufio.Reader
+	opts options.Scanner
+	line []byte
+	err  error
+}
+
+// NewScanner returns a scanner of the lines read from the reader.
+func NewScanner(r io.Reader, opts options.Scanner) (*Scanner, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid scanner options")
+	}
+
+	return &Scanner{r: bufio.NewReader(r), opts: opts}, nil
+}
+
+// Scan advances to the next line, returning false once all lines have been
+// read or an error occurs.
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	s.line = s.line[:0]
+	var truncated bool
+	for done := false; !done; {
+		frag, err := s.r.ReadSlice('\n')
+		if !truncated {
+			s.line = append(s.line, frag...)
+		}
+
+		switch err {
+		case nil:
+			done = true
+		case bufio.ErrBufferFull:
+			// Allow for the line ending, which is not yet read.
+			if len(s.line) > s.opts.MaxLineLength+len("\r\n") {
+				if !s.opts.Truncate {
+					s.err = s.tooLong()
+					return false
+				}
+				truncated = true
+			}
+		case io.EOF:
+			// Yield the final line, if any, and stop at the next call.
+			s.err = io.EOF
+			if len(s.line) == 0 {
+				return false
+			}
+			done = true
+		default:
+			s.err = errors.Wrap(err, "reading line")
+			return false
+		}
+	}
+
+	s.line = bytes.TrimSuffix(s.line, []byte("\n"))
+	s.line = bytes.TrimSuffix(s.line, []byte("\r"))
+	if len(s.line) > s.opts.MaxLineLength {
+		if !s.opts.Truncate {
+			s.err = s.tooLong()
+			return false
+		}
+		s.line = s.line[:s.opts.MaxLineLength]
+	}
+
+	return true
+}
+
+func (s *Scanner) tooLong() error {
+	return errors.Errorf("line exceeds max length of %d bytes", s.opts.MaxLineLength)
+}
+
+// Bytes returns the current line. The slice is only valid until the next
+// call to Scan.
+func (s *Scanner) Bytes() []byte { return s.line }
+
+// Text returns the current line.
+func (s *Scanner) Text() string { return string(s.line) }
+
+// Err returns the first error, other than io.EOF, encountered by the
+// scanner.
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+
+	return s.err
+}
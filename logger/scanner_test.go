@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner(t *testing.T) {
+	scan := func(t *testing.T, data string, opts options.Scanner) ([]string, error) {
+		s, err := NewScanner(strings.NewReader(data), opts)
+		require.NoError(t, err)
+		var lines []string
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		return lines, s.Err()
+	}
+
+	t.Run("AcrossChunks", func(t *testing.T) {
+		ctx := context.Background()
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("line0\nli")})
+		require.NoError(t, err)
+		_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("ne1\nline2")})
+		require.NoError(t, err)
+
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		defer r.Close()
+		s, err := NewScanner(r, options.Scanner{})
+		require.NoError(t, err)
+		var lines []string
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		require.NoError(t, s.Err())
+		assert.Equal(t, []string{"line0", "line1", "line2"}, lines)
+	})
+	t.Run("LineEndings", func(t *testing.T) {
+		lines, err := scan(t, "a\r\nb\n\nc\n", options.Scanner{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "", "c"}, lines)
+
+		lines, err = scan(t, "", options.Scanner{})
+		require.NoError(t, err)
+		assert.Empty(t, lines)
+	})
+	t.Run("TooLong", func(t *testing.T) {
+		lines, err := scan(t, "short\ntoo long\nshort\n", options.Scanner{MaxLineLength: 5})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"short"}, lines)
+
+		lines, err = scan(t, "12345\r\n", options.Scanner{MaxLineLength: 5})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"12345"}, lines, "line endings do not count toward the max length")
+	})
+	t.Run("Truncate", func(t *testing.T) {
+		lines, err := scan(t, "short\ntoo long\n", options.Scanner{MaxLineLength: 5, Truncate: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"short", "too l"}, lines)
+
+		// Lines longer than the read buffer are truncated as they are
+		// read.
+		long := strings.Repeat("x", 10000)
+		lines, err = scan(t, long+"\nnext\n", options.Scanner{MaxLineLength: 5000, Truncate: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{long[:5000], "next"}, lines)
+		_, err = scan(t, long+"\n", options.Scanner{MaxLineLength: 5000})
+		assert.Error(t, err)
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := NewScanner(strings.NewReader(""), options.Scanner{MaxLineLength: -1})
+		assert.Error(t, err)
+	})
+}
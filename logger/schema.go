@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// schemaKeyPrefix is the prefix, within the metadata bucket, under which
+// registered schemas are stored.
+const schemaKeyPrefix = "_schemas"
+
+// RegisterSchema registers a schema for all keys with the given prefix with
+// a schema-based encoding, e.g. Avro, and stores the schema in the metadata
+// bucket at "_schemas/<key prefix>.<schema extension>" so that downstream
+// consumers can read the chunks.
+func (l *bucketLogger) RegisterSchema(ctx context.Context, opts options.RegisterSchema) error {
+	if err := l.checkWritable("RegisterSchema"); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	e, err := l.getEncoding(opts.Encoding)
+	if err != nil {
+		return err
+	}
+	se, ok := e.(encode.SchemaEncoding)
+	if !ok {
+		return errors.Errorf("encoding '%s' does not support schemas", opts.Encoding)
+	}
+
+	if err = se.RegisterSchema(opts.KeyPrefix, opts.Schema); err != nil {
+		return errors.Wrap(err, "registering schema")
+	}
+
+	key := JoinKey(schemaKeyPrefix, opts.KeyPrefix, se.SchemaExtension())
+	return errors.Wrap(l.put(ctx, l.metaBucket, key, []byte(opts.Schema)), "uploading schema")
+}
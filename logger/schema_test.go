@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/encode/avro"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerRegisterSchema(t *testing.T) {
+	ctx := context.Background()
+	const schema = `{"type": "record", "name": "line", "fields": [{"name": "msg", "type": "string"}]}`
+
+	e := avro.NewEncoding()
+	registry := encode.NewEncodingRegistry()
+	require.NoError(t, registry.AddNew(e))
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithEncodingRegistry(registry))
+	require.NoError(t, err)
+
+	t.Run("InvalidOptions", func(t *testing.T) {
+		assert.Error(t, l.RegisterSchema(ctx, options.RegisterSchema{Encoding: avro.AVRO, Schema: schema}))
+		assert.Error(t, l.RegisterSchema(ctx, options.RegisterSchema{KeyPrefix: "logs", Encoding: "unknown", Schema: schema}))
+		assert.Error(t, l.RegisterSchema(ctx, options.RegisterSchema{KeyPrefix: "logs", Encoding: avro.AVRO, Schema: "not a schema"}))
+	})
+	t.Run("UnsupportedEncoding", func(t *testing.T) {
+		json, ok := encode.GetGlobalRegistry().Get(encode.JSON)
+		require.True(t, ok)
+		registry.Replace(json)
+		assert.Error(t, l.RegisterSchema(ctx, options.RegisterSchema{KeyPrefix: "logs", Encoding: encode.JSON, Schema: schema}))
+	})
+	t.Run("WriteWithSchema", func(t *testing.T) {
+		require.NoError(t, l.RegisterSchema(ctx, options.RegisterSchema{KeyPrefix: "logs", Encoding: avro.AVRO, Schema: schema}))
+
+		r, err := l.metaBucket.Get(ctx, "_schemas/logs.avsc")
+		require.NoError(t, err)
+		stored, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, schema, string(stored))
+
+		type line struct {
+			Msg string `json:"msg"`
+		}
+		require.NoError(t, l.Write(ctx, options.Write{Key: "logs/task", Data: []line{{Msg: "a"}}, Encoding: avro.AVRO}))
+		rc, err := l.NewReadCloser(ctx, options.Read{Key: "logs/task"})
+		require.NoError(t, err)
+		defer rc.Close()
+		data, err := rc.ReadPage()
+		require.NoError(t, err)
+
+		var out []map[string]interface{}
+		require.NoError(t, e.Unmarshal(data, &out))
+		assert.Equal(t, []map[string]interface{}{{"msg": "a"}}, out)
+	})
+}
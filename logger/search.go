@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip"
+)
+
+const defaultSearchConcurrency = 8
+
+// SearchResult is a log line matching a search.
+type SearchResult struct {
+	// Chunk is the key of the chunk containing the line.
+	Chunk string
+	// Line is the zero-based position of the line within the chunk.
+	Line int
+	LogLine
+}
+
+// Search scans the chunks of a key concurrently and returns the log lines
+// whose data matches the search's regular expression, in log order. Chunks
+// whose manifest entry lies entirely outside of the search's time range are
+// skipped without being downloaded.
+func (l *bucketLogger) Search(ctx context.Context, opts options.Search) ([]SearchResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if err := l.audit(ctx, AuditRead, opts.Key); err != nil {
+		return nil, err
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = defaultSearchConcurrency
+	}
+	re := regexp.MustCompile(opts.Regexp)
+
+	keys, etags, err := l.searchKeys(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		results []SearchResult
+		wg      sync.WaitGroup
+	)
+	catcher := grip.NewBasicCatcher()
+	work := make(chan string)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				matches, err := l.searchChunk(ctx, key, etags[key], re, opts)
+				mu.Lock()
+				catcher.Add(err)
+				results = append(results, matches...)
+				mu.Unlock()
+				if err != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+sendLoop:
+	for _, key := range keys {
+		select {
+		case work <- key:
+		case <-ctx.Done():
+			break sendLoop
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if catcher.HasErrors() {
+		return nil, catcher.Resolve()
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Chunk != results[j].Chunk {
+			return results[i].Chunk < results[j].Chunk
+		}
+		return results[i].Line < results[j].Line
+	})
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	return results, nil
+}
+
+// searchKeys returns the keys, and ETags where known, of the chunks that may
+// contain matches.
+func (l *bucketLogger) searchKeys(ctx context.Context, opts options.Search) ([]string, map[string]string, error) {
+	r := &bucketReader{ctx: ctx, bucket: l.logsBucket}
+	if err := r.getAndSortKeys(opts.Key, false); err != nil {
+		return nil, nil, err
+	}
+	if opts.Start.IsZero() && opts.End.IsZero() {
+		return r.keys, r.etags, nil
+	}
+
+	entries, err := l.Manifest(ctx, opts.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	entriesByKey := make(map[string]ManifestEntry, len(entries))
+	for _, entry := range entries {
+		entriesByKey[entry.Key] = entry
+	}
+
+	var keys []string
+	for _, key := range r.keys {
+		if entry, ok := entriesByKey[key]; ok {
+			if !opts.Start.IsZero() && entry.End.Before(opts.Start) {
+				continue
+			}
+			if !opts.End.IsZero() && !entry.Start.Before(opts.End) {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, r.etags, nil
+}
+
+func (l *bucketLogger) searchChunk(ctx context.Context, key, etag string, re *regexp.Regexp, opts options.Search) ([]SearchResult, error) {
+	lines, err := l.readChunkLines(ctx, key, etag)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for i, line := range lines {
+		if !line.Timestamp.IsZero() {
+			if !opts.Start.IsZero() && line.Timestamp.Before(opts.Start) {
+				continue
+			}
+			if !opts.End.IsZero() && !line.Timestamp.Before(opts.End) {
+				continue
+			}
+		}
+		if re.MatchString(fmt.Sprint(line.Data)) {
+			results = append(results, SearchResult{Chunk: key, Line: i, LogLine: line})
+		}
+	}
+
+	return results, nil
+}
+
+// readChunkLines downloads and decodes the log lines of a single chunk with
+// the given ETag, which may be empty if unknown.
+func (l *bucketLogger) readChunkLines(ctx context.Context, key, etag string) ([]LogLine, error) {
+	data, err := l.logsChunkReader().get(ctx, key, etag)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeLines(l.encodingRegistry, key, data)
+}
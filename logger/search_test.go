@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerSearch(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+
+	start := time.Unix(1000, 0).UTC()
+	for i := 0; i < 5; i++ {
+		ts := start.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []LogLine{
+			{Timestamp: ts, Data: "match"},
+			{Timestamp: ts.Add(time.Second), Data: "other"},
+		}, Encoding: encode.JSON}))
+	}
+
+	t.Run("InLogOrder", func(t *testing.T) {
+		results, err := l.Search(ctx, options.Search{Key: "key", Regexp: "^mat", Concurrency: 2})
+		require.NoError(t, err)
+		require.Len(t, results, 5)
+		for i, result := range results {
+			assert.Equal(t, "match", result.Data)
+			assert.Zero(t, result.Line)
+			assert.True(t, start.Add(time.Duration(i)*time.Minute).Equal(result.Timestamp))
+		}
+	})
+	t.Run("Limit", func(t *testing.T) {
+		results, err := l.Search(ctx, options.Search{Key: "key", Regexp: "other", Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, 1, results[0].Line)
+		assert.True(t, start.Add(time.Second).Equal(results[0].Timestamp))
+	})
+	t.Run("TimeRange", func(t *testing.T) {
+		results, err := l.Search(ctx, options.Search{Key: "key", Regexp: ".", Start: start.Add(time.Minute + time.Second), End: start.Add(3 * time.Minute)})
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, "other", results[0].Data)
+		assert.Equal(t, "match", results[1].Data)
+		assert.Equal(t, "other", results[2].Data)
+	})
+	t.Run("SkipsChunksOutsideTimeRange", func(t *testing.T) {
+		keys, _, err := l.searchKeys(ctx, options.Search{Key: "key", Start: start.Add(4 * time.Minute)})
+		require.NoError(t, err)
+		assert.Len(t, keys, 1)
+	})
+	t.Run("UndecodableChunk", func(t *testing.T) {
+		require.NoError(t, l.logsBucket.Put(ctx, "bad/chunk.unknown", bytes.NewReader([]byte("data"))))
+		_, err := l.Search(ctx, options.Search{Key: "bad", Regexp: "."})
+		assert.Error(t, err)
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := l.Search(ctx, options.Search{Key: "key", Regexp: "("})
+		assert.Error(t, err)
+		_, err = l.Search(ctx, options.Search{Key: "key", Regexp: ".", Start: start, End: start})
+		assert.Error(t, err)
+	})
+}
@@ -2,11 +2,14 @@ package logger
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/julianedwards/cedar/encode"
 	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip"
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
 	"github.com/mongodb/grip/send"
@@ -22,6 +25,22 @@ type sender struct {
 	lastFlush  time.Time
 	timer      *time.Timer
 	closed     bool
+	now        func() time.Time
+	overflow   *overflowQueue
+	wal        *writeAheadLog
+	// seq is the sequence number of the last line sent and seqSet whether
+	// it was set by WithSenderStartSequence.
+	seq    uint64
+	seqSet bool
+	// attrs, if set, are the enrichment attributes shared by every line.
+	attrs          map[string]interface{}
+	traceExtractor TraceExtractor
+	redactor       *Redactor
+	middleware     []Middleware
+	gauges         senderGauges
+	// onSend, if set, is called with every message sent, e.g. so that a
+	// manager knows the sender is in use.
+	onSend func()
 
 	opts options.Sender
 	l    Logger
@@ -29,11 +48,17 @@ type sender struct {
 	*send.Base
 }
 
-func NewSender(ctx context.Context, l Logger, opts options.Sender) (*sender, error) {
+func NewSender(ctx context.Context, l Logger, opts options.Sender, senderOpts ...SenderOption) (*sender, error) {
+	if err := options.ValidateTags(opts.Tags); err != nil {
+		return nil, errors.Wrap(err, "invalid tags")
+	}
+
 	s := &sender{
-		opts: opts,
-		l:    l,
-		Base: send.NewBase(opts.Key),
+		opts:   opts,
+		l:      l,
+		now:    time.Now,
+		buffer: getLogLines(),
+		Base:   send.NewBase(opts.Key),
 	}
 
 	if err := s.SetErrorHandler(send.ErrorHandlerFromSender(opts.Local)); err != nil {
@@ -46,6 +71,19 @@ func NewSender(ctx context.Context, l Logger, opts options.Sender) (*sender, err
 		}
 	}
 
+	for _, opt := range senderOpts {
+		if err := opt(s); err != nil {
+			return nil, errors.Wrap(err, "applying sender option")
+		}
+	}
+	if r, ok := l.(sequenceResumer); ok && !s.seqSet {
+		last, err := r.lastSequence(ctx, opts.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting the last sequence number of '%s'", opts.Key)
+		}
+		s.seq = last
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	s.ctx = ctx
 	s.cancel = cancel
@@ -53,36 +91,111 @@ func NewSender(ctx context.Context, l Logger, opts options.Sender) (*sender, err
 	if s.opts.MaxBufferSize <= 0 {
 		s.opts.MaxBufferSize = defaultMaxBufferSize
 	}
+	if s.opts.OverflowDir != "" {
+		if s.opts.MaxOverflowSize <= 0 {
+			s.opts.MaxOverflowSize = defaultMaxOverflowSize
+		}
+		overflow, err := newOverflowQueue(s.opts.OverflowDir, s.opts.Key, s.opts.MaxOverflowSize)
+		if err != nil {
+			cancel()
+			return nil, errors.Wrap(err, "creating overflow queue")
+		}
+		s.overflow = overflow
+	}
+	if s.opts.WALDir != "" {
+		wal, err := newWriteAheadLog(s.opts.WALDir, s.opts.Key, s.now())
+		if err != nil {
+			cancel()
+			return nil, errors.Wrap(err, "creating write-ahead log")
+		}
+		s.wal = wal
+	}
+	if s.opts.Enrich {
+		s.attrs = sourceAttrs(s.opts.EnrichFields)
+	}
 	if s.opts.FlushInterval > 0 {
 		go s.timedFlush()
 	}
+	registerSender(s)
 
 	return s, nil
 }
 
+// Send buffers the message as a log line. Trace and span IDs are taken from
+// the TraceIDKey and SpanIDKey fields of structured messages or annotations
+// of the message, if any.
 func (s *sender) Send(m message.Composer) {
+	s.send(m, "", "")
+}
+
+// SendContext is the same as Send but takes the trace and span IDs of the
+// line from the context, using the sender's trace extractor, if the message
+// does not have them.
+func (s *sender) SendContext(ctx context.Context, m message.Composer) {
+	var traceID, spanID string
+	if s.traceExtractor != nil {
+		traceID, spanID = s.traceExtractor(ctx)
+	}
+
+	s.send(m, traceID, spanID)
+}
+
+func (s *sender) send(m message.Composer, traceID, spanID string) {
+	if s.onSend != nil {
+		s.onSend()
+	}
 	if !s.Level().ShouldLog(m) {
 		return
 	}
+	if id, ok := messageField(m, TraceIDKey); ok {
+		traceID = id
+	}
+	if id, ok := messageField(m, SpanIDKey); ok {
+		spanID = id
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.updateGauges()
 
 	if s.closed {
 		s.opts.Local.Send(message.NewErrorMessage(level.Error, errors.New("cannot call Send on a closed bucket logger Sender")))
 		return
 	}
 
-	s.buffer = append(s.buffer, LogLine{
-		Timestamp:      time.Now(),
+	line, ok := applyMiddleware(s.middleware, LogLine{
+		Timestamp:      s.now(),
+		Attrs:          s.attrs,
+		TraceID:        traceID,
+		SpanID:         spanID,
 		Priority:       m.Priority(),
 		PriorityString: m.Priority().String(),
 		Data:           m.Raw(),
 	})
+	if !ok {
+		return
+	}
+	// Sequence numbers are assigned after the middleware so that dropped
+	// lines do not leave gaps.
+	s.seq++
+	line.Sequence = s.seq
+	if s.redactor != nil {
+		line = s.redactor.RedactLine(line)
+	}
+	if s.wal != nil {
+		if err := s.wal.append(line); err != nil {
+			s.opts.Local.Send(message.NewErrorMessage(level.Error, err))
+		}
+	}
+	s.buffer = append(s.buffer, line)
 	s.bufferSize += len(m.String())
 	if s.bufferSize >= s.opts.MaxBufferSize {
+		defer s.trackFlush()()
 		if err := s.flush(s.ctx); err != nil {
 			s.opts.Local.Send(message.NewErrorMessage(level.Error, err))
+			if err = s.spill(); err != nil {
+				s.opts.Local.Send(message.NewErrorMessage(level.Error, err))
+			}
 			return
 		}
 	}
@@ -90,8 +203,10 @@ func (s *sender) Send(m message.Composer) {
 
 // Flush flushes anything data that may be in the buffer to bucket storage.
 func (s *sender) Flush(ctx context.Context) error {
+	defer s.trackFlush()()
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.updateGauges()
 
 	if s.closed {
 		return nil
@@ -101,29 +216,82 @@ func (s *sender) Flush(ctx context.Context) error {
 }
 
 // Close flushes anything that may be left in the underlying buffer and cleans
-// up resources as necessary. Close is thread safe but should only be called
-// once no more calls to Send are needed; after Close has been called any
-// subsequent calls to Send will error. After the first call to Close
-// subsequent calls will no-op.
+// up resources as necessary, giving the final flush up to a minute to
+// complete. Close is thread safe but should only be called once no more
+// calls to Send are needed; after Close has been called any subsequent calls
+// to Send will error. After the first call to Close subsequent calls will
+// no-op.
 func (s *sender) Close() error {
+	return s.CloseWithTimeout(defaultCloseTimeout)
+}
+
+// CloseWithTimeout is like Close, but gives the final flush up to the given
+// duration to complete.
+func (s *sender) CloseWithTimeout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return s.CloseContext(ctx)
+}
+
+// CloseContext is like Close, but flushes with the given context, which is
+// independent of the context the sender was created with. It returns all
+// errors encountered while flushing any buffered and spilled lines and
+// releasing resources.
+func (s *sender) CloseContext(ctx context.Context) error {
+	defer s.trackFlush()()
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	defer s.cancel()
+	defer s.updateGauges()
 
 	if s.closed {
 		return nil
 	}
 	s.closed = true
+	s.cancel()
+	unregisterSender(s)
 
-	if len(s.buffer) > 0 {
-		if err := s.flush(s.ctx); err != nil {
+	catcher := grip.NewBasicCatcher()
+	if s.pending() {
+		if err := s.flush(ctx); err != nil {
 			s.opts.Local.Send(message.NewErrorMessage(level.Error, err))
-			return errors.Wrap(err, "flushing buffer")
+			catcher.Wrap(err, "flushing buffer")
 		}
 	}
+	if !catcher.HasErrors() {
+		putLogLines(s.buffer)
+		s.buffer = nil
+	}
+	if s.wal != nil {
+		catcher.Wrap(s.wal.close(), "closing write-ahead log")
+	}
 
-	return nil
+	return catcher.Resolve()
+}
+
+// Recover uploads the lines left in the write-ahead log directory by
+// previous senders, e.g. ones whose process was killed before they could
+// flush their buffer. Lines of other live senders sharing the directory are
+// left to them. It is a no-op if the sender has no write-ahead log.
+func (s *sender) Recover(ctx context.Context) error {
+	if s.wal == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.updateGauges()
+
+	return errors.Wrap(s.wal.recover(func(lines []LogLine) error {
+		// Continue numbering after the recovered lines, which were sent
+		// by a previous sender for the same key.
+		for _, line := range lines {
+			if line.Sequence > s.seq {
+				s.seq = line.Sequence
+			}
+		}
+		return s.write(ctx, lines)
+	}), "recovering write-ahead log")
 }
 
 func (s *sender) timedFlush() {
@@ -138,10 +306,13 @@ func (s *sender) timedFlush() {
 			return
 		case <-s.timer.C:
 			s.mu.Lock()
-			if len(s.buffer) > 0 && time.Since(s.lastFlush) >= s.opts.FlushInterval {
+			if s.pending() && s.now().Sub(s.lastFlush) >= s.opts.FlushInterval {
+				done := s.trackFlush()
 				if err := s.flush(s.ctx); err != nil {
 					s.opts.Local.Send(message.NewErrorMessage(level.Error, err))
 				}
+				done()
+				s.updateGauges()
 			}
 			_ = s.timer.Reset(s.opts.FlushInterval)
 			s.mu.Unlock()
@@ -150,18 +321,119 @@ func (s *sender) timedFlush() {
 }
 
 func (s *sender) flush(ctx context.Context) error {
-	err := s.l.Write(s.ctx, options.Write{
+	if s.opts.FlushTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.FlushTimeout)
+		defer cancel()
+	}
+
+	if s.overflow != nil {
+		if err := s.overflow.drain(func(lines []LogLine) error { return s.write(ctx, lines) }); err != nil {
+			return errors.Wrap(err, "flushing overflow queue")
+		}
+	}
+	if len(s.buffer) == 0 {
+		return nil
+	}
+
+	if err := s.write(ctx, s.buffer); err != nil {
+		return err
+	}
+	s.resetBuffer()
+	s.lastFlush = s.now()
+
+	return nil
+}
+
+// pending returns whether there are buffered or spilled lines to flush.
+func (s *sender) pending() bool {
+	return len(s.buffer) > 0 || (s.overflow != nil && len(s.overflow.segments) > 0)
+}
+
+func (s *sender) write(ctx context.Context, lines []LogLine) error {
+	opts := options.Write{
 		Key:      s.opts.Key,
-		Data:     s.buffer,
+		Data:     lines,
 		Encoding: encode.JSON,
-	})
+		Tags:     s.opts.Tags,
+	}
+	cw, ok := s.l.(chunkWriter)
+	if s.opts.OnFlush == nil || !ok {
+		return s.l.Write(ctx, opts)
+	}
+
+	start := time.Now()
+	chunks, err := cw.writeWithChunks(ctx, opts)
 	if err != nil {
 		return err
 	}
+	duration := time.Since(start)
+	for _, chunk := range chunks {
+		s.opts.OnFlush(options.FlushInfo{
+			Key:      chunk.Key,
+			Size:     chunk.Size,
+			Lines:    chunk.Lines,
+			Duration: duration,
+		})
+	}
 
-	s.buffer = []LogLine{}
-	s.bufferSize = 0
-	s.lastFlush = time.Now()
+	return nil
+}
+
+// chunkWriter is implemented by loggers that return the manifest entries of
+// the chunks uploaded by a write, which senders report to OnFlush.
+type chunkWriter interface {
+	writeWithChunks(context.Context, options.Write) ([]ManifestEntry, error)
+}
+
+// spill moves the buffered lines to the overflow queue, if any, unless the
+// queue is full.
+func (s *sender) spill() error {
+	if s.overflow == nil {
+		return nil
+	}
+
+	ok, err := s.overflow.push(s.buffer)
+	if err != nil {
+		return errors.Wrap(err, "spilling buffer to overflow queue")
+	}
+	if ok {
+		s.resetBuffer()
+	}
 
 	return nil
 }
+
+// resetBuffer returns the buffer to the pool for reuse by this or any other
+// sender and empties the write-ahead log. Loggers do not retain the data
+// passed to Write and the overflow queue encodes the lines it spills, so the
+// buffer is free once flushed or spilled.
+func (s *sender) resetBuffer() {
+	putLogLines(s.buffer)
+	s.buffer = getLogLines()
+	s.bufferSize = 0
+
+	if s.wal != nil {
+		if err := s.wal.truncate(); err != nil {
+			s.opts.Local.Send(message.NewErrorMessage(level.Error, err))
+		}
+	}
+}
+
+// sourceAttrs returns the attributes identifying the current process,
+// overridden by the given fields. The returned map is shared by every line
+// and must not be modified.
+func sourceAttrs(fields map[string]interface{}) map[string]interface{} {
+	attrs := map[string]interface{}{
+		"pid":     os.Getpid(),
+		"process": filepath.Base(os.Args[0]),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs["hostname"] = hostname
+	}
+	for k, v := range fields {
+		attrs[k] = v
+	}
+
+	return attrs
+}
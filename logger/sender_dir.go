@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// senderDirKeyFile is the file recording the key of the sender that a local
+// sender directory, i.e. an overflow or write-ahead log directory, belongs
+// to.
+const senderDirKeyFile = "KEY"
+
+// claimSenderDir creates the directory if it does not exist and records
+// that it belongs to the sender for the given key. It returns an error if
+// the directory belongs to a sender for a different key, since the lines
+// left in it would otherwise be uploaded under the wrong key.
+func claimSenderDir(dir, key string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "creating directory '%s'", dir)
+	}
+
+	fn := filepath.Join(dir, senderDirKeyFile)
+	tmp, err := os.CreateTemp(dir, senderDirKeyFile+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "creating key file in '%s'", dir)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(key)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "writing key file in '%s'", dir)
+	}
+	// Linking fails if the key file exists, so that concurrent claims
+	// cannot overwrite each other.
+	if err = os.Link(tmp.Name(), fn); err == nil || !os.IsExist(err) {
+		return errors.Wrapf(err, "creating key file '%s'", fn)
+	}
+
+	owner, err := os.ReadFile(fn)
+	if err != nil {
+		return errors.Wrapf(err, "reading key file '%s'", fn)
+	}
+	if string(owner) != key {
+		return errors.Errorf("directory '%s' belongs to the sender for key '%s'", dir, owner)
+	}
+
+	return nil
+}
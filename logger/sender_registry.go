@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mongodb/grip"
+)
+
+// liveSenders is the process-wide set of senders that have not been closed.
+var liveSenders = struct {
+	mu      sync.Mutex
+	senders map[*sender]struct{}
+}{senders: map[*sender]struct{}{}}
+
+func registerSender(s *sender) {
+	liveSenders.mu.Lock()
+	defer liveSenders.mu.Unlock()
+
+	liveSenders.senders[s] = struct{}{}
+}
+
+func unregisterSender(s *sender) {
+	liveSenders.mu.Lock()
+	defer liveSenders.mu.Unlock()
+
+	delete(liveSenders.senders, s)
+}
+
+// eachLiveSender runs the function concurrently for every sender that has
+// not been closed, returning all errors.
+func eachLiveSender(fn func(*sender) error) error {
+	liveSenders.mu.Lock()
+	senders := make([]*sender, 0, len(liveSenders.senders))
+	for s := range liveSenders.senders {
+		senders = append(senders, s)
+	}
+	liveSenders.mu.Unlock()
+
+	var wg sync.WaitGroup
+	catcher := grip.NewBasicCatcher()
+	for _, s := range senders {
+		wg.Add(1)
+		go func(s *sender) {
+			defer wg.Done()
+			catcher.Wrapf(fn(s), "sender '%s'", s.Name())
+		}(s)
+	}
+	wg.Wait()
+
+	return catcher.Resolve()
+}
+
+// FlushAll flushes every sender in the process that has not been closed,
+// so that shutdown paths can persist all buffered lines without tracking
+// each sender.
+func FlushAll(ctx context.Context) error {
+	return eachLiveSender(func(s *sender) error { return s.Flush(ctx) })
+}
+
+// CloseAll closes every sender in the process that has not been closed,
+// flushing their buffered lines with the given context.
+func CloseAll(ctx context.Context) error {
+	return eachLiveSender(func(s *sender) error { return s.CloseContext(ctx) })
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlushAllCloseAll(t *testing.T) {
+	ctx := context.Background()
+	// Isolate the registry from senders left open by other tests.
+	liveSenders.mu.Lock()
+	saved := liveSenders.senders
+	liveSenders.senders = map[*sender]struct{}{}
+	liveSenders.mu.Unlock()
+	defer func() {
+		liveSenders.mu.Lock()
+		liveSenders.senders = saved
+		liveSenders.mu.Unlock()
+	}()
+
+	loggers := []*copyingLogger{{}, {}}
+	senders := make([]*sender, len(loggers))
+	for i, l := range loggers {
+		s, err := NewSender(ctx, l, options.Sender{
+			Key:           "key",
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+			FlushInterval: -1,
+		})
+		require.NoError(t, err)
+		senders[i] = s
+	}
+	require.NoError(t, senders[1].Close())
+	assert.Len(t, liveSenders.senders, 1, "closed senders are unregistered")
+
+	senders[0].Send(message.NewDefaultMessage(level.Info, "flushed"))
+	require.NoError(t, FlushAll(ctx))
+	require.Len(t, loggers[0].writes, 1)
+	assert.Empty(t, loggers[1].writes)
+
+	senders[0].Send(message.NewDefaultMessage(level.Info, "closed"))
+	require.NoError(t, CloseAll(ctx))
+	assert.Len(t, loggers[0].writes, 2)
+	assert.True(t, senders[0].closed)
+	assert.Empty(t, liveSenders.senders)
+
+	loggers[0].err = assert.AnError
+	s, err := NewSender(ctx, loggers[0], options.Sender{Key: "key", Local: send.MakeInternalLogger(), FlushInterval: -1})
+	require.NoError(t, err)
+	s.Send(message.NewDefaultMessage(level.Info, "failed"))
+	assert.Error(t, FlushAll(ctx))
+	require.Error(t, CloseAll(ctx))
+}
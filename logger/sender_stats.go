@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SenderUtilization is a point-in-time snapshot of a sender's buffer, so
+// that applications can slow down or alert when their logs are produced
+// faster than they can be flushed.
+type SenderUtilization struct {
+	// BufferedBytes and BufferedLines are the size and number of the
+	// lines waiting to be flushed.
+	BufferedBytes int
+	BufferedLines int
+	// MaxBufferSize is the buffer size at which the sender flushes.
+	MaxBufferSize int
+	// Percent is BufferedBytes as a percentage of MaxBufferSize. It may
+	// exceed 100 while flushes are failing.
+	Percent float64
+	// PendingFlushes is the number of flushes in progress or waiting for
+	// another flush to finish.
+	PendingFlushes int
+	// OverflowBytes is the size of the lines spilled to the overflow
+	// directory that have not yet been flushed.
+	OverflowBytes int64
+	// LastFlush is the time of the last successful flush, if any.
+	LastFlush time.Time
+}
+
+// senderGauges tracks the state of a sender's buffer. The gauges are
+// updated while the sender's lock is held and may be read concurrently,
+// e.g. while a slow flush holds the lock.
+type senderGauges struct {
+	bufferedBytes  int64
+	bufferedLines  int64
+	overflowBytes  int64
+	pendingFlushes int64
+	lastFlush      int64
+}
+
+// Utilization returns the current utilization of the sender's buffer. It
+// does not block on flushes in progress.
+func (s *sender) Utilization() SenderUtilization {
+	u := SenderUtilization{
+		BufferedBytes:  int(atomic.LoadInt64(&s.gauges.bufferedBytes)),
+		BufferedLines:  int(atomic.LoadInt64(&s.gauges.bufferedLines)),
+		MaxBufferSize:  s.opts.MaxBufferSize,
+		PendingFlushes: int(atomic.LoadInt64(&s.gauges.pendingFlushes)),
+		OverflowBytes:  atomic.LoadInt64(&s.gauges.overflowBytes),
+	}
+	if u.MaxBufferSize > 0 {
+		u.Percent = 100 * float64(u.BufferedBytes) / float64(u.MaxBufferSize)
+	}
+	if lastFlush := atomic.LoadInt64(&s.gauges.lastFlush); lastFlush > 0 {
+		u.LastFlush = time.Unix(0, lastFlush)
+	}
+
+	return u
+}
+
+// updateGauges records the current state of the buffer. The sender's lock
+// must be held.
+func (s *sender) updateGauges() {
+	atomic.StoreInt64(&s.gauges.bufferedBytes, int64(s.bufferSize))
+	atomic.StoreInt64(&s.gauges.bufferedLines, int64(len(s.buffer)))
+	if s.overflow != nil {
+		atomic.StoreInt64(&s.gauges.overflowBytes, s.overflow.size)
+	}
+	if !s.lastFlush.IsZero() {
+		atomic.StoreInt64(&s.gauges.lastFlush, s.lastFlush.UnixNano())
+	}
+}
+
+// trackFlush counts a flush as pending until the returned function is
+// called.
+func (s *sender) trackFlush() func() {
+	atomic.AddInt64(&s.gauges.pendingFlushes, 1)
+	return func() { atomic.AddInt64(&s.gauges.pendingFlushes, -1) }
+}
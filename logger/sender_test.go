@@ -0,0 +1,620 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyingLogger records a copy of the lines of each write, since the sender
+// reuses its buffer once Write returns, unless err is set, in which case
+// writes fail.
+type copyingLogger struct {
+	Logger
+	writes [][]LogLine
+	err    error
+}
+
+func (l *copyingLogger) Write(_ context.Context, opts options.Write) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.writes = append(l.writes, append([]LogLine(nil), opts.Data.([]LogLine)...))
+	return nil
+}
+
+func TestSenderReusesBuffer(t *testing.T) {
+	ctx := context.Background()
+	l := &copyingLogger{}
+	s, err := NewSender(ctx, l, options.Sender{
+		Key:           "key",
+		Local:         send.MakeInternalLogger(),
+		LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+		FlushInterval: -1,
+	})
+	require.NoError(t, err)
+
+	s.Send(message.NewDefaultMessage(level.Info, "first"))
+	require.NoError(t, s.Flush(ctx))
+	assert.Empty(t, s.buffer)
+	assert.NotZero(t, cap(s.buffer))
+	s.Send(message.NewDefaultMessage(level.Info, "second"))
+	require.NoError(t, s.Close())
+
+	require.Len(t, l.writes, 2)
+	for i, data := range []string{"first", "second"} {
+		require.Len(t, l.writes[i], 1)
+		msg, ok := l.writes[i][0].Data.(message.Composer)
+		require.True(t, ok)
+		assert.Equal(t, data, msg.String())
+	}
+}
+
+func TestLogLinePool(t *testing.T) {
+	lines := getLogLines()
+	lines = append(lines, LogLine{Data: "data"})
+	putLogLines(lines)
+
+	reused := getLogLines()
+	assert.Empty(t, reused)
+	for _, line := range reused[:cap(reused)] {
+		assert.Nil(t, line.Data)
+	}
+}
+
+func BenchmarkSenderSend(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l, err := NewBucketLogger(ctx, options.Bucket{
+		Type:   options.PailLocal,
+		Name:   b.TempDir(),
+		Prefix: "bench",
+	})
+	require.NoError(b, err)
+
+	s, err := NewSender(ctx, l, options.Sender{
+		Key:           "bench",
+		Local:         send.MakeInternalLogger(),
+		LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+		MaxBufferSize: 1 << 20,
+		FlushInterval: -1,
+	})
+	require.NoError(b, err)
+
+	line := strings.Repeat("x", 256)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, line))
+	}
+	require.NoError(b, s.Close())
+}
+
+func TestSenderDirs(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name string
+		opt  func(dir string) SenderOption
+	}{
+		{name: "Overflow", opt: func(dir string) SenderOption { return WithSenderOverflow(dir, 0) }},
+		{name: "WAL", opt: WithSenderWAL},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			newSender := func(dir, key string) (*sender, error) {
+				return NewSender(ctx, &copyingLogger{}, options.Sender{
+					Key:           key,
+					Local:         send.MakeInternalLogger(),
+					FlushInterval: -1,
+				}, test.opt(dir))
+			}
+
+			t.Run("SameKey", func(t *testing.T) {
+				dir := t.TempDir()
+				for i := 0; i < 2; i++ {
+					s, err := newSender(dir, "key")
+					require.NoError(t, err)
+					require.NoError(t, s.Close())
+				}
+			})
+			t.Run("OtherKey", func(t *testing.T) {
+				dir := t.TempDir()
+				s, err := newSender(dir, "key")
+				require.NoError(t, err)
+				require.NoError(t, s.Close())
+
+				_, err = newSender(dir, "other")
+				assert.Error(t, err)
+			})
+		})
+	}
+}
+
+func TestSenderOverflowReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	newSender := func(l Logger, maxOverflowSize int64) *sender {
+		s, err := NewSender(ctx, l, options.Sender{
+			Key:           "key",
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+			MaxBufferSize: 1,
+			FlushInterval: -1,
+		}, WithSenderOverflow(dir, maxOverflowSize))
+		require.NoError(t, err)
+		return s
+	}
+
+	failing := newSender(&copyingLogger{err: errors.New("write failed")}, 0)
+	failing.Send(message.NewDefaultMessage(level.Info, "spilled"))
+	require.Len(t, failing.overflow.segments, 1)
+	assert.Empty(t, failing.buffer)
+	assert.Error(t, failing.Close())
+
+	l := &copyingLogger{}
+	s := newSender(l, 0)
+	require.NoError(t, s.Flush(ctx))
+	require.Len(t, l.writes, 1)
+	require.Len(t, l.writes[0], 1)
+	data, ok := l.writes[0][0].Data.(map[string]interface{})
+	require.True(t, ok, "spilled lines are replayed as decoded JSON")
+	assert.Equal(t, "spilled", data["message"])
+	assert.Empty(t, s.overflow.segments)
+	require.NoError(t, s.Close())
+
+	t.Run("Full", func(t *testing.T) {
+		full := newSender(&copyingLogger{err: errors.New("write failed")}, 1)
+		full.Send(message.NewDefaultMessage(level.Info, "kept"))
+		assert.Empty(t, full.overflow.segments)
+		assert.Len(t, full.buffer, 1, "the buffer grows once the queue is full")
+	})
+}
+
+func TestSenderWALRecover(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	newSender := func(l Logger) *sender {
+		s, err := NewSender(ctx, l, options.Sender{
+			Key:           "key",
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+			FlushInterval: -1,
+		}, WithSenderWAL(dir))
+		require.NoError(t, err)
+		return s
+	}
+
+	// The first sender is never flushed or closed, as if its process had
+	// been killed, which closes its segment and releases its lock.
+	crashed := newSender(&copyingLogger{})
+	crashed.Send(message.NewDefaultMessage(level.Info, "first"))
+	crashed.Send(message.NewDefaultMessage(level.Info, "second"))
+	require.NoError(t, crashed.wal.file.Close())
+	// The segments of live senders are not recovered.
+	live := newSender(&copyingLogger{})
+	live.Send(message.NewDefaultMessage(level.Info, "live"))
+
+	l := &copyingLogger{}
+	s := newSender(l)
+	require.NoError(t, s.Recover(ctx))
+	require.Len(t, l.writes, 1)
+	require.Len(t, l.writes[0], 2)
+	for i, msg := range []string{"first", "second"} {
+		data, ok := l.writes[0][i].Data.(map[string]interface{})
+		require.True(t, ok, "recovered lines are decoded JSON")
+		assert.Equal(t, msg, data["message"])
+		assert.EqualValues(t, i+1, l.writes[0][i].Sequence)
+	}
+
+	// Recovered lines are removed from the log, so lines sent after
+	// recovery are not uploaded twice, and lines sent after recovery
+	// continue the recovered sequence.
+	s.Send(message.NewDefaultMessage(level.Info, "third"))
+	require.NoError(t, s.Close())
+	require.Len(t, l.writes, 2)
+	require.Len(t, l.writes[1], 1)
+	assert.EqualValues(t, 3, l.writes[1][0].Sequence)
+
+	require.NoError(t, live.Close())
+	require.Len(t, live.l.(*copyingLogger).writes, 1, "the live sender's lines are only written by the live sender")
+
+	recovered := newSender(&copyingLogger{})
+	require.NoError(t, recovered.Recover(ctx))
+	assert.Empty(t, recovered.l.(*copyingLogger).writes)
+}
+
+// blockingLogger blocks each write until its context is done.
+type blockingLogger struct {
+	Logger
+}
+
+func (l *blockingLogger) Write(ctx context.Context, _ options.Write) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSenderClose(t *testing.T) {
+	newSender := func(ctx context.Context, l Logger) *sender {
+		s, err := NewSender(ctx, l, options.Sender{
+			Key:           "key",
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+			FlushInterval: -1,
+		})
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("CanceledSenderContext", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		l := &copyingLogger{}
+		s := newSender(ctx, l)
+		s.Send(message.NewDefaultMessage(level.Info, "message"))
+		cancel()
+
+		require.NoError(t, s.CloseContext(context.Background()))
+		require.Len(t, l.writes, 1)
+		assert.Len(t, l.writes[0], 1)
+		assert.NoError(t, s.CloseContext(context.Background()), "subsequent calls no-op")
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		s := newSender(context.Background(), &blockingLogger{})
+		s.Send(message.NewDefaultMessage(level.Info, "message"))
+
+		start := time.Now()
+		assert.Error(t, s.CloseWithTimeout(10*time.Millisecond))
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+}
+
+func TestSenderFlushTimeout(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSender(ctx, &blockingLogger{}, options.Sender{
+		Key:           "key",
+		Local:         send.MakeInternalLogger(),
+		LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+		FlushInterval: -1,
+	}, WithSenderFlushTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+
+	s.Send(message.NewDefaultMessage(level.Info, "message"))
+	assert.Error(t, s.Flush(ctx))
+	assert.Len(t, s.buffer, 1, "lines that fail to flush remain buffered")
+
+	_, err = NewSender(ctx, &copyingLogger{}, options.Sender{Key: "key", Local: send.MakeInternalLogger()}, WithSenderFlushTimeout(-time.Second))
+	assert.Error(t, err)
+}
+
+func TestSenderSequence(t *testing.T) {
+	ctx := context.Background()
+	newSender := func(t *testing.T, l Logger, opts ...SenderOption) *sender {
+		s, err := NewSender(ctx, l, options.Sender{
+			Key:           "key",
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+			FlushInterval: -1,
+		}, opts...)
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		l := &copyingLogger{}
+		s := newSender(t, l)
+		for i := 0; i < 3; i++ {
+			s.Send(message.NewDefaultMessage(level.Info, "message"))
+		}
+		require.NoError(t, s.Close())
+		require.Len(t, l.writes, 1)
+		for i, line := range l.writes[0] {
+			assert.EqualValues(t, i+1, line.Sequence)
+		}
+	})
+	t.Run("StartSequence", func(t *testing.T) {
+		l := &copyingLogger{}
+		s := newSender(t, l, WithSenderStartSequence(10))
+		s.Send(message.NewDefaultMessage(level.Info, "message"))
+		require.NoError(t, s.Close())
+		require.Len(t, l.writes, 1)
+		assert.EqualValues(t, 10, l.writes[0][0].Sequence)
+
+		_, err := NewSender(ctx, l, options.Sender{Key: "key", Local: send.MakeInternalLogger()}, WithSenderStartSequence(0))
+		assert.Error(t, err)
+	})
+	t.Run("Manifest", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		s := newSender(t, l, WithSenderStartSequence(5))
+		for i := 0; i < 3; i++ {
+			s.Send(message.NewDefaultMessage(level.Info, "message"))
+		}
+		require.NoError(t, s.Close())
+
+		entries, err := l.Manifest(ctx, "key")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.EqualValues(t, 5, entries[0].FirstSequence)
+		assert.EqualValues(t, 7, entries[0].LastSequence)
+
+		t.Run("Restart", func(t *testing.T) {
+			s := newSender(t, l)
+			s.Send(message.NewDefaultMessage(level.Info, "message"))
+			require.NoError(t, s.Close())
+
+			entries, err := l.Manifest(ctx, "key")
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+			assert.EqualValues(t, 8, entries[1].FirstSequence, "numbering continues after the manifest's last sequence number")
+		})
+		t.Run("Child", func(t *testing.T) {
+			child := l.Child("task")
+			for i := 0; i < 2; i++ {
+				s := newSender(t, child)
+				s.Send(message.NewDefaultMessage(level.Info, "message"))
+				require.NoError(t, s.Close())
+			}
+
+			entries, err := child.(*childLogger).parent.(*bucketLogger).Manifest(ctx, "task.children/key")
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+			assert.EqualValues(t, 2, entries[1].FirstSequence)
+		})
+	})
+	t.Run("ReadFromSequence", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		s := newSender(t, l)
+		for i := 0; i < 6; i++ {
+			s.Send(message.NewDefaultMessage(level.Info, "message"))
+			if i%2 == 1 {
+				require.NoError(t, s.Flush(ctx))
+			}
+		}
+		require.NoError(t, s.Close())
+
+		lines, next, err := l.ReadLines(ctx, options.Read{Key: "key", StartSequence: 4})
+		require.NoError(t, err)
+		require.Len(t, lines, 1, "the earlier lines of the chunk are skipped")
+		assert.EqualValues(t, 4, lines[0].Sequence)
+		lines, _, err = l.ReadLines(ctx, options.Read{Key: "key", PageToken: next})
+		require.NoError(t, err)
+		require.Len(t, lines, 2)
+		assert.EqualValues(t, 5, lines[0].Sequence)
+
+		lines, _, err = l.ReadLines(ctx, options.Read{Key: "key", StartSequence: 7})
+		require.NoError(t, err)
+		assert.Empty(t, lines)
+
+		l.manifestBucket = nil
+		_, _, err = l.ReadLines(ctx, options.Read{Key: "key", StartSequence: 1})
+		assert.Error(t, err)
+	})
+	t.Run("LineGap", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		for _, start := range []uint64{1, 1, 5} {
+			s := newSender(t, l, WithSenderStartSequence(start))
+			s.Send(message.NewDefaultMessage(level.Info, "message"))
+			s.Send(message.NewDefaultMessage(level.Info, "message"))
+			require.NoError(t, s.Close())
+		}
+
+		_, _, err = l.ReadLines(ctx, options.Read{Key: "key", Strict: true})
+		require.True(t, IsGapError(err), "retried lines overlap, but skipped lines are a gap")
+		var gap *GapError
+		require.True(t, errors.As(err, &gap))
+		assert.True(t, gap.Lines)
+		assert.EqualValues(t, 3, gap.From)
+		assert.EqualValues(t, 4, gap.To)
+	})
+}
+
+func TestSenderEnrichment(t *testing.T) {
+	ctx := context.Background()
+	l := &copyingLogger{}
+	s, err := NewSender(ctx, l, options.Sender{
+		Key:           "key",
+		Local:         send.MakeInternalLogger(),
+		LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+		FlushInterval: -1,
+	}, WithSenderEnrichment(map[string]interface{}{"task": "compile", "pid": "overridden"}))
+	require.NoError(t, err)
+
+	s.Send(message.NewDefaultMessage(level.Info, "message"))
+	require.NoError(t, s.Close())
+	require.Len(t, l.writes, 1)
+	require.Len(t, l.writes[0], 1)
+
+	attrs := l.writes[0][0].Attrs
+	assert.Equal(t, "compile", attrs["task"])
+	assert.Equal(t, "overridden", attrs["pid"], "fields take precedence over source fields")
+	assert.NotEmpty(t, attrs["process"])
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	assert.Equal(t, hostname, attrs["hostname"])
+}
+
+func TestSenderTraceIDs(t *testing.T) {
+	ctx := context.Background()
+	type spanKey struct{}
+	extract := func(ctx context.Context) (string, string) {
+		if span, ok := ctx.Value(spanKey{}).([2]string); ok {
+			return span[0], span[1]
+		}
+		return "", ""
+	}
+	l := &copyingLogger{}
+	s, err := NewSender(ctx, l, options.Sender{
+		Key:           "key",
+		Local:         send.MakeInternalLogger(),
+		LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+		FlushInterval: -1,
+	}, WithSenderTraceExtractor(extract))
+	require.NoError(t, err)
+
+	spanCtx := context.WithValue(ctx, spanKey{}, [2]string{"trace", "span"})
+	s.SendContext(spanCtx, message.NewDefaultMessage(level.Info, "context"))
+	s.SendContext(spanCtx, message.NewFields(level.Info, message.Fields{TraceIDKey: "fields-trace", SpanIDKey: "fields-span"}))
+	s.Send(message.NewFields(level.Info, message.Fields{TraceIDKey: "fields-trace"}))
+	s.Send(message.NewDefaultMessage(level.Info, "none"))
+	annotated := message.NewDefaultMessage(level.Info, "annotated")
+	require.NoError(t, annotated.Annotate(TraceIDKey, "annotated-trace"))
+	require.NoError(t, annotated.Annotate(SpanIDKey, "annotated-span"))
+	s.Send(annotated)
+	fields := message.MakeFields(map[string]interface{}{TraceIDKey: "map-trace"})
+	require.NoError(t, fields.SetPriority(level.Info))
+	s.Send(fields)
+	require.NoError(t, s.Close())
+
+	require.Len(t, l.writes, 1)
+	lines := l.writes[0]
+	require.Len(t, lines, 6)
+	for i, expected := range [][2]string{
+		{"trace", "span"},
+		{"fields-trace", "fields-span"},
+		{"fields-trace", ""},
+		{"", ""},
+		{"annotated-trace", "annotated-span"},
+		{"map-trace", ""},
+	} {
+		assert.Equal(t, expected[0], lines[i].TraceID, "line %d", i)
+		assert.Equal(t, expected[1], lines[i].SpanID, "line %d", i)
+	}
+
+	_, err = NewSender(ctx, l, options.Sender{Key: "key", Local: send.MakeInternalLogger()}, WithSenderTraceExtractor(nil))
+	assert.Error(t, err)
+
+	t.Run("Write", func(t *testing.T) {
+		bl, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithTraceExtractor(extract))
+		require.NoError(t, err)
+		in := []LogLine{
+			{Timestamp: time.Now(), Data: "context"},
+			{Timestamp: time.Now(), TraceID: "line-trace", Data: "line"},
+		}
+		require.NoError(t, bl.Write(spanCtx, options.Write{Key: "key", Data: in, Encoding: encode.JSON}))
+		assert.Empty(t, in[0].TraceID, "the written lines are not modified")
+
+		lines, _, err := bl.ReadLines(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		require.Len(t, lines, 2)
+		assert.Equal(t, "trace", lines[0].TraceID)
+		assert.Equal(t, "span", lines[0].SpanID)
+		assert.Equal(t, "line-trace", lines[1].TraceID)
+		assert.Empty(t, lines[1].SpanID)
+
+		_, err = NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithTraceExtractor(nil))
+		assert.Error(t, err)
+	})
+}
+
+func TestSenderOnFlush(t *testing.T) {
+	ctx := context.Background()
+	bucketOpts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+	l, err := NewBucketLogger(ctx, bucketOpts)
+	require.NoError(t, err)
+	sendLines := func(t *testing.T, l Logger, key string) []options.FlushInfo {
+		var flushed []options.FlushInfo
+		s, err := NewSender(ctx, l, options.Sender{
+			Key:           key,
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+			FlushInterval: -1,
+		}, WithSenderOnFlush(func(info options.FlushInfo) { flushed = append(flushed, info) }))
+		require.NoError(t, err)
+
+		s.Send(message.NewDefaultMessage(level.Info, "first"))
+		s.Send(message.NewDefaultMessage(level.Info, "second"))
+		require.NoError(t, s.Close())
+		return flushed
+	}
+	checkFlushed := func(t *testing.T, flushed []options.FlushInfo, key string) {
+		require.Len(t, flushed, 1)
+		entries, err := l.Manifest(ctx, key)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, entries[0].Key, flushed[0].Key)
+		assert.Equal(t, entries[0].Size, flushed[0].Size)
+		assert.Equal(t, 2, flushed[0].Lines)
+		assert.True(t, flushed[0].Duration > 0)
+	}
+
+	t.Run("BucketLogger", func(t *testing.T) {
+		checkFlushed(t, sendLines(t, l, "key"), "key")
+	})
+	t.Run("SpoolLogger", func(t *testing.T) {
+		sl, err := NewSpoolLogger(ctx, bucketOpts, options.Spool{Dir: t.TempDir(), Interval: time.Hour})
+		require.NoError(t, err)
+
+		flushed := sendLines(t, sl, "spool")
+		require.NoError(t, sl.Close(ctx))
+		checkFlushed(t, flushed, "spool")
+	})
+	t.Run("ChildLogger", func(t *testing.T) {
+		checkFlushed(t, sendLines(t, l.Child("task"), "step"), "task.children/step")
+	})
+	t.Run("NilCallback", func(t *testing.T) {
+		_, err := NewSender(ctx, l, options.Sender{Key: "key", Local: send.MakeInternalLogger()}, WithSenderOnFlush(nil))
+		assert.Error(t, err)
+	})
+}
+
+func TestSenderUtilization(t *testing.T) {
+	ctx := context.Background()
+	newSender := func(t *testing.T, l Logger) *sender {
+		s, err := NewSender(ctx, l, options.Sender{
+			Key:           "key",
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+			MaxBufferSize: 100,
+			FlushInterval: -1,
+		})
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("Buffered", func(t *testing.T) {
+		s := newSender(t, &copyingLogger{})
+		assert.Equal(t, SenderUtilization{MaxBufferSize: 100}, s.Utilization())
+
+		s.Send(message.NewDefaultMessage(level.Info, "0123456789"))
+		s.Send(message.NewDefaultMessage(level.Info, "0123456789"))
+		u := s.Utilization()
+		assert.Equal(t, 20, u.BufferedBytes)
+		assert.Equal(t, 2, u.BufferedLines)
+		assert.Equal(t, 20.0, u.Percent)
+		assert.True(t, u.LastFlush.IsZero())
+
+		require.NoError(t, s.Flush(ctx))
+		u = s.Utilization()
+		assert.Zero(t, u.BufferedBytes)
+		assert.Zero(t, u.BufferedLines)
+		assert.Zero(t, u.PendingFlushes)
+		assert.False(t, u.LastFlush.IsZero())
+		require.NoError(t, s.Close())
+	})
+	t.Run("PendingFlushes", func(t *testing.T) {
+		s := newSender(t, &blockingLogger{})
+		s.Send(message.NewDefaultMessage(level.Info, "message"))
+
+		flushCtx, cancel := context.WithCancel(ctx)
+		errs := make(chan error, 1)
+		go func() { errs <- s.Flush(flushCtx) }()
+		require.Eventually(t, func() bool { return s.Utilization().PendingFlushes == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, 1, s.Utilization().BufferedLines, "utilization does not block on flushes")
+
+		cancel()
+		assert.Error(t, <-errs)
+		assert.Zero(t, s.Utilization().PendingFlushes)
+	})
+}
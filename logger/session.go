@@ -0,0 +1,27 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/julianedwards/cedar/internal"
+	"github.com/julianedwards/cedar/options"
+)
+
+// BucketSession creates the buckets of a bucket logger from bucket options.
+// Backends that hold connections, e.g. GridFS and SFTP, share a single
+// connection between all of a session's buckets.
+type BucketSession struct {
+	opts    options.Bucket
+	session internal.BucketSession
+}
+
+// NewBucketSession returns a session for the backend described by the
+// bucket options, which are validated.
+func NewBucketSession(ctx context.Context, opts options.Bucket) (*BucketSession, error) {
+	session, err := internal.NewBucketSession(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BucketSession{opts: opts, session: session}, nil
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// Flusher is implemented by anything that buffers logs, e.g. senders and
+// spool loggers.
+type Flusher interface {
+	Flush(context.Context) error
+}
+
+// FlushOnSignal flushes the flushers each time the process receives a
+// flush signal, SIGUSR1 where supported, so that operators can force
+// buffered logs out of a live process. When the process receives a
+// termination signal, SIGTERM or, on Windows, an interrupt, the flushers
+// are flushed, or closed if configured, and the signal is raised again so
+// that the process terminates as it would have otherwise. Errors are
+// reported to the handler, if not nil. Signals are handled until the
+// returned stop function is called.
+func FlushOnSignal(opts options.FlushOnSignal, handler ErrorHandler, flushers ...Flusher) (func(), error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid flush on signal options")
+	}
+	report := func(err error) {
+		if err != nil && handler != nil {
+			handler("flush on signal", err)
+		}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, append(append([]os.Signal{}, flushSignals...), terminationSignals...)...)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-sigs:
+				if !isTerminationSignal(sig) {
+					report(flushAll(opts, false, flushers))
+					continue
+				}
+
+				report(flushAll(opts, opts.Close, flushers))
+				signal.Stop(sigs)
+				report(errors.Wrap(raise(sig), "raising termination signal"))
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(done)
+		})
+	}, nil
+}
+
+func isTerminationSignal(sig os.Signal) bool {
+	for _, s := range terminationSignals {
+		if s == sig {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flushAll flushes, or closes, each flusher, bounding each by the options'
+// timeout.
+func flushAll(opts options.FlushOnSignal, closeFlushers bool, flushers []Flusher) error {
+	catcher := grip.NewBasicCatcher()
+	for _, f := range flushers {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		if !closeFlushers {
+			catcher.Wrap(f.Flush(ctx), "flushing")
+			cancel()
+			continue
+		}
+
+		switch c := f.(type) {
+		case interface{ Close(context.Context) error }:
+			catcher.Wrap(c.Close(ctx), "closing")
+		case interface{ CloseContext(context.Context) error }:
+			catcher.Wrap(c.CloseContext(ctx), "closing")
+		case io.Closer:
+			catcher.Wrap(c.Close(), "closing")
+		default:
+			catcher.Wrap(f.Flush(ctx), "flushing")
+		}
+		cancel()
+	}
+
+	return catcher.Resolve()
+}
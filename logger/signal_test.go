@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingFlusher counts its flushes, failing them with err if set.
+type countingFlusher struct {
+	mu      sync.Mutex
+	flushes int
+	err     error
+}
+
+func (f *countingFlusher) Flush(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flushes++
+	return f.err
+}
+
+func (f *countingFlusher) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flushes
+}
+
+// closingFlusher is a countingFlusher that also counts its closes.
+type closingFlusher struct {
+	countingFlusher
+	closes int
+}
+
+func (f *closingFlusher) Close(context.Context) error {
+	f.closes++
+	return nil
+}
+
+func TestFlushAll(t *testing.T) {
+	opts := options.FlushOnSignal{Timeout: time.Second}
+
+	t.Run("Flush", func(t *testing.T) {
+		flushers := []Flusher{&countingFlusher{}, &closingFlusher{}}
+		require.NoError(t, flushAll(opts, false, flushers))
+		assert.Equal(t, 1, flushers[0].(*countingFlusher).count())
+		assert.Equal(t, 1, flushers[1].(*closingFlusher).count())
+		assert.Zero(t, flushers[1].(*closingFlusher).closes)
+	})
+	t.Run("Close", func(t *testing.T) {
+		flushers := []Flusher{&countingFlusher{}, &closingFlusher{}}
+		require.NoError(t, flushAll(opts, true, flushers))
+		assert.Equal(t, 1, flushers[0].(*countingFlusher).count(), "flushers that cannot be closed are flushed")
+		assert.Zero(t, flushers[1].(*closingFlusher).count())
+		assert.Equal(t, 1, flushers[1].(*closingFlusher).closes)
+	})
+	t.Run("Errors", func(t *testing.T) {
+		failing := &countingFlusher{err: errors.New("flush failed")}
+		ok := &countingFlusher{}
+		err := flushAll(opts, false, []Flusher{failing, ok})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "flush failed")
+		assert.Equal(t, 1, ok.count(), "later flushers are still flushed")
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := FlushOnSignal(options.FlushOnSignal{Timeout: -time.Second}, nil)
+		assert.Error(t, err)
+	})
+}
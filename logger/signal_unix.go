@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	flushSignals       = []os.Signal{syscall.SIGUSR1}
+	terminationSignals = []os.Signal{syscall.SIGTERM}
+)
+
+// raise sends the signal to the current process.
+func raise(sig os.Signal) error {
+	return syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+// +build !windows
+
+package logger
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlushOnSignal(t *testing.T) {
+	f := &countingFlusher{}
+	failing := &countingFlusher{err: errors.New("flush failed")}
+	errs := make(chan error, 10)
+	stop, err := FlushOnSignal(options.FlushOnSignal{}, func(op string, err error) {
+		assert.Equal(t, "flush on signal", op)
+		errs <- err
+	}, f, failing)
+	require.NoError(t, err)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, func() bool { return f.count() == 1 }, 5*time.Second, time.Millisecond)
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "flush failed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("flush error was not reported")
+	}
+
+	stop()
+	stop()
+	assert.Equal(t, 1, f.count())
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package logger
+
+import "os"
+
+var (
+	flushSignals       []os.Signal
+	terminationSignals = []os.Signal{os.Interrupt}
+)
+
+// raise exits the process, since Windows processes cannot signal
+// themselves.
+func raise(os.Signal) error {
+	os.Exit(1)
+	return nil
+}
@@ -0,0 +1,281 @@
+package logger
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/pkg/errors"
+)
+
+// ManifestSigner signs manifest entries and verifies their signatures, e.g.
+// with a key shared by, or a key pair split between, the writers and
+// readers of a bucket; see WithManifestSigner.
+type ManifestSigner interface {
+	// Sign returns the signature of the data.
+	Sign(data []byte) ([]byte, error)
+	// Verify returns an error if the signature of the data is not valid.
+	Verify(data, signature []byte) error
+}
+
+// minHMACKeySize is the minimum size, in bytes, of HMAC signing keys.
+const minHMACKeySize = 32
+
+type hmacSigner struct {
+	key []byte
+}
+
+// NewHMACSigner returns a manifest signer that signs with HMAC-SHA256
+// using the given key, which must be at least 32 bytes long.
+func NewHMACSigner(key []byte) (ManifestSigner, error) {
+	if len(key) < minHMACKeySize {
+		return nil, errors.Errorf("HMAC key must be at least %d bytes", minHMACKeySize)
+	}
+
+	return &hmacSigner{key: append([]byte(nil), key...)}, nil
+}
+
+func (s *hmacSigner) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+func (s *hmacSigner) Verify(data, signature []byte) error {
+	expected, _ := s.Sign(data)
+	if !hmac.Equal(expected, signature) {
+		return errors.New("signature does not match")
+	}
+
+	return nil
+}
+
+type ed25519Signer struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// NewEd25519Signer returns a manifest signer that signs with the Ed25519
+// private key.
+func NewEd25519Signer(key ed25519.PrivateKey) (ManifestSigner, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid Ed25519 private key")
+	}
+
+	return &ed25519Signer{private: key, public: key.Public().(ed25519.PublicKey)}, nil
+}
+
+// NewEd25519Verifier returns a manifest signer that only verifies
+// signatures with the Ed25519 public key, e.g. for readers, which never
+// sign, so that they do not need access to the private key.
+func NewEd25519Verifier(key ed25519.PublicKey) (ManifestSigner, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 public key")
+	}
+
+	return &ed25519Signer{public: key}, nil
+}
+
+func (s *ed25519Signer) Sign(data []byte) ([]byte, error) {
+	if s.private == nil {
+		return nil, errors.New("cannot sign without an Ed25519 private key")
+	}
+
+	return ed25519.Sign(s.private, data), nil
+}
+
+func (s *ed25519Signer) Verify(data, signature []byte) error {
+	if !ed25519.Verify(s.public, data, signature) {
+		return errors.New("signature does not match")
+	}
+
+	return nil
+}
+
+// chunkHash returns the hex encoded SHA-256 hash of a chunk's contents.
+func chunkHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signedManifestData returns the data signed for the entry: its JSON
+// encoding without a signature.
+func signedManifestData(entry ManifestEntry) ([]byte, error) {
+	entry.Signature = nil
+	data, err := json.Marshal(entry)
+	return data, errors.Wrap(err, "encoding manifest entry")
+}
+
+func (l *bucketLogger) signManifestEntry(entry *ManifestEntry) error {
+	data, err := signedManifestData(*entry)
+	if err != nil {
+		return err
+	}
+	entry.Signature, err = l.manifestSigner.Sign(data)
+
+	return err
+}
+
+// verifyManifestEntry verifies the signature of the entry stored for the
+// chunk with the given key, if the logger signs manifests.
+func (l *bucketLogger) verifyManifestEntry(key string, entry ManifestEntry) error {
+	if l.manifestSigner == nil {
+		return nil
+	}
+	if entry.Key != key {
+		return &ManifestVerificationError{Key: key, Reason: fmt.Sprintf("entry is for chunk '%s'", entry.Key)}
+	}
+	if len(entry.Signature) == 0 {
+		return &ManifestVerificationError{Key: key, Reason: "entry is not signed"}
+	}
+
+	data, err := signedManifestData(entry)
+	if err != nil {
+		return err
+	}
+	if err = l.manifestSigner.Verify(data, entry.Signature); err != nil {
+		return &ManifestVerificationError{Key: key, Reason: err.Error()}
+	}
+
+	return nil
+}
+
+// verifyChunk verifies the contents of the chunk with the given key against
+// its signed manifest entry, for reads with VerifyChunks set.
+func (l *bucketLogger) verifyChunk(ctx context.Context, key string, data []byte) error {
+	r, err := l.manifestBucket.Get(ctx, key+manifestExtension)
+	if pail.IsKeyNotFoundError(errors.Cause(err)) {
+		return &ManifestVerificationError{Key: key, Reason: "chunk has no manifest entry"}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "getting manifest entry for chunk '%s'", key)
+	}
+	defer r.Close()
+
+	var entry ManifestEntry
+	if err = json.NewDecoder(r).Decode(&entry); err != nil {
+		return errors.Wrapf(err, "decoding manifest entry for chunk '%s'", key)
+	}
+	if err = l.verifyManifestEntry(key, entry); err != nil {
+		return err
+	}
+	if entry.SHA256 == "" || chunkHash(data) != entry.SHA256 {
+		return &ManifestVerificationError{Key: key, Reason: "chunk does not match its manifest entry"}
+	}
+
+	return nil
+}
+
+// ManifestVerification is the result of verifying the chunks of a key
+// against their signed manifest entries.
+type ManifestVerification struct {
+	Key string `json:"key"`
+	// Verified is the number of chunks that match their entries.
+	Verified int `json:"verified"`
+	// Failed are the verification failures, sorted by chunk key: chunks
+	// that were modified or removed, chunks without an entry, and entries
+	// that were modified.
+	Failed []ManifestVerificationError `json:"failed,omitempty"`
+}
+
+// Valid returns whether every chunk matches its entry.
+func (v *ManifestVerification) Valid() bool { return len(v.Failed) == 0 }
+
+// VerifyManifest downloads every chunk written to the key and verifies it
+// against its signed manifest entry. Chunks without an entry, e.g. because
+// they were added or their entry was removed, and entries without a chunk
+// also fail verification. Reads verify the chunks they return only if
+// VerifyChunks is set, and cannot detect removed chunks.
+func (l *bucketLogger) VerifyManifest(ctx context.Context, key string) (*ManifestVerification, error) {
+	if l.manifestSigner == nil {
+		return nil, errors.New("cannot verify the manifest of a logger that does not sign manifests")
+	}
+	if l.manifestBucket == nil {
+		return nil, errors.New("cannot verify the manifest of a logger without a manifest bucket")
+	}
+	if err := l.audit(ctx, AuditRead, key); err != nil {
+		return nil, err
+	}
+
+	// Entries are listed before the chunks, which are always uploaded
+	// first, so that concurrent writes are not reported as failures.
+	it, err := l.manifestBucket.List(ctx, keyListPrefix(key))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing manifest entries")
+	}
+	v := &ManifestVerification{Key: key}
+	fail := func(chunk, reason string) {
+		v.Failed = append(v.Failed, ManifestVerificationError{Key: chunk, Reason: reason})
+	}
+	entries := map[string]ManifestEntry{}
+	invalid := map[string]bool{}
+	for it.Next(ctx) {
+		name := it.Item().Name()
+		if !strings.HasSuffix(name, manifestExtension) {
+			continue
+		}
+		chunk := strings.TrimSuffix(name, manifestExtension)
+
+		entry, err := l.getManifestEntry(ctx, it.Item())
+		if err == nil {
+			err = l.verifyManifestEntry(chunk, entry)
+		}
+		if err != nil {
+			invalid[chunk] = true
+			if verr, ok := errors.Cause(err).(*ManifestVerificationError); ok {
+				err = errors.New(verr.Reason)
+			}
+			fail(chunk, err.Error())
+			continue
+		}
+		entries[chunk] = entry
+	}
+	if err = it.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating manifest entries")
+	}
+
+	r := &bucketReader{ctx: ctx, bucket: l.logsBucket}
+	if err = r.getAndSortKeys(key, false); err != nil {
+		return nil, err
+	}
+	chunks := make(map[string]bool, len(r.keys))
+	for _, chunk := range r.keys {
+		chunks[chunk] = true
+		entry, ok := entries[chunk]
+		if !ok {
+			if !invalid[chunk] {
+				fail(chunk, "chunk has no manifest entry")
+			}
+			continue
+		}
+
+		data, err := getChunk(ctx, l.logsBucket, chunk)
+		if pail.IsKeyNotFoundError(errors.Cause(err)) {
+			chunks[chunk] = false
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "downloading chunk '%s'", chunk)
+		}
+		if entry.SHA256 == "" || chunkHash(data) != entry.SHA256 {
+			fail(chunk, "chunk does not match its manifest entry")
+			continue
+		}
+		v.Verified++
+	}
+	for chunk := range entries {
+		if !chunks[chunk] {
+			fail(chunk, "chunk is missing")
+		}
+	}
+	sort.Slice(v.Failed, func(i, j int) bool { return v.Failed[i].Key < v.Failed[j].Key })
+
+	return v, nil
+}
@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestSigners(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPublic, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	hmacSigner, err := NewHMACSigner(bytes.Repeat([]byte("k"), minHMACKeySize))
+	require.NoError(t, err)
+	otherHMACSigner, err := NewHMACSigner(bytes.Repeat([]byte("o"), minHMACKeySize))
+	require.NoError(t, err)
+	edSigner, err := NewEd25519Signer(private)
+	require.NoError(t, err)
+	edVerifier, err := NewEd25519Verifier(public)
+	require.NoError(t, err)
+	otherEdVerifier, err := NewEd25519Verifier(otherPublic)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name     string
+		signer   ManifestSigner
+		verifier ManifestSigner
+		valid    bool
+	}{
+		{name: "HMAC", signer: hmacSigner, verifier: hmacSigner, valid: true},
+		{name: "HMACOtherKey", signer: hmacSigner, verifier: otherHMACSigner, valid: false},
+		{name: "Ed25519", signer: edSigner, verifier: edSigner, valid: true},
+		{name: "Ed25519Verifier", signer: edSigner, verifier: edVerifier, valid: true},
+		{name: "Ed25519OtherKey", signer: edSigner, verifier: otherEdVerifier, valid: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data := []byte("entry")
+			signature, err := test.signer.Sign(data)
+			require.NoError(t, err)
+
+			if test.valid {
+				assert.NoError(t, test.verifier.Verify(data, signature))
+				assert.Error(t, test.verifier.Verify([]byte("modified"), signature))
+			} else {
+				assert.Error(t, test.verifier.Verify(data, signature))
+			}
+		})
+	}
+
+	t.Run("ShortHMACKey", func(t *testing.T) {
+		_, err := NewHMACSigner(bytes.Repeat([]byte("k"), minHMACKeySize-1))
+		assert.Error(t, err)
+	})
+	t.Run("InvalidEd25519Keys", func(t *testing.T) {
+		_, err := NewEd25519Signer(ed25519.PrivateKey("short"))
+		assert.Error(t, err)
+		_, err = NewEd25519Verifier(ed25519.PublicKey("short"))
+		assert.Error(t, err)
+	})
+	t.Run("VerifierCannotSign", func(t *testing.T) {
+		_, err := edVerifier.Sign([]byte("entry"))
+		assert.Error(t, err)
+	})
+}
+
+func TestBucketLoggerVerifyManifest(t *testing.T) {
+	ctx := context.Background()
+	signer, err := NewHMACSigner(bytes.Repeat([]byte("k"), minHMACKeySize))
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name   string
+		tamper func(t *testing.T, l *bucketLogger, key string)
+		reason string
+	}{
+		{
+			name:   "Unmodified",
+			tamper: func(*testing.T, *bucketLogger, string) {},
+		},
+		{
+			name: "ModifiedChunk",
+			tamper: func(t *testing.T, l *bucketLogger, key string) {
+				require.NoError(t, l.logsBucket.Put(ctx, key, bytes.NewBufferString("forged\n")))
+			},
+			reason: "chunk does not match its manifest entry",
+		},
+		{
+			name: "RemovedChunk",
+			tamper: func(t *testing.T, l *bucketLogger, key string) {
+				require.NoError(t, l.logsBucket.Remove(ctx, key))
+			},
+			reason: "chunk is missing",
+		},
+		{
+			name: "RemovedEntry",
+			tamper: func(t *testing.T, l *bucketLogger, key string) {
+				require.NoError(t, l.manifestBucket.Remove(ctx, key+manifestExtension))
+			},
+			reason: "chunk has no manifest entry",
+		},
+		{
+			name: "ModifiedEntry",
+			tamper: func(t *testing.T, l *bucketLogger, key string) {
+				r, err := l.manifestBucket.Get(ctx, key+manifestExtension)
+				require.NoError(t, err)
+				data, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.NoError(t, r.Close())
+
+				var entry ManifestEntry
+				require.NoError(t, json.Unmarshal(data, &entry))
+				entry.Lines = 100
+				data, err = json.Marshal(entry)
+				require.NoError(t, err)
+				require.NoError(t, l.manifestBucket.Put(ctx, key+manifestExtension, bytes.NewReader(data)))
+			},
+			reason: "signature does not match",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithManifestSigner(signer))
+			require.NoError(t, err)
+			var keys []string
+			for _, data := range []string{"a\n", "b\n"} {
+				res, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte(data)})
+				require.NoError(t, err)
+				keys = append(keys, res.Key)
+			}
+			test.tamper(t, l, keys[0])
+
+			v, err := l.VerifyManifest(ctx, "key")
+			require.NoError(t, err)
+			if test.reason == "" {
+				assert.True(t, v.Valid())
+				assert.Equal(t, 2, v.Verified)
+				return
+			}
+			assert.False(t, v.Valid())
+			assert.Equal(t, 1, v.Verified)
+			require.Len(t, v.Failed, 1)
+			assert.Equal(t, keys[0], v.Failed[0].Key)
+			assert.Equal(t, test.reason, v.Failed[0].Reason)
+		})
+	}
+
+	t.Run("SiblingKey", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithManifestSigner(signer))
+		require.NoError(t, err)
+		for _, key := range []string{"key", "key2"} {
+			_, err = l.WriteBytes(ctx, options.WriteBytes{Key: key, Data: []byte("a\n")})
+			require.NoError(t, err)
+		}
+
+		v, err := l.VerifyManifest(ctx, "key")
+		require.NoError(t, err)
+		assert.True(t, v.Valid())
+		assert.Equal(t, 1, v.Verified, "chunks of sibling keys are not verified")
+	})
+	t.Run("WithoutSigner", func(t *testing.T) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		_, err = l.VerifyManifest(ctx, "key")
+		assert.Error(t, err)
+	})
+}
+
+func TestBucketLoggerVerifyChunks(t *testing.T) {
+	ctx := context.Background()
+	signer, err := NewHMACSigner(bytes.Repeat([]byte("k"), minHMACKeySize))
+	require.NoError(t, err)
+	setup := func(t *testing.T, loggerOpts ...BucketLoggerOption) (*bucketLogger, []string) {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, loggerOpts...)
+		require.NoError(t, err)
+		var keys []string
+		for _, data := range []string{"a\n", "b\n"} {
+			res, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte(data)})
+			require.NoError(t, err)
+			keys = append(keys, res.Key)
+		}
+		return l, keys
+	}
+	read := func(l *bucketLogger, opts options.Read) (string, error) {
+		r, err := l.NewReadCloser(ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		return string(data), err
+	}
+
+	t.Run("Unmodified", func(t *testing.T) {
+		l, _ := setup(t, WithManifestSigner(signer))
+		data, err := read(l, options.Read{Key: "key", VerifyChunks: true})
+		require.NoError(t, err)
+		assert.Equal(t, "a\nb\n", data)
+
+		data, err = read(l, options.Read{Key: "key", VerifyChunks: true, Offset: 1, PrefetchCount: 1})
+		require.NoError(t, err)
+		assert.Equal(t, "\nb\n", data)
+	})
+	t.Run("ModifiedChunk", func(t *testing.T) {
+		l, keys := setup(t, WithManifestSigner(signer))
+		require.NoError(t, l.logsBucket.Put(ctx, keys[1], bytes.NewBufferString("forged\n")))
+
+		data, err := read(l, options.Read{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, "a\nforged\n", data, "chunks are only verified if requested")
+		for _, prefetch := range []int{0, 1} {
+			_, err = read(l, options.Read{Key: "key", VerifyChunks: true, PrefetchCount: prefetch})
+			require.Error(t, err)
+			assert.True(t, IsManifestVerificationError(err))
+			assert.Contains(t, err.Error(), keys[1])
+		}
+	})
+	t.Run("RemovedEntry", func(t *testing.T) {
+		l, keys := setup(t, WithManifestSigner(signer))
+		require.NoError(t, l.manifestBucket.Remove(ctx, keys[0]+manifestExtension))
+
+		_, err := read(l, options.Read{Key: "key", VerifyChunks: true})
+		assert.True(t, IsManifestVerificationError(err))
+	})
+	t.Run("Cached", func(t *testing.T) {
+		cache, err := NewChunkCache(1024)
+		require.NoError(t, err)
+		l, keys := setup(t, WithManifestSigner(signer), WithChunkCache(cache))
+		_, err = read(l, options.Read{Key: "key"})
+		require.NoError(t, err)
+		require.NoError(t, l.manifestBucket.Remove(ctx, keys[0]+manifestExtension))
+
+		_, err = read(l, options.Read{Key: "key", VerifyChunks: true})
+		assert.True(t, IsManifestVerificationError(err), "cached chunks are verified")
+	})
+	t.Run("WithoutSigner", func(t *testing.T) {
+		l, _ := setup(t)
+		_, err := read(l, options.Read{Key: "key", VerifyChunks: true})
+		assert.Error(t, err)
+	})
+	t.Run("Metadata", func(t *testing.T) {
+		l, _ := setup(t, WithManifestSigner(signer))
+		_, err := read(l, options.Read{Key: "key", VerifyChunks: true, Metadata: true})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// encodedChunk is a part of the data of a write and its encoded form.
+type encodedChunk struct {
+	data    interface{}
+	encoded []byte
+	release func()
+}
+
+// splitChunks splits already encoded data into chunks whose encoded size is
+// at most the target, by recursively halving the data and encoding each
+// half on its own. Data that cannot be halved is returned as is.
+func (l *bucketLogger) splitChunks(chunk encodedChunk, prefix, encoding string, target int) ([]encodedChunk, error) {
+	if len(chunk.encoded) <= target {
+		return []encodedChunk{chunk}, nil
+	}
+	first, second, ok := halveData(chunk.data)
+	if !ok {
+		return []encodedChunk{chunk}, nil
+	}
+	chunk.release()
+
+	var chunks []encodedChunk
+	for _, half := range []interface{}{first, second} {
+		_, encoded, release, err := l.encode(half, prefix, encoding)
+		if err != nil {
+			releaseChunks(chunks)
+			return nil, err
+		}
+		split, err := l.splitChunks(encodedChunk{data: half, encoded: encoded, release: release}, prefix, encoding, target)
+		if err != nil {
+			releaseChunks(chunks)
+			return nil, err
+		}
+		chunks = append(chunks, split...)
+	}
+
+	return chunks, nil
+}
+
+func releaseChunks(chunks []encodedChunk) {
+	for _, chunk := range chunks {
+		chunk.release()
+	}
+}
+
+// halveData splits the data in two, between lines for byte slices and
+// strings and between elements for other slices.
+func halveData(data interface{}) (interface{}, interface{}, bool) {
+	switch d := data.(type) {
+	case []byte:
+		i := splitIndex(d)
+		return d[:i], d[i:], i > 0 && i < len(d)
+	case string:
+		i := splitIndex([]byte(d))
+		return d[:i], d[i:], i > 0 && i < len(d)
+	}
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice || v.Len() < 2 {
+		return nil, nil, false
+	}
+
+	return v.Slice(0, v.Len()/2).Interface(), v.Slice(v.Len()/2, v.Len()).Interface(), true
+}
+
+// splitIndex returns the index just after the newline closest to the
+// middle of the data, or 0 if the data is a single line.
+func splitIndex(data []byte) int {
+	mid := len(data) / 2
+	if i := bytes.IndexByte(data[mid:], '\n'); i >= 0 && mid+i+1 < len(data) {
+		return mid + i + 1
+	}
+	if i := bytes.LastIndexByte(data[:mid], '\n'); i >= 0 {
+		return i + 1
+	}
+
+	return 0
+}
+
+// writeChunks uploads the chunks of a single write, which must be split
+// from data encoded with the given encoding, concurrently under consecutive
+// keys, and returns their manifest entries in key order. The caller must
+// hold the key's lock.
+func (l *bucketLogger) writeChunks(ctx context.Context, opts options.Write, e encode.Encoding, chunks []encodedChunk) ([]ManifestEntry, error) {
+	defer releaseChunks(chunks)
+
+	contentType := encodingContentType(e)
+	bucket, err := l.getContentTypeBucket(ctx, false, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := chunkAttributes(contentType, opts.Tags, opts.StorageClass)
+	keys := make([]string, len(chunks))
+	releaseKeys := make([]func(), len(chunks))
+	for i, chunk := range chunks {
+		keys[i], releaseKeys[i] = l.newKey(KeyInfo{
+			Prefix:    opts.Key,
+			Extension: e.Extension(),
+			Size:      len(chunk.encoded),
+			Lines:     countLines(chunk.data),
+		})
+	}
+
+	var wg sync.WaitGroup
+	catcher := grip.NewBasicCatcher()
+	putKeys := make([]string, len(chunks))
+	entries := make([]ManifestEntry, len(chunks))
+	entryKeys := make([]string, len(chunks))
+	for i := range chunks {
+		wg.Add(1)
+		go func(i int, key string, chunk encodedChunk) {
+			defer wg.Done()
+			putKey, err := l.putChunk(ctx, bucket, attrs, key, chunk.encoded)
+			if err != nil {
+				catcher.Wrapf(err, "uploading chunk '%s'", key)
+				return
+			}
+			putKeys[i] = putKey
+			entries[i] = l.newManifestEntry(putKey, chunk.data, chunk.encoded)
+			if err = l.putManifestEntry(ctx, entries[i]); err != nil {
+				catcher.Add(err)
+				return
+			}
+			if l.manifestBucket != nil {
+				entryKeys[i] = putKey + manifestExtension
+			}
+		}(i, keys[i], chunks[i])
+	}
+	wg.Wait()
+
+	failed := len(chunks)
+	for i := range putKeys {
+		if putKeys[i] == "" {
+			failed = i
+			break
+		}
+	}
+	if failed == len(chunks) {
+		if catcher.HasErrors() {
+			return nil, errors.Wrap(catcher.Resolve(), "uploading data")
+		}
+		return entries, nil
+	}
+
+	// Only the chunks before the first one that failed keep their sequence
+	// numbers, so that a failed chunk does not leave a gap that fails
+	// strict reads. Later chunks of the write that were uploaded are
+	// removed, and the sequence numbers from the failed chunk on released
+	// for the next write.
+	if err := l.removeChunks(ctx, bucket, putKeys[failed+1:], entryKeys[failed+1:]); err != nil {
+		catcher.Wrap(err, "removing the chunks uploaded after a failed chunk")
+		return nil, errors.Wrap(catcher.Resolve(), "uploading data")
+	}
+	for i := len(chunks) - 1; i >= failed; i-- {
+		releaseKeys[i]()
+	}
+
+	return nil, errors.Wrap(catcher.Resolve(), "uploading data")
+}
+
+// removeChunks removes the uploaded chunks and manifest entries of a write
+// that failed. Empty keys, of chunks or entries that were not uploaded, are
+// skipped.
+func (l *bucketLogger) removeChunks(ctx context.Context, bucket pail.Bucket, chunkKeys, entryKeys []string) error {
+	if l.dryRun != nil {
+		return nil
+	}
+
+	catcher := grip.NewBasicCatcher()
+	catcher.Wrap(removeKeys(ctx, bucket, nonEmpty(chunkKeys)), "removing chunks")
+	if l.manifestBucket != nil {
+		catcher.Wrap(removeKeys(ctx, l.manifestBucket, nonEmpty(entryKeys)), "removing manifest entries")
+	}
+
+	return catcher.Resolve()
+}
+
+func nonEmpty(keys []string) []string {
+	var out []string
+	for _, key := range keys {
+		if key != "" {
+			out = append(out, key)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingChunkBucket is a bucket whose puts of the chunks with the given
+// sequence numbers fail.
+type failingChunkBucket struct {
+	pail.Bucket
+	seqs map[uint64]bool
+}
+
+func (b *failingChunkBucket) Put(ctx context.Context, key string, r io.Reader) error {
+	if chunk, err := parseChunkKey(key); err == nil && b.seqs[chunk.seq] {
+		return errors.New("put failed")
+	}
+
+	return b.Bucket.Put(ctx, key, r)
+}
+
+func TestBucketLoggerTargetChunkSize(t *testing.T) {
+	ctx := context.Background()
+	newLogger := func(t *testing.T) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		return l
+	}
+	data := "line0\nline1\nline2\nline3\n"
+	read := func(l Logger) (string, error) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key", Strict: true})
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		return string(data), err
+	}
+	entries := func(t *testing.T, l *bucketLogger) []ManifestEntry {
+		entries, err := l.Manifest(ctx, "key")
+		require.NoError(t, err)
+		return entries
+	}
+
+	t.Run("Splits", func(t *testing.T) {
+		l := newLogger(t)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: data, Encoding: encode.TEXT, TargetChunkSize: 6}))
+		assert.Len(t, entries(t, l), 4)
+
+		read, err := read(l)
+		require.NoError(t, err)
+		assert.Equal(t, data, read)
+	})
+	t.Run("Slices", func(t *testing.T) {
+		l := newLogger(t)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: []string{"a", "b", "c"}, Encoding: encode.JSON, TargetChunkSize: 6}))
+		assert.Len(t, entries(t, l), 3)
+	})
+	t.Run("Unsplittable", func(t *testing.T) {
+		l := newLogger(t)
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: strings.Repeat("a", 100), Encoding: encode.TEXT, TargetChunkSize: 10}))
+		assert.Len(t, entries(t, l), 1)
+	})
+	t.Run("FailedMiddleChunk", func(t *testing.T) {
+		l := newLogger(t)
+		l.logsBucket = &failingChunkBucket{Bucket: l.logsBucket, seqs: map[uint64]bool{1: true}}
+
+		err := l.Write(ctx, options.Write{Key: "key", Data: data, Encoding: encode.TEXT, TargetChunkSize: 6})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "put failed")
+		assert.Len(t, entries(t, l), 1, "the chunks after the failed one are removed")
+
+		// The sequence numbers from the failed chunk on are released, so
+		// the next write continues the sequence without a gap.
+		l.logsBucket = l.logsBucket.(*failingChunkBucket).Bucket
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line4\n", Encoding: encode.TEXT}))
+		read, err := read(l)
+		require.NoError(t, err)
+		assert.Equal(t, "line0\nline4\n", read)
+		assert.Len(t, entries(t, l), 2)
+	})
+	t.Run("FailedLastChunk", func(t *testing.T) {
+		l := newLogger(t)
+		l.logsBucket = &failingChunkBucket{Bucket: l.logsBucket, seqs: map[uint64]bool{2: true, 3: true}}
+
+		require.Error(t, l.Write(ctx, options.Write{Key: "key", Data: data, Encoding: encode.TEXT, TargetChunkSize: 6}))
+		assert.Len(t, entries(t, l), 2)
+
+		// The trailing chunks' sequence numbers are released, so the next
+		// write continues the sequence without a gap.
+		l.logsBucket = l.logsBucket.(*failingChunkBucket).Bucket
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line4\n", Encoding: encode.TEXT}))
+		read, err := read(l)
+		require.NoError(t, err)
+		assert.Equal(t, "line0\nline1\nline4\n", read)
+	})
+}
@@ -0,0 +1,431 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julianedwards/cedar/internal"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// spoolLogger writes chunks to a local spool and uploads them to the remote
+// bucket in the background.
+type spoolLogger struct {
+	local  *bucketLogger
+	remote *bucketLogger
+	opts   options.Spool
+	// uploadMu serializes upload passes over the spool.
+	uploadMu sync.Mutex
+	// metaMu is held for reading while metadata is written to the spool.
+	metaMu  sync.RWMutex
+	written chan struct{}
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewSpoolLogger returns a Logger that writes chunks to a local spool
+// directory, so that writes never block on or fail because of the remote
+// bucket, and uploads them to the remote bucket asynchronously, retrying
+// until they succeed. Uploaded chunks are removed from the spool, and chunks
+// left in the spool by a previous process are uploaded on creation. Reads
+// are served by the remote bucket, so chunks are only visible once
+// uploaded. The tags and storage class of each write are kept in the spool
+// alongside its chunks and applied on upload. The logger options apply to
+// both the spool and the remote bucket. Close must be called to stop the
+// background uploads.
+//
+// Chunks left in the spool by a process that crashed mid-write, i.e.
+// without a manifest entry or with only a temporary one, are recovered on
+// creation by rebuilding their entries. The spool directory must therefore
+// not be shared with another live spool logger.
+func NewSpoolLogger(ctx context.Context, remoteOpts options.Bucket, spoolOpts options.Spool, loggerOpts ...BucketLoggerOption) (*spoolLogger, error) {
+	if err := spoolOpts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid spool options")
+	}
+
+	remote, err := NewBucketLogger(ctx, remoteOpts, loggerOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating remote bucket logger")
+	}
+	local, err := NewBucketLogger(ctx, options.Bucket{
+		Type:   options.PailLocal,
+		Name:   spoolOpts.Dir,
+		Prefix: remoteOpts.Prefix,
+		Tenant: remoteOpts.Tenant,
+		// Encrypted chunks are compressed, or not, in the spool as
+		// they would be by the remote bucket.
+		S3: remoteOpts.S3,
+	}, loggerOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating spool bucket logger")
+	}
+	local.attributesBucket, err = local.session.Create(ctx, bucketPrefix(local.opts, "attributes"))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating spool attributes bucket")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l := &spoolLogger{
+		local:   local,
+		remote:  remote,
+		opts:    spoolOpts,
+		written: make(chan struct{}, 1),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	if err = l.recoverSpool(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+	go l.uploadLoop(ctx)
+
+	return l, nil
+}
+
+func (l *spoolLogger) AddMetadata(ctx context.Context, opts options.AddMetadata) error {
+	defer l.notify()
+
+	l.metaMu.RLock()
+	defer l.metaMu.RUnlock()
+
+	return l.local.AddMetadata(ctx, opts)
+}
+
+func (l *spoolLogger) Write(ctx context.Context, opts options.Write) error {
+	defer l.notify()
+	return l.local.Write(ctx, opts)
+}
+
+// writeWithChunks returns the chunks written to the spool, which are
+// uploaded under the same keys.
+func (l *spoolLogger) writeWithChunks(ctx context.Context, opts options.Write) ([]ManifestEntry, error) {
+	defer l.notify()
+	return l.local.writeWithChunks(ctx, opts)
+}
+
+func (l *spoolLogger) WriteBytes(ctx context.Context, opts options.WriteBytes) (WriteResult, error) {
+	defer l.notify()
+	return l.local.WriteBytes(ctx, opts)
+}
+
+func (l *spoolLogger) WriteReader(ctx context.Context, opts options.WriteReader) error {
+	defer l.notify()
+	return l.local.WriteReader(ctx, opts)
+}
+
+func (l *spoolLogger) FollowFile(ctx context.Context, opts options.FollowFile) error {
+	defer l.notify()
+	return l.local.FollowFile(ctx, opts)
+}
+
+func (l *spoolLogger) NewReadCloser(ctx context.Context, opts options.Read) (ReadCloser, error) {
+	return l.remote.NewReadCloser(ctx, opts)
+}
+
+func (l *spoolLogger) NewReverseReadCloser(ctx context.Context, opts options.Read) (ReadCloser, error) {
+	return l.remote.NewReverseReadCloser(ctx, opts)
+}
+
+func (l *spoolLogger) ReadLines(ctx context.Context, opts options.Read) ([]LogLine, string, error) {
+	return l.remote.ReadLines(ctx, opts)
+}
+
+func (l *spoolLogger) Search(ctx context.Context, opts options.Search) ([]SearchResult, error) {
+	return l.remote.Search(ctx, opts)
+}
+
+// Check checks both the spool and the remote bucket.
+func (l *spoolLogger) Check(ctx context.Context) error {
+	if err := l.local.Check(ctx); err != nil {
+		return errors.Wrap(err, "checking spool")
+	}
+
+	return errors.Wrap(l.remote.Check(ctx), "checking remote bucket")
+}
+
+func (l *spoolLogger) Child(prefix string) Logger { return NewChildLogger(l, prefix) }
+
+// Flush synchronously uploads all completely written spooled chunks, in the
+// order they were written, stopping at the first failure.
+func (l *spoolLogger) Flush(ctx context.Context) error {
+	l.uploadMu.Lock()
+	defer l.uploadMu.Unlock()
+
+	files, err := l.listSpool()
+	if err != nil {
+		return err
+	}
+
+	// A chunk is completely written once its manifest entry is, which is
+	// once the entry's temporary file has been removed.
+	complete := map[string]bool{}
+	for _, file := range files {
+		if file.dir != "manifest" || !strings.HasSuffix(file.key, manifestExtension) {
+			continue
+		}
+		if _, err = os.Stat(file.path + ".tmp"); os.IsNotExist(err) {
+			complete[strings.TrimSuffix(file.key, manifestExtension)] = true
+		}
+	}
+
+	attributes := map[string]spooledFile{}
+	for _, file := range files {
+		if file.dir == "attributes" {
+			attributes[strings.TrimSuffix(file.key, spooledAttributesExtension)] = file
+		}
+	}
+
+	for _, file := range files {
+		switch file.dir {
+		case "logs":
+			if !complete[file.key] {
+				continue
+			}
+			if err = l.uploadSpooledChunk(ctx, file, attributes); err != nil {
+				return err
+			}
+			continue
+		case "manifest":
+			if !complete[strings.TrimSuffix(file.key, manifestExtension)] {
+				continue
+			}
+		case "metadata":
+		default:
+			// The dedupe state and chunk attributes are only used by
+			// the spool.
+			continue
+		}
+
+		if err = l.uploadSpooled(ctx, file, spooledAttributes{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close stops the background uploads and then flushes the spool, returning
+// an error if any chunks could not be uploaded. Chunks that remain in the
+// spool are uploaded by the next spool logger created with the same
+// directory.
+func (l *spoolLogger) Close(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+
+	return l.Flush(ctx)
+}
+
+// spooledFile is a file in the spool, stored under the given key of the
+// spool's logs, metadata, manifest, dedupe, or attributes bucket.
+type spooledFile struct {
+	path string
+	dir  string
+	key  string
+}
+
+// listSpool returns the files in the spool sorted by path. Metadata is
+// listed while no metadata is being written, since metadata, unlike
+// chunks, has no manifest entry marking it as completely written.
+func (l *spoolLogger) listSpool() ([]spooledFile, error) {
+	l.metaMu.Lock()
+	defer l.metaMu.Unlock()
+
+	root := filepath.Join(l.opts.Dir, filepath.FromSlash(bucketRoot(l.local.opts)))
+	var files []spooledFile
+	err := filepath.WalkDir(root, func(fn string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, fn)
+		if err != nil {
+			return err
+		}
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		if len(parts) == 2 {
+			files = append(files, spooledFile{path: fn, dir: parts[0], key: parts[1]})
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "listing spool directory '%s'", root)
+	}
+
+	return files, nil
+}
+
+// recoverSpool removes the temporary manifest entries and the attributes
+// of chunks left in the spool by a process that crashed mid-write, and
+// rebuilds the manifest entries of their chunks, so that they are uploaded.
+func (l *spoolLogger) recoverSpool(ctx context.Context) error {
+	files, err := l.listSpool()
+	if err != nil {
+		return err
+	}
+
+	entries := map[string]bool{}
+	chunks := map[string]bool{}
+	for _, file := range files {
+		switch {
+		case file.dir == "manifest" && strings.HasSuffix(file.key, manifestTempExtension):
+			if err = os.Remove(file.path); err != nil {
+				return errors.Wrapf(err, "removing temporary manifest entry '%s'", file.path)
+			}
+		case file.dir == "manifest" && strings.HasSuffix(file.key, manifestExtension):
+			entries[strings.TrimSuffix(file.key, manifestExtension)] = true
+		case file.dir == "logs":
+			chunks[file.key] = true
+		}
+	}
+
+	for _, file := range files {
+		switch {
+		case file.dir == "logs" && !entries[file.key]:
+			if _, err = l.local.repairManifestEntry(ctx, file.key); err != nil {
+				return errors.Wrapf(err, "rebuilding manifest entry of spooled chunk '%s'", file.key)
+			}
+		case file.dir == "attributes" && !chunks[strings.TrimSuffix(file.key, spooledAttributesExtension)]:
+			if err = os.Remove(file.path); err != nil {
+				return errors.Wrapf(err, "removing attributes of missing chunk '%s'", file.path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// spooledAttributesExtension is the extension of the attributes stored
+// alongside spooled chunks.
+const spooledAttributesExtension = ".json"
+
+// spooledAttributes are the attributes of a spooled chunk that are only
+// stored by the remote bucket.
+type spooledAttributes struct {
+	Tags         map[string]string      `json:"tags,omitempty"`
+	StorageClass options.S3StorageClass `json:"storage_class,omitempty"`
+}
+
+// putSpooledAttributes stores the tags and storage class, if any, of a chunk
+// written to a spool, before the chunk itself.
+func (l *bucketLogger) putSpooledAttributes(ctx context.Context, key string, attrs internal.ObjectAttributes) error {
+	if l.attributesBucket == nil || (len(attrs.Tags) == 0 && attrs.StorageClass == "") {
+		return nil
+	}
+
+	data, err := json.Marshal(spooledAttributes{Tags: attrs.Tags, StorageClass: attrs.StorageClass})
+	if err != nil {
+		return errors.Wrap(err, "encoding chunk attributes")
+	}
+
+	return errors.Wrapf(l.put(ctx, l.attributesBucket, key+spooledAttributesExtension, data), "storing attributes of chunk '%s'", key)
+}
+
+// uploadSpooledChunk uploads a spooled chunk with its stored attributes, if
+// any, and removes both from the spool.
+func (l *spoolLogger) uploadSpooledChunk(ctx context.Context, file spooledFile, attributes map[string]spooledFile) error {
+	var attrs spooledAttributes
+	attrsFile, ok := attributes[file.key]
+	if ok {
+		data, err := os.ReadFile(attrsFile.path)
+		if err != nil {
+			return errors.Wrapf(err, "reading spooled attributes '%s'", attrsFile.path)
+		}
+		if err = json.Unmarshal(data, &attrs); err != nil {
+			return errors.Wrapf(err, "decoding spooled attributes '%s'", attrsFile.path)
+		}
+	}
+
+	if err := l.uploadSpooled(ctx, file, attrs); err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	return errors.Wrapf(os.Remove(attrsFile.path), "removing spooled attributes '%s'", attrsFile.path)
+}
+
+// uploadSpooled uploads a spooled file to the corresponding remote bucket,
+// with the given attributes if it is a chunk, and removes it from the spool.
+func (l *spoolLogger) uploadSpooled(ctx context.Context, file spooledFile, attrs spooledAttributes) error {
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		return errors.Wrapf(err, "reading spooled file '%s'", file.path)
+	}
+
+	if file.dir == "manifest" {
+		err = l.remote.put(ctx, l.remote.manifestBucket, file.key, data)
+	} else {
+		err = l.uploadSpooledObject(ctx, file, data, attrs)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "uploading spooled %s '%s'", file.dir, file.key)
+	}
+
+	return errors.Wrapf(os.Remove(file.path), "removing spooled file '%s'", file.path)
+}
+
+// uploadSpooledObject uploads a chunk or metadata object with the content
+// type of the encoding detected from its key.
+func (l *spoolLogger) uploadSpooledObject(ctx context.Context, file spooledFile, data []byte, attrs spooledAttributes) error {
+	var contentType string
+	if e, err := detectEncoding(l.remote.encodingRegistry, file.key); err == nil {
+		contentType = encodingContentType(e)
+	}
+
+	metadata := file.dir == "metadata"
+	bucket, err := l.remote.getContentTypeBucket(ctx, metadata, contentType)
+	if err != nil {
+		return err
+	}
+	// Spooled objects are already encrypted by the spool's buckets.
+	bucket = unencrypted(bucket)
+	if metadata {
+		return l.remote.put(ctx, bucket, file.key, data)
+	}
+
+	// The hooks already ran when the chunk was written to the spool.
+	return l.remote.uploadChunk(ctx, bucket, chunkAttributes(contentType, attrs.Tags, attrs.StorageClass), file.key, data)
+}
+
+// notify wakes the upload loop after a write.
+func (l *spoolLogger) notify() {
+	select {
+	case l.written <- struct{}{}:
+	default:
+	}
+}
+
+// uploadLoop flushes the spool on creation, after every write, and at every
+// interval until the logger is closed.
+func (l *spoolLogger) uploadLoop(ctx context.Context) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		// Failed uploads are left in the spool and retried on the
+		// next pass.
+		if err := l.Flush(ctx); err != nil && ctx.Err() == nil {
+			l.remote.handleError("spool upload", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.written:
+		case <-ticker.C:
+		}
+	}
+}
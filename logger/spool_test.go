@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpoolLogger(t *testing.T) {
+	ctx := context.Background()
+	remoteOpts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+	spoolOpts := options.Spool{Dir: t.TempDir(), Interval: time.Hour}
+
+	t.Run("UploadsOnFlush", func(t *testing.T) {
+		l, err := NewSpoolLogger(ctx, remoteOpts, spoolOpts)
+		require.NoError(t, err)
+		l.cancel()
+		<-l.done
+
+		require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "a\n"}))
+		_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("b\n")})
+		require.NoError(t, err)
+		require.NoError(t, l.AddMetadata(ctx, options.AddMetadata{Key: "key", Data: "meta"}))
+		assert.Len(t, spooledFiles(t, spoolOpts.Dir), 5)
+
+		lines, _, err := l.ReadLines(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		assert.Empty(t, lines, "chunks are not visible before they are uploaded")
+
+		require.NoError(t, l.Flush(ctx))
+		assert.Empty(t, spooledFiles(t, spoolOpts.Dir))
+		entries, err := l.remote.Manifest(ctx, "key")
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+		lines, _, err = l.ReadLines(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "a", lines[0].Data)
+	})
+	t.Run("UploadsInBackground", func(t *testing.T) {
+		l, err := NewSpoolLogger(ctx, remoteOpts, spoolOpts)
+		require.NoError(t, err)
+		defer func() { assert.NoError(t, l.Close(ctx)) }()
+
+		require.NoError(t, l.Write(ctx, options.Write{Key: "background", Data: "a\n"}))
+		assert.Eventually(t, func() bool {
+			entries, err := l.remote.Manifest(ctx, "background")
+			return err == nil && len(entries) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		_, err := NewSpoolLogger(ctx, remoteOpts, options.Spool{})
+		assert.Error(t, err)
+	})
+}
+
+func TestSpoolLoggerRecovery(t *testing.T) {
+	ctx := context.Background()
+	remoteOpts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+	spoolOpts := options.Spool{Dir: t.TempDir()}
+
+	// Simulate a process that crashed mid-write by writing to the spool
+	// directly and then undoing the last step of each write.
+	crashed, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: spoolOpts.Dir, Prefix: remoteOpts.Prefix})
+	require.NoError(t, err)
+	ts := time.Unix(100, 0).UTC()
+	for _, key := range []string{"complete", "no-entry", "temporary-entry", "partial-entry"} {
+		require.NoError(t, crashed.Write(ctx, options.Write{Key: key, Data: []LogLine{{Timestamp: ts, Data: key}}}))
+	}
+	manifestDir := filepath.Join(spoolOpts.Dir, "test", "manifest")
+	entryPath := func(key string) string {
+		entries, err := crashed.Manifest(ctx, key)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		return filepath.Join(manifestDir, entries[0].Key+manifestExtension)
+	}
+	require.NoError(t, os.Remove(entryPath("no-entry")))
+	temporary := entryPath("temporary-entry")
+	require.NoError(t, os.Rename(temporary, temporary+".tmp"))
+	// A partial entry is a temporary entry cut off mid-write.
+	partial := entryPath("partial-entry")
+	data, err := os.ReadFile(partial)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(partial))
+	require.NoError(t, os.WriteFile(partial+".tmp", data[:len(data)/2], 0644))
+
+	l, err := NewSpoolLogger(ctx, remoteOpts, spoolOpts)
+	require.NoError(t, err)
+	require.NoError(t, l.Close(ctx))
+
+	remote, err := NewBucketLogger(ctx, remoteOpts)
+	require.NoError(t, err)
+	for _, key := range []string{"complete", "no-entry", "temporary-entry", "partial-entry"} {
+		lines, _, err := remote.ReadLines(ctx, options.Read{Key: key})
+		require.NoError(t, err, key)
+		require.Len(t, lines, 1, key)
+		assert.Equal(t, key, lines[0].Data)
+
+		entries, err := remote.Manifest(ctx, key)
+		require.NoError(t, err)
+		require.Len(t, entries, 1, key)
+		assert.Equal(t, 1, entries[0].Lines, key)
+		assert.True(t, ts.Equal(entries[0].Start), key)
+	}
+	assert.Empty(t, spooledFiles(t, spoolOpts.Dir))
+}
+
+func TestSpoolLoggerAttributes(t *testing.T) {
+	ctx := context.Background()
+	tags := map[string]string{"project": "cedar"}
+	class := options.S3StorageClassStandardIA
+
+	for _, test := range []struct {
+		name  string
+		write func(Logger) error
+	}{
+		{
+			name: "Write",
+			write: func(l Logger) error {
+				return l.Write(ctx, options.Write{Key: "key", Data: "line\n", Tags: tags, StorageClass: class})
+			},
+		},
+		{
+			name: "WriteBytes",
+			write: func(l Logger) error {
+				_, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("line\n"), Tags: tags, StorageClass: class})
+				return err
+			},
+		},
+		{
+			name: "WriteBytesSniffed",
+			write: func(l Logger) error {
+				_, err := l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("line\n"), SniffContentType: true, Tags: tags, StorageClass: class})
+				return err
+			},
+		},
+		{
+			name: "WriteReader",
+			write: func(l Logger) error {
+				return l.WriteReader(ctx, options.WriteReader{Key: "key", Reader: strings.NewReader("line\n"), Tags: tags, StorageClass: class})
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			remoteOpts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+			spoolOpts := options.Spool{Dir: t.TempDir()}
+
+			l, err := NewSpoolLogger(ctx, remoteOpts, spoolOpts)
+			require.NoError(t, err)
+			// Stop the background uploads so that the spool can be
+			// inspected before the chunk is uploaded.
+			l.cancel()
+			<-l.done
+
+			require.NoError(t, test.write(l))
+			files := spooledFiles(t, spoolOpts.Dir)
+			var stored []string
+			for _, file := range files {
+				if strings.Contains(file, string(filepath.Separator)+"attributes"+string(filepath.Separator)) {
+					stored = append(stored, file)
+				}
+			}
+			require.Len(t, stored, 1)
+			data, err := os.ReadFile(stored[0])
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"tags":{"project":"cedar"},"storage_class":"STANDARD_IA"}`, string(data))
+
+			require.NoError(t, l.Flush(ctx))
+			assert.Empty(t, spooledFiles(t, spoolOpts.Dir))
+		})
+	}
+}
+
+// spooledFiles returns the chunks, metadata, manifest entries, and chunk
+// attributes left in the spool directory.
+func spooledFiles(t *testing.T, dir string) []string {
+	var files []string
+	for _, name := range []string{"logs", "metadata", "manifest", "attributes"} {
+		err := filepath.WalkDir(filepath.Join(dir, "test", name), func(path string, d os.DirEntry, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err == nil && !d.IsDir() {
+				files = append(files, path)
+			}
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	return files
+}
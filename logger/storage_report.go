@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julianedwards/cedar/internal"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// StorageReport breaks the storage used by the log chunks under a prefix
+// down by key prefix and storage class, e.g. to find which projects drive
+// storage costs.
+type StorageReport struct {
+	Prefix    string    `json:"prefix"`
+	Generated time.Time `json:"generated"`
+	// Prefixes are the usage of each group of keys, sorted by prefix.
+	Prefixes []PrefixStorage `json:"prefixes"`
+	Total    PrefixStorage   `json:"total"`
+}
+
+// PrefixStorage is the storage used by the log chunks of the keys under a
+// prefix.
+type PrefixStorage struct {
+	Prefix string `json:"prefix"`
+	Chunks int    `json:"chunks"`
+	Bytes  int64  `json:"bytes"`
+	// StorageClasses break the chunks down by storage class. Backends
+	// without storage classes report all chunks under the empty class.
+	StorageClasses map[string]StorageClassUsage `json:"storage_classes"`
+}
+
+// StorageClassUsage is the storage used by the log chunks of a storage
+// class.
+type StorageClassUsage struct {
+	Chunks int   `json:"chunks"`
+	Bytes  int64 `json:"bytes"`
+}
+
+func (s *PrefixStorage) add(class string, size int64) {
+	s.Chunks++
+	s.Bytes += size
+	if s.StorageClasses == nil {
+		s.StorageClasses = map[string]StorageClassUsage{}
+	}
+	usage := s.StorageClasses[class]
+	usage.Chunks++
+	usage.Bytes += size
+	s.StorageClasses[class] = usage
+}
+
+// StorageReport lists the log chunks under the options' prefix and
+// aggregates their count, size, and storage class by the leading elements
+// of their keys. Sizes and storage classes are taken from the listing where
+// the backend provides them, e.g. S3, where sizes are the stored, possibly
+// compressed, sizes. Otherwise sizes are taken from the manifest, and
+// chunks without a manifest entry are counted without a size.
+func (l *bucketLogger) StorageReport(ctx context.Context, opts options.StorageReport) (*StorageReport, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	it, err := internal.ListRange(ctx, l.logsBucket, keyListPrefix(opts.Prefix), "", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "listing log chunks")
+	}
+
+	report := &StorageReport{Prefix: opts.Prefix, Generated: l.now()}
+	groups := map[string]*PrefixStorage{}
+	var manifestSizes map[string]int64
+	for it.Next(ctx) {
+		item := it.Item()
+		var (
+			size  int64
+			class string
+		)
+		if info, ok := item.(internal.ObjectInfoItem); ok {
+			size, class = info.Size(), info.StorageClass()
+		} else {
+			if manifestSizes == nil {
+				if manifestSizes, err = l.manifestSizes(ctx, opts.Prefix); err != nil {
+					return nil, err
+				}
+			}
+			size = manifestSizes[item.Name()]
+		}
+
+		group := keyGroup(path.Dir(item.Name()), opts.Depth)
+		if groups[group] == nil {
+			groups[group] = &PrefixStorage{Prefix: group}
+		}
+		groups[group].add(class, size)
+		report.Total.add(class, size)
+	}
+	if err = it.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating log chunks")
+	}
+
+	report.Total.Prefix = opts.Prefix
+	for _, group := range groups {
+		report.Prefixes = append(report.Prefixes, *group)
+	}
+	sort.Slice(report.Prefixes, func(i, j int) bool { return report.Prefixes[i].Prefix < report.Prefixes[j].Prefix })
+
+	return report, nil
+}
+
+// manifestSizes returns the sizes recorded in the manifest of the chunks
+// under the prefix.
+func (l *bucketLogger) manifestSizes(ctx context.Context, prefix string) (map[string]int64, error) {
+	entries, err := l.Manifest(ctx, prefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting manifest of '%s'", prefix)
+	}
+
+	sizes := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		sizes[entry.Key] = int64(entry.Size)
+	}
+
+	return sizes, nil
+}
+
+// keyGroup returns the first depth elements of the key.
+func keyGroup(key string, depth int) string {
+	parts := strings.SplitN(key, "/", depth+1)
+	if len(parts) <= depth {
+		return key
+	}
+
+	return strings.Join(parts[:depth], "/")
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerStorageReport(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	for key, data := range map[string]string{
+		"project/task0":      "line0\n",
+		"project/task1":      "line0\nline1\n",
+		"project/task1/test": "line0\n",
+		"project2/task0":     "line0\n",
+	} {
+		require.NoError(t, l.Write(ctx, options.Write{Key: key, Data: data}))
+	}
+	require.NoError(t, l.Write(ctx, options.Write{Key: "project/task0", Data: "line1\n"}))
+	sizes, err := l.manifestSizes(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, sizes, 5)
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+
+	t.Run("AllKeys", func(t *testing.T) {
+		r, err := l.StorageReport(ctx, options.StorageReport{})
+		require.NoError(t, err)
+		assert.Equal(t, now, r.Generated)
+		require.Len(t, r.Prefixes, 2)
+		assert.Equal(t, "project", r.Prefixes[0].Prefix)
+		assert.Equal(t, 4, r.Prefixes[0].Chunks)
+		assert.Equal(t, "project2", r.Prefixes[1].Prefix)
+		assert.Equal(t, 1, r.Prefixes[1].Chunks)
+		assert.Equal(t, 5, r.Total.Chunks)
+		assert.Equal(t, total, r.Total.Bytes, "local sizes are taken from the manifest")
+		assert.Equal(t, map[string]StorageClassUsage{"": {Chunks: 5, Bytes: total}}, r.Total.StorageClasses)
+	})
+	t.Run("Depth", func(t *testing.T) {
+		r, err := l.StorageReport(ctx, options.StorageReport{Depth: 2})
+		require.NoError(t, err)
+		var prefixes []string
+		var chunks []int
+		for _, p := range r.Prefixes {
+			prefixes = append(prefixes, p.Prefix)
+			chunks = append(chunks, p.Chunks)
+		}
+		assert.Equal(t, []string{"project/task0", "project/task1", "project2/task0"}, prefixes)
+		assert.Equal(t, []int{2, 2, 1}, chunks)
+	})
+	t.Run("Prefix", func(t *testing.T) {
+		r, err := l.StorageReport(ctx, options.StorageReport{Prefix: "project"})
+		require.NoError(t, err)
+		assert.Equal(t, "project", r.Total.Prefix)
+		assert.Equal(t, 4, r.Total.Chunks, "sibling prefixes are not reported")
+		require.Len(t, r.Prefixes, 1)
+		assert.Equal(t, "project", r.Prefixes[0].Prefix)
+	})
+	t.Run("Empty", func(t *testing.T) {
+		r, err := l.StorageReport(ctx, options.StorageReport{Prefix: "missing"})
+		require.NoError(t, err)
+		assert.Empty(t, r.Prefixes)
+		assert.Zero(t, r.Total.Chunks)
+	})
+	t.Run("InvalidDepth", func(t *testing.T) {
+		_, err := l.StorageReport(ctx, options.StorageReport{Depth: -1})
+		assert.Error(t, err)
+	})
+}
+
+func TestKeyGroup(t *testing.T) {
+	assert.Equal(t, "project", keyGroup("project/task/test", 1))
+	assert.Equal(t, "project/task", keyGroup("project/task/test", 2))
+	assert.Equal(t, "project/task", keyGroup("project/task", 3))
+	assert.Equal(t, "project", keyGroup("project", 1))
+}
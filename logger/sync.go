@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/pkg/errors"
+)
+
+// SyncLocal mirrors all chunks of a key to a local directory. Chunk
+// filenames begin with their creation timestamp and sequence, so sorting
+// the files by name recovers log order. Since chunks are immutable, files
+// already present in the directory are not downloaded again, making
+// repeated syncs incremental.
+func (l *bucketLogger) SyncLocal(ctx context.Context, key, dir string) error {
+	if key == "" {
+		return errors.New("must specify a key")
+	}
+	if dir == "" {
+		return errors.New("must specify a directory")
+	}
+	if err := l.audit(ctx, AuditExport, key); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "creating directory '%s'", dir)
+	}
+	remote := keyListPrefix(key)
+	exclude, err := syncExclude(dir, remote)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(l.logsBucket.Pull(ctx, pail.SyncOptions{
+		Local:   dir,
+		Remote:  remote,
+		Exclude: exclude,
+	}), "syncing key '%s' to directory '%s'", key, dir)
+}
+
+// syncExclude returns a regular expression matching the files already in
+// the directory, or the empty string if there are none. Files are matched by
+// their slash-separated path relative to the directory, optionally preceded
+// by the remote prefix since backends may match against full object keys.
+func syncExclude(dir, remote string) (string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(fn string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, fn)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, regexp.QuoteMeta(filepath.ToSlash(rel)))
+
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "reading directory '%s'", dir)
+	}
+	if len(paths) == 0 {
+		return "", nil
+	}
+
+	return "^(" + regexp.QuoteMeta(remote) + ")?(" + strings.Join(paths, "|") + ")$", nil
+}
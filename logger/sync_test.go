@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerSyncLocal(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	write := func(key, data string) string {
+		res, err := l.WriteBytes(ctx, options.WriteBytes{Key: key, Data: []byte(data)})
+		require.NoError(t, err)
+		return res.Key
+	}
+	// files returns the slash-separated paths of all files in the
+	// directory, sorted.
+	files := func(t *testing.T, dir string) []string {
+		var paths []string
+		require.NoError(t, filepath.Walk(dir, func(fn string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, fn)
+			paths = append(paths, filepath.ToSlash(rel))
+			return err
+		}))
+		sort.Strings(paths)
+		return paths
+	}
+
+	first := write("key", "a\n")
+	nested := write("key/child", "b\n")
+	write("key2", "sibling\n")
+
+	dir := t.TempDir()
+	require.NoError(t, l.SyncLocal(ctx, "key", dir))
+	assert.Equal(t, []string{first[len("key/"):], nested[len("key/"):]}, files(t, dir))
+
+	t.Run("Incremental", func(t *testing.T) {
+		nestedFile := filepath.Join(dir, filepath.FromSlash(nested[len("key/"):]))
+		require.NoError(t, os.WriteFile(nestedFile, []byte("local\n"), 0644))
+		second := write("key", "c\n")
+
+		require.NoError(t, l.SyncLocal(ctx, "key", dir))
+		assert.Contains(t, files(t, dir), second[len("key/"):])
+		data, err := os.ReadFile(nestedFile)
+		require.NoError(t, err)
+		assert.Equal(t, "local\n", string(data), "existing nested file was downloaded again")
+	})
+	t.Run("InvalidArguments", func(t *testing.T) {
+		assert.Error(t, l.SyncLocal(ctx, "", dir))
+		assert.Error(t, l.SyncLocal(ctx, "key", ""))
+	})
+}
+
+func TestSyncExclude(t *testing.T) {
+	dir := t.TempDir()
+	exclude, err := syncExclude(dir, "key/")
+	require.NoError(t, err)
+	assert.Empty(t, exclude)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "child"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "child", "b.txt"), nil, 0644))
+	exclude, err = syncExclude(dir, "key/")
+	require.NoError(t, err)
+	re := regexp.MustCompile(exclude)
+
+	for _, name := range []string{"a.txt", "key/a.txt", "child/b.txt", "key/child/b.txt"} {
+		assert.True(t, re.MatchString(name), name)
+	}
+	for _, name := range []string{"b.txt", "child", "other/a.txt", "aatxt"} {
+		assert.False(t, re.MatchString(name), name)
+	}
+}
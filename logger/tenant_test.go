@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerTenant(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	newLogger := func(t *testing.T, tenant string) Logger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: dir, Prefix: "test", Tenant: tenant})
+		require.NoError(t, err)
+		return l
+	}
+	readLines := func(t *testing.T, l Logger, key string) []LogLine {
+		lines, _, err := l.ReadLines(ctx, options.Read{Key: key})
+		require.NoError(t, err)
+		return lines
+	}
+
+	a, b := newLogger(t, "a"), newLogger(t, "b")
+	require.NoError(t, a.Write(ctx, options.Write{Key: "key", Data: "a"}))
+	require.NoError(t, b.Write(ctx, options.Write{Key: "key", Data: "b"}))
+
+	lines := readLines(t, a, "key")
+	require.Len(t, lines, 1)
+	assert.Equal(t, "a", lines[0].Data, "tenants do not see each other's keys")
+	assert.Empty(t, readLines(t, newLogger(t, ""), "key"))
+
+	t.Run("EscapingKeys", func(t *testing.T) {
+		assert.Error(t, a.Write(ctx, options.Write{Key: "../../b/logs/key", Data: "escaped"}))
+		_, _, err := a.ReadLines(ctx, options.Read{Key: "../../b/logs/key"})
+		assert.Error(t, err)
+		assert.Len(t, readLines(t, b, "key"), 1)
+	})
+	t.Run("InvalidTenant", func(t *testing.T) {
+		for _, tenant := range []string{"a/b", "..", "."} {
+			_, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: dir, Prefix: "test", Tenant: tenant})
+			assert.Error(t, err, tenant)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// UploadFile uploads an existing file, or each regular file of a directory
+// in lexical order, to a key in chunks of at most the given size, split
+// between lines. This is useful for ingesting logs written by tools that do
+// not use the logger.
+func (l *bucketLogger) UploadFile(ctx context.Context, opts options.UploadFile) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	files, err := uploadFiles(opts.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range files {
+		if err = l.uploadFile(ctx, fn, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *bucketLogger) uploadFile(ctx context.Context, fn string, opts options.UploadFile) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return errors.Wrapf(err, "opening file '%s'", fn)
+	}
+	defer f.Close()
+
+	return errors.Wrapf(l.WriteReader(ctx, options.WriteReader{
+		Key:       opts.Key,
+		Reader:    f,
+		Encoding:  opts.Encoding,
+		ChunkSize: opts.ChunkSize,
+	}), "uploading file '%s'", fn)
+}
+
+// uploadFiles returns the path if it is a file, otherwise the regular files
+// of the directory sorted by name.
+func uploadFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting file info for '%s'", path)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading directory '%s'", path)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	return files, nil
+}
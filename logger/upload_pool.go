@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// UploadPool bounds the number of concurrent bucket uploads. A single pool
+// may be shared by any number of bucket loggers (and therefore senders), so
+// that a host running many tasks has a bounded total number of in-flight
+// requests to the backing store.
+type UploadPool struct {
+	slots    chan struct{}
+	inFlight int64
+}
+
+// NewUploadPool returns a new upload pool that runs at most concurrency
+// uploads at a time.
+func NewUploadPool(concurrency int) (*UploadPool, error) {
+	if concurrency <= 0 {
+		return nil, errors.New("upload pool concurrency must be positive")
+	}
+
+	return &UploadPool{slots: make(chan struct{}, concurrency)}, nil
+}
+
+// Concurrency returns the maximum number of concurrent uploads.
+func (p *UploadPool) Concurrency() int { return cap(p.slots) }
+
+// InFlight returns the number of uploads currently running.
+func (p *UploadPool) InFlight() int { return int(atomic.LoadInt64(&p.inFlight)) }
+
+// Do blocks until an upload slot is available, or the context errors, and
+// then runs the upload in the calling goroutine. It is safe to call on a nil
+// pool, in which case the upload runs immediately.
+func (p *UploadPool) Do(ctx context.Context, upload func() error) error {
+	if p == nil {
+		return upload()
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "waiting for upload slot")
+	}
+	atomic.AddInt64(&p.inFlight, 1)
+	defer func() {
+		atomic.AddInt64(&p.inFlight, -1)
+		<-p.slots
+	}()
+
+	return upload()
+}
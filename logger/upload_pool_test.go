@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadPool(t *testing.T) {
+	t.Run("InvalidConcurrency", func(t *testing.T) {
+		_, err := NewUploadPool(0)
+		assert.Error(t, err)
+	})
+	t.Run("NilPool", func(t *testing.T) {
+		var p *UploadPool
+		var ran bool
+		require.NoError(t, p.Do(context.Background(), func() error {
+			ran = true
+			return nil
+		}))
+		assert.True(t, ran)
+	})
+	t.Run("BoundsConcurrency", func(t *testing.T) {
+		p, err := NewUploadPool(2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, p.Concurrency())
+
+		var running, max int64
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, p.Do(context.Background(), func() error {
+					n := atomic.AddInt64(&running, 1)
+					for {
+						m := atomic.LoadInt64(&max)
+						if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					atomic.AddInt64(&running, -1)
+					return nil
+				}))
+			}()
+		}
+		wg.Wait()
+
+		assert.LessOrEqual(t, max, int64(2))
+		assert.Zero(t, p.InFlight())
+	})
+	t.Run("ContextCanceledWhileWaiting", func(t *testing.T) {
+		p, err := NewUploadPool(1)
+		require.NoError(t, err)
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		go func() {
+			_ = p.Do(context.Background(), func() error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		<-started
+		assert.Equal(t, 1, p.InFlight())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Error(t, p.Do(ctx, func() error {
+			t.Error("upload should not run")
+			return nil
+		}))
+		close(release)
+	})
+	t.Run("SharedByLoggers", func(t *testing.T) {
+		ctx := context.Background()
+		p, err := NewUploadPool(1)
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithUploadPool(p))
+			require.NoError(t, err)
+			assert.Equal(t, p, l.uploadPool)
+			_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("data\n")})
+			assert.NoError(t, err)
+		}
+		_, err = NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}, WithUploadPool(nil))
+		assert.Error(t, err)
+	})
+}
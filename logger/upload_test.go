@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerUploadFile(t *testing.T) {
+	ctx := context.Background()
+	newLogger := func(t *testing.T) *bucketLogger {
+		l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+		require.NoError(t, err)
+		return l
+	}
+	readAll := func(t *testing.T, l Logger) (string, int) {
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		defer r.Close()
+
+		var (
+			data   []byte
+			chunks int
+		)
+		for {
+			page, err := r.ReadPage()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			data = append(data, page...)
+			chunks++
+		}
+		return string(data), chunks
+	}
+
+	t.Run("File", func(t *testing.T) {
+		l := newLogger(t)
+		fn := filepath.Join(t.TempDir(), "file.log")
+		require.NoError(t, os.WriteFile(fn, []byte("abcdefgh\n"), 0644))
+
+		require.NoError(t, l.UploadFile(ctx, options.UploadFile{Key: "key", Path: fn, ChunkSize: 4}))
+		data, chunks := readAll(t, l)
+		assert.Equal(t, "abcdefgh\n", data)
+		assert.Equal(t, 3, chunks)
+	})
+	t.Run("LineBoundaries", func(t *testing.T) {
+		l := newLogger(t)
+		fn := filepath.Join(t.TempDir(), "file.log")
+		require.NoError(t, os.WriteFile(fn, []byte("{\"a\":1}\n{\"b\":2}\n{\"c\":3}\n"), 0644))
+
+		require.NoError(t, l.UploadFile(ctx, options.UploadFile{Key: "key", Path: fn, ChunkSize: 20}))
+		r, err := l.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		defer r.Close()
+		var pages []string
+		for {
+			page, err := r.ReadPage()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			pages = append(pages, string(page))
+		}
+		assert.Equal(t, []string{"{\"a\":1}\n{\"b\":2}\n", "{\"c\":3}\n"}, pages, "lines are not split across chunks")
+	})
+	t.Run("Directory", func(t *testing.T) {
+		l := newLogger(t)
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "b.log"), []byte("b\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.log"), []byte("a\n"), 0644))
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "c"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "c", "c.log"), []byte("c\n"), 0644))
+
+		require.NoError(t, l.UploadFile(ctx, options.UploadFile{Key: "key", Path: dir}))
+		data, chunks := readAll(t, l)
+		assert.Equal(t, "a\nb\n", data)
+		assert.Equal(t, 2, chunks)
+	})
+	t.Run("MissingPath", func(t *testing.T) {
+		assert.Error(t, newLogger(t).UploadFile(ctx, options.UploadFile{Key: "key", Path: filepath.Join(t.TempDir(), "missing")}))
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		l := newLogger(t)
+		assert.Error(t, l.UploadFile(ctx, options.UploadFile{Key: "key"}))
+		assert.Error(t, l.UploadFile(ctx, options.UploadFile{Key: "key", Path: "file.log", ChunkSize: -1}))
+	})
+}
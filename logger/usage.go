@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Usage summarizes the log chunks stored under a key prefix.
+type Usage struct {
+	Chunks int
+	Bytes  int64
+	Lines  int64
+	// First and Last are the earliest and latest log line timestamps of
+	// the chunks, or zero if there are no chunks.
+	First time.Time
+	Last  time.Time
+}
+
+// Usage returns the number, total size, and line count of the chunks
+// stored under the key prefix, e.g. a task or project, along with the time
+// span of their lines. It is computed from the manifest, so chunks written
+// without a manifest bucket are not counted.
+func (l *bucketLogger) Usage(ctx context.Context, prefix string) (Usage, error) {
+	if l.manifestBucket == nil {
+		return Usage{}, errors.New("usage statistics require a manifest bucket")
+	}
+
+	entries, err := l.Manifest(ctx, prefix)
+	if err != nil {
+		return Usage{}, errors.Wrapf(err, "getting manifest of '%s'", prefix)
+	}
+
+	var usage Usage
+	for _, entry := range entries {
+		usage.Chunks++
+		usage.Bytes += int64(entry.Size)
+		usage.Lines += int64(entry.Lines)
+		if usage.First.IsZero() || entry.Start.Before(usage.First) {
+			usage.First = entry.Start
+		}
+		if entry.End.After(usage.Last) {
+			usage.Last = entry.End
+		}
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerUsage(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	lines := func(offsets ...int) []LogLine {
+		var lines []LogLine
+		for _, offset := range offsets {
+			lines = append(lines, LogLine{Priority: level.Info, Timestamp: start.Add(time.Duration(offset) * time.Minute), Data: "line"})
+		}
+		return lines
+	}
+
+	require.NoError(t, l.Write(ctx, options.Write{Key: "task/a", Data: lines(5, 6), Encoding: encode.JSON}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "task/b", Data: lines(0, 1, 2), Encoding: encode.JSON}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "other", Data: lines(10), Encoding: encode.JSON}))
+
+	usage, err := l.Usage(ctx, "task")
+	require.NoError(t, err)
+	assert.Equal(t, 2, usage.Chunks)
+	assert.EqualValues(t, 5, usage.Lines)
+	entries, err := l.Manifest(ctx, "task")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.EqualValues(t, entries[0].Size+entries[1].Size, usage.Bytes)
+	assert.True(t, start.Equal(usage.First))
+	assert.True(t, start.Add(6*time.Minute).Equal(usage.Last))
+
+	usage, err = l.Usage(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, Usage{}, usage)
+}
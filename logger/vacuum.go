@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/pkg/errors"
+)
+
+// VacuumReport describes the inconsistencies between the log chunks and
+// the manifest found, and fixed, by Vacuum.
+type VacuumReport struct {
+	Prefix string `json:"prefix"`
+	// Chunks and Entries are the number of log chunks and manifest
+	// entries checked.
+	Chunks  int `json:"chunks"`
+	Entries int `json:"entries"`
+	// RepairedChunks are the keys of the chunks that had no manifest
+	// entry, e.g. because a write failed between uploading the chunk and
+	// its entry, and whose entry was rebuilt from their contents.
+	RepairedChunks []string `json:"repaired_chunks,omitempty"`
+	// UnsignedChunks are the keys of the chunks that had no manifest
+	// entry and were left as is because the logger signs manifests, so
+	// that chunks added by someone else are not signed; see
+	// VerifyManifest.
+	UnsignedChunks []string `json:"unsigned_chunks,omitempty"`
+	// RemovedEntries are the keys of the chunks whose manifest entries
+	// were removed because the chunk no longer exists.
+	RemovedEntries []string `json:"removed_entries,omitempty"`
+}
+
+// Vacuum reconciles the log chunks under the prefix with the manifest:
+// entries are rebuilt for chunks missing from the manifest and entries of
+// missing chunks are removed. Loggers that sign manifests only report
+// chunks without an entry instead of signing new entries for them. The
+// prefix matches whole key elements, e.g. "project" does not match
+// "project2". Dry run loggers only report what they find. Vacuum is safe
+// to run alongside writers, which always upload a chunk before its entry,
+// since the manifest is listed before the chunks.
+func (l *bucketLogger) Vacuum(ctx context.Context, prefix string) (*VacuumReport, error) {
+	if err := l.checkWritable("Vacuum"); err != nil {
+		return nil, err
+	}
+	if l.manifestBucket == nil {
+		return nil, errors.New("cannot vacuum a logger without a manifest bucket")
+	}
+
+	entries, err := listManifestKeys(ctx, l.manifestBucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	r := &bucketReader{ctx: ctx, bucket: l.logsBucket}
+	if err = r.getAndSortKeys(prefix, false); err != nil {
+		return nil, err
+	}
+
+	report := &VacuumReport{Prefix: prefix, Chunks: len(r.keys), Entries: len(entries)}
+	chunks := make(map[string]bool, len(r.keys))
+	for _, key := range r.keys {
+		chunks[key] = true
+		if entries[key] {
+			continue
+		}
+		if l.manifestSigner != nil {
+			report.UnsignedChunks = append(report.UnsignedChunks, key)
+			continue
+		}
+
+		repaired, err := l.repairManifestEntry(ctx, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "repairing manifest entry for chunk '%s'", key)
+		}
+		if repaired {
+			report.RepairedChunks = append(report.RepairedChunks, key)
+		}
+	}
+
+	var remove []string
+	for key := range entries {
+		if !chunks[key] {
+			report.RemovedEntries = append(report.RemovedEntries, key)
+			remove = append(remove, key+manifestExtension)
+		}
+	}
+	sort.Strings(report.RemovedEntries)
+	if l.dryRun == nil {
+		if err = removeKeys(ctx, l.manifestBucket, remove); err != nil {
+			return nil, errors.Wrap(err, "removing manifest entries of missing chunks")
+		}
+	}
+
+	return report, nil
+}
+
+// listManifestKeys returns the set of chunk keys under the prefix with a
+// manifest entry.
+func listManifestKeys(ctx context.Context, bucket pail.Bucket, prefix string) (map[string]bool, error) {
+	it, err := bucket.List(ctx, keyListPrefix(prefix))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing manifest entries")
+	}
+
+	keys := map[string]bool{}
+	for it.Next(ctx) {
+		if name := it.Item().Name(); strings.HasSuffix(name, manifestExtension) {
+			keys[strings.TrimSuffix(name, manifestExtension)] = true
+		}
+	}
+
+	return keys, errors.Wrap(it.Err(), "iterating manifest entries")
+}
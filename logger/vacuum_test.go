@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketLoggerVacuum(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (options.Bucket, *bucketLogger, string, string) {
+		opts := options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"}
+		l, err := NewBucketLogger(ctx, opts)
+		require.NoError(t, err)
+
+		var keys []string
+		for _, data := range []string{"a\n", "b\n", "c\n"} {
+			res, err := l.WriteBytes(ctx, options.WriteBytes{Key: "project", Data: []byte(data)})
+			require.NoError(t, err)
+			keys = append(keys, res.Key)
+		}
+		_, err = l.WriteBytes(ctx, options.WriteBytes{Key: "project2", Data: []byte("d\n")})
+		require.NoError(t, err)
+
+		unlisted, removed := keys[0], keys[1]
+		require.NoError(t, l.manifestBucket.Remove(ctx, unlisted+manifestExtension))
+		require.NoError(t, l.logsBucket.Remove(ctx, removed))
+
+		return opts, l, unlisted, removed
+	}
+
+	t.Run("Repairs", func(t *testing.T) {
+		_, l, unlisted, removed := setup(t)
+
+		report, err := l.Vacuum(ctx, "project")
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Chunks)
+		assert.Equal(t, 2, report.Entries)
+		assert.Equal(t, []string{unlisted}, report.RepairedChunks)
+		assert.Equal(t, []string{removed}, report.RemovedEntries)
+
+		entries, err := l.Manifest(ctx, "project")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, unlisted, entries[0].Key)
+		assert.Equal(t, 1, entries[0].Lines)
+
+		report, err = l.Vacuum(ctx, "project")
+		require.NoError(t, err)
+		assert.Empty(t, report.RepairedChunks)
+		assert.Empty(t, report.RemovedEntries)
+	})
+	t.Run("DryRun", func(t *testing.T) {
+		opts, _, unlisted, removed := setup(t)
+		dryRun, err := NewBucketLogger(ctx, opts, WithDryRun(send.MakeInternalLogger()))
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			report, err := dryRun.Vacuum(ctx, "project")
+			require.NoError(t, err)
+			assert.Equal(t, []string{unlisted}, report.RepairedChunks)
+			assert.Equal(t, []string{removed}, report.RemovedEntries)
+		}
+	})
+	t.Run("SignedManifest", func(t *testing.T) {
+		opts, _, unlisted, removed := setup(t)
+		signer, err := NewHMACSigner(bytes.Repeat([]byte("k"), minHMACKeySize))
+		require.NoError(t, err)
+		l, err := NewBucketLogger(ctx, opts, WithManifestSigner(signer))
+		require.NoError(t, err)
+
+		report, err := l.Vacuum(ctx, "project")
+		require.NoError(t, err)
+		assert.Empty(t, report.RepairedChunks)
+		assert.Equal(t, []string{unlisted}, report.UnsignedChunks)
+		assert.Equal(t, []string{removed}, report.RemovedEntries)
+		exists, err := l.manifestBucket.Exists(ctx, unlisted+manifestExtension)
+		require.NoError(t, err)
+		assert.False(t, exists, "chunks without an entry are not signed")
+	})
+	t.Run("SiblingPrefix", func(t *testing.T) {
+		_, l, _, _ := setup(t)
+
+		report, err := l.Vacuum(ctx, "project2")
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Chunks)
+		assert.Equal(t, 1, report.Entries)
+		assert.Empty(t, report.RepairedChunks)
+		assert.Empty(t, report.RemovedEntries)
+	})
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const walExtension = ".wal"
+
+// writeAheadLog is an append-only local file of the lines currently in a
+// sender's buffer, one JSON encoded line per line of the file. The file is
+// truncated whenever the buffer is flushed or spilled, so after a crash it
+// holds exactly the lines that were lost from the buffer.
+type writeAheadLog struct {
+	dir  string
+	file *os.File
+}
+
+// newWriteAheadLog creates a new write-ahead log segment for the sender for
+// the given key in the given directory, which is created if it does not
+// exist. Segment names begin with their creation time, so that they sort in
+// creation order. The directory must not belong to a sender for a
+// different key.
+func newWriteAheadLog(dir, key string, now time.Time) (*writeAheadLog, error) {
+	if err := claimSenderDir(dir, key); err != nil {
+		return nil, errors.Wrap(err, "claiming write-ahead log directory")
+	}
+	id, err := newInstanceID()
+	if err != nil {
+		return nil, err
+	}
+
+	fn := filepath.Join(dir, fmt.Sprintf("%019d_%s%s", now.UnixNano(), id, walExtension))
+	f, err := os.OpenFile(fn, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating write-ahead log segment '%s'", fn)
+	}
+	// The segment stays locked while the sender is alive, so that other
+	// senders sharing the directory do not recover it.
+	if locked, err := tryLockFile(f); !locked {
+		_ = f.Close()
+		_ = os.Remove(fn)
+		if err == nil {
+			err = errors.New("segment is locked")
+		}
+		return nil, errors.Wrapf(err, "locking write-ahead log segment '%s'", fn)
+	}
+
+	return &writeAheadLog{dir: dir, file: f}, nil
+}
+
+// append writes the line to the segment. The line is written directly to
+// the file, so that it survives the process being killed, but is not synced
+// to disk.
+func (w *writeAheadLog) append(line LogLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return errors.Wrap(err, "encoding write-ahead log line")
+	}
+
+	_, err = w.file.Write(append(data, '\n'))
+	return errors.Wrap(err, "writing write-ahead log line")
+}
+
+// truncate discards all lines in the segment.
+func (w *writeAheadLog) truncate() error {
+	if err := w.file.Truncate(0); err != nil {
+		return errors.Wrap(err, "truncating write-ahead log segment")
+	}
+	_, err := w.file.Seek(0, io.SeekStart)
+
+	return errors.Wrap(err, "truncating write-ahead log segment")
+}
+
+// close closes the segment, removing it if it is empty. A segment that is
+// not empty is left for recovery.
+func (w *writeAheadLog) close() error {
+	info, err := w.file.Stat()
+	if err != nil {
+		return errors.Wrap(err, "getting write-ahead log segment info")
+	}
+	if err = w.file.Close(); err != nil {
+		return errors.Wrap(err, "closing write-ahead log segment")
+	}
+	if info.Size() > 0 {
+		return nil
+	}
+
+	return errors.Wrap(os.Remove(w.file.Name()), "removing write-ahead log segment")
+}
+
+// recover passes the lines of each segment in the directory, other than the
+// log's own and those locked by other live senders, to write and removes
+// the segment once written, stopping at the first error. A trailing
+// partially written line, e.g. from a process killed mid-append, is
+// ignored.
+func (w *writeAheadLog) recover(write func([]LogLine) error) error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return errors.Wrapf(err, "reading write-ahead log directory '%s'", w.dir)
+	}
+
+	var segments []string
+	for _, entry := range entries {
+		fn := filepath.Join(w.dir, entry.Name())
+		if entry.IsDir() || !strings.HasSuffix(fn, walExtension) || fn == w.file.Name() {
+			continue
+		}
+		segments = append(segments, fn)
+	}
+	sort.Strings(segments)
+
+	for _, fn := range segments {
+		if err = recoverWriteAheadLogSegment(fn, write); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// minStaleSegmentAge is the age after which an empty unlocked segment is
+// removed. New segments are briefly empty and unlocked before their sender
+// locks them.
+const minStaleSegmentAge = time.Minute
+
+// recoverWriteAheadLogSegment passes the lines of the segment to write and
+// removes it, unless it is locked by a live sender.
+func recoverWriteAheadLogSegment(fn string, write func([]LogLine) error) error {
+	f, err := os.OpenFile(fn, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		// The segment was recovered by another sender.
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "opening write-ahead log segment '%s'", fn)
+	}
+	defer f.Close()
+
+	locked, err := tryLockFile(f)
+	if err != nil {
+		return errors.Wrapf(err, "locking write-ahead log segment '%s'", fn)
+	}
+	if !locked {
+		return nil
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return errors.Wrapf(err, "reading write-ahead log segment '%s'", fn)
+	}
+	if len(data) == 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return errors.Wrapf(err, "getting write-ahead log segment info '%s'", fn)
+		}
+		if time.Since(info.ModTime()) < minStaleSegmentAge {
+			return nil
+		}
+	}
+
+	lines, err := decodeWriteAheadLogSegment(fn, data)
+	if err != nil {
+		return err
+	}
+	if len(lines) > 0 {
+		if err = write(lines); err != nil {
+			return errors.Wrapf(err, "writing lines of write-ahead log segment '%s'", fn)
+		}
+	}
+	// The segment is emptied while still locked, so that a sender that
+	// locks it before it is removed does not write its lines again.
+	if err = f.Truncate(0); err != nil {
+		return errors.Wrapf(err, "truncating write-ahead log segment '%s'", fn)
+	}
+	if err = f.Close(); err != nil {
+		return errors.Wrapf(err, "closing write-ahead log segment '%s'", fn)
+	}
+	if err = os.Remove(fn); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "removing write-ahead log segment '%s'", fn)
+	}
+
+	return nil
+}
+
+func decodeWriteAheadLogSegment(fn string, data []byte) ([]LogLine, error) {
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[:i+1]
+	} else {
+		data = nil
+	}
+
+	var lines []LogLine
+	for i, raw := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+		if len(raw) == 0 {
+			continue
+		}
+		var line LogLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, errors.Wrapf(err, "decoding line %d of write-ahead log segment '%s'", i+1, fn)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,36 @@
+package options
+
+import "github.com/mongodb/grip"
+
+const defaultAssembleChunkLines = 10000
+
+type Assemble struct {
+	// Keys are the keys whose lines are merged, e.g. the logs of every
+	// host of a distributed test.
+	Keys []string
+	// Key is the key the merged log is written to.
+	Key string
+	// ChunkLines is the number of lines written per chunk of the merged
+	// log. Defaults to 10,000.
+	ChunkLines int
+	// Encoding is the encoding of the merged log's chunks. Defaults to
+	// JSON, which preserves every field of the lines.
+	Encoding string
+}
+
+func (o *Assemble) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(len(o.Keys) == 0, "must specify at least one key to assemble")
+	catcher.NewWhen(o.Key == "", "must specify a key")
+	catcher.NewWhen(o.ChunkLines < 0, "chunk lines cannot be negative")
+	for _, key := range o.Keys {
+		catcher.NewWhen(key == "", "keys to assemble cannot be empty")
+		catcher.ErrorfWhen(key == o.Key, "cannot assemble key '%s' into itself", key)
+	}
+
+	if o.ChunkLines == 0 {
+		o.ChunkLines = defaultAssembleChunkLines
+	}
+
+	return catcher.Resolve()
+}
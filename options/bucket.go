@@ -1,33 +1,127 @@
 package options
 
 import (
+	"strings"
+	"time"
+
 	"github.com/mongodb/grip"
 	"github.com/pkg/errors"
 )
 
-const defaultS3Region = "us-east-1"
+const (
+	defaultS3Region               = "us-east-1"
+	defaultS3MultipartThreshold   = 100 * 1024 * 1024
+	defaultS3MultipartPartSize    = 16 * 1024 * 1024
+	minS3MultipartPartSize        = 5 * 1024 * 1024
+	defaultS3MultipartConcurrency = 5
+)
 
 type PailType string
 
+// S3StorageClass is the S3 storage class of uploaded log chunks. Only
+// classes whose objects can be read without being restored are supported.
+type S3StorageClass string
+
 const (
-	PailS3    = "s3"
-	PailLocal = "local"
+	S3StorageClassStandard           S3StorageClass = "STANDARD"
+	S3StorageClassStandardIA         S3StorageClass = "STANDARD_IA"
+	S3StorageClassOneZoneIA          S3StorageClass = "ONEZONE_IA"
+	S3StorageClassIntelligentTiering S3StorageClass = "INTELLIGENT_TIERING"
+	S3StorageClassGlacierIR          S3StorageClass = "GLACIER_IR"
 )
 
-func (t PailType) validate() error {
-	switch t {
-	case PailS3, PailLocal:
+func (c S3StorageClass) validate() error {
+	switch c {
+	case "", S3StorageClassStandard, S3StorageClassStandardIA, S3StorageClassOneZoneIA, S3StorageClassIntelligentTiering, S3StorageClassGlacierIR:
+		return nil
+	default:
+		return errors.Errorf("unsupported S3 storage class '%s'", c)
+	}
+}
+
+const (
+	PailS3     = "s3"
+	PailLocal  = "local"
+	PailGridFS = "gridfs"
+	PailSFTP   = "sftp"
+)
+
+// LocalShardScheme determines how a local bucket fans its objects out into
+// subdirectories.
+type LocalShardScheme string
+
+const (
+	// LocalShardNone stores objects directly under their key's directory.
+	LocalShardNone LocalShardScheme = ""
+	// LocalShardHash stores each object under two levels of subdirectories
+	// named by a hash of its name, e.g. "<key>/3f/a2/<chunk>".
+	LocalShardHash LocalShardScheme = "hash"
+	// LocalShardDate stores each log chunk under subdirectories named by
+	// the UTC date it was written, e.g. "<key>/2021/11/19/<chunk>". Objects
+	// whose names do not begin with a timestamp are not sharded.
+	LocalShardDate LocalShardScheme = "date"
+)
+
+func (s LocalShardScheme) validate() error {
+	switch s {
+	case LocalShardNone, LocalShardHash, LocalShardDate:
 		return nil
 	default:
-		return errors.Errorf("unrecognized Pail type '%s'", t)
+		return errors.Errorf("unrecognized local shard scheme '%s'", s)
+	}
+}
+
+func isBuiltinPailType(t PailType) bool {
+	switch t {
+	case PailS3, PailLocal, PailGridFS, PailSFTP:
+		return true
+	default:
+		return false
+	}
+}
+
+// validate accepts the built-in types and any type registered with the
+// global bucket session registry.
+func (t PailType) validate() error {
+	if isBuiltinPailType(t) {
+		return nil
 	}
+	if _, ok := GetGlobalBucketSessionRegistry().Get(t); ok {
+		return nil
+	}
+
+	return errors.Errorf("unrecognized Pail type '%s'", t)
 }
 
 type Bucket struct {
-	Type   PailType
-	Name   string
-	Prefix string
-	S3     *S3Bucket
+	Type PailType `bson:"type" json:"type" yaml:"type"`
+	// Name is the name of the bucket. For GridFS buckets, it is the name
+	// of the GridFS bucket, i.e. the prefix of its files and chunks
+	// collections. For SFTP buckets, it is the root directory on the
+	// server.
+	Name   string        `bson:"name" json:"name" yaml:"name"`
+	Prefix string        `bson:"prefix" json:"prefix" yaml:"prefix"`
+	S3     *S3Bucket     `bson:"s3" json:"s3" yaml:"s3"`
+	GridFS *GridFSBucket `bson:"gridfs" json:"gridfs" yaml:"gridfs"`
+	SFTP   *SFTPBucket   `bson:"sftp" json:"sftp" yaml:"sftp"`
+	// Custom holds backend specific settings for bucket types registered
+	// with the global bucket session registry.
+	Custom interface{} `bson:"-" json:"-" yaml:"-"`
+	// OperationTimeout, if set, bounds each single object bucket
+	// operation, e.g. a Put, Get, or page of a List, so that a hung
+	// connection cannot block a write or read forever. Multipart uploads
+	// are not bounded.
+	OperationTimeout time.Duration `bson:"operation_timeout" json:"operation_timeout" yaml:"operation_timeout"`
+	// LocalSharding, if set, fans the objects of a local bucket out into
+	// subdirectories, so that no single directory holds millions of
+	// chunks. Keys are unaffected, though listings are no longer in key
+	// order.
+	LocalSharding LocalShardScheme `bson:"local_sharding" json:"local_sharding" yaml:"local_sharding"`
+	// Tenant, if set, confines the logger to the tenant's own buckets,
+	// stored under "<Prefix>/tenants/<Tenant>". Keys are then rejected
+	// if they could address objects outside of the tenant's buckets,
+	// e.g. if they contain ".." elements.
+	Tenant string `bson:"tenant" json:"tenant" yaml:"tenant"`
 }
 
 func (o *Bucket) Validate() error {
@@ -35,19 +129,47 @@ func (o *Bucket) Validate() error {
 	catcher.Add(o.Type.validate())
 	catcher.NewWhen(o.Name == "", "must specify bucket name")
 	catcher.NewWhen(o.Prefix == "", "must specify prefix name")
+	catcher.NewWhen(o.OperationTimeout < 0, "operation timeout cannot be negative")
+	catcher.Add(o.LocalSharding.validate())
+	catcher.NewWhen(strings.Contains(o.Tenant, "/") || o.Tenant == "." || o.Tenant == "..", "tenant cannot contain '/' or be a relative path element")
+	catcher.NewWhen(o.LocalSharding != LocalShardNone && o.Type != PailLocal, "local sharding is only supported by local buckets")
 
 	switch o.Type {
 	case PailS3:
 		catcher.Add(o.S3.validate())
+	case PailGridFS:
+		catcher.Add(o.GridFS.validate())
+	case PailSFTP:
+		catcher.Add(o.SFTP.validate())
 	}
 
 	return catcher.Resolve()
 }
 
 type S3Bucket struct {
-	Key    string
-	Secret string
-	Region string
+	Key    string `bson:"key" json:"key" yaml:"key"`
+	Secret string `bson:"secret" json:"secret" yaml:"secret"`
+	Region string `bson:"region" json:"region" yaml:"region"`
+
+	// MultipartThreshold is the size, in bytes, at or above which a chunk
+	// is uploaded with S3 multipart upload instead of a single Put.
+	// Defaults to 100MB; a negative value disables multipart uploads.
+	MultipartThreshold int64 `bson:"multipart_threshold" json:"multipart_threshold" yaml:"multipart_threshold"`
+	// MultipartPartSize is the size, in bytes, of each part of a multipart
+	// upload. Defaults to 16MB and must be at least 5MB.
+	MultipartPartSize int64 `bson:"multipart_part_size" json:"multipart_part_size" yaml:"multipart_part_size"`
+	// MultipartConcurrency is the number of parts of a single multipart
+	// upload that are uploaded concurrently. Defaults to 5.
+	MultipartConcurrency int `bson:"multipart_concurrency" json:"multipart_concurrency" yaml:"multipart_concurrency"`
+	// DisableCompression uploads objects as is instead of gzipping them,
+	// e.g. to avoid compressing chunks of already compressed formats,
+	// such as Parquet, twice.
+	DisableCompression bool `bson:"disable_compression" json:"disable_compression" yaml:"disable_compression"`
+	// StorageClass, if set, is the storage class of uploaded log chunks,
+	// e.g. STANDARD_IA for rarely read logs. Metadata and manifest
+	// entries are always stored in the STANDARD class. Defaults to the
+	// bucket's default, i.e. STANDARD.
+	StorageClass S3StorageClass `bson:"storage_class" json:"storage_class" yaml:"storage_class"`
 }
 
 func (o *S3Bucket) validate() error {
@@ -59,9 +181,67 @@ func (o *S3Bucket) validate() error {
 	catcher.NewWhen(o.Key == "", "must specify AWS S3 key")
 	catcher.NewWhen(o.Secret == "", "must specify AWS S3 secret")
 
+	catcher.NewWhen(o.MultipartPartSize != 0 && o.MultipartPartSize < minS3MultipartPartSize, "multipart part size must be at least 5MB")
+	catcher.NewWhen(o.MultipartConcurrency < 0, "multipart concurrency cannot be negative")
+	catcher.Add(o.StorageClass.validate())
+
 	if o.Region == "" {
 		o.Region = defaultS3Region
 	}
+	if o.MultipartThreshold == 0 {
+		o.MultipartThreshold = defaultS3MultipartThreshold
+	}
+	if o.MultipartPartSize == 0 {
+		o.MultipartPartSize = defaultS3MultipartPartSize
+	}
+	if o.MultipartConcurrency == 0 {
+		o.MultipartConcurrency = defaultS3MultipartConcurrency
+	}
+
+	return catcher.Resolve()
+}
+
+type GridFSBucket struct {
+	// URI is the MongoDB connection string.
+	URI      string `bson:"uri" json:"uri" yaml:"uri"`
+	Database string `bson:"database" json:"database" yaml:"database"`
+}
+
+func (o *GridFSBucket) validate() error {
+	if o == nil {
+		return errors.New("must specify GridFS bucket options")
+	}
+
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.URI == "", "must specify MongoDB URI")
+	catcher.NewWhen(o.Database == "", "must specify MongoDB database")
+
+	return catcher.Resolve()
+}
+
+type SFTPBucket struct {
+	// Address is the host and port of the SFTP server.
+	Address string `bson:"address" json:"address" yaml:"address"`
+	User    string `bson:"user" json:"user" yaml:"user"`
+	// Password and PrivateKey, a PEM encoded key, are the credentials
+	// used to authenticate. At least one must be set.
+	Password   string `bson:"password" json:"password" yaml:"password"`
+	PrivateKey string `bson:"private_key" json:"private_key" yaml:"private_key"`
+	// HostKey is the server's public key, in authorized_keys format.
+	// Connections to servers presenting any other key are refused.
+	HostKey string `bson:"host_key" json:"host_key" yaml:"host_key"`
+}
+
+func (o *SFTPBucket) validate() error {
+	if o == nil {
+		return errors.New("must specify SFTP bucket options")
+	}
+
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Address == "", "must specify SFTP server address")
+	catcher.NewWhen(o.User == "", "must specify SFTP user")
+	catcher.NewWhen(o.Password == "" && o.PrivateKey == "", "must specify SFTP password or private key")
+	catcher.NewWhen(o.HostKey == "", "must specify SFTP server host key")
 
 	return catcher.Resolve()
 }
@@ -0,0 +1,87 @@
+package options
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/pkg/errors"
+)
+
+// BucketSession creates the buckets of a backend at any key prefix.
+type BucketSession interface {
+	Create(context.Context, string) (pail.Bucket, error)
+}
+
+// BucketSessionFactory returns a session for the given bucket options. The
+// options have already been validated; backend specific settings may be
+// passed in Bucket.Custom.
+type BucketSessionFactory func(context.Context, Bucket) (BucketSession, error)
+
+var globalBucketSessionRegistry = &bucketSessionRegistry{
+	registry: map[PailType]BucketSessionFactory{},
+}
+
+// GetGlobalBucketSessionRegistry returns the registry of custom bucket
+// types consulted when creating buckets.
+func GetGlobalBucketSessionRegistry() *bucketSessionRegistry { return globalBucketSessionRegistry }
+
+// bucketSessionRegistry maps custom bucket types, referenced by
+// Bucket.Type, to the factories of their sessions, so that in-house object
+// stores can be plugged in alongside the built-in backends.
+type bucketSessionRegistry struct {
+	mu       sync.RWMutex
+	registry map[PailType]BucketSessionFactory
+}
+
+// AddNew registers a new bucket type, returning an error if the type is
+// built in or already registered.
+func (r *bucketSessionRegistry) AddNew(t PailType, factory BucketSessionFactory) error {
+	if t == "" || factory == nil {
+		return errors.New("must specify bucket type and session factory")
+	}
+	if isBuiltinPailType(t) {
+		return errors.Errorf("bucket type '%s' is built in", t)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.registry[t]; ok {
+		return errors.Errorf("bucket type '%s' is already registered", t)
+	}
+	r.registry[t] = factory
+
+	return nil
+}
+
+// Remove unregisters the bucket type, if registered.
+func (r *bucketSessionRegistry) Remove(t PailType) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.registry, t)
+}
+
+func (r *bucketSessionRegistry) Get(t PailType) (BucketSessionFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	factory, ok := r.registry[t]
+	return factory, ok
+}
+
+// Types returns the registered bucket types, sorted.
+func (r *bucketSessionRegistry) Types() []PailType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]PailType, 0, len(r.registry))
+	for t := range r.registry {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return types
+}
@@ -0,0 +1,41 @@
+package options
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketSessionRegistry(t *testing.T) {
+	factory := func(context.Context, Bucket) (BucketSession, error) { return nil, nil }
+	registry := GetGlobalBucketSessionRegistry()
+
+	t.Run("InvalidArguments", func(t *testing.T) {
+		assert.Error(t, registry.AddNew("", factory))
+		assert.Error(t, registry.AddNew("custom", nil))
+		assert.Error(t, registry.AddNew(PailS3, factory), "built-in types cannot be replaced")
+	})
+	t.Run("AddNew", func(t *testing.T) {
+		opts := Bucket{Type: "custom", Name: "bucket", Prefix: "prefix"}
+		assert.Error(t, opts.Validate(), "unregistered types are invalid")
+
+		require.NoError(t, registry.AddNew("custom", factory))
+		defer registry.Remove("custom")
+		assert.Error(t, registry.AddNew("custom", factory), "types cannot be registered twice")
+
+		_, ok := registry.Get("custom")
+		assert.True(t, ok)
+		assert.Contains(t, registry.Types(), PailType("custom"))
+		assert.NoError(t, opts.Validate())
+	})
+	t.Run("Remove", func(t *testing.T) {
+		require.NoError(t, registry.AddNew("removed", factory))
+		registry.Remove("removed")
+
+		_, ok := registry.Get("removed")
+		assert.False(t, ok)
+		assert.NotContains(t, registry.Types(), PailType("removed"))
+	})
+}
@@ -0,0 +1,39 @@
+package options
+
+import (
+	"time"
+
+	"github.com/mongodb/grip"
+)
+
+const (
+	defaultCircuitBreakerThreshold = 5
+	defaultCircuitBreakerCooldown  = 30 * time.Second
+)
+
+// CircuitBreaker describes when uploads stop being attempted during bucket
+// outages.
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive failed uploads, each after
+	// any retries, that trips the breaker. Defaults to 5.
+	Threshold int
+	// Cooldown is how long uploads fail immediately once the breaker
+	// trips, after which a single trial upload is attempted. Defaults to
+	// 30s.
+	Cooldown time.Duration
+}
+
+func (o *CircuitBreaker) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Threshold < 0, "circuit breaker threshold cannot be negative")
+	catcher.NewWhen(o.Cooldown < 0, "circuit breaker cooldown cannot be negative")
+
+	if o.Threshold == 0 {
+		o.Threshold = defaultCircuitBreakerThreshold
+	}
+	if o.Cooldown == 0 {
+		o.Cooldown = defaultCircuitBreakerCooldown
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,27 @@
+package options
+
+import "github.com/mongodb/grip"
+
+type Copy struct {
+	// SrcKey is the key whose chunks, metadata, and manifest entries are
+	// copied.
+	SrcKey string
+	// DstKey is the key the chunks are copied to. Each chunk keeps its
+	// name, so that the copy reads back in the same order.
+	DstKey string
+	// DstBucket, if set, describes the bucket the chunks are copied to,
+	// e.g. a cold storage bucket. Defaults to the logger's own bucket.
+	DstBucket *Bucket
+	// DeleteSource removes the source chunks, metadata, and manifest
+	// entries once all of them have been copied.
+	DeleteSource bool
+}
+
+func (o Copy) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.SrcKey == "", "must specify a source key")
+	catcher.NewWhen(o.DstKey == "", "must specify a destination key")
+	catcher.NewWhen(o.DstBucket == nil && o.SrcKey == o.DstKey, "source and destination cannot be the same")
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,34 @@
+package options
+
+import (
+	"io"
+
+	"github.com/mongodb/grip"
+)
+
+const defaultDiffContext = 3
+
+type Diff struct {
+	// KeyA and KeyB are the keys of the logs compared, e.g. of a passing
+	// and a failing run.
+	KeyA string
+	KeyB string
+	// Writer, if set, receives a unified diff of the logs.
+	Writer io.Writer
+	// Context, if set, is the number of unchanged lines shown around each
+	// change of the unified diff, which may be 0. Defaults to 3.
+	Context *int
+}
+
+func (o *Diff) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.KeyA == "" || o.KeyB == "", "must specify both keys")
+	catcher.NewWhen(o.Context != nil && *o.Context < 0, "context cannot be negative")
+
+	if o.Context == nil {
+		context := defaultDiffContext
+		o.Context = &context
+	}
+
+	return catcher.Resolve()
+}
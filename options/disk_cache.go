@@ -0,0 +1,26 @@
+package options
+
+import "github.com/mongodb/grip"
+
+const defaultDiskCacheMaxSize = 10 * 1024 * 1024 * 1024
+
+type DiskCache struct {
+	// Dir is the local directory storing cached chunks. It is created if
+	// it does not exist and may be reused across processes.
+	Dir string
+	// MaxSize is the maximum total size in bytes of the cached chunks.
+	// Defaults to 10GB.
+	MaxSize int64
+}
+
+func (o *DiskCache) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Dir == "", "must specify a cache directory")
+	catcher.NewWhen(o.MaxSize < 0, "max size cannot be negative")
+
+	if o.MaxSize == 0 {
+		o.MaxSize = defaultDiskCacheMaxSize
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,31 @@
+package options
+
+import (
+	"io"
+
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/level"
+)
+
+type Download struct {
+	Key string
+	// Writer receives the matching lines. Exactly one of Writer and
+	// Filename must be set.
+	Writer io.Writer
+	// Filename is the local file that the matching lines are written to.
+	// The file is truncated if it exists.
+	Filename string
+	// MinPriority is the lowest priority of the lines downloaded.
+	// Defaults to level.Warning.
+	MinPriority level.Priority
+}
+
+func (o Download) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Key == "", "must specify a key")
+	catcher.NewWhen(o.Writer == nil && o.Filename == "", "must specify a writer or a filename")
+	catcher.NewWhen(o.Writer != nil && o.Filename != "", "cannot specify both a writer and a filename")
+	catcher.NewWhen(o.MinPriority != 0 && !o.MinPriority.IsValid(), "invalid minimum priority")
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,32 @@
+package options
+
+import (
+	"io"
+
+	"github.com/mongodb/grip"
+)
+
+// Export archive formats.
+const (
+	ExportTarGz = "tar.gz"
+	ExportZip   = "zip"
+)
+
+type Export struct {
+	Key    string
+	Writer io.Writer
+	// Format is the archive format, either ExportTarGz or ExportZip.
+	// Defaults to ExportTarGz.
+	Format string
+	// IncludeMetadata also exports the key's metadata.
+	IncludeMetadata bool
+}
+
+func (o Export) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Key == "", "must specify a key")
+	catcher.NewWhen(o.Writer == nil, "must specify a writer")
+	catcher.ErrorfWhen(o.Format != "" && o.Format != ExportTarGz && o.Format != ExportZip, "unrecognized export format '%s'", o.Format)
+
+	return catcher.Resolve()
+}
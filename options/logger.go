@@ -23,4 +23,53 @@ type Sender struct {
 	// whether the max buffer size has been reached or not. Setting
 	// FlushInterval to a duration less than 0 will disable timed flushes.
 	FlushInterval time.Duration `bson:"flush_interval" json:"flush_interval" yaml:"flush_interval"`
+	// FlushTimeout, if set, bounds each flush, so that a hung upload
+	// cannot block Send or timed flushes forever. Lines that fail to
+	// flush remain buffered.
+	FlushTimeout time.Duration `bson:"flush_timeout" json:"flush_timeout" yaml:"flush_timeout"`
+	// OverflowDir, if set, is a local directory that buffered lines are
+	// spilled to when the buffer is full and cannot be flushed, instead of
+	// growing the buffer. Spilled lines are flushed, ahead of newer lines,
+	// once flushing succeeds again, including by a later sender using the
+	// same directory. The directory must therefore not be shared with
+	// senders for other keys, or their lines would be uploaded under the
+	// wrong key; NewSender rejects a directory claimed by another key.
+	OverflowDir string `bson:"overflow_dir" json:"overflow_dir" yaml:"overflow_dir"`
+	// MaxOverflowSize is the maximum number of bytes stored in the
+	// overflow directory, after which the buffer grows instead. Defaults
+	// to 1GB.
+	MaxOverflowSize int64 `bson:"max_overflow_size" json:"max_overflow_size" yaml:"max_overflow_size"`
+	// WALDir, if set, is a local directory that each line is appended to
+	// before it is buffered, so that buffered lines survive the process
+	// being killed. Lines left behind by a previous process are uploaded
+	// by the sender's Recover method. The directory must not be shared
+	// with senders for other keys; NewSender rejects a directory claimed
+	// by another key.
+	WALDir string `bson:"wal_dir" json:"wal_dir" yaml:"wal_dir"`
+	// Enrich stamps the hostname, PID, and name of the sending process,
+	// along with EnrichFields, onto the attributes of every line, so that
+	// lines merged from many hosts remain attributable. EnrichFields take
+	// precedence over the source fields. Attributes are only stored by
+	// structured encodings, e.g. JSON, not by plain text.
+	Enrich       bool                   `bson:"enrich" json:"enrich" yaml:"enrich"`
+	EnrichFields map[string]interface{} `bson:"enrich_fields" json:"enrich_fields" yaml:"enrich_fields"`
+	// OnFlush, if set, is called with each chunk uploaded by a successful
+	// flush, e.g. to record chunk locations as logs stream in. Chunks are
+	// reported by bucket loggers, spool loggers, once the chunks are
+	// spooled, and children of either.
+	OnFlush func(FlushInfo) `bson:"-" json:"-" yaml:"-"`
+	// Tags are applied to every chunk flushed; see Write.Tags.
+	Tags map[string]string `bson:"tags" json:"tags" yaml:"tags"`
+}
+
+// FlushInfo describes a log chunk uploaded by a sender flush.
+type FlushInfo struct {
+	// Key is the key of the chunk within the logs bucket.
+	Key string
+	// Size is the size of the chunk in bytes.
+	Size int
+	// Lines is the number of log lines in the chunk.
+	Lines int
+	// Duration is how long the write that uploaded the chunk took.
+	Duration time.Duration
 }
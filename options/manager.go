@@ -0,0 +1,34 @@
+package options
+
+import (
+	"time"
+
+	"github.com/mongodb/grip"
+)
+
+const defaultManagerIdleTimeout = 10 * time.Minute
+
+// Manager describes the loggers and senders a logger manager creates per
+// ID.
+type Manager struct {
+	// Sender is the template of the senders created for each ID. The
+	// sender for an ID writes to the key Sender.Key of the ID's child
+	// logger, i.e. "<ID>.children/<Sender.Key>", or to the key "<ID>" if
+	// Sender.Key is empty.
+	Sender Sender
+	// IdleTimeout is how long the logger and sender of an ID are cached
+	// after they were last requested or sent to. The sender is closed,
+	// flushing its buffer, once evicted. Defaults to 10 minutes.
+	IdleTimeout time.Duration
+}
+
+func (o *Manager) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.IdleTimeout < 0, "idle timeout cannot be negative")
+
+	if o.IdleTimeout == 0 {
+		o.IdleTimeout = defaultManagerIdleTimeout
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,40 @@
+package options
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/mongodb/grip"
+)
+
+type OIDC struct {
+	// Issuer is the issuer URL of the OpenID Connect provider, e.g.
+	// "https://accounts.google.com". Only tokens issued by it are
+	// accepted.
+	Issuer string `bson:"issuer" json:"issuer" yaml:"issuer"`
+	// Audience is the audience, typically the client ID, that tokens
+	// must be issued for.
+	Audience string `bson:"audience" json:"audience" yaml:"audience"`
+	// JWKSURL is the URL of the provider's token signing keys. Defaults
+	// to the "jwks_uri" of the issuer's discovery document.
+	JWKSURL string `bson:"jwks_url" json:"jwks_url" yaml:"jwks_url"`
+	// HTTPClient fetches the discovery document and signing keys.
+	// Defaults to http.DefaultClient.
+	HTTPClient *http.Client `bson:"-" json:"-" yaml:"-"`
+}
+
+func (o OIDC) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Issuer == "", "must specify an issuer")
+	catcher.NewWhen(o.Audience == "", "must specify an audience")
+	if o.Issuer != "" {
+		_, err := url.Parse(o.Issuer)
+		catcher.Wrap(err, "invalid issuer URL")
+	}
+	if o.JWKSURL != "" {
+		_, err := url.Parse(o.JWKSURL)
+		catcher.Wrap(err, "invalid JWKS URL")
+	}
+
+	return catcher.Resolve()
+}
@@ -1,16 +1,87 @@
 package options
 
-import "github.com/pkg/errors"
+import (
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip"
+)
 
 type Read struct {
 	Key      string
 	Metadata bool
+	// Strict validates that the sequence numbers of the chunks stored
+	// under Key are contiguous before reading, returning a
+	// logger.GapError identifying the missing range otherwise. If the
+	// logger has a manifest, the line sequence numbers recorded in it are
+	// validated as well.
+	Strict bool
+	// VerifyChunks verifies every chunk read against its signed manifest
+	// entry, returning a logger.ManifestVerificationError for a chunk
+	// that does not match its entry or has no valid entry. The logger
+	// must sign manifests, and chunks are downloaded in full, rather than
+	// from Offset, to be verified. It cannot be combined with Metadata.
+	VerifyChunks bool
+	// PageToken resumes reading at the page identified by a token
+	// previously returned by the reader's PageToken method.
+	PageToken string
+	// Offset is the byte offset within the first chunk read, i.e. the
+	// chunk identified by PageToken, if set, at which to start reading.
+	// Backends supporting ranged fetches only download the chunk from the
+	// offset on.
+	Offset int64
+	// StartKey and EndKey, if set, restrict the read to the chunks with
+	// keys after StartKey and at or before EndKey, e.g. a page token or
+	// keys taken from the manifest, so that only that range of chunk
+	// keys is listed where the backend supports it. Both must be keys of
+	// chunks under Key.
+	StartKey string
+	EndKey   string
+	// StartSequence, if set, starts the read at the chunk containing the
+	// log line with the sequence number, found via the line sequence
+	// numbers recorded in the manifest, which the logger must have.
+	// ReadLines also skips the earlier lines of that chunk. It cannot be
+	// combined with StartKey, PageToken, or Metadata.
+	StartSequence uint64
+	// Follow, once all chunks have been read, waits for new chunks of a
+	// log that is not yet marked complete, e.g. of a running task,
+	// polling for them every PollInterval, instead of returning io.EOF.
+	// Reads return io.EOF once the log is marked complete or no new
+	// chunk is written for FollowTimeout. Follow cannot be combined with
+	// Metadata or EndKey.
+	Follow bool
+	// PollInterval defaults to 5s and FollowTimeout to 5m.
+	PollInterval  time.Duration
+	FollowTimeout time.Duration
+	// PrefetchCount is the number of chunks after the current one that
+	// are downloaded, in full and in the background, while the current
+	// one is read. Defaults to 0, i.e. chunks are downloaded as they are
+	// read.
+	PrefetchCount int
+	// ChunkConcurrency is the number of chunks prefetched concurrently.
+	// Defaults to 1.
+	ChunkConcurrency int
+	// BufferSize, if set, is the size in bytes of the buffer each chunk is
+	// read through, e.g. to read backends that return data in small
+	// pieces in fewer, larger reads.
+	BufferSize int
 }
 
 func (o Read) Validate() error {
-	if o.Key == "" {
-		return errors.New("must specify a key")
-	}
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Key == "", "must specify a key")
+	catcher.NewWhen(o.Offset < 0, "offset cannot be negative")
+	catcher.NewWhen(o.StartKey != "" && !strings.HasPrefix(o.StartKey, o.Key), "start key must be under the key")
+	catcher.NewWhen(o.EndKey != "" && !strings.HasPrefix(o.EndKey, o.Key), "end key must be under the key")
+	catcher.NewWhen(o.StartKey != "" && o.EndKey != "" && o.StartKey >= o.EndKey, "start key must be before end key")
+	catcher.NewWhen(o.StartSequence > 0 && (o.StartKey != "" || o.PageToken != "" || o.Metadata), "cannot combine a start sequence with a start key, page token, or metadata")
+	catcher.NewWhen(o.PrefetchCount < 0, "prefetch count cannot be negative")
+	catcher.NewWhen(o.ChunkConcurrency < 0, "chunk concurrency cannot be negative")
+	catcher.NewWhen(o.BufferSize < 0, "buffer size cannot be negative")
+	catcher.NewWhen(o.VerifyChunks && o.Metadata, "cannot verify metadata chunks")
+	catcher.NewWhen(o.Follow && (o.Metadata || o.EndKey != ""), "cannot follow metadata or reads with an end key")
+	catcher.NewWhen(o.PollInterval < 0, "poll interval cannot be negative")
+	catcher.NewWhen(o.FollowTimeout < 0, "follow timeout cannot be negative")
 
-	return nil
+	return catcher.Resolve()
 }
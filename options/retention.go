@@ -0,0 +1,65 @@
+package options
+
+import "github.com/mongodb/grip"
+
+// minIATransitionDays is the number of days S3 requires objects to be
+// stored before they transition to an infrequent access storage class.
+const minIATransitionDays = 30
+
+type Retention struct {
+	Rules []RetentionRule
+}
+
+// RetentionRule expires, or transitions to cheaper storage classes, the
+// objects of the keys under a prefix some number of days after they are
+// written.
+type RetentionRule struct {
+	// ID identifies the rule among the rules of the logger's bucket. The
+	// IDs of the lifecycle rules created for it, which also include the
+	// bucket's prefix, are limited to 255 characters by S3.
+	ID string
+	// Prefix is the key prefix, e.g. "project/", whose chunks, metadata,
+	// manifest entries, and dedupe manifests the rule applies to. Defaults
+	// to every key.
+	Prefix string
+	// Tags, if set, restrict the rule to the log chunks with all of the
+	// tags; see Write.Tags. Since metadata, manifest entries, and dedupe
+	// manifests are not tagged, rules with tags only apply to log chunks.
+	Tags map[string]string
+	// ExpireAfterDays, if set, deletes objects the number of days after
+	// they are written.
+	ExpireAfterDays int
+	// Transitions move objects to other storage classes as they age.
+	Transitions []RetentionTransition
+}
+
+type RetentionTransition struct {
+	// AfterDays must be at least 30 for transitions to the STANDARD_IA
+	// and ONEZONE_IA storage classes.
+	AfterDays    int
+	StorageClass S3StorageClass
+}
+
+func (o *Retention) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	ids := map[string]bool{}
+	for _, rule := range o.Rules {
+		catcher.NewWhen(rule.ID == "", "must specify a rule ID")
+		catcher.ErrorfWhen(ids[rule.ID], "duplicate rule ID '%s'", rule.ID)
+		ids[rule.ID] = true
+
+		catcher.ErrorfWhen(rule.ExpireAfterDays < 0, "expiry of rule '%s' cannot be negative", rule.ID)
+		catcher.ErrorfWhen(rule.ExpireAfterDays == 0 && len(rule.Transitions) == 0, "rule '%s' must expire or transition objects", rule.ID)
+		catcher.Wrapf(ValidateTags(rule.Tags), "invalid tags of rule '%s'", rule.ID)
+		for _, t := range rule.Transitions {
+			catcher.ErrorfWhen(t.AfterDays <= 0, "transitions of rule '%s' must be after a positive number of days", rule.ID)
+			catcher.ErrorfWhen(rule.ExpireAfterDays > 0 && t.AfterDays >= rule.ExpireAfterDays, "transitions of rule '%s' must be before its expiry", rule.ID)
+			catcher.ErrorfWhen(t.StorageClass == "" || t.StorageClass == S3StorageClassStandard, "transitions of rule '%s' must be to a non-standard storage class", rule.ID)
+			catcher.ErrorfWhen((t.StorageClass == S3StorageClassStandardIA || t.StorageClass == S3StorageClassOneZoneIA) && t.AfterDays < minIATransitionDays,
+				"transitions of rule '%s' to %s must be after at least %d days", rule.ID, t.StorageClass, minIATransitionDays)
+			catcher.Add(t.StorageClass.validate())
+		}
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,55 @@
+package options
+
+import (
+	"context"
+	"time"
+
+	"github.com/evergreen-ci/pail"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = 100 * time.Millisecond
+)
+
+// Retry describes how failed bucket operations are retried.
+type Retry struct {
+	// Attempts is the maximum number of times an operation is attempted,
+	// including the first attempt. Defaults to 3.
+	Attempts int
+	// Backoff is the initial wait between attempts, doubled after each
+	// failed attempt. Defaults to 100ms.
+	Backoff time.Duration
+	// MaxBackoff, if set, caps the wait between attempts.
+	MaxBackoff time.Duration
+	// Retryable classifies errors as transient, e.g. S3 503s, and
+	// therefore worth retrying. Defaults to DefaultRetryable.
+	Retryable func(error) bool `bson:"-" json:"-" yaml:"-"`
+}
+
+// DefaultRetryable reports all errors as retryable except for context
+// cancellations and deadlines and missing keys.
+func DefaultRetryable(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !pail.IsKeyNotFoundError(err)
+}
+
+func (o *Retry) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Attempts < 0, "retry attempts cannot be negative")
+	catcher.NewWhen(o.Backoff < 0, "retry backoff cannot be negative")
+	catcher.NewWhen(o.MaxBackoff < 0, "max retry backoff cannot be negative")
+
+	if o.Attempts == 0 {
+		o.Attempts = defaultRetryAttempts
+	}
+	if o.Backoff == 0 {
+		o.Backoff = defaultRetryBackoff
+	}
+	if o.Retryable == nil {
+		o.Retryable = DefaultRetryable
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,25 @@
+package options
+
+import "github.com/mongodb/grip"
+
+const defaultMaxLineLength = 1024 * 1024
+
+type Scanner struct {
+	// MaxLineLength is the maximum length in bytes of a line, excluding
+	// its line ending. Defaults to 1MB.
+	MaxLineLength int
+	// Truncate yields lines longer than MaxLineLength truncated to it
+	// rather than failing the scan.
+	Truncate bool
+}
+
+func (o *Scanner) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.MaxLineLength < 0, "max line length cannot be negative")
+
+	if o.MaxLineLength == 0 {
+		o.MaxLineLength = defaultMaxLineLength
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,21 @@
+package options
+
+import "github.com/mongodb/grip"
+
+type RegisterSchema struct {
+	// KeyPrefix is the prefix of the keys the schema applies to.
+	KeyPrefix string
+	// Encoding is the name of the registered encoding the schema is for,
+	// which must support schemas.
+	Encoding string
+	Schema   string
+}
+
+func (o RegisterSchema) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.KeyPrefix == "", "must specify a key prefix")
+	catcher.NewWhen(o.Encoding == "", "must specify an encoding")
+	catcher.NewWhen(o.Schema == "", "must specify a schema")
+
+	return catcher.Resolve()
+}
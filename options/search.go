@@ -0,0 +1,39 @@
+package options
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/mongodb/grip"
+)
+
+type Search struct {
+	Key string
+	// Regexp is matched against the data of each log line.
+	Regexp string
+	// Start and End, if set, restrict matches to log lines with
+	// timestamps in the range [Start, End).
+	Start time.Time
+	End   time.Time
+	// Limit is the maximum number of matches returned, in log order. No
+	// limit is applied if zero.
+	Limit int
+	// Concurrency is the number of chunks scanned concurrently. Defaults
+	// to 8.
+	Concurrency int
+}
+
+func (o Search) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Key == "", "must specify a key")
+	catcher.NewWhen(o.Regexp == "", "must specify a regular expression")
+	if o.Regexp != "" {
+		_, err := regexp.Compile(o.Regexp)
+		catcher.Wrap(err, "invalid regular expression")
+	}
+	catcher.NewWhen(!o.Start.IsZero() && !o.End.IsZero() && !o.End.After(o.Start), "end must be after start")
+	catcher.NewWhen(o.Limit < 0, "limit cannot be negative")
+	catcher.NewWhen(o.Concurrency < 0, "concurrency cannot be negative")
+
+	return catcher.Resolve()
+}
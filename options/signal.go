@@ -0,0 +1,30 @@
+package options
+
+import (
+	"time"
+
+	"github.com/mongodb/grip"
+)
+
+const defaultSignalFlushTimeout = time.Minute
+
+// FlushOnSignal describes how buffered logs are persisted when the process
+// is signaled.
+type FlushOnSignal struct {
+	// Close closes, rather than flushes, everything that can be closed
+	// when the process is asked to terminate.
+	Close bool
+	// Timeout bounds each flush or close. Defaults to 1 minute.
+	Timeout time.Duration
+}
+
+func (o *FlushOnSignal) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Timeout < 0, "timeout cannot be negative")
+
+	if o.Timeout == 0 {
+		o.Timeout = defaultSignalFlushTimeout
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,33 @@
+package options
+
+import (
+	"time"
+
+	"github.com/mongodb/grip"
+)
+
+const defaultSpoolInterval = 30 * time.Second
+
+// Spool describes the local spool of a spooling logger.
+type Spool struct {
+	// Dir is the local directory that chunks are written to before being
+	// uploaded. Chunks left over from a previous process are uploaded
+	// when the logger is created.
+	Dir string
+	// Interval is how often the spool is checked for chunks that have not
+	// been uploaded, e.g. because the remote bucket was failing. Chunks
+	// are also uploaded as soon as each write completes. Defaults to 30s.
+	Interval time.Duration
+}
+
+func (o *Spool) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Dir == "", "must specify a spool directory")
+	catcher.NewWhen(o.Interval < 0, "interval cannot be negative")
+
+	if o.Interval == 0 {
+		o.Interval = defaultSpoolInterval
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,25 @@
+package options
+
+import "github.com/mongodb/grip"
+
+type StorageReport struct {
+	// Prefix is the key prefix whose chunks are reported on. It matches
+	// whole key elements, e.g. "project" does not match "project2".
+	// Defaults to every key.
+	Prefix string
+	// Depth is the number of leading elements of the chunks' keys that
+	// they are grouped by, e.g. 1 to group the key "project/task" under
+	// "project". Defaults to 1.
+	Depth int
+}
+
+func (o *StorageReport) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Depth < 0, "depth cannot be negative")
+
+	if o.Depth == 0 {
+		o.Depth = 1
+	}
+
+	return catcher.Resolve()
+}
@@ -0,0 +1,24 @@
+package options
+
+import "github.com/mongodb/grip"
+
+type UploadFile struct {
+	Key string
+	// Path is the file to upload or, if a directory, the directory whose
+	// regular files are uploaded in lexical order.
+	Path     string
+	Encoding string
+	// ChunkSize is the maximum number of bytes uploaded per chunk. Chunks
+	// end at line boundaries, unless a single line is larger than the
+	// chunk size. Defaults to 10MB.
+	ChunkSize int
+}
+
+func (o UploadFile) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Key == "", "must specify a key")
+	catcher.NewWhen(o.Path == "", "must specify a path")
+	catcher.NewWhen(o.ChunkSize < 0, "chunk size cannot be negative")
+
+	return catcher.Resolve()
+}
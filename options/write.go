@@ -1,6 +1,9 @@
 package options
 
 import (
+	"io"
+	"time"
+
 	"github.com/mongodb/grip"
 )
 
@@ -14,12 +17,32 @@ type Write struct {
 	Key      string
 	Data     interface{}
 	Encoding string
+	// TargetChunkSize, if set, is the maximum encoded size, in bytes, of
+	// each chunk written. Data encoding to more than the target is split
+	// into multiple chunks, each encoded on its own, which are uploaded
+	// concurrently. Slices are split between elements and byte slices and
+	// strings between lines; other data, or a single element larger than
+	// the target, is written as a single chunk.
+	TargetChunkSize int
+	// Tags, e.g. a project, task, or TTL class, are applied as object
+	// tags to the chunks uploaded to S3, so that bucket lifecycle rules
+	// and cost allocation reports can key off them. They are ignored by
+	// other backends.
+	Tags map[string]string
+	// StorageClass, if set, overrides the storage class of the S3
+	// bucket's options for the chunks of this write, e.g. to store the
+	// logs of a backfill straight in an infrequent access class. It is
+	// ignored by other backends.
+	StorageClass S3StorageClass
 }
 
 func (o Write) Validate() error {
 	catcher := grip.NewBasicCatcher()
 	catcher.NewWhen(o.Key == "", "must specify a key")
 	catcher.NewWhen(o.Data == nil, "data cannot be nil")
+	catcher.NewWhen(o.TargetChunkSize < 0, "target chunk size cannot be negative")
+	catcher.Wrap(ValidateTags(o.Tags), "invalid tags")
+	catcher.Add(o.StorageClass.validate())
 
 	return catcher.Resolve()
 }
@@ -28,29 +51,100 @@ type WriteBytes struct {
 	Key      string
 	Data     []byte
 	Encoding string
+	// SniffContentType detects the MIME type of Data and uses it, instead
+	// of Encoding, to determine the key extension and the content type
+	// stored with the uploaded object. The data is stored as a blob, see
+	// encode.BLOB. This is useful when writing non-log payloads such as
+	// artifacts.
+	SniffContentType bool
+	// Dedupe skips the upload if a chunk with identical contents was
+	// already written to the key, e.g. by a retried task re-uploading the
+	// same data.
+	Dedupe bool
+	// Tags and StorageClass are the same as for Write.
+	Tags         map[string]string
+	StorageClass S3StorageClass
 }
 
 func (o WriteBytes) Validate() error {
 	catcher := grip.NewBasicCatcher()
 	catcher.NewWhen(o.Key == "", "must specify a key")
 	catcher.NewWhen(o.Data == nil, "data cannot be nil")
+	catcher.Wrap(ValidateTags(o.Tags), "invalid tags")
+	catcher.Add(o.StorageClass.validate())
+
+	return catcher.Resolve()
+}
+
+type WriteReader struct {
+	Key      string
+	Reader   io.Reader
+	Encoding string
+	// ChunkSize is the maximum number of bytes uploaded per chunk; once
+	// reached, writing rotates to a new chunk. Chunks end at line
+	// boundaries, unless a single line is larger than the chunk size.
+	// Defaults to 10MB.
+	ChunkSize int
+	// Tags and StorageClass are the same as for Write.
+	Tags         map[string]string
+	StorageClass S3StorageClass
+}
+
+func (o WriteReader) Validate() error {
+	catcher := grip.NewBasicCatcher()
+	catcher.NewWhen(o.Key == "", "must specify a key")
+	catcher.NewWhen(o.Reader == nil, "reader cannot be nil")
+	catcher.NewWhen(o.ChunkSize < 0, "chunk size cannot be negative")
+	catcher.Wrap(ValidateTags(o.Tags), "invalid tags")
+	catcher.Add(o.StorageClass.validate())
 
 	return catcher.Resolve()
 }
 
 type FollowFile struct {
-	Key           string
-	Filename      string
+	Key      string
+	Filename string
+	// Exit stops following the file, after a final flush, once closed. It
+	// may only be nil if MaxDuration or MaxBytes is set.
 	Exit          chan struct{}
 	Encoding      string
 	MaxBufferSize int
+	// MaxDuration, if set, stops following the file, after a final
+	// flush, once the duration has elapsed.
+	MaxDuration time.Duration
+	// MaxBytes, if set, stops following the file, after a final flush,
+	// once the lines read reach the given total size.
+	MaxBytes int64
 }
 
 func (o FollowFile) Validate() error {
 	catcher := grip.NewBasicCatcher()
 	catcher.NewWhen(o.Key == "", "must specify a key")
 	catcher.NewWhen(o.Filename == "", "must specify a filename")
-	catcher.NewWhen(o.Exit == nil, "exit channel cannot be nil")
+	catcher.NewWhen(o.Exit == nil && o.MaxDuration <= 0 && o.MaxBytes <= 0, "must specify an exit channel or a maximum duration or size")
+	catcher.NewWhen(o.MaxDuration < 0, "max duration cannot be negative")
+	catcher.NewWhen(o.MaxBytes < 0, "max bytes cannot be negative")
+
+	return catcher.Resolve()
+}
+
+// S3 limits on object tags.
+const (
+	maxTags           = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// ValidateTags returns an error if the tags cannot be applied as S3 object
+// tags.
+func ValidateTags(tags map[string]string) error {
+	catcher := grip.NewBasicCatcher()
+	catcher.ErrorfWhen(len(tags) > maxTags, "cannot specify more than %d tags", maxTags)
+	for k, v := range tags {
+		catcher.NewWhen(k == "", "tag keys cannot be empty")
+		catcher.ErrorfWhen(len(k) > maxTagKeyLength, "tag key '%s' is longer than %d characters", k, maxTagKeyLength)
+		catcher.ErrorfWhen(len(v) > maxTagValueLength, "value of tag '%s' is longer than %d characters", k, maxTagValueLength)
+	}
 
 	return catcher.Resolve()
 }
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/pkg/errors"
+)
+
+// Principal is an authenticated client of the server.
+type Principal struct {
+	// ID identifies the client, e.g. the subject of an OIDC token.
+	ID string
+	// Claims are the claims of the client's token, if any.
+	Claims map[string]interface{}
+}
+
+// Authenticator authenticates the clients of a server.
+type Authenticator interface {
+	// Authenticate returns the client making the request, or an error if
+	// the request is not authenticated.
+	Authenticate(*http.Request) (*Principal, error)
+}
+
+type principalContextKey struct{}
+
+// PrincipalFromContext returns the authenticated client of the request with
+// the given context, or nil if the server does not authenticate requests.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return p
+}
+
+// authenticate wraps the handler to reject requests not authenticated by
+// the server's authenticator, if any.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.authenticator == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := s.authenticator.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, errors.Wrap(err, "unauthenticated").Error(), http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), principalContextKey{}, p)
+		next.ServeHTTP(w, r.WithContext(logger.ContextWithPrincipal(ctx, p.ID)))
+	})
+}
+
+// bearerToken returns the token of the request's "Bearer" authorization
+// header or, since browsers cannot set headers on WebSocket and
+// EventSource requests, of its "access_token" query parameter.
+func bearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if len(header) > len("Bearer ") && strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+			return strings.TrimSpace(header[len("Bearer "):])
+		}
+		return ""
+	}
+
+	return r.URL.Query().Get("access_token")
+}
+
+type apiKeyAuthenticator struct {
+	keys map[string]string
+}
+
+// NewAPIKeyAuthenticator returns an authenticator accepting requests with
+// any of the given API keys as a bearer token, mapping each key to the ID of
+// its principal.
+func NewAPIKeyAuthenticator(keys map[string]string) (Authenticator, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("must specify at least one API key")
+	}
+
+	a := &apiKeyAuthenticator{keys: make(map[string]string, len(keys))}
+	for key, id := range keys {
+		if key == "" || id == "" {
+			return nil, errors.New("API keys and principal IDs cannot be empty")
+		}
+		a.keys[key] = id
+	}
+
+	return a, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, errors.New("missing API key")
+	}
+
+	// Every key is compared in constant time so that response times do
+	// not reveal how much of a key matched.
+	var id string
+	for key, keyID := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			id = keyID
+		}
+	}
+	if id == "" {
+		return nil, errors.New("invalid API key")
+	}
+
+	return &Principal{ID: id}, nil
+}
+
+// authorizationStatus returns the HTTP status of an authorization failure.
+func authorizationStatus(err error) int {
+	if logger.IsAuthorizationError(err) {
+		return http.StatusForbidden
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/julianedwards/cedar/server/cedarpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	a, err := NewAPIKeyAuthenticator(map[string]string{"key0": "ci", "key1": "ui"})
+	require.NoError(t, err)
+	authenticate := func(header, query string) (*Principal, error) {
+		r := httptest.NewRequest(http.MethodGet, "/logs/events"+query, nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		return a.Authenticate(r)
+	}
+
+	p, err := authenticate("Bearer key0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "ci", p.ID)
+	p, err = authenticate("bearer key1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "ui", p.ID)
+	p, err = authenticate("", "?access_token=key1")
+	require.NoError(t, err)
+	assert.Equal(t, "ui", p.ID, "browsers pass the key as a query parameter")
+
+	for _, test := range []struct {
+		name   string
+		header string
+		query  string
+	}{
+		{name: "Missing"},
+		{name: "Invalid", header: "Bearer key2"},
+		{name: "Basic", header: "Basic key0"},
+		{name: "HeaderTakesPrecedence", header: "Bearer key2", query: "?access_token=key0"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := authenticate(test.header, test.query)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("InvalidKeys", func(t *testing.T) {
+		_, err := NewAPIKeyAuthenticator(nil)
+		assert.Error(t, err)
+		_, err = NewAPIKeyAuthenticator(map[string]string{"": "ci"})
+		assert.Error(t, err)
+		_, err = NewAPIKeyAuthenticator(map[string]string{"key": ""})
+		assert.Error(t, err)
+	})
+}
+
+func TestServerAuthentication(t *testing.T) {
+	ctx := context.Background()
+	l, err := logger.NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line0\n"}))
+	a, err := NewAPIKeyAuthenticator(map[string]string{"token": "ci"})
+	require.NoError(t, err)
+	s, err := New(l, WithAuthenticator(a))
+	require.NoError(t, err)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/logs/events?key=key&follow=false")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+
+	resp, err = http.Get(srv.URL + "/logs/events?key=key&follow=false&access_token=token")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	client := newLogServiceClient(t, s)
+	_, code := streamLines(t, client, &cedarpb.StreamLinesRequest{Key: "key"})
+	assert.Equal(t, codes.Unauthenticated, code)
+	stream, err := client.StreamLines(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer token"), &cedarpb.StreamLinesRequest{Key: "key"})
+	require.NoError(t, err)
+	line, err := stream.Recv()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, line.GetPosition())
+
+	_, err = New(l, WithAuthenticator(nil))
+	assert.Error(t, err)
+}
+
+func TestServerAuthorization(t *testing.T) {
+	ctx := context.Background()
+	l, err := logger.NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "project/task", Data: "line0\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "project2/task", Data: "line0\n"}))
+	authn, err := NewAPIKeyAuthenticator(map[string]string{"token": "ci"})
+	require.NoError(t, err)
+	authz, err := logger.NewPrefixAuthorizer(logger.PrefixPolicy{Principal: "ci", Prefixes: []string{"project"}, Permissions: []logger.Permission{logger.PermissionRead}})
+	require.NoError(t, err)
+	s, err := New(l, WithAuthenticator(authn), WithAuthorizer(authz))
+	require.NoError(t, err)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	client := newLogServiceClient(t, s)
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer token")
+
+	for _, test := range []struct {
+		name   string
+		key    string
+		status int
+		code   codes.Code
+	}{
+		{name: "Authorized", key: "project/task", status: http.StatusOK, code: codes.OK},
+		{name: "SiblingPrefix", key: "project2/task", status: http.StatusForbidden, code: codes.PermissionDenied},
+		{name: "TraversalKey", key: "project/../project2/task", status: http.StatusForbidden, code: codes.PermissionDenied},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, endpoint := range []string{"/logs/events", "/logs/ws"} {
+				resp, err := http.Get(srv.URL + endpoint + "?follow=false&access_token=token&key=" + url.QueryEscape(test.key))
+				require.NoError(t, err)
+				require.NoError(t, resp.Body.Close())
+				if endpoint == "/logs/ws" && test.status == http.StatusOK {
+					// Authorized plain HTTP requests fail to upgrade.
+					assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+					continue
+				}
+				assert.Equal(t, test.status, resp.StatusCode, endpoint)
+			}
+
+			stream, err := client.StreamLines(ctx, &cedarpb.StreamLinesRequest{Key: test.key})
+			require.NoError(t, err)
+			var lines int
+			for {
+				_, err = stream.Recv()
+				if err != nil {
+					break
+				}
+				lines++
+			}
+			if test.code == codes.OK {
+				assert.Equal(t, io.EOF, err)
+				assert.Equal(t, 1, lines)
+			} else {
+				assert.Equal(t, test.code, status.Code(err))
+			}
+		})
+	}
+
+	_, err = New(l, WithAuthorizer(authz))
+	assert.Error(t, err, "authorizing requires an authenticator")
+}
@@ -0,0 +1,140 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: cedar.proto
+
+package cedarpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// LogServiceClient is the client API for LogService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LogServiceClient interface {
+	// StreamLines streams the lines of a key matching the request's filters,
+	// followed by newly flushed lines if following, until the key is marked
+	// complete, the client cancels the call, or, if not following, the
+	// current end of the log is reached.
+	StreamLines(ctx context.Context, in *StreamLinesRequest, opts ...grpc.CallOption) (LogService_StreamLinesClient, error)
+}
+
+type logServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLogServiceClient(cc grpc.ClientConnInterface) LogServiceClient {
+	return &logServiceClient{cc}
+}
+
+func (c *logServiceClient) StreamLines(ctx context.Context, in *StreamLinesRequest, opts ...grpc.CallOption) (LogService_StreamLinesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LogService_ServiceDesc.Streams[0], "/cedar.v1.LogService/StreamLines", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &logServiceStreamLinesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type LogService_StreamLinesClient interface {
+	Recv() (*LogLine, error)
+	grpc.ClientStream
+}
+
+type logServiceStreamLinesClient struct {
+	grpc.ClientStream
+}
+
+func (x *logServiceStreamLinesClient) Recv() (*LogLine, error) {
+	m := new(LogLine)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LogServiceServer is the server API for LogService service.
+// All implementations must embed UnimplementedLogServiceServer
+// for forward compatibility
+type LogServiceServer interface {
+	// StreamLines streams the lines of a key matching the request's filters,
+	// followed by newly flushed lines if following, until the key is marked
+	// complete, the client cancels the call, or, if not following, the
+	// current end of the log is reached.
+	StreamLines(*StreamLinesRequest, LogService_StreamLinesServer) error
+	mustEmbedUnimplementedLogServiceServer()
+}
+
+// UnimplementedLogServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedLogServiceServer struct {
+}
+
+func (UnimplementedLogServiceServer) StreamLines(*StreamLinesRequest, LogService_StreamLinesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLines not implemented")
+}
+func (UnimplementedLogServiceServer) mustEmbedUnimplementedLogServiceServer() {}
+
+// UnsafeLogServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LogServiceServer will
+// result in compilation errors.
+type UnsafeLogServiceServer interface {
+	mustEmbedUnimplementedLogServiceServer()
+}
+
+func RegisterLogServiceServer(s grpc.ServiceRegistrar, srv LogServiceServer) {
+	s.RegisterService(&LogService_ServiceDesc, srv)
+}
+
+func _LogService_StreamLines_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLinesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogServiceServer).StreamLines(m, &logServiceStreamLinesServer{stream})
+}
+
+type LogService_StreamLinesServer interface {
+	Send(*LogLine) error
+	grpc.ServerStream
+}
+
+type logServiceStreamLinesServer struct {
+	grpc.ServerStream
+}
+
+func (x *logServiceStreamLinesServer) Send(m *LogLine) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// LogService_ServiceDesc is the grpc.ServiceDesc for LogService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LogService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cedar.v1.LogService",
+	HandlerType: (*LogServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLines",
+			Handler:       _LogService_StreamLines_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cedar.proto",
+}
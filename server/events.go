@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/pkg/errors"
+)
+
+// eventsHandler streams the lines of the key given by the "key" query
+// parameter, followed by newly flushed lines if following, to the client as
+// Server-Sent Events. Each line is sent as a "line" event whose data is the
+// line encoded as a single line of JSON, including its timestamp and level,
+// and whose ID is the line's 1-based position in the log. Clients
+// reconnecting with a Last-Event-ID header resume after that line. Once the
+// stream ends, e.g. the log is complete, a "complete" event is sent, or an
+// "error" event if streaming failed, so that clients know not to reconnect.
+func (s *Server) eventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			http.Error(w, "must specify a key", http.StatusBadRequest)
+			return
+		}
+		filter, err := parseStreamFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var last int
+		if id := r.Header.Get("Last-Event-ID"); id != "" {
+			if last, err = strconv.Atoi(id); err != nil || last < 0 {
+				http.Error(w, fmt.Sprintf("invalid last event ID '%s'", id), http.StatusBadRequest)
+				return
+			}
+		}
+		if err = s.authorize(r.Context(), logger.PermissionRead, key); err != nil {
+			http.Error(w, err.Error(), authorizationStatus(err))
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		err = s.streamLines(r.Context(), key, filter, last, func(id int, line logger.LogLine) error {
+			data, err := json.Marshal(line)
+			if err != nil {
+				return errors.Wrap(err, "encoding log line")
+			}
+			if _, err = fmt.Fprintf(w, "id: %d\nevent: line\ndata: %s\n\n", id, data); err != nil {
+				return errors.Wrap(err, "writing event")
+			}
+			flusher.Flush()
+
+			return nil
+		})
+		if r.Context().Err() != nil {
+			return
+		}
+		if err != nil {
+			data, _ := json.Marshal(streamError{Error: err.Error()})
+			_, _ = fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+		} else {
+			_, _ = fmt.Fprint(w, "event: complete\ndata: {}\n\n")
+		}
+		flusher.Flush()
+	})
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// event is a parsed Server-Sent Event.
+type event struct {
+	id    string
+	event string
+	data  string
+}
+
+func readEvents(t *testing.T, resp *http.Response) []event {
+	var events []event
+	var e event
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, e)
+			e = event{}
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	return events
+}
+
+func TestEventsHandler(t *testing.T) {
+	ctx := context.Background()
+	l, err := logger.NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line0\nline1\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line2\n"}))
+	require.NoError(t, l.MarkComplete(ctx, "key"))
+	s, err := New(l)
+	require.NoError(t, err)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	get := func(t *testing.T, query, lastID string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/logs/events"+query, nil)
+		require.NoError(t, err)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	lines := func(t *testing.T, events []event) ([]string, []string) {
+		var ids, data []string
+		for _, e := range events[:len(events)-1] {
+			require.Equal(t, "line", e.event)
+			var line logger.LogLine
+			require.NoError(t, json.Unmarshal([]byte(e.data), &line))
+			ids = append(ids, e.id)
+			data = append(data, line.Data.(string))
+		}
+		assert.Equal(t, "complete", events[len(events)-1].event)
+		return ids, data
+	}
+
+	t.Run("Streams", func(t *testing.T) {
+		resp := get(t, "?key=key", "")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		ids, data := lines(t, readEvents(t, resp))
+		assert.Equal(t, []string{"1", "2", "3"}, ids)
+		assert.Equal(t, []string{"line0", "line1", "line2"}, data)
+	})
+	t.Run("Resumes", func(t *testing.T) {
+		resp := get(t, "?key=key", "2")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		ids, data := lines(t, readEvents(t, resp))
+		assert.Equal(t, []string{"3"}, ids)
+		assert.Equal(t, []string{"line2"}, data)
+	})
+	t.Run("BadRequest", func(t *testing.T) {
+		for _, test := range []struct {
+			query  string
+			lastID string
+		}{
+			{query: ""},
+			{query: "?key=key", lastID: "-1"},
+			{query: "?key=key", lastID: "one"},
+		} {
+			resp := get(t, test.query, test.lastID)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/mongodb/grip/level"
+	"github.com/pkg/errors"
+)
+
+// streamFilter restricts the lines streamed to a client. It is parsed from
+// the StreamLinesRequest of gRPC calls or the query parameters of other
+// streaming requests:
+//
+//	start, end  RFC 3339 timestamps bounding the timestamps of the lines
+//	level       the lowest level of the lines, e.g. "warning"
+//	follow      "false" to stop at the current end of the log instead of
+//	            waiting for newly flushed lines
+type streamFilter struct {
+	start       time.Time
+	end         time.Time
+	minPriority level.Priority
+	follow      bool
+}
+
+func parseStreamFilter(query url.Values) (streamFilter, error) {
+	filter := streamFilter{follow: true}
+	var err error
+	if v := query.Get("start"); v != "" {
+		if filter.start, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return filter, errors.Wrap(err, "parsing start time")
+		}
+	}
+	if v := query.Get("end"); v != "" {
+		if filter.end, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return filter, errors.Wrap(err, "parsing end time")
+		}
+	}
+	if !filter.start.IsZero() && !filter.end.IsZero() && filter.end.Before(filter.start) {
+		return filter, errors.New("end time cannot be before start time")
+	}
+	if v := query.Get("level"); v != "" {
+		if filter.minPriority = level.FromString(v); filter.minPriority == level.Invalid {
+			return filter, errors.Errorf("invalid level '%s'", v)
+		}
+	}
+	if v := query.Get("follow"); v != "" {
+		if filter.follow, err = strconv.ParseBool(v); err != nil {
+			return filter, errors.Wrap(err, "parsing follow")
+		}
+	}
+
+	return filter, nil
+}
+
+func (f streamFilter) match(line logger.LogLine) bool {
+	switch {
+	case !f.start.IsZero() && line.Timestamp.Before(f.start):
+		return false
+	case !f.end.IsZero() && line.Timestamp.After(f.end):
+		return false
+	case f.minPriority != 0 && line.Priority < f.minPriority:
+		return false
+	default:
+		return true
+	}
+}
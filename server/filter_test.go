@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamFilter(t *testing.T) {
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Defaults", func(t *testing.T) {
+		filter, err := parseStreamFilter(url.Values{})
+		require.NoError(t, err)
+		assert.True(t, filter.follow)
+		assert.True(t, filter.match(logger.LogLine{Timestamp: base, Priority: level.Debug}))
+	})
+	t.Run("Match", func(t *testing.T) {
+		filter, err := parseStreamFilter(url.Values{
+			"start":  {base.Format(time.RFC3339)},
+			"end":    {base.Add(time.Minute).Format(time.RFC3339)},
+			"level":  {"warning"},
+			"follow": {"false"},
+		})
+		require.NoError(t, err)
+		assert.False(t, filter.follow)
+		assert.True(t, filter.match(logger.LogLine{Timestamp: base, Priority: level.Warning}))
+		assert.True(t, filter.match(logger.LogLine{Timestamp: base.Add(time.Minute), Priority: level.Error}))
+		assert.False(t, filter.match(logger.LogLine{Timestamp: base.Add(-time.Second), Priority: level.Error}))
+		assert.False(t, filter.match(logger.LogLine{Timestamp: base.Add(2 * time.Minute), Priority: level.Error}))
+		assert.False(t, filter.match(logger.LogLine{Timestamp: base, Priority: level.Info}))
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, query := range []url.Values{
+			{"start": {"yesterday"}},
+			{"end": {"tomorrow"}},
+			{"start": {base.Format(time.RFC3339)}, "end": {base.Add(-time.Second).Format(time.RFC3339)}},
+			{"level": {"loud"}},
+			{"follow": {"maybe"}},
+		} {
+			_, err := parseStreamFilter(query)
+			assert.Error(t, err, query.Encode())
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/server/cedarpb"
+	"github.com/mongodb/grip/level"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:generate protoc --proto_path=../proto --go_out=. --go_opt=module=github.com/julianedwards/cedar/server --go-grpc_out=. --go-grpc_opt=module=github.com/julianedwards/cedar/server cedar.proto
+
+// logService implements the LogService gRPC service defined in
+// proto/cedar.proto.
+type logService struct {
+	cedarpb.UnimplementedLogServiceServer
+	server *Server
+}
+
+// StreamLines streams the lines of the requested key that match the
+// request's filters as LogLine messages, if the client is authorized to
+// read the key.
+func (s *logService) StreamLines(req *cedarpb.StreamLinesRequest, stream cedarpb.LogService_StreamLinesServer) error {
+	filter, err := parseStreamLinesRequest(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx := stream.Context()
+	if err = s.server.authorize(ctx, logger.PermissionRead, req.GetKey()); err != nil {
+		if logger.IsAuthorizationError(err) {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	err = s.server.streamLines(ctx, req.GetKey(), filter, int(req.GetAfter()), func(pos int, line logger.LogLine) error {
+		msg, err := newLogLineMessage(pos, line)
+		if err != nil {
+			return err
+		}
+
+		return stream.Send(msg)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return status.Error(codes.Canceled, ctx.Err().Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// parseStreamLinesRequest validates the request and returns its filter.
+// Unlike the query parameters of other streaming requests, follow defaults
+// to false, as for any proto3 bool.
+func parseStreamLinesRequest(req *cedarpb.StreamLinesRequest) (streamFilter, error) {
+	filter := streamFilter{follow: req.GetFollow()}
+	if req.GetKey() == "" {
+		return filter, errors.New("must specify a key")
+	}
+	if req.GetAfter() < 0 {
+		return filter, errors.New("after cannot be negative")
+	}
+	if start := req.GetStartUnixNano(); start != 0 {
+		filter.start = time.Unix(0, start)
+	}
+	if end := req.GetEndUnixNano(); end != 0 {
+		filter.end = time.Unix(0, end)
+	}
+	if !filter.start.IsZero() && !filter.end.IsZero() && filter.end.Before(filter.start) {
+		return filter, errors.New("end time cannot be before start time")
+	}
+	if lvl := req.GetLevel(); lvl != "" {
+		if filter.minPriority = level.FromString(lvl); filter.minPriority == level.Invalid {
+			return filter, errors.Errorf("invalid level '%s'", lvl)
+		}
+	}
+
+	return filter, nil
+}
+
+// newLogLineMessage returns the LogLine message of the line at the given
+// position.
+func newLogLineMessage(pos int, line logger.LogLine) (*cedarpb.LogLine, error) {
+	msg := &cedarpb.LogLine{
+		Position: int64(pos),
+		Sequence: line.Sequence,
+		Priority: int32(line.Priority),
+		Message:  line.Message,
+		TraceId:  line.TraceID,
+		SpanId:   line.SpanID,
+	}
+	if !line.Timestamp.IsZero() {
+		msg.TimeUnixNano = line.Timestamp.UnixNano()
+	}
+	if line.Priority != 0 {
+		msg.Level = line.Priority.String()
+	}
+	if line.Data != nil {
+		data, err := json.Marshal(line.Data)
+		if err != nil {
+			return nil, errors.Wrap(err, "encoding line data")
+		}
+		msg.DataJson = string(data)
+	}
+	if len(line.Attrs) > 0 {
+		attrs, err := json.Marshal(line.Attrs)
+		if err != nil {
+			return nil, errors.Wrap(err, "encoding line attributes")
+		}
+		msg.AttrsJson = string(attrs)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/julianedwards/cedar/server/cedarpb"
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// newLogServiceClient serves the server with h2c and returns a client of its
+// gRPC service.
+func newLogServiceClient(t *testing.T, s *Server) cedarpb.LogServiceClient {
+	srv := httptest.NewServer(h2c.NewHandler(s, &http2.Server{}))
+	t.Cleanup(srv.Close)
+	conn, err := grpc.Dial(strings.TrimPrefix(srv.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return cedarpb.NewLogServiceClient(conn)
+}
+
+// streamLines calls the StreamLines RPC, returning the streamed lines and
+// the call's status code.
+func streamLines(t *testing.T, client cedarpb.LogServiceClient, req *cedarpb.StreamLinesRequest) ([]*cedarpb.LogLine, codes.Code) {
+	stream, err := client.StreamLines(context.Background(), req)
+	require.NoError(t, err)
+
+	var lines []*cedarpb.LogLine
+	for {
+		line, err := stream.Recv()
+		if err == io.EOF {
+			return lines, codes.OK
+		}
+		if err != nil {
+			return lines, status.Code(err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStreamLinesRPC(t *testing.T) {
+	ctx := context.Background()
+	l, err := logger.NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	base := time.Unix(1000, 0)
+	require.NoError(t, l.Write(ctx, options.Write{
+		Key:      "project/task",
+		Encoding: encode.JSON,
+		Data: []logger.LogLine{
+			{Timestamp: base, Priority: level.Info, Message: "first"},
+			{Timestamp: base.Add(time.Second), Priority: level.Warning, Message: "second"},
+			{Timestamp: base.Add(2 * time.Second), Priority: level.Error, Message: "third", Attrs: map[string]interface{}{"host": "a"}},
+		},
+	}))
+	s, err := New(l)
+	require.NoError(t, err)
+	client := newLogServiceClient(t, s)
+
+	for _, test := range []struct {
+		name     string
+		req      *cedarpb.StreamLinesRequest
+		messages []string
+		code     codes.Code
+	}{
+		{name: "AllLines", req: &cedarpb.StreamLinesRequest{Key: "project/task"}, messages: []string{"first", "second", "third"}},
+		{name: "Level", req: &cedarpb.StreamLinesRequest{Key: "project/task", Level: "warning"}, messages: []string{"second", "third"}},
+		{name: "Start", req: &cedarpb.StreamLinesRequest{Key: "project/task", StartUnixNano: base.Add(2 * time.Second).UnixNano()}, messages: []string{"third"}},
+		{name: "End", req: &cedarpb.StreamLinesRequest{Key: "project/task", EndUnixNano: base.UnixNano()}, messages: []string{"first"}},
+		{name: "After", req: &cedarpb.StreamLinesRequest{Key: "project/task", After: 1}, messages: []string{"second", "third"}},
+		{name: "MissingKey", req: &cedarpb.StreamLinesRequest{}, code: codes.InvalidArgument},
+		{name: "InvalidLevel", req: &cedarpb.StreamLinesRequest{Key: "project/task", Level: "loud"}, code: codes.InvalidArgument},
+		{name: "InvalidRange", req: &cedarpb.StreamLinesRequest{Key: "project/task", StartUnixNano: 2, EndUnixNano: 1}, code: codes.InvalidArgument},
+		{name: "NegativeAfter", req: &cedarpb.StreamLinesRequest{Key: "project/task", After: -1}, code: codes.InvalidArgument},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			lines, code := streamLines(t, client, test.req)
+			assert.Equal(t, test.code, code)
+			var messages []string
+			for _, line := range lines {
+				messages = append(messages, line.GetMessage())
+			}
+			assert.Equal(t, test.messages, messages)
+		})
+	}
+
+	t.Run("Fields", func(t *testing.T) {
+		lines, code := streamLines(t, client, &cedarpb.StreamLinesRequest{Key: "project/task", Level: "error"})
+		require.Equal(t, codes.OK, code)
+		require.Len(t, lines, 1)
+		assert.EqualValues(t, 3, lines[0].GetPosition())
+		assert.Equal(t, base.Add(2*time.Second).UnixNano(), lines[0].GetTimeUnixNano())
+		assert.EqualValues(t, level.Error, lines[0].GetPriority())
+		assert.Equal(t, "error", lines[0].GetLevel())
+		assert.Equal(t, `{"host":"a"}`, lines[0].GetAttrsJson())
+	})
+	t.Run("HTTPRoutes", func(t *testing.T) {
+		srv := httptest.NewServer(s)
+		defer srv.Close()
+		resp, err := srv.Client().Get(srv.URL + "/logs/events?key=project/task&follow=false")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"), "HTTP/1 requests are not routed to gRPC")
+	})
+}
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+const (
+	// oidcClockSkew is the tolerance for the expiry and not before times
+	// of tokens.
+	oidcClockSkew = time.Minute
+	// oidcMinKeyRefresh is the minimum interval between refreshes of the
+	// signing keys when a token is signed with an unknown key.
+	oidcMinKeyRefresh = time.Minute
+)
+
+type oidcAuthenticator struct {
+	opts options.OIDC
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	lastRefresh time.Time
+}
+
+// NewOIDCAuthenticator returns an authenticator accepting requests with an
+// OpenID Connect ID token, or a JWT access token, of the provider as a
+// bearer token. Tokens must be signed with RS256 or ES256 by one of the
+// provider's signing keys, which are fetched on creation and refreshed when
+// a token is signed with an unknown key, and be issued by the provider for
+// the configured audience. The principal ID is the token's subject.
+func NewOIDCAuthenticator(ctx context.Context, opts options.OIDC) (Authenticator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid OIDC options")
+	}
+
+	a := &oidcAuthenticator{opts: opts}
+	if a.opts.HTTPClient == nil {
+		a.opts.HTTPClient = http.DefaultClient
+	}
+	if a.opts.JWKSURL == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(ctx, strings.TrimSuffix(opts.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, errors.Wrap(err, "getting OIDC discovery document")
+		}
+		if discovery.Issuer != opts.Issuer {
+			return nil, errors.Errorf("discovery document issuer '%s' does not match '%s'", discovery.Issuer, opts.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("discovery document does not specify a JWKS URI")
+		}
+		a.opts.JWKSURL = discovery.JWKSURI
+	}
+	keys, err := a.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.keys = keys
+	a.lastRefresh = time.Now()
+
+	return a, nil
+}
+
+func (a *oidcAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, errors.New("missing bearer token")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return nil, errors.Wrap(err, "decoding token header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding token signature")
+	}
+
+	key, err := a.getKey(r.Context(), header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyTokenSignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err = decodeTokenPart(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "decoding token claims")
+	}
+	if err = a.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return &Principal{ID: claims["sub"].(string), Claims: claims}, nil
+}
+
+func (a *oidcAuthenticator) validateClaims(claims map[string]interface{}) error {
+	now := time.Now()
+	if iss, _ := claims["iss"].(string); iss != a.opts.Issuer {
+		return errors.Errorf("token issuer '%s' is not '%s'", iss, a.opts.Issuer)
+	}
+	if !hasAudience(claims["aud"], a.opts.Audience) {
+		return errors.Errorf("token is not issued for audience '%s'", a.opts.Audience)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token does not expire")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+		return errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(oidcClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token is not valid yet")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return errors.New("token does not have a subject")
+	}
+
+	return nil
+}
+
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getKey returns the signing key with the given ID, refreshing the keys if
+// it is unknown and they were not refreshed recently. The keys are fetched
+// without holding the lock, so that requests signed with known keys are not
+// blocked by a slow provider.
+func (a *oidcAuthenticator) getKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	key, ok := a.keys[kid]
+	refresh := !ok && time.Since(a.lastRefresh) >= oidcMinKeyRefresh
+	if refresh {
+		// Claiming the refresh up front keeps concurrent requests with
+		// unknown keys from each fetching the keys, and failed fetches
+		// from being retried more often than the minimum interval.
+		a.lastRefresh = time.Now()
+	}
+	a.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+	if refresh {
+		keys, err := a.fetchKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		a.keys = keys
+		a.mu.Unlock()
+
+		if key, ok = keys[kid]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, errors.Errorf("unknown signing key '%s'", kid)
+}
+
+// fetchKeys fetches the provider's signing keys. Keys of unsupported types
+// are ignored.
+func (a *oidcAuthenticator) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := a.getJSON(ctx, a.opts.JWKSURL, &jwks); err != nil {
+		return nil, errors.Wrap(err, "getting OIDC signing keys")
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) > 4 {
+				return nil, errors.Errorf("invalid RSA signing key '%s'", k.Kid)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				return nil, errors.Errorf("invalid EC signing key '%s'", k.Kid)
+			}
+			key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if !key.Curve.IsOnCurve(key.X, key.Y) {
+				return nil, errors.Errorf("invalid EC signing key '%s'", k.Kid)
+			}
+			keys[k.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+func (a *oidcAuthenticator) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	resp, err := a.opts.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "getting '%s'", url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("getting '%s' returned status %d", url, resp.StatusCode)
+	}
+
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(v), "decoding '%s'", url)
+}
+
+func decodeTokenPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(json.Unmarshal(data, v))
+}
+
+func verifyTokenSignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("token algorithm does not match signing key")
+		}
+		return errors.Wrap(rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], sig), "invalid token signature")
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("token algorithm does not match signing key")
+		}
+		if len(sig) != 64 {
+			return errors.New("invalid token signature")
+		}
+		if !ecdsa.Verify(ecKey, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported token algorithm '%s'", alg)
+	}
+}
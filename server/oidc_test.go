@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oidcProvider is a fake OpenID Connect provider serving a discovery
+// document and the public keys of its signing keys.
+type oidcProvider struct {
+	*httptest.Server
+	mu          sync.Mutex
+	keys        map[string]*rsa.PrivateKey
+	jwksFetches int32
+}
+
+func newOIDCProvider(t *testing.T) *oidcProvider {
+	p := &oidcProvider{keys: map[string]*rsa.PrivateKey{}}
+	p.addKey(t, "key0")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": p.URL, "jwks_uri": p.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&p.jwksFetches, 1)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		var keys []map[string]string
+		for kid, key := range p.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func (p *oidcProvider) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys[kid] = key
+}
+
+// token returns an RS256 token signed with the provider's key with the given
+// ID.
+func (p *oidcProvider) token(t *testing.T, kid string, claims map[string]interface{}) string {
+	p.mu.Lock()
+	key := p.keys[kid]
+	p.mu.Unlock()
+
+	return signToken(t, key, kid, claims)
+}
+
+// signToken returns an RS256 token with the given key ID signed with the key.
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(map[string]string{"alg": "RS256", "kid": kid}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (p *oidcProvider) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss": p.URL,
+		"aud": "cedar",
+		"sub": "user",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	p := newOIDCProvider(t)
+	a, err := NewOIDCAuthenticator(ctx, options.OIDC{Issuer: p.URL, Audience: "cedar"})
+	require.NoError(t, err)
+	authenticate := func(token string) (*Principal, error) {
+		r := httptest.NewRequest(http.MethodGet, "/logs/events", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return a.Authenticate(r)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		claims := p.claims()
+		claims["aud"] = []string{"other", "cedar"}
+		principal, err := authenticate(p.token(t, "key0", claims))
+		require.NoError(t, err)
+		assert.Equal(t, "user", principal.ID)
+		assert.Equal(t, "user", principal.Claims["sub"])
+	})
+	t.Run("BadSignature", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		_, err = authenticate(signToken(t, other, "key0", p.claims()))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid token signature")
+
+		// Claims cannot be changed without invalidating the signature.
+		token := strings.Split(p.token(t, "key0", p.claims()), ".")
+		claims := p.claims()
+		claims["sub"] = "admin"
+		forged := strings.Split(p.token(t, "key0", claims), ".")
+		_, err = authenticate(strings.Join([]string{token[0], forged[1], token[2]}, "."))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid token signature")
+	})
+	t.Run("Expired", func(t *testing.T) {
+		claims := p.claims()
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		_, err := authenticate(p.token(t, "key0", claims))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "expired")
+
+		claims["exp"] = time.Now().Add(-oidcClockSkew / 2).Unix()
+		_, err = authenticate(p.token(t, "key0", claims))
+		assert.NoError(t, err, "clock skew is tolerated")
+	})
+	t.Run("InvalidClaims", func(t *testing.T) {
+		for name, edit := range map[string]func(map[string]interface{}){
+			"Issuer":    func(c map[string]interface{}) { c["iss"] = "https://other.example.com" },
+			"Audience":  func(c map[string]interface{}) { c["aud"] = "other" },
+			"NoExpiry":  func(c map[string]interface{}) { delete(c, "exp") },
+			"NotBefore": func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() },
+			"NoSubject": func(c map[string]interface{}) { delete(c, "sub") },
+		} {
+			t.Run(name, func(t *testing.T) {
+				claims := p.claims()
+				edit(claims)
+				_, err := authenticate(p.token(t, "key0", claims))
+				assert.Error(t, err)
+			})
+		}
+	})
+	t.Run("Malformed", func(t *testing.T) {
+		for _, token := range []string{"", "token", "a.b.c", p.token(t, "key0", p.claims()) + "."} {
+			_, err := authenticate(token)
+			assert.Error(t, err)
+		}
+	})
+	t.Run("KeyRotation", func(t *testing.T) {
+		fetches := atomic.LoadInt32(&p.jwksFetches)
+		p.addKey(t, "key1")
+		token := p.token(t, "key1", p.claims())
+
+		_, err := authenticate(token)
+		require.Error(t, err, "keys were refreshed too recently")
+		assert.Contains(t, err.Error(), "unknown signing key")
+		assert.Equal(t, fetches, atomic.LoadInt32(&p.jwksFetches))
+
+		a.(*oidcAuthenticator).lastRefresh = time.Now().Add(-oidcMinKeyRefresh)
+		principal, err := authenticate(token)
+		require.NoError(t, err)
+		assert.Equal(t, "user", principal.ID)
+		assert.Equal(t, fetches+1, atomic.LoadInt32(&p.jwksFetches))
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		opts := options.OIDC{Issuer: p.URL, Audience: "cedar"}
+		require.NoError(t, opts.Validate())
+		assert.Nil(t, opts.HTTPClient, "validation does not modify the options")
+
+		_, err := NewOIDCAuthenticator(ctx, options.OIDC{Issuer: p.URL})
+		assert.Error(t, err)
+		_, err = NewOIDCAuthenticator(ctx, options.OIDC{Issuer: p.URL + "/other", Audience: "cedar"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"github.com/julianedwards/cedar/logger"
+	"github.com/pkg/errors"
+)
+
+// Option configures optional behavior of a server returned by New.
+type Option func(*Server) error
+
+// WithAuthenticator requires every request to be authenticated by the
+// authenticator. By default requests are not authenticated.
+func WithAuthenticator(a Authenticator) Option {
+	return func(s *Server) error {
+		if a == nil {
+			return errors.New("authenticator cannot be nil")
+		}
+		s.authenticator = a
+		return nil
+	}
+}
+
+// WithAuthorizer requires the authenticated client of every request to be
+// authorized by the authorizer to read the requested key. It requires an
+// authenticator.
+func WithAuthorizer(a logger.Authorizer) Option {
+	return func(s *Server) error {
+		if a == nil {
+			return errors.New("authorizer cannot be nil")
+		}
+		s.authorizer = a
+		return nil
+	}
+}
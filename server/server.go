@@ -0,0 +1,124 @@
+// Package server serves the logs of a logger over HTTP and gRPC, e.g. to
+// show the live output of running tasks in web UIs.
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/julianedwards/cedar/server/cedarpb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// Server is an http.Handler serving the logs of a logger. It serves:
+//
+//	GET /logs/ws?key=<key>      streams the key's lines over a WebSocket
+//	GET /logs/events?key=<key>  streams the key's lines as Server-Sent Events
+//
+// and the LogService gRPC service defined in proto/cedar.proto. All accept
+// the filters described by streamFilter and require the client to be
+// authorized to read the key. gRPC requires HTTP/2, so the server must be
+// served over TLS or, for cleartext connections, wrapped with h2c.
+type Server struct {
+	logger        logger.Logger
+	mux           *http.ServeMux
+	grpc          *grpc.Server
+	handler       http.Handler
+	authenticator Authenticator
+	authorizer    logger.Authorizer
+}
+
+// New returns a server for the logs of the given logger.
+func New(l logger.Logger, opts ...Option) (*Server, error) {
+	if l == nil {
+		return nil, errors.New("must specify a logger")
+	}
+
+	s := &Server{
+		logger: l,
+		mux:    http.NewServeMux(),
+		grpc:   grpc.NewServer(),
+	}
+	s.mux.Handle("/logs/ws", s.webSocketHandler())
+	s.mux.Handle("/logs/events", s.eventsHandler())
+	cedarpb.RegisterLogServiceServer(s.grpc, &logService{server: s})
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, errors.Wrap(err, "applying server option")
+		}
+	}
+	if s.authorizer != nil && s.authenticator == nil {
+		return nil, errors.New("cannot authorize requests without an authenticator")
+	}
+	s.handler = s.authenticate(http.HandlerFunc(s.route))
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// route passes gRPC requests to the gRPC server and all others to the HTTP
+// endpoints.
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		s.grpc.ServeHTTP(w, r)
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorize returns an AuthorizationError unless the authenticated client
+// of the request with the given context may perform the operation on the
+// key. Keys failing logger.CheckKeyPath are never authorized.
+func (s *Server) authorize(ctx context.Context, perm logger.Permission, key string) error {
+	if s.authorizer == nil {
+		return nil
+	}
+	principal := logger.PrincipalFromContext(ctx)
+	if err := logger.CheckKeyPath(key); err != nil {
+		return errors.Wrap(&logger.AuthorizationError{Principal: principal, Permission: perm, Key: key}, err.Error())
+	}
+
+	return s.authorizer.Authorize(ctx, principal, perm, key)
+}
+
+// streamLines sends the lines of the key matching the filter to send, along
+// with their 1-based positions among all lines of the key, starting after
+// the given position. If the filter follows the log, the lines of chunks
+// flushed to the key afterwards are sent until the key is marked complete,
+// no chunk is flushed for the follow timeout, or the context is done.
+func (s *Server) streamLines(ctx context.Context, key string, filter streamFilter, after int, send func(int, logger.LogLine) error) error {
+	r, err := s.logger.NewReadCloser(ctx, options.Read{Key: key, Follow: filter.follow})
+	if err != nil {
+		return errors.Wrapf(err, "reading '%s'", key)
+	}
+	defer r.Close()
+
+	var pos int
+	for {
+		var lines []logger.LogLine
+		if err = r.DecodePage(&lines); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrapf(err, "reading '%s'", key)
+		}
+
+		for _, line := range lines {
+			pos++
+			if pos <= after || !filter.match(line) {
+				continue
+			}
+			if err = send(pos, line); err != nil {
+				return err
+			}
+		}
+	}
+}
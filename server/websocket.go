@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/julianedwards/cedar/logger"
+	"golang.org/x/net/websocket"
+)
+
+// streamError is sent to clients in place of a line if streaming fails.
+type streamError struct {
+	Error string `json:"error"`
+}
+
+// webSocketHandler streams the lines of the key given by the "key" query
+// parameter, followed by newly flushed lines if following, to the client as
+// JSON text messages, one per line. The connection is closed once the stream
+// ends, after sending a streamError message if streaming failed. Invalid
+// requests are rejected before upgrading the connection.
+func (s *Server) webSocketHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			http.Error(w, "must specify a key", http.StatusBadRequest)
+			return
+		}
+		filter, err := parseStreamFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = s.authorize(r.Context(), logger.PermissionRead, key); err != nil {
+			http.Error(w, err.Error(), authorizationStatus(err))
+			return
+		}
+
+		websocket.Handler(func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			// Clients do not send messages, so a failed read means
+			// the client went away.
+			go func() {
+				defer cancel()
+				_, _ = io.Copy(io.Discard, ws)
+			}()
+
+			err := s.streamLines(ctx, key, filter, 0, func(_ int, line logger.LogLine) error {
+				return websocket.JSON.Send(ws, line)
+			})
+			if err != nil && ctx.Err() == nil {
+				_ = websocket.JSON.Send(ws, streamError{Error: err.Error()})
+			}
+		}).ServeHTTP(w, r)
+	})
+}
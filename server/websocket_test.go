@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	ctx := context.Background()
+	l, err := logger.NewBucketLogger(ctx, options.Bucket{Type: options.PailLocal, Name: t.TempDir(), Prefix: "test"})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line0\nline1\n"}))
+	require.NoError(t, l.Write(ctx, options.Write{Key: "key", Data: "line2\n"}))
+	require.NoError(t, l.MarkComplete(ctx, "key"))
+	s, err := New(l)
+	require.NoError(t, err)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	dial := func(t *testing.T, query string) *websocket.Conn {
+		ws, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/logs/ws"+query, "", ts.URL)
+		require.NoError(t, err)
+		return ws
+	}
+
+	t.Run("Streams", func(t *testing.T) {
+		ws := dial(t, "?key=key")
+		defer ws.Close()
+		var data []string
+		for {
+			var line logger.LogLine
+			if err := websocket.JSON.Receive(ws, &line); err == io.EOF {
+				break
+			} else {
+				require.NoError(t, err)
+			}
+			data = append(data, line.Data.(string))
+		}
+		assert.Equal(t, []string{"line0", "line1", "line2"}, data)
+	})
+	t.Run("InvalidRequest", func(t *testing.T) {
+		for _, query := range []string{"", "?key=key&level=loud"} {
+			resp, err := http.Get(ts.URL + "/logs/ws" + query)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "invalid requests are rejected before upgrading")
+		}
+	})
+	t.Run("NilLogger", func(t *testing.T) {
+		_, err := New(nil)
+		assert.Error(t, err)
+	})
+}
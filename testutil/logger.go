@@ -0,0 +1,337 @@
+// Package testutil provides helpers for unit testing code that logs through
+// the cedar logger package without creating real pail buckets.
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/julianedwards/cedar/encode"
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/pkg/errors"
+)
+
+// MockLogger is a logger.Logger that records every call in memory. Errors
+// returned by each method are configurable via the exported fields, which
+// should be set before the logger is used. The zero value is ready to use,
+// and it is safe for concurrent use.
+type MockLogger struct {
+	AddMetadataError error
+	WriteError       error
+	WriteBytesError  error
+	WriteReaderError error
+	FollowFileError  error
+	ReadError        error
+	CheckError       error
+
+	mu          sync.Mutex
+	metadata    []options.AddMetadata
+	writes      []options.Write
+	writeBytes  []options.WriteBytes
+	writeReader []options.WriteReader
+	followFile  []options.FollowFile
+	data        map[string][]byte
+}
+
+var _ logger.Logger = &MockLogger{}
+
+func (m *MockLogger) AddMetadata(_ context.Context, opts options.AddMetadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metadata = append(m.metadata, opts)
+
+	return m.AddMetadataError
+}
+
+// Write records the call. If the data is a slice of log lines, it is copied
+// since callers such as the sender reuse their buffers after a flush.
+func (m *MockLogger) Write(_ context.Context, opts options.Write) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if lines, ok := opts.Data.([]logger.LogLine); ok {
+		opts.Data = append([]logger.LogLine(nil), lines...)
+	}
+	m.writes = append(m.writes, opts)
+
+	return m.WriteError
+}
+
+// WriteBytes records the call and, if no error is configured, appends the
+// data to that read back for the key.
+func (m *MockLogger) WriteBytes(_ context.Context, opts options.WriteBytes) (logger.WriteResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	opts.Data = append([]byte(nil), opts.Data...)
+	m.writeBytes = append(m.writeBytes, opts)
+	if m.WriteBytesError != nil {
+		return logger.WriteResult{}, m.WriteBytesError
+	}
+	m.appendData(opts.Key, opts.Data)
+
+	return logger.WriteResult{Key: opts.Key}, nil
+}
+
+// WriteReader records the call and, if no error is configured, reads the
+// data to completion and appends it to that read back for the key.
+func (m *MockLogger) WriteReader(_ context.Context, opts options.WriteReader) error {
+	var (
+		data []byte
+		err  error
+	)
+	if opts.Reader != nil && m.WriteReaderError == nil {
+		data, err = io.ReadAll(opts.Reader)
+		if err != nil {
+			return errors.Wrap(err, "reading data")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.writeReader = append(m.writeReader, opts)
+	if m.WriteReaderError != nil {
+		return m.WriteReaderError
+	}
+	m.appendData(opts.Key, data)
+
+	return nil
+}
+
+// FollowFile records the call and returns immediately without reading the
+// file.
+func (m *MockLogger) FollowFile(_ context.Context, opts options.FollowFile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.followFile = append(m.followFile, opts)
+
+	return m.FollowFileError
+}
+
+// NewReadCloser returns a reader over the data written to the key via
+// WriteBytes and WriteReader.
+func (m *MockLogger) NewReadCloser(_ context.Context, opts options.Read) (logger.ReadCloser, error) {
+	return m.newReadCloser(opts)
+}
+
+// NewReverseReadCloser is the same as NewReadCloser, since the mock logger
+// does not track chunk boundaries.
+func (m *MockLogger) NewReverseReadCloser(_ context.Context, opts options.Read) (logger.ReadCloser, error) {
+	return m.newReadCloser(opts)
+}
+
+// ReadLines returns all log lines written to the key via Write as a single
+// page.
+func (m *MockLogger) ReadLines(_ context.Context, opts options.Read) ([]logger.LogLine, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ReadError != nil {
+		return nil, "", m.ReadError
+	}
+
+	var lines []logger.LogLine
+	for _, w := range m.writes {
+		if l, ok := w.Data.([]logger.LogLine); ok && w.Key == opts.Key {
+			lines = append(lines, l...)
+		}
+	}
+
+	return lines, "", nil
+}
+
+// Search matches the log lines written to the key via Write, treating each
+// Write call as a chunk.
+func (m *MockLogger) Search(_ context.Context, opts options.Search) ([]logger.SearchResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	re := regexp.MustCompile(opts.Regexp)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ReadError != nil {
+		return nil, m.ReadError
+	}
+
+	var results []logger.SearchResult
+	for i, w := range m.writes {
+		lines, ok := w.Data.([]logger.LogLine)
+		if !ok || w.Key != opts.Key {
+			continue
+		}
+		for j, line := range lines {
+			if !opts.Start.IsZero() && line.Timestamp.Before(opts.Start) {
+				continue
+			}
+			if !opts.End.IsZero() && !line.Timestamp.Before(opts.End) {
+				continue
+			}
+			if re.MatchString(fmt.Sprint(line.Data)) {
+				results = append(results, logger.SearchResult{Chunk: strconv.Itoa(i), Line: j, LogLine: line})
+			}
+			if opts.Limit > 0 && len(results) == opts.Limit {
+				return results, nil
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Check returns CheckError.
+func (m *MockLogger) Check(context.Context) error {
+	return m.CheckError
+}
+
+// Child returns a view of the mock logger whose calls are recorded with
+// keys nested under the prefix.
+func (m *MockLogger) Child(prefix string) logger.Logger { return logger.NewChildLogger(m, prefix) }
+
+// appendData appends to the data read back for the key. The caller must hold
+// the lock.
+func (m *MockLogger) appendData(key string, data []byte) {
+	if m.data == nil {
+		m.data = map[string][]byte{}
+	}
+	m.data[key] = append(m.data[key], data...)
+}
+
+func (m *MockLogger) newReadCloser(opts options.Read) (logger.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ReadError != nil {
+		return nil, m.ReadError
+	}
+
+	return &mockReadCloser{Reader: bytes.NewReader(append([]byte(nil), m.data[opts.Key]...))}, nil
+}
+
+// AddMetadataCalls returns the recorded AddMetadata calls.
+func (m *MockLogger) AddMetadataCalls() []options.AddMetadata {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]options.AddMetadata(nil), m.metadata...)
+}
+
+// WriteCalls returns the recorded Write calls.
+func (m *MockLogger) WriteCalls() []options.Write {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]options.Write(nil), m.writes...)
+}
+
+// WriteBytesCalls returns the recorded WriteBytes calls.
+func (m *MockLogger) WriteBytesCalls() []options.WriteBytes {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]options.WriteBytes(nil), m.writeBytes...)
+}
+
+// WriteReaderCalls returns the recorded WriteReader calls.
+func (m *MockLogger) WriteReaderCalls() []options.WriteReader {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]options.WriteReader(nil), m.writeReader...)
+}
+
+// FollowFileCalls returns the recorded FollowFile calls.
+func (m *MockLogger) FollowFileCalls() []options.FollowFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]options.FollowFile(nil), m.followFile...)
+}
+
+// Lines returns all log lines written via Write, in order, across all keys.
+func (m *MockLogger) Lines() []logger.LogLine {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var lines []logger.LogLine
+	for _, w := range m.writes {
+		if l, ok := w.Data.([]logger.LogLine); ok {
+			lines = append(lines, l...)
+		}
+	}
+
+	return lines
+}
+
+// Reset clears all recorded calls and data. Configured errors are kept.
+func (m *MockLogger) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metadata = nil
+	m.writes = nil
+	m.writeBytes = nil
+	m.writeReader = nil
+	m.followFile = nil
+	m.data = map[string][]byte{}
+}
+
+type mockReadCloser struct {
+	*bytes.Reader
+}
+
+func (r *mockReadCloser) ReadPage() ([]byte, error) {
+	if r.Len() == 0 {
+		return nil, io.EOF
+	}
+
+	return io.ReadAll(r.Reader)
+}
+
+// DecodePage decodes the next page as plain text.
+func (r *mockReadCloser) DecodePage(v interface{}) error {
+	data, err := r.ReadPage()
+	if err != nil {
+		return err
+	}
+
+	e, _ := encode.GetGlobalRegistry().Get(encode.TEXT)
+	return e.Unmarshal(data, v)
+}
+
+// PageToken always returns an empty string since the mock logger returns
+// all data as a single page.
+func (r *mockReadCloser) PageToken() string { return "" }
+
+// Checkpoint returns the byte offset of the reader, or an empty string once
+// all data has been read.
+func (r *mockReadCloser) Checkpoint() string {
+	if r.Len() == 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(r.Size()-int64(r.Len()), 10)
+}
+
+// Resume seeks to the byte offset of a token returned by Checkpoint.
+func (r *mockReadCloser) Resume(token string) error {
+	offset, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return errors.Wrap(err, "parsing checkpoint token")
+	}
+
+	_, err = r.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (r *mockReadCloser) Close() error { return nil }
@@ -0,0 +1,79 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/julianedwards/cedar/logger"
+	"github.com/julianedwards/cedar/options"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockLogger(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RecordsSenderWrites", func(t *testing.T) {
+		m := &MockLogger{}
+		s, err := logger.NewSender(ctx, m, options.Sender{
+			Key:           "key",
+			Local:         send.MakeInternalLogger(),
+			LevelInfo:     &send.LevelInfo{Default: level.Info, Threshold: level.Debug},
+			FlushInterval: -1,
+		})
+		require.NoError(t, err)
+
+		s.Send(message.NewDefaultMessage(level.Info, "first"))
+		require.NoError(t, s.Flush(ctx))
+		s.Send(message.NewDefaultMessage(level.Info, "second"))
+		require.NoError(t, s.Close())
+
+		assert.Len(t, m.WriteCalls(), 2)
+		lines := m.Lines()
+		require.Len(t, lines, 2)
+		for i, data := range []string{"first", "second"} {
+			assert.Equal(t, data, lines[i].Data.(message.Composer).String())
+		}
+	})
+	t.Run("ReadsBackWrittenData", func(t *testing.T) {
+		m := &MockLogger{}
+		_, err := m.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("a\n")})
+		require.NoError(t, err)
+		require.NoError(t, m.WriteReader(ctx, options.WriteReader{Key: "key", Reader: strings.NewReader("b\n")}))
+
+		r, err := m.NewReadCloser(ctx, options.Read{Key: "key"})
+		require.NoError(t, err)
+		data, err := r.ReadPage()
+		require.NoError(t, err)
+		assert.Equal(t, "a\nb\n", string(data))
+		_, err = r.ReadPage()
+		assert.Equal(t, io.EOF, err)
+		assert.Len(t, m.WriteBytesCalls(), 1)
+		assert.Len(t, m.WriteReaderCalls(), 1)
+	})
+	t.Run("ConfiguredErrors", func(t *testing.T) {
+		m := &MockLogger{
+			WriteError:      errors.New("write"),
+			WriteBytesError: errors.New("write bytes"),
+			ReadError:       errors.New("read"),
+		}
+		assert.EqualError(t, m.Write(ctx, options.Write{Key: "key", Data: "data"}), "write")
+		_, err := m.WriteBytes(ctx, options.WriteBytes{Key: "key", Data: []byte("data")})
+		assert.EqualError(t, err, "write bytes")
+		_, err = m.NewReadCloser(ctx, options.Read{Key: "key"})
+		assert.EqualError(t, err, "read")
+		assert.Len(t, m.WriteCalls(), 1)
+		assert.Len(t, m.WriteBytesCalls(), 1)
+
+		m.Reset()
+		assert.Empty(t, m.WriteCalls())
+		assert.Empty(t, m.WriteBytesCalls())
+		assert.Error(t, m.Write(ctx, options.Write{Key: "key", Data: "data"}))
+	})
+}